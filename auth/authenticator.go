@@ -48,6 +48,10 @@
 // placed on the connection itself), the passwords themselves are handled on both client
 // and server in plaintext form.
 //
+// The server and clients may provide that protection by connecting over TLS (see the
+// -tls-cert option of the server and mapper.WithTLSConfig). In that case, the server may
+// also accept client certificates in place of passwords.
+//
 // In case you missed it above, DO NOT USE this authenticator for ANYTHING that is worth
 // protecting. We only use it to play a game together.
 //
@@ -153,6 +157,14 @@
 // encoded representation of the response to the challenge (D above), and the optional <user> and
 // <client> values are the desired user name and description of the client program.
 //
+// If the client logged in with a TLS client certificate the server trusts, the server's
+// greeting includes no challenge, but instead the user named by the certificate:
+//
+//	(server->client) OK {"Protocol":<v>, "CertificateUser":"<user>"}
+//
+// The client then sends an AUTH line without a <response>, so the server knows which
+// client program it is.
+//
 //	(server->client) DENIED {"Reason":"<message>"}
 //
 // Server response indicating that the authentication was unsuccessful.
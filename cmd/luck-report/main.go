@@ -0,0 +1,273 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+#
+# Adapted for the Pathfinder RPG, which is what we're playing now
+# (and this software is primarily for our own use in our play group,
+# anyway, but could be generalized later as a stand-alone product).
+#
+# Copyright (c) 2026 by Steven L. Willoughby, Aloha, Oregon, USA.
+# All Rights Reserved.
+# Licensed under the terms and conditions of the BSD 3-Clause license.
+#
+# Based on earlier code by the same author, unreleased for the author's
+# personal use; copyright (c) 1992-2019.
+#
+########################################################################
+*/
+
+/*
+Luck-report connects to a GMA server and reports statistics about the d20 rolls each player
+has made through the server's die roller, such as how many natural 20s and natural 1s they
+rolled, their average d20 roll, and their longest streaks of good and bad rolls.
+This provides material for end-of-session “luck awards” as well as data to settle
+arguments about whether anyone's dice are broken.
+
+Rolls made privately to the GM are only included if you log in as the GM.
+
+# OPTIONS
+
+The following options control the action of luck-report.
+
+	−endpoint [hostname]: port
+	   Connect to the server at the specified TCP port.
+
+	−for username[,username...]
+	   Report only on the named users (default is everyone).
+
+	−json
+	   Print the statistics as JSON instead of a text report.
+
+	−pass password
+	   Log in to the server with the specified password
+
+	−rolls
+	   List each die roll as well as the statistics.
+
+	−since time
+	   Only include rolls made at or after the given time, which may be
+	   a date and time (e.g., 2026-10-16 or 2026-10-16T19:00:00) or a
+	   duration before the present time (e.g., 4h30m).
+
+	−until time
+	   Only include rolls made before the given time (in the same format as −since).
+
+	−user username
+	   Log in to the server with the specified username (default “GM”).
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/MadScienceZone/go-gma/v5/auth"
+	"github.com/MadScienceZone/go-gma/v5/dice"
+	"github.com/MadScienceZone/go-gma/v5/mapper"
+)
+
+func main() {
+	var fEndpoint = flag.String("endpoint", "", "endpoint of server to query")
+	var fUser = flag.String("user", "GM", "username to log in to server as [default=GM]")
+	var fPass = flag.String("pass", "", "password to log in to server")
+	var fFor = flag.String("for", "", "comma-separated list of users to report on [default is everyone]")
+	var fSince = flag.String("since", "", "only report rolls made since this date/time or duration ago")
+	var fUntil = flag.String("until", "", "only report rolls made before this date/time or duration ago")
+	var fJSON = flag.Bool("json", false, "output JSON instead of a text report")
+	var fRolls = flag.Bool("rolls", false, "list the individual die rolls too")
+
+	flag.Parse()
+
+	since, err := parseTime(*fSince)
+	if err != nil {
+		fmt.Printf("invalid -since value: %v\n", err)
+		os.Exit(1)
+	}
+	until, err := parseTime(*fUntil)
+	if err != nil {
+		fmt.Printf("invalid -until value: %v\n", err)
+		os.Exit(1)
+	}
+	var users []string
+	if *fFor != "" {
+		for _, user := range strings.Split(*fFor, ",") {
+			users = append(users, strings.TrimSpace(user))
+		}
+	}
+
+	replies := make(chan mapper.MessagePayload, 1)
+	ready := make(chan byte, 1)
+
+	server, err := mapper.NewConnection(*fEndpoint,
+		mapper.WithAuthenticator(auth.NewClientAuthenticator(*fUser, []byte(*fPass), "luck-report")),
+		mapper.WithSubscription(replies, mapper.UpdateRollHistory, mapper.Failed),
+		mapper.WhenReady(ready),
+	)
+	if err != nil {
+		fmt.Printf("can't set up server connection: %v\n", err)
+		os.Exit(1)
+	}
+	go server.Dial()
+	<-ready
+
+	if err := server.QueryRollHistory(users, since, until, 0); err != nil {
+		fmt.Printf("can't query roll history: %v\n", err)
+		os.Exit(1)
+	}
+
+	var history []dice.RollHistoryEntry
+	switch reply := (<-replies).(type) {
+	case mapper.UpdateRollHistoryMessagePayload:
+		history = reply.Rolls
+	case mapper.FailedMessagePayload:
+		fmt.Printf("server could not report roll history: %s\n", reply.Reason)
+		os.Exit(1)
+	}
+
+	report := dice.LuckReport(history)
+	if *fJSON {
+		data := struct {
+			Stats []dice.LuckStats
+			Rolls []dice.RollHistoryEntry `json:",omitempty"`
+		}{Stats: report}
+		if *fRolls {
+			data.Rolls = history
+		}
+		j, err := json.MarshalIndent(data, "", "    ")
+		if err != nil {
+			fmt.Printf("can't format JSON output: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(j))
+		return
+	}
+
+	if *fRolls {
+		for _, roll := range history {
+			fmt.Printf("%s %-12s %4d %s", roll.When.Local().Format("2006-01-02 15:04:05"), roll.User, roll.Result, roll.RollSpec)
+			if roll.ToGM {
+				fmt.Print(" (to GM)")
+			}
+			fmt.Println()
+		}
+		fmt.Println()
+	}
+	printReport(report)
+}
+
+// parseTime interprets a -since or -until value, which may be empty (for no limit),
+// a date and time, or a duration before the present.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("\"%s\" is not a date/time or duration", value)
+}
+
+// printReport prints the statistics for each user followed by the luck awards.
+func printReport(report []dice.LuckStats) {
+	if len(report) == 0 {
+		fmt.Println("No die rolls found.")
+		return
+	}
+
+	fmt.Printf("%-16s %6s %6s %6s %6s %7s %5s %5s\n", "PLAYER", "ROLLS", "d20s", "NAT20", "NAT1", "AVG d20", "HOT", "COLD")
+	for _, s := range report {
+		fmt.Printf("%-16s %6d %6d %6d %6d %7.2f %5d %5d\n", s.User, s.Rolls, s.D20s, s.Natural20s, s.Natural1s, s.AverageD20, s.LongestHotStreak, s.LongestColdStreak)
+	}
+	fmt.Printf("(the average of a fair d20 is 10.50; HOT and COLD are the longest streaks of d20 rolls of 11+ and 10-)\n\n")
+
+	award := func(title string, value func(dice.LuckStats) float64, lowest bool, format string) {
+		var winners []string
+		var best float64
+		for _, s := range report {
+			if s.D20s == 0 {
+				continue
+			}
+			v := value(s)
+			if winners == nil || (lowest && v < best) || (!lowest && v > best) {
+				winners = []string{s.User}
+				best = v
+			} else if v == best {
+				winners = append(winners, s.User)
+			}
+		}
+		if winners != nil {
+			fmt.Printf("%-24s %s ("+format+")\n", title, strings.Join(winners, ", "), best)
+		}
+	}
+
+	award("Luckiest Dice:", func(s dice.LuckStats) float64 { return s.AverageD20 }, false, "average %.2f")
+	award("Most Cursed Dice:", func(s dice.LuckStats) float64 { return s.AverageD20 }, true, "average %.2f")
+	award("Most Natural 20s:", func(s dice.LuckStats) float64 { return float64(s.Natural20s) }, false, "%.0f")
+	award("Most Natural 1s:", func(s dice.LuckStats) float64 { return float64(s.Natural1s) }, false, "%.0f")
+	award("Longest Hot Streak:", func(s dice.LuckStats) float64 { return float64(s.LongestHotStreak) }, false, "%.0f in a row")
+	award("Longest Cold Streak:", func(s dice.LuckStats) float64 { return float64(s.LongestColdStreak) }, false, "%.0f in a row")
+}
+
+/*
+# @[00]@| Go-GMA 5.33.0
+# @[01]@|
+# @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+# @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+# @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+# @[13]@| points along that historical time line.
+# @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+# @[15]@| License as described in the accompanying LICENSE file distributed
+# @[16]@| with GMA.
+# @[17]@|
+# @[20]@| Redistribution and use in source and binary forms, with or without
+# @[21]@| modification, are permitted provided that the following conditions
+# @[22]@| are met:
+# @[23]@| 1. Redistributions of source code must retain the above copyright
+# @[24]@|    notice, this list of conditions and the following disclaimer.
+# @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+# @[26]@|    right notice, this list of conditions and the following dis-
+# @[27]@|    claimer in the documentation and/or other materials provided
+# @[28]@|    with the distribution.
+# @[29]@| 3. Neither the name of the copyright holder nor the names of its
+# @[30]@|    contributors may be used to endorse or promote products derived
+# @[31]@|    from this software without specific prior written permission.
+# @[32]@|
+# @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+# @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+# @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+# @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+# @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+# @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+# @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+# @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+# @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+# @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+# @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+# @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+# @[45]@| SUCH DAMAGE.
+# @[46]@|
+# @[50]@| This software is not intended for any use or application in which
+# @[51]@| the safety of lives or property would be at risk due to failure or
+# @[52]@| defect of the software.
+*/
@@ -70,6 +70,18 @@ You may not combine multiple single-letter options into a single composite argum
 	      mono
 	      debug=i/o
 
+	      The file may also set the following options which have no
+	      command-line equivalent, to connect to the server over TLS:
+
+	      tls                   Connect using TLS.
+	      tls-pin=fingerprints  Trust only a server certificate with one of these
+	                            (comma-separated) SHA-256 fingerprints.
+	      tls-ca-cert=file      Trust server certificates signed by this CA.
+	      tls-client-cert=file  Log in with this client certificate...
+	      tls-client-key=file   ...and its private key.
+
+	      These may also be set in the server profile selected with -select.
+
 	  -D, -debug flags
 	      Adds debugging messages to map-console's output. The flags
 	      value is a comma-separated list of debug flag names, which
@@ -363,6 +375,7 @@ func main() {
 			mapper.PlayAudio,
 			mapper.RemoveObjAttributes,
 			mapper.RollResult,
+			mapper.RollSeed,
 			mapper.TimerAcknowledge,
 			mapper.TimerRequest,
 			mapper.Toolbar,
@@ -374,6 +387,8 @@ func main() {
 			mapper.UpdateObjAttributes,
 			mapper.UpdatePeerList,
 			mapper.UpdateProgress,
+			mapper.UpdateRandomTables,
+			mapper.UpdateRollHistory,
 			mapper.UpdateStatusMarker,
 			mapper.UpdateTurn,
 		),
@@ -386,6 +401,13 @@ func main() {
 			fmt.Sprintf("map-console %s", GoVersionNumber))
 		conOpts = append(conOpts, mapper.WithAuthenticator(a))
 	}
+	tlsConfig, err := prefs.Prefs.Profiles[prefs.SelectedIdx].TLSConfig()
+	if err != nil {
+		log.Fatalf("unable to set up TLS: %v", err)
+	}
+	if tlsConfig != nil {
+		conOpts = append(conOpts, mapper.WithTLSConfig(tlsConfig))
+	}
 	server, conerr := mapper.NewConnection(fmt.Sprintf("%s:%d",
 		prefs.Prefs.Profiles[prefs.SelectedIdx].Host,
 		prefs.Prefs.Profiles[prefs.SelectedIdx].Port),
@@ -1050,6 +1072,28 @@ func describeIncomingMessage(msg mapper.MessagePayload, mono bool, cal gma.Calen
 			fieldDesc{"targets", m.Targets},
 			fieldDesc{"type", m.Type},
 		)
+		if m.Verification != nil {
+			printFields(mono, "  verification",
+				fieldDesc{"commitment", m.Verification.Commitment},
+				fieldDesc{"sequence", m.Verification.Sequence},
+				fieldDesc{"spec", m.Verification.RollSpec},
+			)
+		}
+
+	case mapper.RollSeedMessagePayload:
+		if m.Revealed != nil {
+			printFields(mono, "RollSeed",
+				fieldDesc{"user", m.User},
+				fieldDesc{"commitment", m.Commitment},
+				fieldDesc{"seed", m.Revealed.Seed},
+				fieldDesc{"salt", m.Revealed.Salt},
+			)
+		} else {
+			printFields(mono, "RollSeed",
+				fieldDesc{"user", m.User},
+				fieldDesc{"commitment", m.Commitment},
+			)
+		}
 
 	case mapper.TimerAcknowledgeMessagePayload:
 		printFields(mono, "TimerAcknowledge",
@@ -1127,6 +1171,37 @@ func describeIncomingMessage(msg mapper.MessagePayload, mono bool, cal gma.Calen
 			)
 		}
 
+	case mapper.UpdateRollHistoryMessagePayload:
+		printFields(mono, "UpdateRollHistory",
+			fieldDesc{"rolls", len(m.Rolls)},
+		)
+		for i, r := range m.Rolls {
+			printFields(mono, colorize(fmt.Sprintf("  [%02d] ", i), "Blue", mono),
+				fieldDesc{"user", r.User},
+				fieldDesc{"when", r.When},
+				fieldDesc{"spec", r.RollSpec},
+				fieldDesc{"result", r.Result},
+				fieldDesc{"toall", r.ToAll},
+				fieldDesc{"to", r.Recipients},
+				fieldDesc{"togm", r.ToGM},
+			)
+		}
+
+	case mapper.UpdateRandomTablesMessagePayload:
+		printFields(mono, "UpdateRandomTables",
+			fieldDesc{"global", m.Global},
+			fieldDesc{"for", m.For},
+		)
+		for i, t := range m.Tables {
+			printFields(mono, colorize(fmt.Sprintf("  [%02d] ", i), "Blue", mono),
+				fieldDesc{"g", t.Global},
+				fieldDesc{"name", t.Name},
+				fieldDesc{"desc", t.Description},
+				fieldDesc{"roll", t.Roll},
+				fieldDesc{"entries", len(t.Entries)},
+			)
+		}
+
 	case mapper.UpdateInitiativeMessagePayload:
 		printFields(mono, "UpdateInitiative")
 		printFields(mono, "",
@@ -0,0 +1,296 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+#
+# Adapted for the Pathfinder RPG, which is what we're playing now
+# (and this software is primarily for our own use in our play group,
+# anyway, but could be generalized later as a stand-alone product).
+#
+# Copyright (c) 2026 by Steven L. Willoughby, Aloha, Oregon, USA.
+# All Rights Reserved.
+# Licensed under the terms and conditions of the BSD 3-Clause license.
+#
+# Based on earlier code by the same author, unreleased for the author's
+# personal use; copyright (c) 1992-2019.
+#
+########################################################################
+*/
+
+/*
+Map-replay plays back a session recording, such as those made by the GMA server when started with
+its −record-dir option, so that problems seen during a live game can be reproduced later.
+
+It can take the part of the client, connecting to a server and sending it the messages the client
+sent during the recorded session, or take the part of the server, waiting for a client to connect
+and then sending it the messages the server sent during the recorded session. Either way, the
+messages are sent at the same pace as they were originally, unless the −speed option is given.
+After playing the part of the server, map-replay waits for the client to disconnect before exiting.
+The messages received from the other side are not interpreted, but they may themselves be recorded
+with the −record option to compare with the original session.
+
+When taking the part of the client, map-replay logs in to the server on its own, so it does not
+replay the client's authentication messages from the recording, nor its replies to the server's pings.
+
+# OPTIONS
+
+The following options control the action of map-replay. Exactly one of −endpoint or −listen
+must be given.
+
+	−endpoint [hostname]: port
+	   Connect to the server at the specified TCP port and play the part of the client.
+
+	−listen [hostname]: port
+	   Wait for a client to connect to the specified TCP port and play the part of the server.
+
+	−pass password
+	   Log in to the server with the specified password
+
+	−record file
+	   Record the replayed session in the named file.
+
+	−speed factor
+	   Play back the recording factor times as fast as it was recorded (default 1).
+	   If factor is 0, the messages are sent without pausing between them.
+
+	−user username
+	   Log in to the server with the specified username (default “GM”).
+*/
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/MadScienceZone/go-gma/v5/auth"
+	"github.com/MadScienceZone/go-gma/v5/mapper"
+	"github.com/MadScienceZone/go-gma/v5/util"
+)
+
+// replayDone is the string we echo from the server to know it has
+// processed everything we sent it.
+const replayDone = "map-replay finished"
+
+func main() {
+	var fEndpoint = flag.String("endpoint", "", "endpoint of server to replay the client's messages into")
+	var fListen = flag.String("listen", "", "endpoint on which to wait for a client to replay the server's messages into")
+	var fUser = flag.String("user", "GM", "username to log in to server as [default=GM]")
+	var fPass = flag.String("pass", "", "password to log in to server")
+	var fRecord = flag.String("record", "", "record the replayed session in this file")
+	var fSpeed = flag.Float64("speed", 1, "playback speed relative to the original session (0 to send without pausing)")
+
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Printf("You need to specify the session recording to be played back.\n")
+		os.Exit(1)
+	}
+	if (*fEndpoint == "") == (*fListen == "") {
+		fmt.Printf("You need to specify either -endpoint or -listen (but not both).\n")
+		os.Exit(1)
+	}
+	if *fSpeed < 0 {
+		fmt.Printf("The -speed value may not be negative.\n")
+		os.Exit(1)
+	}
+
+	input, err := os.Open(flag.Arg(0))
+	if err != nil {
+		fmt.Printf("can't open recording: %v\n", err)
+		os.Exit(1)
+	}
+	defer input.Close()
+	recording := mapper.NewRecordingReader(input)
+
+	var recorder *mapper.Recorder
+	if *fRecord != "" {
+		if recorder, err = mapper.CreateRecording(*fRecord); err != nil {
+			fmt.Printf("can't create recording: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	var count int
+	if *fEndpoint != "" {
+		count, err = replayToServer(recording, *fEndpoint, *fUser, *fPass, *fSpeed, recorder)
+	} else {
+		count, err = replayToClient(recording, *fListen, *fSpeed, recorder)
+	}
+	if recorder != nil {
+		if cerr := recorder.Close(); cerr != nil {
+			fmt.Printf("error saving recording: %v\n", cerr)
+		}
+	}
+	if err != nil {
+		fmt.Printf("replay stopped after %d %s: %v\n", count, util.PluralizeString("message", count), err)
+		os.Exit(1)
+	}
+	fmt.Printf("Replayed %d %s.\n", count, util.PluralizeString("message", count))
+}
+
+// replayToServer connects to the server at endpoint and sends it the client's
+// messages from the recording. It returns the number of messages sent.
+func replayToServer(recording *mapper.RecordingReader, endpoint, user, pass string, speed float64, recorder *mapper.Recorder) (int, error) {
+	echoes := make(chan mapper.MessagePayload, 1)
+	ready := make(chan byte, 1)
+	opts := []mapper.ConnectionOption{
+		mapper.WithAuthenticator(auth.NewClientAuthenticator(user, []byte(pass), "map-replay")),
+		mapper.WithSubscription(echoes, mapper.Echo),
+		mapper.WhenReady(ready),
+	}
+	if recorder != nil {
+		opts = append(opts, mapper.WithRecorder(recorder))
+	}
+
+	server, err := mapper.NewConnection(endpoint, opts...)
+	if err != nil {
+		return 0, fmt.Errorf("can't set up server connection: %v", err)
+	}
+	go server.Dial()
+	fmt.Printf("Waiting for server to be ready\n")
+	<-ready
+
+	// The recording may include echo requests of its own, so watch for ours.
+	done := make(chan byte)
+	go func() {
+		for p := range echoes {
+			if echo, ok := p.(mapper.EchoMessagePayload); ok && echo.S == replayDone {
+				close(done)
+				return
+			}
+		}
+	}()
+
+	var count int
+	err = recording.Replay(context.Background(), false, speed, func(m mapper.RecordedMessage) error {
+		if command, _, _ := strings.Cut(m.Message, " "); command == "AUTH" || command == "POLO" {
+			return nil
+		}
+		count++
+		return server.UNSAFEsendRaw(m.Message)
+	})
+	if err != nil {
+		return count, err
+	}
+
+	fmt.Printf("Server sync...\n")
+	if err := server.EchoString(replayDone); err != nil {
+		return count, fmt.Errorf("can't send echo to server: %v", err)
+	}
+	<-done
+	return count, nil
+}
+
+// replayToClient waits for a client to connect to endpoint and sends it the
+// server's messages from the recording, then waits for the client to disconnect.
+// It returns the number of messages sent.
+func replayToClient(recording *mapper.RecordingReader, endpoint string, speed float64, recorder *mapper.Recorder) (int, error) {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+
+	fmt.Printf("Waiting for a client to connect to %v\n", listener.Addr())
+	socket, err := listener.Accept()
+	if err != nil {
+		return 0, err
+	}
+
+	var opts []mapper.ClientConnectionOption
+	if recorder != nil {
+		opts = append(opts, mapper.WithClientRecorder(recorder))
+	}
+	client, err := mapper.NewClientConnection(socket, opts...)
+	if err != nil {
+		socket.Close()
+		return 0, err
+	}
+	defer client.Close()
+	fmt.Printf("Client connected from %s\n", client.Address)
+
+	// We don't do anything with what the client sends us, but we need to
+	// read it so it doesn't back up (and so it's recorded).
+	disconnected := make(chan byte)
+	go func() {
+		defer close(disconnected)
+		for {
+			if p, err := client.Conn.Receive(); p == nil || err != nil {
+				return
+			}
+		}
+	}()
+
+	var count int
+	err = recording.Replay(context.Background(), true, speed, func(m mapper.RecordedMessage) error {
+		count++
+		if err := client.Conn.UNSAFEsendRaw(m.Message); err != nil {
+			return err
+		}
+		return client.Conn.Flush()
+	})
+	if err != nil {
+		return count, err
+	}
+
+	fmt.Printf("Waiting for the client to disconnect\n")
+	<-disconnected
+	return count, nil
+}
+
+/*
+# @[00]@| Go-GMA 5.33.0
+# @[01]@|
+# @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+# @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+# @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+# @[13]@| points along that historical time line.
+# @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+# @[15]@| License as described in the accompanying LICENSE file distributed
+# @[16]@| with GMA.
+# @[17]@|
+# @[20]@| Redistribution and use in source and binary forms, with or without
+# @[21]@| modification, are permitted provided that the following conditions
+# @[22]@| are met:
+# @[23]@| 1. Redistributions of source code must retain the above copyright
+# @[24]@|    notice, this list of conditions and the following disclaimer.
+# @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+# @[26]@|    right notice, this list of conditions and the following dis-
+# @[27]@|    claimer in the documentation and/or other materials provided
+# @[28]@|    with the distribution.
+# @[29]@| 3. Neither the name of the copyright holder nor the names of its
+# @[30]@|    contributors may be used to endorse or promote products derived
+# @[31]@|    from this software without specific prior written permission.
+# @[32]@|
+# @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+# @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+# @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+# @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+# @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+# @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+# @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+# @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+# @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+# @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+# @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+# @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+# @[45]@| SUCH DAMAGE.
+# @[46]@|
+# @[50]@| This software is not intended for any use or application in which
+# @[51]@| the safety of lives or property would be at risk due to failure or
+# @[52]@| defect of the software.
+*/
@@ -36,6 +36,8 @@ If no files are named, preset-update reads from its standard input and writes to
 
 The file format changed significantly between format versions 1 and 2.
 The preset-update program can read format 1 files, so this provides a way to update existing map files to the newer format.
+
+Format version 3 adds a list of the parameters each preset declares (if any).
 */
 package main
 
@@ -48,7 +50,7 @@ import (
 )
 
 const GoVersionNumber="5.33.0"     //@@##@@
-const GMADieRollPresetFileFormat = 2 //@@##@@
+const GMADieRollPresetFileFormat = 3 //@@##@@
 
 func main() {
 	if len(os.Args) < 2 {
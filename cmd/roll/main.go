@@ -44,6 +44,9 @@ It can be used interactively by users or it can be embedded in scripts for web-b
 	roll -help
 	roll -syntax
 	roll [-seed value] [-dice spec] [-json]
+	roll -stats [-dc target] [-seed value] [-dice spec] [-json]
+	roll -verify transcript
+	roll -fairness [-samples n] [-seed value] [-json]
 
 # OPTIONS
 
@@ -51,21 +54,52 @@ Command-line options may be specified with one or two hyphens (e.g., -json or --
 
 Options which take parameter values may have the value separated from the option name by a space or an equals sign (e.g., -dice="3d6" or -dice "3d6"), except for boolean flags which may be given alone (e.g., -json) to indicate that the option is set to “true” or may be given an explicit value which must be attached to the option with an equals sign (e.g., -json=true or -json=false).
 
+	  -dc target
+	      With -stats, also report the chance of each roll meeting or exceeding the target value. If this is not given,
+	      the target from a "| dc" option in the die-roll expression (if any) is used.
+
 	  -dice spec[;...]
 	      Specify the die-roll expression to be rolled, such as "3d6". If this is not given, roll will interactively prompt for die-roll expressions. Typing a blank line repeats the previous expression. The program will exit on EOF. Multiple die-roll specs may be given here, separated by semicolons. These will be rolled in order after setting the seed (if any).
 
+	  -fairness
+	      Instead of rolling dice, test the fairness of the die roller by rolling each common size of die (d2, d3,
+	      d4, d6, d8, d10, d12, d20, and d100) many times. For each die, a chi-square test checks that all faces
+	      came up about equally often, and a runs test checks that the rolls don't fall into a pattern of high
+	      and low values. The p-value of each test is reported; this is the probability that a fair die would
+	      give results at least as far from what was expected. P-values below 0.001 are flagged, and the program
+	      exits with a nonzero status if there are any.
+
 	  -help
 	      Print a command summary and exit.
 
 	  -json
 	      Print die-roll results in JSON format.
 
+	  -samples n
+	      With -fairness, roll each die n times (default 100000).
+
 	  -seed value
 	      Instead of using a random seed value, base the die roll results on the given value.
 		  Value is a 64-bit integer expressed in decimal digits.
 
+	  -stats
+	      Instead of rolling the dice, report statistics about the possible results of each die-roll expression:
+	      the minimum, maximum, mean, standard deviation, and median, along with a histogram of the results.
+	      If the expression has permutations (e.g., "d20+{17/12/7}"), each permutation is reported separately.
+	      For rolls with the "| sf" or "| c" options, the chances of automatic success or failure and of critical
+	      threats are also reported. The statistics are calculated exactly where possible; otherwise (e.g., for the
+	      "| until" option) they are estimated by simulating many rolls, and confidence intervals are reported for them.
+
 	  -syntax
 	      Print a summary of the die-roll expression syntax and exit. In interactive mode, this help text may be produced by typing "help" as the input line.
+
+	  -verify transcript
+	      Check the verifiable die rolls made by a game server. The transcript file holds the server messages
+	      received by a client, one per line, as they were sent over the wire (use "-" to read them from the
+	      standard input). Messages sent in BATCH fragments or compressed into DEFLATE messages are decoded
+	      just as a client would. Each ROLL message made from a committed seed is rolled again from that seed
+	      once a SEED message reveals it, and the results are compared. The program exits with a nonzero status
+	      if any roll does not match or if any message in the transcript can't be read.
 */
 package main
 
@@ -78,9 +112,11 @@ import (
 	"math"
 	"os"
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/MadScienceZone/go-gma/v5/dice"
+	"github.com/MadScienceZone/go-gma/v5/mapper"
 	"github.com/MadScienceZone/go-gma/v5/text"
 )
 
@@ -91,7 +127,7 @@ func main() {
 	var seedUsed int64
 
 	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [-help] [-dice spec] [-json] [-seed value] [-syntax]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s [-help] [-dc target] [-dice spec] [-fairness] [-json] [-samples n] [-seed value] [-stats] [-syntax] [-verify transcript]\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "  An option 'x' with a value may be set by '-x value', '-x=value', '--x value', or '--x=value'.\n")
 		fmt.Fprintf(os.Stderr, "  A flag 'x' may be set by '-x', '--x', '-x=true|false' or '--x=true|false'\n")
 		fmt.Fprintf(os.Stderr, "  Options may NOT be combined into a single argument (use '-h -m', not '-hm').\n")
@@ -99,10 +135,15 @@ func main() {
 		flag.PrintDefaults()
 	}
 	help := flag.Bool("help", false, "list command-line options and exit")
+	targetDC := flag.Int("dc", 0, "with -stats, report the chance of meeting this target")
 	rollSpec := flag.String("dice", "", "die-roll expression(s) to be rolled (semicolon-separated) (interactive if this is not given)")
+	fairness := flag.Bool("fairness", false, "test the fairness of the die roller instead of rolling")
 	asJSON := flag.Bool("json", false, "print results in JSON")
+	samples := flag.Int("samples", dice.FairnessSamples, "with -fairness, the number of times to roll each die")
 	seedValue := flag.Int64("seed", 0, "seed value (0 for random)")
+	stats := flag.Bool("stats", false, "report statistics about the possible results instead of rolling")
 	syntaxHelp := flag.Bool("syntax", false, "print die-roll syntax description and exit")
+	transcript := flag.String("verify", "", "check the verifiable die rolls in this transcript of server messages (- for stdin)")
 	flag.Parse()
 
 	if *help {
@@ -121,6 +162,28 @@ func main() {
 		os.Exit(0)
 	}
 
+	if *transcript != "" {
+		var in io.Reader = os.Stdin
+		if *transcript != "-" {
+			f, err := os.Open(*transcript)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			in = f
+		}
+		ok, err := verifyTranscript(in, os.Stdout)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !ok {
+			os.Exit(2)
+		}
+		return
+	}
+
 	var roller *dice.DieRoller
 	if *seedValue != 0 {
 		roller, err = dice.NewDieRoller(dice.WithSeed(*seedValue))
@@ -138,18 +201,65 @@ func main() {
 		Seed: seedUsed,
 	}
 
+	if *fairness {
+		if report.Fairness, err = roller.Fairness(nil, *samples); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *asJSON {
+			if err := report.WriteJSON(os.Stdout); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			report.WriteText(os.Stdout)
+		}
+		for _, r := range report.Fairness {
+			if r.Suspicious(fairnessAlpha) {
+				os.Exit(2)
+			}
+		}
+		return
+	}
+
+	// evaluate either rolls the dice or works out their statistics,
+	// depending on the -stats option.
+	evaluate := func(spec string) (ReportedResultSet, error) {
+		if *stats {
+			title, dists, err := roller.Distributions(spec)
+			if err != nil {
+				return ReportedResultSet{}, err
+			}
+			dc := *targetDC
+			if dc == 0 {
+				dc = roller.DC
+			}
+			r := ReportedResultSet{Title: title}
+			for _, dist := range dists {
+				r.Distributions = append(r.Distributions, NewDistributionStats(dist, dc))
+			}
+			return r, nil
+		}
+
+		title, results, err := roller.DoRoll(spec)
+		if err != nil {
+			return ReportedResultSet{}, err
+		}
+		r := ReportedResultSet{
+			Title:   title,
+			Results: results,
+		}
+		r.CalculateStats()
+		return r, nil
+	}
+
 	if *rollSpec != "" {
 		for i, thisRoll := range strings.Split(*rollSpec, ";") {
-			title, results, err := roller.DoRoll(thisRoll)
+			r, err := evaluate(thisRoll)
 			if err != nil {
 				fmt.Printf("Error in die-roll expression #%d: %v\n", i+1, err)
 				os.Exit(1)
 			}
-			r := ReportedResultSet{
-				Title:   title,
-				Results: results,
-			}
-			r.CalculateStats()
 			report.AddResult(r)
 		}
 
@@ -173,15 +283,10 @@ func main() {
 					fmt.Printf("Unable to print help text: %v\n", err)
 				}
 			} else {
-				title, results, err := roller.DoRoll(scanner.Text())
+				r, err := evaluate(scanner.Text())
 				if err != nil {
 					fmt.Printf("ERROR: %v\n", err)
 				} else {
-					r := ReportedResultSet{
-						Title:   title,
-						Results: results,
-					}
-					r.CalculateStats()
 					r.WriteText(os.Stdout)
 				}
 			}
@@ -194,9 +299,13 @@ func main() {
 // the result set from each discrete die-roll request.
 type ReportedData struct {
 	ResultSet []ReportedResultSet
-	Seed      int64 `json:",omitempty"`
+	Fairness  []dice.FairnessReport `json:",omitempty"`
+	Seed      int64                 `json:",omitempty"`
 }
 
+// fairnessAlpha is the p-value below which the -fairness tests are flagged as failing.
+const fairnessAlpha = 0.001
+
 // ResultStats provides statistics about the data set.
 type ResultStats struct {
 	N      int     // population size
@@ -211,9 +320,105 @@ type ResultStats struct {
 // This may involve multiple die rolls, depending on the options included, and each of those
 // may involve multiple dice being rolled.
 type ReportedResultSet struct {
-	Title   string `json:",omitempty"`
-	Results []dice.StructuredResult
-	Stats   *ResultStats `json:",omitempty"`
+	Title         string `json:",omitempty"`
+	Results       []dice.StructuredResult `json:",omitempty"`
+	Stats         *ResultStats            `json:",omitempty"`
+	Distributions []DistributionStats     `json:",omitempty"`
+}
+
+// DistributionStats describes the possible results of a die-roll request
+// (or one permutation of it) as reported by the -stats option.
+type DistributionStats struct {
+	Expression string    `json:",omitempty"` // the permutation described here (if there are permutations)
+	Exact      bool      // false if these are estimates from simulated rolls
+	Samples    int       `json:",omitempty"` // number of simulated rolls (if not exact)
+	Min        int       // smallest possible result
+	Max        int       // largest possible result
+	Mean       float64   // expected result
+	MeanCI     []float64 `json:",omitempty"` // confidence interval for Mean (if not exact)
+	StdDev     float64   // standard deviation of the results
+	Median     int       // median result
+
+	// If there is a target DC, these give the chance of meeting it.
+	DC       int       `json:",omitempty"`
+	Chance   *float64  `json:",omitempty"`
+	ChanceCI []float64 `json:",omitempty"`
+
+	// For rolls with the sf or c options, the chances of automatic success and failure,
+	// of a critical threat, and of confirming that threat against the target DC.
+	AutoSuccess float64  `json:",omitempty"`
+	AutoFail    float64  `json:",omitempty"`
+	Threat      float64  `json:",omitempty"`
+	Confirmed   *float64 `json:",omitempty"`
+
+	Histogram []HistogramBar
+}
+
+// HistogramBar gives the probability of getting a result from Low to High (inclusive).
+type HistogramBar struct {
+	Low         int
+	High        int
+	Probability float64
+}
+
+// NewDistributionStats summarizes a die roll's probability distribution.
+// If dc is nonzero, the chance of meeting it is included.
+func NewDistributionStats(dist *dice.RollDistribution, dc int) DistributionStats {
+	ds := DistributionStats{
+		Expression:  dist.Expression,
+		Exact:       dist.Exact,
+		Samples:     dist.Samples,
+		Min:         dist.Min(),
+		Max:         dist.Max(),
+		Mean:        dist.Mean(),
+		StdDev:      dist.StdDev(),
+		Median:      dist.Percentile(50),
+		AutoSuccess: dist.AutoSuccessChance,
+		AutoFail:    dist.AutoFailChance,
+		Threat:      dist.ThreatChance,
+	}
+	if !dist.Exact {
+		lo, hi := dist.MeanInterval()
+		ds.MeanCI = []float64{lo, hi}
+	}
+	if dc != 0 {
+		ds.DC = dc
+		chance := dist.ChanceToSucceed(dc)
+		ds.Chance = &chance
+		if !dist.Exact {
+			lo, hi := dist.ChanceToSucceedInterval(dc)
+			ds.ChanceCI = []float64{lo, hi}
+		}
+		if dist.Confirmation != nil {
+			confirmed := dist.ConfirmedCriticalChance(dc)
+			ds.Confirmed = &confirmed
+		}
+	}
+	for _, v := range dist.Results() {
+		ds.Histogram = append(ds.Histogram, HistogramBar{Low: v, High: v, Probability: dist.Probability[v]})
+	}
+	return ds
+}
+
+// maxHistogramBars is the most lines we'll use for a histogram in text output.
+const maxHistogramBars = 40
+
+// bucketedHistogram returns the histogram with adjacent values grouped together
+// so there are no more than maxBars bars.
+func (ds DistributionStats) bucketedHistogram(maxBars int) []HistogramBar {
+	width := (ds.Max-ds.Min)/maxBars + 1
+	if width == 1 {
+		return ds.Histogram
+	}
+	var bars []HistogramBar
+	for _, bar := range ds.Histogram {
+		low := ds.Min + ((bar.Low-ds.Min)/width)*width
+		if len(bars) == 0 || bars[len(bars)-1].Low != low {
+			bars = append(bars, HistogramBar{Low: low, High: min(low+width-1, ds.Max)})
+		}
+		bars[len(bars)-1].Probability += bar.Probability
+	}
+	return bars
 }
 
 // AddResult adds a new result set to the output data.
@@ -309,6 +514,11 @@ func (rd ReportedData) WriteJSON(o io.Writer) error {
 
 // WriteText outputs reported data in plain text format.
 func (rd ReportedData) WriteText(o io.Writer) {
+	for _, r := range rd.Fairness {
+		o.Write([]byte(fmt.Sprintf("\033[1md%-3d\033[0m %d rolls: χ²=%.2f (%d df) p=%s; %d runs (%.1f expected) z=%.2f p=%s\n",
+			r.Sides, r.Samples, r.ChiSquare, r.DegreesOfFreedom, pValue(r.ChiSquareP),
+			r.Runs, r.ExpectedRuns, r.RunsZ, pValue(r.RunsP))))
+	}
 	for i, set := range rd.ResultSet {
 		if i > 0 {
 			o.Write([]byte(strings.Repeat("\u2550", 80) + "\n"))
@@ -324,6 +534,13 @@ func (rs ReportedResultSet) WriteText(o io.Writer) {
 		o.Write([]byte("\033[1m\"" + rs.Title + "\":\033[0m\n"))
 	}
 
+	for i, ds := range rs.Distributions {
+		if i > 0 {
+			o.Write([]byte("\n"))
+		}
+		ds.WriteText(o)
+	}
+
 	for i, res := range rs.Results {
 		if len(rs.Results) > 1 {
 			o.Write([]byte(fmt.Sprintf("\033[1;34mRoll #%d: \033[0m", i+1)))
@@ -357,3 +574,153 @@ func (rs ReportedResultSet) WriteText(o io.Writer) {
 			rs.Stats.Sum)))
 	}
 }
+
+// percentage formats a probability as a percentage, with its confidence
+// interval if there is one.
+func percentage(p float64, ci []float64) string {
+	if len(ci) == 2 {
+		return fmt.Sprintf("%.2f%% (%.2f%%-%.2f%%)", p*100, ci[0]*100, ci[1]*100)
+	}
+	return fmt.Sprintf("%.2f%%", p*100)
+}
+
+// WriteText outputs the distribution statistics in plain text format.
+func (ds DistributionStats) WriteText(o io.Writer) {
+	if ds.Expression != "" {
+		o.Write([]byte("\033[1;34m" + ds.Expression + ":\033[0m\n"))
+	}
+	if ds.Exact {
+		o.Write([]byte(fmt.Sprintf("min=%d, max=%d, μ=%.4f, σ=%.4f, Md=%d\n",
+			ds.Min, ds.Max, ds.Mean, ds.StdDev, ds.Median)))
+	} else {
+		o.Write([]byte(fmt.Sprintf("min=%d, max=%d, μ=%.4f (%.4f-%.4f), σ=%.4f, Md=%d\n",
+			ds.Min, ds.Max, ds.Mean, ds.MeanCI[0], ds.MeanCI[1], ds.StdDev, ds.Median)))
+		o.Write([]byte(fmt.Sprintf("\033[33m(estimated from %d simulated rolls; ranges are %.0f%% confidence intervals)\033[0m\n",
+			ds.Samples, dice.ConfidenceLevel*100)))
+	}
+	if ds.AutoSuccess > 0 || ds.AutoFail > 0 {
+		o.Write([]byte(fmt.Sprintf("automatic success %s, automatic failure %s\n",
+			percentage(ds.AutoSuccess, nil), percentage(ds.AutoFail, nil))))
+	}
+	if ds.Threat > 0 {
+		o.Write([]byte(fmt.Sprintf("critical threat %s\n", percentage(ds.Threat, nil))))
+	}
+	if ds.Chance != nil {
+		o.Write([]byte(fmt.Sprintf("\033[1mchance to meet DC %d: %s\033[0m\n", ds.DC, percentage(*ds.Chance, ds.ChanceCI))))
+		if ds.Confirmed != nil {
+			o.Write([]byte(fmt.Sprintf("\033[1mchance of confirmed critical vs DC %d: %s\033[0m\n", ds.DC, percentage(*ds.Confirmed, nil))))
+		}
+	}
+
+	bars := ds.bucketedHistogram(maxHistogramBars)
+	var tallest float64
+	labelWidth := 0
+	for _, bar := range bars {
+		tallest = max(tallest, bar.Probability)
+		labelWidth = max(labelWidth, len(bar.label()))
+	}
+	for _, bar := range bars {
+		length := 0
+		if tallest > 0 {
+			length = int(math.Round(bar.Probability / tallest * 50))
+		}
+		o.Write([]byte(fmt.Sprintf("%*s \033[36m%s\033[0m %.2f%%\n",
+			labelWidth, bar.label(), strings.Repeat("\u2588", length), bar.Probability*100)))
+	}
+}
+
+// label describes the range of results covered by the histogram bar.
+func (bar HistogramBar) label() string {
+	if bar.Low == bar.High {
+		return strconv.Itoa(bar.Low)
+	}
+	return fmt.Sprintf("%d-%d", bar.Low, bar.High)
+}
+
+// pValue formats a p-value from the fairness tests, flagging it if it is suspiciously low.
+func pValue(p float64) string {
+	if p < fairnessAlpha {
+		return fmt.Sprintf("\033[1;31m%.4g\033[0m", p)
+	}
+	return fmt.Sprintf("%.4f", p)
+}
+
+// verifiedRoll collects the results of one verifiable die-roll request
+// found in a transcript.
+type verifiedRoll struct {
+	verification dice.RollVerification
+	results      []dice.StructuredResult
+}
+
+// verifyTranscript reads the server messages in a transcript, replays each
+// verifiable die roll from its revealed seed, and reports whether they all
+// match what the server sent.
+func verifyTranscript(in io.Reader, o io.Writer) (bool, error) {
+	var rolls []*verifiedRoll
+	seeds := make(map[string]dice.SeedCommitment)
+	requests := make(map[dice.RollVerification]*verifiedRoll)
+
+	conn := mapper.NewMapConnectionReader(in)
+	for {
+		msg, err := conn.Receive()
+		if err != nil {
+			return false, err
+		}
+		if msg == nil {
+			break
+		}
+		switch m := msg.(type) {
+		case mapper.RollResultMessagePayload:
+			if m.Verification == nil {
+				continue
+			}
+			if v, ok := requests[*m.Verification]; ok {
+				v.results = append(v.results, m.Result)
+				continue
+			}
+			v := &verifiedRoll{verification: *m.Verification, results: []dice.StructuredResult{m.Result}}
+			requests[*m.Verification] = v
+			rolls = append(rolls, v)
+
+		case mapper.RollSeedMessagePayload:
+			if m.Revealed != nil {
+				if m.Revealed.Commitment() != m.Commitment {
+					fmt.Fprintf(o, "\033[31mFAIL\033[0m seed revealed for %s does not match its commitment\n", m.Commitment)
+					return false, nil
+				}
+				seeds[m.Commitment] = *m.Revealed
+			}
+
+		case mapper.ErrorMessagePayload:
+			return false, fmt.Errorf("unable to read message %q: %v", m.RawMessage(), m.Error)
+
+		default:
+			if msg.MessageType() == mapper.UNKNOWN {
+				return false, fmt.Errorf("unable to read message %q", msg.RawMessage())
+			}
+		}
+	}
+	if incomplete := conn.ExpireBatches(0); len(incomplete) > 0 {
+		return false, fmt.Errorf("transcript ends in the middle of batched message %s", strings.Join(incomplete, ", "))
+	}
+
+	var passed, failed, unverified int
+	for _, r := range rolls {
+		v := r.verification
+		seed, ok := seeds[v.Commitment]
+		if !ok {
+			fmt.Fprintf(o, "\033[33m??\033[0m   #%d %s: seed %s not revealed\n", v.Sequence, v.RollSpec, v.Commitment)
+			unverified++
+			continue
+		}
+		if err := seed.Verify(v, r.results); err != nil {
+			fmt.Fprintf(o, "\033[31mFAIL\033[0m #%d %s: %v\n", v.Sequence, v.RollSpec, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(o, "\033[32mOK\033[0m   #%d %s\n", v.Sequence, v.RollSpec)
+		passed++
+	}
+	fmt.Fprintf(o, "%d verified, %d failed, %d could not be checked.\n", passed, failed, unverified)
+	return failed == 0, nil
+}
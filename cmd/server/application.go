@@ -19,6 +19,8 @@ package main
 
 import (
 	"bufio"
+	"crypto/tls"
+	"crypto/x509"
 	"database/sql"
 	"encoding/json"
 	"flag"
@@ -162,6 +164,21 @@ type Application struct {
 	// incoming socket is listening.
 	Endpoint string
 
+	// If WebSocketEndpoint is not empty, it is the "[host]:port" string
+	// on which we also listen for clients connecting over WebSockets,
+	// with the URL path given by WebSocketPath.
+	WebSocketEndpoint string
+	WebSocketPath     string
+
+	// If TLSConfig is not nil, clients connect to us over TLS with this
+	// configuration. If it includes a pool of ClientCAs, clients may log in
+	// with certificates signed by those authorities instead of passwords.
+	TLSConfig *tls.Config
+
+	// If RecordDir is not empty, we record each client's session in a
+	// new file in this directory (see mapper.Recorder).
+	RecordDir string
+
 	// If not empty, this gives the filename from which we are to read in
 	// the initial client command set.
 	InitFile string
@@ -220,6 +237,10 @@ type Application struct {
 
 	// The QoS settings as configured for the server
 	QoSLimits QoSLimitsDescription
+
+	// If true, die rolls are made from a seed committed to in advance
+	// and revealed afterward, so clients can verify them.
+	VerifiableRolls bool
 }
 
 type QoSLimitsDescription struct {
@@ -253,6 +274,9 @@ func (a *Application) GetAllowedClients() []mapper.PackageUpdate {
 func (a *Application) AddClient(c *mapper.ClientConnection) {
 	a.clientData.add <- c
 	//a.SendPeerListToAll()
+	if a.VerifiableRolls && c.Auth != nil {
+		a.StartRollSeries(c)
+	}
 }
 
 // DropAllClients severs the connection to all clients.
@@ -272,6 +296,57 @@ func (a *Application) DropAllClients() {
 func (a *Application) RemoveClient(c *mapper.ClientConnection) {
 	a.clientData.remove <- c
 	//a.SendPeerListToAll()
+	if c.RollSeed != nil {
+		a.EndRollSeries(c)
+	}
+}
+
+// StartRollSeries begins a new series of verifiable die rolls for a client,
+// announcing the commitment to its seed to everyone.
+func (a *Application) StartRollSeries(c *mapper.ClientConnection) {
+	seed, err := dice.NewSeedCommitment()
+	if err != nil {
+		a.Logf("unable to generate die-roll seed for %s: %v", c.Auth.Username, err)
+		return
+	}
+	if err := a.StoreRollSeed(c.Auth.Username, seed); err != nil {
+		a.Logf("unable to store die-roll seed for %s: %v", c.Auth.Username, err)
+		return
+	}
+	c.RollSeed = &seed
+	c.RollSequence = 0
+	a.Logf("committed to die-roll seed %s for %s", seed.Commitment(), c.Auth.Username)
+
+	announcement := mapper.RollSeedMessagePayload{
+		User:       c.Auth.Username,
+		Commitment: seed.Commitment(),
+	}
+	for _, peer := range a.GetClients() {
+		peer.Conn.Send(mapper.RollSeed, announcement)
+	}
+}
+
+// EndRollSeries ends a client's current series of verifiable die rolls,
+// revealing its seed to everyone.
+func (a *Application) EndRollSeries(c *mapper.ClientConnection) {
+	if c.RollSeed == nil {
+		return
+	}
+	seed := *c.RollSeed
+	c.RollSeed = nil
+	if err := a.EndRollSeed(seed.Commitment()); err != nil {
+		a.Logf("unable to record end of die-roll seed %s: %v", seed.Commitment(), err)
+	}
+	a.Logf("revealed die-roll seed %s for %s after %d rolls: %d (salt %s)", seed.Commitment(), c.Auth.Username, c.RollSequence, seed.Seed, seed.Salt)
+
+	reveal := mapper.RollSeedMessagePayload{
+		User:       c.Auth.Username,
+		Commitment: seed.Commitment(),
+		Revealed:   &seed,
+	}
+	for _, peer := range a.GetClients() {
+		peer.Conn.Send(mapper.RollSeed, reveal)
+	}
 }
 
 // GetClients returns a copy of the client list as it existed
@@ -419,12 +494,18 @@ func (a *Application) GetAppOptions() error {
 	var logFile = flag.String("log-file", "-", "Write log to given pathname (stderr if '-'); special % tokens allowed in path")
 	var passFile = flag.String("password-file", "", "Require authentication with named password file")
 	var endPoint = flag.String("endpoint", ":2323", "Incoming connection endpoint ([host]:port)")
+	var webSocket = flag.String("websocket", "", "Also accept WebSocket connections at [host]:port[/path]")
+	var tlsCert = flag.String("tls-cert", "", "Accept TLS connections with the server certificate in this PEM file")
+	var tlsKey = flag.String("tls-key", "", "Private key for -tls-cert (PEM file)")
+	var tlsClientCA = flag.String("tls-client-ca", "", "Allow clients to log in with certificates signed by the CA certificate(s) in this PEM file")
+	var recordDir = flag.String("record-dir", "", "Record each client session in a file in this directory")
 	//	var saveInterval = flag.String("save-interval", "10m", "Save internal state this often")
 	var sqlDbName = flag.String("sqlite", "", "Specify filename for sqlite database to use")
 	var debugFlags = flag.String("debug", "", "List the debugging trace types to enable")
 	var nrLogger = flag.String("telemetry-log", "", "Debugging log for telemetry collection")
 	var nrAppName = flag.String("telemetry-name", "", "Application name for telemetry collection (default: \"gma-server\")")
 	var profFile = flag.String("cpuprofile", "", "CPU Profiling output file (default: no profiling)")
+	var verifiable = flag.Bool("verifiable-rolls", false, "Make die rolls which clients can verify after the seed is revealed")
 	flag.Parse()
 
 	if *debugFlags != "" {
@@ -504,6 +585,58 @@ func (a *Application) GetAppOptions() error {
 		return fmt.Errorf("non-empty tcp [host]:port value required")
 	}
 
+	if *webSocket != "" {
+		a.WebSocketEndpoint, a.WebSocketPath = *webSocket, "/"
+		if i := strings.IndexRune(*webSocket, '/'); i >= 0 {
+			a.WebSocketEndpoint, a.WebSocketPath = (*webSocket)[:i], (*webSocket)[i:]
+		}
+		if a.WebSocketEndpoint == "" {
+			return fmt.Errorf("-websocket value must include [host]:port")
+		}
+		a.Logf("configured to listen for WebSocket connections on \"%s\" at path \"%s\"", a.WebSocketEndpoint, a.WebSocketPath)
+	}
+
+	if *tlsCert != "" || *tlsKey != "" {
+		if *tlsCert == "" || *tlsKey == "" {
+			return fmt.Errorf("-tls-cert and -tls-key must be given together")
+		}
+		cert, err := tls.LoadX509KeyPair(*tlsCert, *tlsKey)
+		if err != nil {
+			return fmt.Errorf("unable to load TLS certificate: %v", err)
+		}
+		a.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+		a.Logf("TLS enabled with certificate \"%s\"", *tlsCert)
+
+		if *tlsClientCA != "" {
+			pem, err := os.ReadFile(*tlsClientCA)
+			if err != nil {
+				return fmt.Errorf("unable to read TLS client CA certificates: %v", err)
+			}
+			a.TLSConfig.ClientCAs = x509.NewCertPool()
+			if !a.TLSConfig.ClientCAs.AppendCertsFromPEM(pem) {
+				return fmt.Errorf("no certificates found in %s", *tlsClientCA)
+			}
+			a.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+			a.Logf("clients may log in with certificates signed by the CA in \"%s\"", *tlsClientCA)
+		}
+	} else {
+		if *tlsClientCA != "" {
+			return fmt.Errorf("-tls-client-ca requires -tls-cert and -tls-key")
+		}
+		a.Log("WARNING: TLS not enabled!")
+	}
+
+	if *recordDir != "" {
+		if info, err := os.Stat(*recordDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("-record-dir value \"%s\" is not a directory", *recordDir)
+		}
+		a.RecordDir = *recordDir
+		a.Logf("recording client sessions in \"%s\"", a.RecordDir)
+	}
+
 	/*
 		if *saveInterval == "" {
 			a.SaveInterval = 10 * time.Minute
@@ -518,6 +651,11 @@ func (a *Application) GetAppOptions() error {
 		}
 	*/
 
+	if *verifiable {
+		a.VerifiableRolls = true
+		a.Log("die rolls will be verifiable")
+	}
+
 	if *sqlDbName == "" {
 		return fmt.Errorf("database name is required")
 	}
@@ -795,7 +933,34 @@ func (a *Application) HandleServerMessage(payload mapper.MessagePayload, request
 			return
 		}
 
-		label, results, err := requester.D.DoRoll(p.RollSpec)
+		var presets []dice.DieRollPreset
+		if strings.ContainsRune(p.RollSpec, '@') {
+			// the roll refers to stored presets; make sure we have the current set
+			var err error
+			presets, err = a.QueryDicePresets(requester.Auth.Username, false)
+			if err != nil {
+				a.Logf("unable to load die-roll presets for %s: %v", requester.Auth.Username, err)
+			}
+			requester.D.DefinePresets(presets)
+		}
+
+		roller := requester.D
+		var verification *dice.RollVerification
+		if requester.RollSeed != nil {
+			// this roll must be reproducible from the committed seed
+			var err error
+			requester.RollSequence++
+			verification = &dice.RollVerification{
+				Commitment: requester.RollSeed.Commitment(),
+				Sequence:   requester.RollSequence,
+			}
+			if roller, err = requester.RollSeed.DieRoller(requester.RollSequence, dice.WithPresets(presets...)); err != nil {
+				a.Logf("unable to set up verifiable die roller for %s: %v", requester.Auth.Username, err)
+				return
+			}
+		}
+
+		label, results, err := roller.DoRoll(p.RollSpec)
 		if err != nil {
 			// Bad request. Notify the requester
 			requester.Conn.Send(mapper.RollResult, mapper.RollResultMessagePayload{
@@ -819,130 +984,32 @@ func (a *Application) HandleServerMessage(payload mapper.MessagePayload, request
 			})
 			return
 		}
-		var genericParts []string
-		for _, part := range strings.Split(label, "‖") {
-			if pos := strings.IndexRune(part, '≡'); pos >= 0 {
-				genericParts = append(genericParts, part[:pos])
-			} else {
-				genericParts = append(genericParts, part)
-			}
-		}
-		genericLabel := strings.Join(genericParts, ", ")
 
-		response := mapper.RollResultMessagePayload{
-			ChatCommon: mapper.ChatCommon{
-				Sender:     requester.Auth.Username,
-				Recipients: p.Recipients,
-				ToAll:      p.ToAll,
-				ToGM:       p.ToGM,
-				Sent:       time.Now(),
-			},
-			Title:     label,
-			RequestID: p.RequestID,
-			Type:      p.Type,
-			Targets:   p.Targets,
+		entry := dice.RollHistoryEntry{
+			User:       requester.Auth.Username,
+			Title:      genericRollLabel(label),
+			RollSpec:   p.RollSpec,
+			Dice:       roller.NaturalRolls(),
+			ToAll:      p.ToAll,
+			Recipients: p.Recipients,
+			ToGM:       p.ToGM,
+			When:       time.Now(),
 		}
-
-		if p.ToGM {
-			receiptMessageID := <-a.MessageIDGenerator
-			receiptGenericLabel := genericLabel
-			var receiptLabel string
-			var receiptResult dice.StructuredResult
-			var err error
-			if requester.Auth.GmMode {
-				receiptGenericLabel = ""
-				receiptResult = dice.StructuredResult{
-					ResultSuppressed: true,
-					Details: dice.StructuredDescriptionSet{
-						{Type: "notice", Value: "rolls behind screen"},
-					},
-				}
-			} else {
-				receiptLabel, receiptResult, err = requester.D.ExplainSecretRoll(p.RollSpec, "roll to GM")
-				if err != nil {
-					receiptResult = dice.StructuredResult{
-						ResultSuppressed: true,
-						Details: dice.StructuredDescriptionSet{
-							{Type: "notice", Value: "roll to GM"},
-							{Type: "error", Value: fmt.Sprintf("error preparing receipt message: %v", err)},
-						},
-					}
-				}
-			}
-
-			receiptPayload := mapper.RollResultMessagePayload{
-				ChatCommon: mapper.ChatCommon{
-					MessageID: receiptMessageID,
-					Sender:    requester.Auth.Username,
-					ToAll:     true,
-					Sent:      time.Now(),
-				},
-				RequestID: p.RequestID,
-				Title:     receiptLabel,
-				Result:    receiptResult,
-				Type:      p.Type,
-				Targets:   p.Targets,
-			}
-			if err := a.AddToChatHistory(receiptMessageID, mapper.RollResult, receiptPayload); err != nil {
-				a.Logf("unable to add RollResult receipt to chat history: %v", err)
-			}
-			for _, peer := range a.GetClients() {
-				if peer.Auth == nil || !peer.Auth.GmMode {
-					if !peer.Features.DiceColorBoxes {
-						receiptPayload.Title = receiptGenericLabel
-					} else {
-						receiptPayload.Title = receiptLabel
-					}
-
-					receiptPayload.Origin = peer == requester
-					peer.Conn.Send(mapper.RollResult, receiptPayload)
-				}
-			}
+		if len(results) > 0 {
+			entry.Result = results[0].Result
+		}
+		if err := a.AddToRollHistory(entry); err != nil {
+			a.Logf("unable to add die roll to roll history: %v", err)
 		}
 
-		for seq, r := range results {
-			response.MessageID = <-a.MessageIDGenerator
-			response.Result = r
-			response.MoreResults = seq+1 < len(results)
-
-			if err := a.AddToChatHistory(response.MessageID, mapper.RollResult, response); err != nil {
-				a.Logf("unable to add RollResult event to chat history: %v", err)
-			}
-
-			for _, peer := range a.GetClients() {
-				if p.ToGM {
-					if peer.Auth == nil || !peer.Auth.GmMode {
-						// we already handled this case above
-						continue
-					}
-				} else if !p.ToAll {
-					if peer.Auth == nil || peer.Auth.Username == "" {
-						a.Debugf(DebugIO, "sending to explicit list but we don't know who %v is (skipped)", peer.IdTag())
-						continue
-					}
-					if peer.Auth.Username != requester.Auth.Username && slices.Index(p.Recipients, peer.Auth.Username) < 0 {
-						a.Debugf(DebugIO, "sending to explicit list but user \"%s\" (from %v) isn't on the list (skipped)", peer.Auth.Username, peer.IdTag())
-						continue
-					}
-				}
-
-				if peer.Features.DiceColorBoxes {
-					response.Title = label
-				} else {
-					response.Title = genericLabel
-				}
-
-				response.Origin = peer == requester
-				if !peer.Features.DiceColorLabels {
-					if err := peer.Conn.Send(mapper.RollResult, stripColorsFromResponse(response)); err != nil {
-						a.Logf("error sending color-stripped die-roll result %v to %v: %v", response, peer.IdTag(), err)
-					}
-				} else if err := peer.Conn.Send(mapper.RollResult, response); err != nil {
-					a.Logf("error sending die-roll result %v to %v: %v", response, peer.IdTag(), err)
-				}
-			}
+		if verification != nil {
+			verification.RollSpec, _ = roller.ExpandPresets(p.RollSpec)
 		}
 
+		a.sendRollResults(requester, p, label, results, verification, func() (string, dice.StructuredResult, error) {
+			return requester.D.ExplainSecretRoll(p.RollSpec, "roll to GM")
+		})
+
 	case mapper.SyncChatMessagePayload:
 		if err := a.QueryChatHistory(p.Target, requester); err != nil {
 			a.Logf("error syncing chat history (target=%d): %v", p.Target, err)
@@ -996,6 +1063,16 @@ func (a *Application) HandleServerMessage(payload mapper.MessagePayload, request
 			dataset = GlobalPresetUser
 		}
 
+		if err := validateDicePresets(p.Presets); err != nil {
+			a.Logf("refusing to store invalid die-roll preset from %s: %v", requester.Auth.Username, err)
+			requester.Conn.Send(mapper.Failed, mapper.FailedMessagePayload{
+				IsError: true,
+				Command: p.RawMessage(),
+				Reason:  err.Error(),
+			})
+			return
+		}
+
 		if err := a.StoreDicePresets(dataset, p.Presets, true); err != nil {
 			a.Logf("error storing die-roll preset: %v", err)
 		}
@@ -1079,6 +1156,16 @@ func (a *Application) HandleServerMessage(payload mapper.MessagePayload, request
 			dataset = GlobalPresetUser
 		}
 
+		if err := validateDicePresets(p.Presets); err != nil {
+			a.Logf("refusing to store invalid die-roll preset from %s: %v", requester.Auth.Username, err)
+			requester.Conn.Send(mapper.Failed, mapper.FailedMessagePayload{
+				IsError: true,
+				Command: p.RawMessage(),
+				Reason:  err.Error(),
+			})
+			return
+		}
+
 		if err := a.StoreDicePresets(dataset, p.Presets, false); err != nil {
 			a.Logf("error adding to die-roll preset: %v", err)
 		}
@@ -1274,6 +1361,181 @@ func (a *Application) HandleServerMessage(payload mapper.MessagePayload, request
 			a.Logf("error sending die-roll presets: %v", err)
 		}
 
+	case mapper.DefineRandomTablesMessagePayload:
+		if requester.Auth == nil {
+			a.Logf("Unable to store random tables for unauthenticated user")
+			return
+		}
+
+		target := requester.Auth.Username
+		dataset := target
+		if (p.Global || (p.For != "" && p.For != target)) && !requester.Auth.GmMode {
+			a.Logf("refusing to execute privileged command %v %v for non-GM user %s", p.MessageType(), p, requester.Auth.Username)
+			requester.Conn.Send(mapper.Priv, mapper.PrivMessagePayload{
+				Command: p.RawMessage(),
+				Reason:  "You are not authorized to change the random tables for that user",
+			})
+			return
+		}
+		if p.For != "" {
+			target = p.For
+			dataset = target
+		}
+		if p.Global {
+			dataset = GlobalPresetUser
+		}
+
+		for _, table := range p.Tables {
+			if err := table.Validate(); err != nil {
+				a.Logf("refusing to store invalid random table from %s: %v", requester.Auth.Username, err)
+				requester.Conn.Send(mapper.Failed, mapper.FailedMessagePayload{
+					IsError: true,
+					Command: p.RawMessage(),
+					Reason:  err.Error(),
+				})
+				return
+			}
+		}
+
+		if err := a.StoreRandomTables(dataset, p.Tables); err != nil {
+			a.Logf("error storing random tables: %v", err)
+		}
+		if err := a.SendRandomTables(target, p.Global, true); err != nil {
+			a.Logf("error sending random tables after changing them: %v", err)
+		}
+
+	case mapper.QueryRandomTablesMessagePayload:
+		if requester.Auth == nil {
+			a.Logf("Unable to query random tables for unauthenticated user")
+			return
+		}
+
+		target := requester.Auth.Username
+		if p.For != "" && p.For != target {
+			if !requester.Auth.GmMode {
+				a.Logf("refusing to execute privileged command %v %v for non-GM user %s", p.MessageType(), p, requester.Auth.Username)
+				requester.Conn.Send(mapper.Priv, mapper.PrivMessagePayload{
+					Command: p.RawMessage(),
+					Reason:  "You are not authorized to retrieve the random tables for that user",
+				})
+				return
+			}
+			target = p.For
+		}
+
+		if err := a.SendRandomTables(target, p.Global, false); err != nil {
+			a.Logf("error sending random tables: %v", err)
+		}
+
+	case mapper.RevealRollSeedMessagePayload:
+		if requester.Auth == nil {
+			a.Logf("Unable to reveal roll seed for unauthenticated user")
+			return
+		}
+
+		if p.Commitment == "" {
+			if requester.RollSeed == nil {
+				requester.Conn.Send(mapper.Failed, mapper.FailedMessagePayload{
+					Command: p.RawMessage(),
+					Reason:  "This server is not making verifiable die rolls.",
+				})
+				return
+			}
+			a.EndRollSeries(requester)
+			a.StartRollSeries(requester)
+			return
+		}
+
+		user, seed, err := a.QueryRollSeed(p.Commitment)
+		if err != nil {
+			requester.Conn.Send(mapper.Failed, mapper.FailedMessagePayload{
+				Command: p.RawMessage(),
+				Reason:  fmt.Sprintf("Unable to reveal that roll seed: %v", err),
+			})
+			return
+		}
+		requester.Conn.Send(mapper.RollSeed, mapper.RollSeedMessagePayload{
+			User:       user,
+			Commitment: p.Commitment,
+			Revealed:   &seed,
+		})
+
+	case mapper.QueryRollHistoryMessagePayload:
+		if requester.Auth == nil {
+			a.Logf("Unable to query roll history for unauthenticated user")
+			return
+		}
+
+		rolls, err := a.QueryRollHistory(p.Users, p.Since, p.Until, p.Limit, requester.Auth.Username, requester.Auth.GmMode)
+		if err != nil {
+			a.Logf("error querying roll history: %v", err)
+			requester.Conn.Send(mapper.Failed, mapper.FailedMessagePayload{
+				Command: p.RawMessage(),
+				Reason:  fmt.Sprintf("Unable to retrieve roll history: %v", err),
+			})
+			return
+		}
+		requester.Conn.Send(mapper.UpdateRollHistory, mapper.UpdateRollHistoryMessagePayload{Rolls: rolls})
+
+	case mapper.RollTableMessagePayload:
+		if requester.Auth == nil {
+			a.Logf("refusing to accept table roll from unauthenticated user")
+			requester.Conn.Send(mapper.RollResult, mapper.RollResultMessagePayload{
+				ChatCommon: mapper.ChatCommon{
+					MessageID: <-a.MessageIDGenerator,
+					Sent:      time.Now(),
+				},
+				RequestID: p.RequestID,
+				Result: dice.StructuredResult{
+					InvalidRequest: true,
+					Details: dice.StructuredDescriptionSet{
+						{Type: "error", Value: "I can't accept your table roll request. I don't know who you even are."},
+					},
+				},
+			})
+			return
+		}
+
+		var results []dice.StructuredResult
+		tables, err := a.QueryRandomTables(requester.Auth.Username, false)
+		if err == nil {
+			requester.D.DefineTables(tables)
+			_, results, err = requester.D.RollTable(p.Table)
+		}
+		if err != nil {
+			requester.Conn.Send(mapper.RollResult, mapper.RollResultMessagePayload{
+				ChatCommon: mapper.ChatCommon{
+					MessageID:  <-a.MessageIDGenerator,
+					Recipients: p.Recipients,
+					ToAll:      p.ToAll,
+					ToGM:       p.ToGM,
+					Sender:     requester.Auth.Username,
+					Sent:       time.Now(),
+				},
+				RequestID: p.RequestID,
+				Result: dice.StructuredResult{
+					InvalidRequest: true,
+					Details: dice.StructuredDescriptionSet{
+						{Type: "error", Value: fmt.Sprintf("Unable to roll on that table: %v", err)},
+					},
+				},
+			})
+			return
+		}
+
+		a.sendRollResults(requester, mapper.RollDiceMessagePayload{
+			ChatCommon: p.ChatCommon,
+			RequestID:  p.RequestID,
+		}, "", results, nil, func() (string, dice.StructuredResult, error) {
+			return "", dice.StructuredResult{
+				ResultSuppressed: true,
+				Details: dice.StructuredDescriptionSet{
+					{Type: "notice", Value: "roll to GM"},
+					{Type: "table", Value: p.Table},
+				},
+			}, nil
+		})
+
 	case mapper.ChatMessageMessagePayload:
 		if requester.Auth == nil {
 			a.Logf("refusing to pass on chat message from unauthenticated user")
@@ -2353,6 +2615,145 @@ func (a *Application) SendGameState(client *mapper.ClientConnection) {
 	a.gameState.sync <- client
 }
 
+// genericRollLabel returns a die-roll title with the color information removed,
+// for clients which can't display it.
+func genericRollLabel(label string) string {
+	var genericParts []string
+	for _, part := range strings.Split(label, "‖") {
+		if pos := strings.IndexRune(part, '≡'); pos >= 0 {
+			genericParts = append(genericParts, part[:pos])
+		} else {
+			genericParts = append(genericParts, part)
+		}
+	}
+	return strings.Join(genericParts, ", ")
+}
+
+// sendRollResults distributes the results of a die roll or table roll made on
+// behalf of the requester to everyone who is supposed to see them, as described
+// by the ChatCommon fields of p, and adds them to the chat history. If the results
+// are going only to the GM, the receipt function is called to produce the
+// description of the roll sent to everyone else. If verification is not nil, it is
+// included with each result so they can be checked later.
+func (a *Application) sendRollResults(requester *mapper.ClientConnection, p mapper.RollDiceMessagePayload, label string, results []dice.StructuredResult, verification *dice.RollVerification, receipt func() (string, dice.StructuredResult, error)) {
+	genericLabel := genericRollLabel(label)
+
+	response := mapper.RollResultMessagePayload{
+		ChatCommon: mapper.ChatCommon{
+			Sender:     requester.Auth.Username,
+			Recipients: p.Recipients,
+			ToAll:      p.ToAll,
+			ToGM:       p.ToGM,
+			Sent:       time.Now(),
+		},
+		Title:        label,
+		RequestID:    p.RequestID,
+		Type:         p.Type,
+		Targets:      p.Targets,
+		Verification: verification,
+	}
+
+	if p.ToGM {
+		receiptMessageID := <-a.MessageIDGenerator
+		receiptGenericLabel := genericLabel
+		var receiptLabel string
+		var receiptResult dice.StructuredResult
+		var err error
+		if requester.Auth.GmMode {
+			receiptGenericLabel = ""
+			receiptResult = dice.StructuredResult{
+				ResultSuppressed: true,
+				Details: dice.StructuredDescriptionSet{
+					{Type: "notice", Value: "rolls behind screen"},
+				},
+			}
+		} else {
+			receiptLabel, receiptResult, err = receipt()
+			if err != nil {
+				receiptResult = dice.StructuredResult{
+					ResultSuppressed: true,
+					Details: dice.StructuredDescriptionSet{
+						{Type: "notice", Value: "roll to GM"},
+						{Type: "error", Value: fmt.Sprintf("error preparing receipt message: %v", err)},
+					},
+				}
+			}
+		}
+
+		receiptPayload := mapper.RollResultMessagePayload{
+			ChatCommon: mapper.ChatCommon{
+				MessageID: receiptMessageID,
+				Sender:    requester.Auth.Username,
+				ToAll:     true,
+				Sent:      time.Now(),
+			},
+			RequestID: p.RequestID,
+			Title:     receiptLabel,
+			Result:    receiptResult,
+			Type:      p.Type,
+			Targets:   p.Targets,
+		}
+		if err := a.AddToChatHistory(receiptMessageID, mapper.RollResult, receiptPayload); err != nil {
+			a.Logf("unable to add RollResult receipt to chat history: %v", err)
+		}
+		for _, peer := range a.GetClients() {
+			if peer.Auth == nil || !peer.Auth.GmMode {
+				if !peer.Features.DiceColorBoxes {
+					receiptPayload.Title = receiptGenericLabel
+				} else {
+					receiptPayload.Title = receiptLabel
+				}
+
+				receiptPayload.Origin = peer == requester
+				peer.Conn.Send(mapper.RollResult, receiptPayload)
+			}
+		}
+	}
+
+	for seq, r := range results {
+		response.MessageID = <-a.MessageIDGenerator
+		response.Result = r
+		response.MoreResults = seq+1 < len(results)
+
+		if err := a.AddToChatHistory(response.MessageID, mapper.RollResult, response); err != nil {
+			a.Logf("unable to add RollResult event to chat history: %v", err)
+		}
+
+		for _, peer := range a.GetClients() {
+			if p.ToGM {
+				if peer.Auth == nil || !peer.Auth.GmMode {
+					// we already handled this case above
+					continue
+				}
+			} else if !p.ToAll {
+				if peer.Auth == nil || peer.Auth.Username == "" {
+					a.Debugf(DebugIO, "sending to explicit list but we don't know who %v is (skipped)", peer.IdTag())
+					continue
+				}
+				if peer.Auth.Username != requester.Auth.Username && slices.Index(p.Recipients, peer.Auth.Username) < 0 {
+					a.Debugf(DebugIO, "sending to explicit list but user \"%s\" (from %v) isn't on the list (skipped)", peer.Auth.Username, peer.IdTag())
+					continue
+				}
+			}
+
+			if peer.Features.DiceColorBoxes {
+				response.Title = label
+			} else {
+				response.Title = genericLabel
+			}
+
+			response.Origin = peer == requester
+			if !peer.Features.DiceColorLabels {
+				if err := peer.Conn.Send(mapper.RollResult, stripColorsFromResponse(response)); err != nil {
+					a.Logf("error sending color-stripped die-roll result %v to %v: %v", response, peer.IdTag(), err)
+				}
+			} else if err := peer.Conn.Send(mapper.RollResult, response); err != nil {
+				a.Logf("error sending die-roll result %v to %v: %v", response, peer.IdTag(), err)
+			}
+		}
+	}
+}
+
 // Strip color codes from strings in the message payload, returning a new copy without those color codes.
 func stripColorsFromResponse(result mapper.RollResultMessagePayload) mapper.RollResultMessagePayload {
 	if func() bool {
@@ -30,6 +30,7 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/MadScienceZone/go-gma/v5/dice"
 	"github.com/MadScienceZone/go-gma/v5/mapper"
@@ -102,6 +103,51 @@ func (a *Application) dbOpen() error {
 		}
 	} else {
 		a.sqldb, err = sql.Open("sqlite3", "file:"+a.DatabaseName)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Tables added since the last schema upgrade script are created here
+	// if they're missing from an existing database.
+	_, err = a.sqldb.Exec(`
+		create table if not exists randomtables (
+			user       text not null,
+			name       text not null,
+			definition text not null,
+				primary key (user, name)
+		);
+		create table if not exists rollhistory (
+			id         integer primary key,
+			user       text    not null,
+			title      text    not null,
+			rollspec   text    not null,
+			dice       text    not null,
+			result     integer not null,
+			toall      integer(1) not null,
+			recipients text    not null,
+			togm       integer(1) not null,
+			rolltime   integer not null
+		);
+		create index if not exists rollhistory_user on rollhistory (user, rolltime);
+		create table if not exists rollseeds (
+			commitment text    primary key,
+			user       text    not null,
+			seed       integer not null,
+			salt       text    not null,
+			started    integer not null,
+			ended      integer not null default 0
+		);`)
+	if err != nil {
+		a.Logf("unable to update sqlite3 database %s schema: %v", a.DatabaseName, err)
+		return err
+	}
+
+	// Any series of verifiable die rolls still open when the server last
+	// stopped is over now, so their seeds may be revealed.
+	_, err = a.sqldb.Exec(`update rollseeds set ended = ? where ended = 0`, time.Now().UnixNano())
+	if err != nil {
+		a.Logf("unable to close unfinished die-roll seeds in %s: %v", a.DatabaseName, err)
 	}
 	return err
 }
@@ -382,6 +428,17 @@ func (a *Application) StoreDicePresetDelegates(user string, delegates []string)
 	return nil
 }
 
+// validateDicePresets checks that the parameters declared in each of a set of
+// die-roll presets make sense, so we don't store presets nobody can use.
+func validateDicePresets(presets []dice.DieRollPreset) error {
+	for _, preset := range presets {
+		if _, err := dice.ParsePresetParameters(preset.DieRollSpec); err != nil {
+			return fmt.Errorf("die-roll preset \"%s\" has invalid parameters: %v", preset.Name, err)
+		}
+	}
+	return nil
+}
+
 func (a *Application) StoreDicePresets(user string, presets []dice.DieRollPreset, deleteOld bool) error {
 	if deleteOld {
 		a.Debugf(DebugDB, "removing existing die-roll presets for %s", user)
@@ -466,8 +523,34 @@ func (a *Application) SendDicePresets(user string, onlyGlobal bool, broadcast bo
 		return err
 	}
 
-	var rows *sql.Rows
 	var pset mapper.UpdateDicePresetsMessagePayload
+	if pset.Presets, err = a.QueryDicePresets(user, onlyGlobal); err != nil {
+		return err
+	}
+	pset.Delegates = delegates
+	pset.DelegateFor = delegateFor
+	if onlyGlobal {
+		pset.Global = true
+	} else {
+		pset.For = user
+	}
+
+	for _, peer := range a.GetClients() {
+		if peer.Auth != nil {
+			if (onlyGlobal && broadcast) || (peer.Auth.Username == user || slices.Contains(delegates, peer.Auth.Username)) {
+				peer.Conn.Send(mapper.UpdateDicePresets, pset)
+			}
+		}
+	}
+	return nil
+}
+
+// QueryDicePresets returns the global die-roll presets and, unless onlyGlobal
+// is true, those belonging to the user.
+func (a *Application) QueryDicePresets(user string, onlyGlobal bool) ([]dice.DieRollPreset, error) {
+	var rows *sql.Rows
+	var err error
+	var presets []dice.DieRollPreset
 
 	if onlyGlobal {
 		rows, err = a.sqldb.Query(`select user, name, description, rollspec from dicepresets where user = ?`, GlobalPresetUser)
@@ -475,7 +558,7 @@ func (a *Application) SendDicePresets(user string, onlyGlobal bool, broadcast bo
 		rows, err = a.sqldb.Query(`select user, name, description, rollspec from dicepresets where user = ? or user = ?`, user, GlobalPresetUser)
 	}
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer rows.Close()
 
@@ -483,28 +566,106 @@ func (a *Application) SendDicePresets(user string, onlyGlobal bool, broadcast bo
 		var preset dice.DieRollPreset
 		var puser string
 		if err := rows.Scan(&puser, &preset.Name, &preset.Description, &preset.DieRollSpec); err != nil {
-			return err
+			return nil, err
 		}
 		if puser == GlobalPresetUser {
 			preset.Global = true
 		}
-		pset.Presets = append(pset.Presets, preset)
+		var perr error
+		if preset.Parameters, perr = dice.ParsePresetParameters(preset.DieRollSpec); perr != nil {
+			a.Logf("die-roll preset \"%s\" for %s has invalid parameters: %v", preset.Name, puser, perr)
+		}
+		presets = append(presets, preset)
 	}
 	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return presets, nil
+}
+
+// StoreRandomTables replaces the set of random tables stored for a user
+// (or the global set if user is GlobalPresetUser).
+func (a *Application) StoreRandomTables(user string, tables []dice.RandomTable) error {
+	a.Debugf(DebugDB, "removing existing random tables for %s", user)
+	result, err := a.sqldb.Exec(`delete from randomtables where user = ?`, user)
+	if err != nil {
 		return err
 	}
-	pset.Delegates = delegates
-	pset.DelegateFor = delegateFor
+	a.debugDbAffected(result, fmt.Sprintf("clear old random tables for %s", user))
+
+	for i, table := range tables {
+		table.Global = false
+		definition, err := json.Marshal(table)
+		if err != nil {
+			return err
+		}
+		a.Debugf(DebugDB, "adding new random table %s for %s", table.Name, user)
+		result, err := a.sqldb.Exec(`replace into randomtables (user, name, definition) values (?, ?, ?)`,
+			user, table.Name, string(definition))
+		if err != nil {
+			return err
+		}
+		a.debugDbAffected(result, fmt.Sprintf("add random table #%d for %s", i, user))
+	}
+	return nil
+}
+
+// QueryRandomTables returns the random tables available to a user: the system-wide
+// global tables followed by the user's own. If onlyGlobal is true, only the global
+// tables are returned.
+func (a *Application) QueryRandomTables(user string, onlyGlobal bool) ([]dice.RandomTable, error) {
+	var rows *sql.Rows
+	var err error
+	var tables []dice.RandomTable
+
 	if onlyGlobal {
-		pset.Global = true
+		rows, err = a.sqldb.Query(`select user, definition from randomtables where user = ? order by name`, GlobalPresetUser)
 	} else {
-		pset.For = user
+		rows, err = a.sqldb.Query(`select user, definition from randomtables where user = ? or user = ? order by user = ? desc, name`, user, GlobalPresetUser, GlobalPresetUser)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table dice.RandomTable
+		var tuser, definition string
+		if err := rows.Scan(&tuser, &definition); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(definition), &table); err != nil {
+			return nil, fmt.Errorf("random table definition for %s is corrupt: %v", tuser, err)
+		}
+		table.Global = tuser == GlobalPresetUser
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+// SendRandomTables transmits the random tables available to a user as an
+// UpdateRandomTablesMessage ("DT=") to all clients logged in as that user.
+//
+// If the onlyGlobal parameter is true, then only the system-wide global set will be sent,
+// and if broadcast is also true, it will be sent to all connected users.
+func (a *Application) SendRandomTables(user string, onlyGlobal bool, broadcast bool) error {
+	tables, err := a.QueryRandomTables(user, onlyGlobal)
+	if err != nil {
+		return err
+	}
+
+	update := mapper.UpdateRandomTablesMessagePayload{
+		Global: onlyGlobal,
+		Tables: tables,
+	}
+	if !onlyGlobal {
+		update.For = user
 	}
 
 	for _, peer := range a.GetClients() {
 		if peer.Auth != nil {
-			if (onlyGlobal && broadcast) || (peer.Auth.Username == user || slices.Contains(delegates, peer.Auth.Username)) {
-				peer.Conn.Send(mapper.UpdateDicePresets, pset)
+			if (onlyGlobal && broadcast) || peer.Auth.Username == user {
+				peer.Conn.Send(mapper.UpdateRandomTables, update)
 			}
 		}
 	}
@@ -538,6 +699,130 @@ func (a *Application) AddToChatHistory(id int, chatType mapper.ServerMessage, ch
 	return nil
 }
 
+// AddToRollHistory records a die roll made by the server.
+func (a *Application) AddToRollHistory(entry dice.RollHistoryEntry) error {
+	jdata, err := json.Marshal(entry.Dice)
+	if err != nil {
+		return err
+	}
+	jrecipients, err := json.Marshal(entry.Recipients)
+	if err != nil {
+		return err
+	}
+	result, err := a.sqldb.Exec(`insert into rollhistory (user, title, rollspec, dice, result, toall, recipients, togm, rolltime) values (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.User, entry.Title, entry.RollSpec, string(jdata), entry.Result, entry.ToAll, string(jrecipients), entry.ToGM, entry.When.UnixNano())
+	if err != nil {
+		return err
+	}
+	a.debugDbAffected(result, "add to roll history")
+	return nil
+}
+
+// QueryRollHistory returns the die rolls made by the given users (or everyone if
+// users is empty) between the since and until times (if not zero), in the order
+// they were made. If limit is positive, only the most recent limit rolls are returned.
+// Only the rolls the requesting viewer (who is the GM if gm is true) was allowed to
+// see when they were made are included (see dice.RollHistoryEntry.VisibleTo).
+func (a *Application) QueryRollHistory(users []string, since, until time.Time, limit int, viewer string, gm bool) ([]dice.RollHistoryEntry, error) {
+	var conditions []string
+	var args []any
+
+	if len(users) > 0 {
+		conditions = append(conditions, "user in (?"+strings.Repeat(",?", len(users)-1)+")")
+		for _, user := range users {
+			args = append(args, user)
+		}
+	}
+	if !since.IsZero() {
+		conditions = append(conditions, "rolltime >= ?")
+		args = append(args, since.UnixNano())
+	}
+	if !until.IsZero() {
+		conditions = append(conditions, "rolltime < ?")
+		args = append(args, until.UnixNano())
+	}
+
+	query := `select user, title, rollspec, dice, result, toall, recipients, togm, rolltime from rollhistory`
+	if len(conditions) > 0 {
+		query += " where " + strings.Join(conditions, " and ")
+	}
+	query += " order by id desc"
+
+	rows, err := a.sqldb.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []dice.RollHistoryEntry
+	for rows.Next() && (limit <= 0 || len(history) < limit) {
+		var entry dice.RollHistoryEntry
+		var jdata, jrecipients string
+		var when int64
+		if err := rows.Scan(&entry.User, &entry.Title, &entry.RollSpec, &jdata, &entry.Result, &entry.ToAll, &jrecipients, &entry.ToGM, &when); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(jrecipients), &entry.Recipients); err != nil {
+			return nil, err
+		}
+		if !entry.VisibleTo(viewer, gm) {
+			continue
+		}
+		if err := json.Unmarshal([]byte(jdata), &entry.Dice); err != nil {
+			return nil, err
+		}
+		entry.When = time.Unix(0, when)
+		history = append(history, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	slices.Reverse(history)
+	return history, nil
+}
+
+// StoreRollSeed records the seed for a new series of verifiable die rolls.
+func (a *Application) StoreRollSeed(user string, seed dice.SeedCommitment) error {
+	result, err := a.sqldb.Exec(`insert into rollseeds (commitment, user, seed, salt, started) values (?, ?, ?, ?, ?)`,
+		seed.Commitment(), user, seed.Seed, seed.Salt, time.Now().UnixNano())
+	if err != nil {
+		return err
+	}
+	a.debugDbAffected(result, "store roll seed")
+	return nil
+}
+
+// EndRollSeed records that a series of verifiable die rolls has ended,
+// so its seed may be revealed.
+func (a *Application) EndRollSeed(commitment string) error {
+	result, err := a.sqldb.Exec(`update rollseeds set ended = ? where commitment = ?`, time.Now().UnixNano(), commitment)
+	if err != nil {
+		return err
+	}
+	a.debugDbAffected(result, "end roll seed")
+	return nil
+}
+
+// QueryRollSeed returns the user and seed for a series of verifiable die rolls
+// which has ended. It is an error to ask for the seed of one still in progress.
+func (a *Application) QueryRollSeed(commitment string) (string, dice.SeedCommitment, error) {
+	var user string
+	var seed dice.SeedCommitment
+	var ended int64
+
+	err := a.sqldb.QueryRow(`select user, seed, salt, ended from rollseeds where commitment = ?`, commitment).Scan(&user, &seed.Seed, &seed.Salt, &ended)
+	if err == sql.ErrNoRows {
+		return "", seed, fmt.Errorf("there is no die-roll seed with commitment %s", commitment)
+	}
+	if err != nil {
+		return "", seed, err
+	}
+	if ended == 0 {
+		return "", dice.SeedCommitment{}, fmt.Errorf("the die rolls made with that seed are still in progress")
+	}
+	return user, seed, nil
+}
+
 func (a *Application) LogDatabaseContents() error {
 	a.Log("Database Contents:")
 
@@ -566,9 +851,18 @@ func (a *Application) LogDatabaseContents() error {
 	if err := dumpTable("dice presets", "dicepresets", "user", "name", "description", "rollspec"); err != nil {
 		return err
 	}
+	if err := dumpTable("random tables", "randomtables", "user", "name", "definition"); err != nil {
+		return err
+	}
 	if err := dumpTable("chat history", "chats", "msgid", "msgtype", "rawdata"); err != nil {
 		return err
 	}
+	if err := dumpTable("roll history", "rollhistory", "user", "title", "rollspec", "dice", "result", "toall", "recipients", "togm", "rolltime"); err != nil {
+		return err
+	}
+	if err := dumpTable("roll seeds", "rollseeds", "commitment", "user", "seed", "salt", "started", "ended"); err != nil {
+		return err
+	}
 	if err := dumpTable("images known", "images", "name", "zoom", "location", "islocal"); err != nil {
 		return err
 	}
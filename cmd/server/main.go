@@ -52,7 +52,10 @@ To guard against nuisance or malicious port scans and other superfluous connecti
 Usage:
 
 	   server [-cpuprofile path] [−debug flags] [−endpoint [hostname]:port] [-help] [−init−file path]
-	          [−log−file path] [−password−file path] −sqlite path [−telemetry−log path] [-telemetry-name name]
+	          [−log−file path] [−password−file path] [-record-dir path] −sqlite path
+	          [−telemetry−log path] [-telemetry-name name]
+	          [-tls-cert path -tls-key path [-tls-client-ca path]] [-verifiable-rolls]
+	          [-websocket [hostname]:port[/path]]
 
 	   -debug flags
 	      Add debugging information to the log file. The flags value is a comma-separated
@@ -91,6 +94,10 @@ Usage:
 	          user3:password3
 	      Only the first line is required.
 
+	   -record-dir path
+	      Record each client's session in a new file in the named directory (see
+	      map-replay).
+
 	   -cpuprofile path
 	      Enables CPU profiling, saving sampled performance data to the named path, which can
 		  then be analyzed with tools such as "go tool pprof".
@@ -111,6 +118,33 @@ Usage:
 		  You can also accomplish this by setting the NEW_RELIC_APP_NAME
 		  environment variable.
 
+	   -tls-cert path
+	   -tls-key path
+	      Accept client connections over TLS (on both the -endpoint and -websocket ports),
+	      using the server certificate and private key in the named PEM files. Clients must
+	      then connect using TLS. Without TLS, the authentication protocol is vulnerable to
+	      an attacker able to intercept and alter the traffic between clients and the server.
+
+	   -tls-client-ca path
+	      With -tls-cert and -tls-key, allow clients to log in by presenting a certificate
+	      signed by one of the certificate authorities in the named PEM file, instead of
+	      giving a password. Such clients are logged in as the user named by the common
+	      name in their certificate (with GM privileges if that name is "GM"). Clients
+	      without a certificate log in with a password as usual.
+
+	   -verifiable-rolls
+	      Make every die roll from a seed which the server commits to (by publishing its
+	      hash) when each client logs in, and reveals when the client logs out. Clients
+	      may then check that their rolls were not tampered with (see "roll -verify").
+
+	   -websocket [hostname]:port[/path]
+	      In addition to the TCP endpoint, accept client connections over WebSockets
+	      on the specified port, at the given URL path (default "/"). Clients such as
+	      web browsers which can't open plain TCP sockets may connect this way, using
+	      a URL such as "ws://hostname:port/path" (or "wss://hostname:port/path" if TLS
+	      is enabled). The protocol is otherwise exactly the same, with each line of text
+	      sent in text frames.
+
 See the full documentation in the accompanying manual file man/man6/server.6.pdf (or run “gma man go server” if you have the GMA Core package installed as well as Go-GMA).
 
 See also the server protocol specification in the man/man7/mapper-protocol.7.pdf of the GMA-Mapper package (or run “gma man mapper-protocol”). This is also printed in Appendix F of the GMA Game Master's Guide.
@@ -119,11 +153,14 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime/pprof"
+	"strings"
 	"syscall"
 	"time"
 
@@ -298,6 +335,9 @@ func main() {
 		app.Logf("unable to open incoming TCP %s: %v", app.Endpoint, err)
 		os.Exit(2)
 	}
+	if app.TLSConfig != nil {
+		incoming = tls.NewListener(incoming, app.TLSConfig)
+	}
 	app.Logf("Listening on %s", app.Endpoint)
 	defer func() {
 		if err := incoming.Close(); err != nil {
@@ -313,11 +353,37 @@ func main() {
 	go eventMonitor(sigChannel, stopChannel, &app)
 	go acceptIncomingConnections(incoming, &app)
 
+	if app.WebSocketEndpoint != "" {
+		wsTCP, err := net.Listen("tcp", app.WebSocketEndpoint)
+		if err != nil {
+			app.Logf("unable to open incoming WebSocket %s: %v", app.WebSocketEndpoint, err)
+			os.Exit(2)
+		}
+		if app.TLSConfig != nil {
+			wsTCP = tls.NewListener(wsTCP, app.TLSConfig)
+		}
+		wsIncoming := mapper.NewWebSocketListener(wsTCP, app.WebSocketPath)
+		app.Logf("Listening for WebSocket connections on %s at %s", app.WebSocketEndpoint, app.WebSocketPath)
+		defer func() {
+			if err := wsIncoming.Close(); err != nil {
+				app.Logf("failure closing incoming WebSocket listener: %v", err)
+			}
+		}()
+		go acceptIncomingConnections(wsIncoming, &app)
+	}
+
 	<-stopChannel
 	app.Log("received STOP signal; shutting down")
 	app.Log("server shut down")
 }
 
+// recordingName returns the filename for a recording of the session
+// with the client at the given address.
+func recordingName(addr net.Addr) string {
+	return fmt.Sprintf("%s-%s.rec", time.Now().Format("20060102-150405.000"),
+		strings.NewReplacer(":", "_", "[", "", "]", "", "/", "_").Replace(addr.String()))
+}
+
 func acceptIncomingConnections(incoming net.Listener, app *Application) {
 	for {
 		app.Debug(DebugIO, "waiting for next incoming client")
@@ -338,20 +404,43 @@ func acceptIncomingConnections(incoming net.Listener, app *Application) {
 		ourDebugFlags := DebugFlagNameSlice(app.DebugLevel)
 		debugFlags, _ := mapper.NamedDebugFlags(ourDebugFlags...)
 
-		newConnection, err := mapper.NewClientConnection(client,
+		opts := []mapper.ClientConnectionOption{
 			mapper.WithServer(app),
 			mapper.WithClientDebuggingLevel(debugFlags),
 			mapper.WithClientAuthenticator(auth),
 			mapper.WithQoSLogWindow(app.QoSLimits.Log.window),
 			mapper.WithQoSMessageRateLimit(app.QoSLimits.MessageRate.Count, app.QoSLimits.MessageRate.window),
 			mapper.WithQoSQueryImageLimit(app.QoSLimits.QueryImage.Count, app.QoSLimits.QueryImage.window),
-		)
+		}
+		if app.TLSConfig != nil && app.TLSConfig.ClientCAs != nil {
+			opts = append(opts, mapper.WithClientCertificateUsers(mapper.CertificateUsername))
+		}
+		var recorder *mapper.Recorder
+		if app.RecordDir != "" {
+			recorder, err = mapper.CreateRecording(filepath.Join(app.RecordDir, recordingName(client.RemoteAddr())))
+			if err != nil {
+				app.Logf("unable to record session: %v", err)
+			} else {
+				opts = append(opts, mapper.WithClientRecorder(recorder))
+			}
+		}
+		newConnection, err := mapper.NewClientConnection(client, opts...)
 		if err != nil {
 			app.Logf("unable to initialize client session: %v", err)
 			client.Close()
+			if recorder != nil {
+				recorder.Close()
+			}
 			continue
 		}
-		go newConnection.ServeToClient(context.Background(), app.ServerStarted, app.LastPing, app.NrApp)
+		go func() {
+			newConnection.ServeToClient(context.Background(), app.ServerStarted, app.LastPing, app.NrApp)
+			if recorder != nil {
+				if err := recorder.Close(); err != nil {
+					app.Logf("error closing session recording: %v", err)
+				}
+			}
+		}()
 	}
 }
 
@@ -0,0 +1,422 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package dice
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// UntypedDamage is the type of damage assigned to parts of a die-roll
+// expression which aren't labeled with a damage type.
+const UntypedDamage = "untyped"
+
+// EnergyDamageTypes lists the types of damage which aren't reduced by damage
+// reduction (although they may be by energy resistance). All other types of
+// damage, including untyped damage and physical damage types such as “slashing”,
+// are subject to damage reduction.
+//
+// These are the Pathfinder 1e energy types, but this may be changed to suit
+// other game systems.
+var EnergyDamageTypes = []string{
+	"acid", "cold", "electricity", "fire", "force", "negative", "positive", "sonic",
+}
+
+// PhysicalDamageTypes lists the types of physical damage a label may name,
+// which (unlike EnergyDamageTypes) are subject to damage reduction.
+//
+// These are the Pathfinder 1e physical damage types, but this may be changed
+// to suit other game systems.
+var PhysicalDamageTypes = []string{
+	"bludgeoning", "piercing", "slashing",
+}
+
+// damageType returns the type of damage a label indicates, which is the first
+// word of the label in lower case if that is one of the EnergyDamageTypes or
+// PhysicalDamageTypes. Any other value (including those without a label, or
+// labeled “attack” or “sneak”) is untyped damage.
+func damageType(label string) string {
+	words := labelWords(label)
+	if len(words) == 0 || !(containsFold(EnergyDamageTypes, words[0]) || containsFold(PhysicalDamageTypes, words[0])) {
+		return UntypedDamage
+	}
+	return strings.ToLower(words[0])
+}
+
+// DamageTypes breaks down the result of the most recent roll of the dice by
+// the type of damage each part of the expression represents. The type of damage
+// is given by the label of each value added together at the top level of the
+// expression, if its first word names one of the EnergyDamageTypes or
+// PhysicalDamageTypes, so rolling “15d6 + 15 fire + 1 acid” might yield
+//
+//	map[string]int{"untyped": 52, "fire": 15, "acid": 1}
+//
+// A parenthesized group followed by a label (as in “(2d6+3) slashing”) is all
+// of that type. A group without one is broken down by the values added within it.
+// Any other part of the expression (such as “2d6 × 2 fire”) takes its type from
+// its labels if they agree on one.
+//
+// If the dice haven't been rolled, or none of the damage has a type, nil is returned.
+// The breakdown does not take into account any min or max limits placed on the
+// overall result.
+func (d *Dice) DamageTypes() map[string]int {
+	if d == nil || !d.Rolled {
+		return nil
+	}
+	damage := make(map[string]int)
+	if err := addDamageTerms(damage, d.multiDice, 1); err != nil {
+		return nil
+	}
+	for t := range damage {
+		if t != UntypedDamage {
+			return damage
+		}
+	}
+	return nil
+}
+
+// addDamageTerms adds the value of each of the terms added together at the top level
+// of the expression components (multiplied by sign) to the damage total for its type.
+func addDamageTerms(damage map[string]int, components []dieComponent, sign int) error {
+	var term []dieComponent
+	termSign := sign
+	depth := 0
+
+	addTerm := func() error {
+		if len(term) == 0 {
+			return nil
+		}
+		if group := groupContents(term); group != nil {
+			return addDamageTerms(damage, group, termSign)
+		}
+		value, err := lastTermValue(term)
+		if err != nil {
+			return err
+		}
+		damage[termDamageType(term)] += termSign * value
+		return nil
+	}
+
+	for _, c := range components {
+		switch v := c.(type) {
+		case *dieBeginGroup:
+			depth++
+		case *dieEndGroup:
+			depth--
+		case *dieOperator:
+			if depth == 0 && (*v == '+' || *v == '-') {
+				if err := addTerm(); err != nil {
+					return err
+				}
+				term = nil
+				termSign = sign
+				if *v == '-' {
+					termSign = -sign
+				}
+				continue
+			}
+		}
+		term = append(term, c)
+	}
+	return addTerm()
+}
+
+// groupContents returns the components inside the parentheses if the term consists
+// of nothing but a single parenthesized group, or nil otherwise.
+func groupContents(term []dieComponent) []dieComponent {
+	if _, ok := term[0].(*dieBeginGroup); !ok || len(term) < 3 {
+		return nil
+	}
+	depth := 0
+	for i, c := range term {
+		switch c.(type) {
+		case *dieBeginGroup:
+			depth++
+		case *dieEndGroup:
+			depth--
+			if depth == 0 {
+				if i != len(term)-1 {
+					return nil
+				}
+				return term[1:i]
+			}
+		}
+	}
+	return nil
+}
+
+// lastTermValue evaluates a term of the expression using the values from the last
+// time the dice were rolled.
+func lastTermValue(term []dieComponent) (int, error) {
+	s := &evalStack{}
+	for _, c := range term {
+		switch v := c.(type) {
+		case *dieSpec:
+			s.push(float64(v.lastValue()))
+		case *dieConstant:
+			s.push(v.effectiveValue())
+		case *dieVariable:
+			s.push(v.effectiveValue())
+		default:
+			if err := c.compute(s); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return s.evaluate()
+}
+
+// termDamageType figures out the type of damage a term of the expression
+// represents. A label outside of any parentheses in the term decides it;
+// otherwise, if all the labels inside parentheses name the same type,
+// that's the type. If all else fails, the term is untyped.
+func termDamageType(term []dieComponent) string {
+	var outer string
+	inner := make(map[string]bool)
+	depth := 0
+	for _, c := range term {
+		var label string
+		switch v := c.(type) {
+		case *dieBeginGroup:
+			depth++
+		case *dieEndGroup:
+			depth--
+		case *dieLabel:
+			label = string(*v)
+		case *dieSpec:
+			label = v.Label
+		case *dieConstant:
+			label = v.Label
+		case *dieVariable:
+			label = v.Label
+		}
+		if strings.TrimSpace(label) == "" {
+			continue
+		}
+		if depth == 0 {
+			outer = damageType(label)
+		} else {
+			inner[damageType(label)] = true
+		}
+	}
+	if outer != "" {
+		return outer
+	}
+	if len(inner) == 1 {
+		for t := range inner {
+			return t
+		}
+	}
+	return UntypedDamage
+}
+
+// DefenderProfile describes a creature's defenses against damage.
+type DefenderProfile struct {
+	// Energy resistance: the amount by which damage of each type is reduced.
+	Resistances map[string]int `json:",omitempty"`
+
+	// Damage reduction against all types of damage other than the EnergyDamageTypes.
+	// If there is more than one, the best one not bypassed by the attack applies.
+	DR []DamageReduction `json:",omitempty"`
+
+	// Types of damage which do not harm the creature at all.
+	Immunities []string `json:",omitempty"`
+
+	// Types of damage the creature takes half again as much (+50%) of.
+	Vulnerabilities []string `json:",omitempty"`
+}
+
+// DamageReduction describes damage reduction such as “DR 10/magic”, which reduces
+// the damage from each attack by Amount unless the attack is one of the kinds
+// listed in BypassedBy. If BypassedBy is empty, nothing bypasses it (“DR 10/—”).
+type DamageReduction struct {
+	Amount     int
+	BypassedBy []string `json:",omitempty"`
+}
+
+func (dr DamageReduction) String() string {
+	if len(dr.BypassedBy) == 0 {
+		return fmt.Sprintf("DR %d/—", dr.Amount)
+	}
+	return fmt.Sprintf("DR %d/%s", dr.Amount, strings.Join(dr.BypassedBy, " or "))
+}
+
+// DamageTaken reports the damage a creature actually takes from an attack
+// after its defenses are taken into account.
+type DamageTaken struct {
+	// The total damage taken.
+	Total int
+
+	// The damage taken of each type.
+	ByType map[string]int
+
+	// Explanations of how the creature's defenses changed the damage, such as
+	// “fire resistance 10 (-10)”.
+	Notes []string `json:",omitempty"`
+}
+
+// DamageFrom applies the defender's defenses to the damage from a die-roll result.
+// If the result has no breakdown by damage type, it is all untyped damage.
+// See ApplyDamage for the meaning of attack.
+func (p DefenderProfile) DamageFrom(r StructuredResult, attack ...string) DamageTaken {
+	if r.DamageTypes == nil {
+		return p.ApplyDamage(map[string]int{UntypedDamage: r.Result}, attack...)
+	}
+	return p.ApplyDamage(r.DamageTypes, attack...)
+}
+
+// ApplyDamage figures out how much of the given damage (broken down by type, as from
+// Dice.DamageTypes) the defender actually takes.
+//
+// The optional attack values describe the attack, for the purpose of bypassing damage
+// reduction (e.g., "magic" or "silver"). Damage reduction is also bypassed by any type
+// of damage the attack deals, so 3 points of slashing damage bypass DR 5/slashing.
+//
+// For each type of damage, immunity negates it entirely. Otherwise, vulnerability
+// increases it by half, and then energy resistance reduces it. Finally, damage
+// reduction is subtracted from the total of all damage types which are not
+// EnergyDamageTypes. No type of damage is reduced below zero.
+func (p DefenderProfile) ApplyDamage(damage map[string]int, attack ...string) DamageTaken {
+	taken := DamageTaken{ByType: make(map[string]int)}
+	var types []string
+	for t := range damage {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	for _, t := range types {
+		amount := max(damage[t], 0)
+		switch {
+		case amount == 0:
+		case containsFold(p.Immunities, t):
+			taken.Notes = append(taken.Notes, fmt.Sprintf("immune to %s (-%d)", t, amount))
+			amount = 0
+		default:
+			if containsFold(p.Vulnerabilities, t) {
+				taken.Notes = append(taken.Notes, fmt.Sprintf("vulnerable to %s (+%d)", t, amount/2))
+				amount += amount / 2
+			}
+			for rt, r := range p.Resistances {
+				if r > 0 && strings.EqualFold(rt, t) {
+					reduced := min(r, amount)
+					taken.Notes = append(taken.Notes, fmt.Sprintf("%s resistance %d (-%d)", t, r, reduced))
+					amount -= reduced
+					break
+				}
+			}
+		}
+		taken.ByType[t] = amount
+	}
+
+	var physical []string
+	var physicalTotal int
+	for _, t := range types {
+		if !containsFold(EnergyDamageTypes, t) && taken.ByType[t] > 0 {
+			physical = append(physical, t)
+			physicalTotal += taken.ByType[t]
+		}
+	}
+	if dr, ok := p.bestDR(append(slices.Clone(attack), physical...)); ok && physicalTotal > 0 {
+		reduced := min(dr.Amount, physicalTotal)
+		taken.Notes = append(taken.Notes, dr.String()+" (-"+strconv.Itoa(reduced)+")")
+		for _, t := range physical {
+			r := min(reduced, taken.ByType[t])
+			taken.ByType[t] -= r
+			reduced -= r
+		}
+	}
+
+	for _, t := range types {
+		taken.Total += taken.ByType[t]
+	}
+	return taken
+}
+
+// bestDR returns the highest damage reduction the defender has which
+// isn't bypassed by any of the given attack qualities.
+func (p DefenderProfile) bestDR(attack []string) (DamageReduction, bool) {
+	var best DamageReduction
+	var found bool
+	for _, dr := range p.DR {
+		if dr.Amount <= 0 || (found && dr.Amount <= best.Amount) {
+			continue
+		}
+		bypassed := false
+		for _, b := range dr.BypassedBy {
+			if containsFold(attack, b) {
+				bypassed = true
+				break
+			}
+		}
+		if !bypassed {
+			best, found = dr, true
+		}
+	}
+	return best, found
+}
+
+// containsFold reports whether s is in list, without regard to case.
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(v string) bool {
+		return strings.EqualFold(v, s)
+	})
+}
+
+// @[00]@| Go-GMA 5.33.0
+// @[01]@|
+// @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+// @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+// @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+// @[13]@| points along that historical time line.
+// @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+// @[15]@| License as described in the accompanying LICENSE file distributed
+// @[16]@| with GMA.
+// @[17]@|
+// @[20]@| Redistribution and use in source and binary forms, with or without
+// @[21]@| modification, are permitted provided that the following conditions
+// @[22]@| are met:
+// @[23]@| 1. Redistributions of source code must retain the above copyright
+// @[24]@|    notice, this list of conditions and the following disclaimer.
+// @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+// @[26]@|    right notice, this list of conditions and the following dis-
+// @[27]@|    claimer in the documentation and/or other materials provided
+// @[28]@|    with the distribution.
+// @[29]@| 3. Neither the name of the copyright holder nor the names of its
+// @[30]@|    contributors may be used to endorse or promote products derived
+// @[31]@|    from this software without specific prior written permission.
+// @[32]@|
+// @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+// @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+// @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+// @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// @[45]@| SUCH DAMAGE.
+// @[46]@|
+// @[50]@| This software is not intended for any use or application in which
+// @[51]@| the safety of lives or property would be at risk due to failure or
+// @[52]@| defect of the software.
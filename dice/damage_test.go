@@ -0,0 +1,198 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package dice
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDamageTypes(t *testing.T) {
+	for i, test := range []struct {
+		spec     string
+		max      int
+		expected map[string]int
+	}{
+		{"15d6 + 15 fire + 1 acid", 106, map[string]int{"untyped": 90, "fire": 15, "acid": 1}},
+		{"2d6 fire + 1d6 Fire damage + 3 cold", 21, map[string]int{"fire": 18, "cold": 3}},
+		{"1d8 slashing + 2 enhancement + 1 morale + 2d6 sneak", 23, map[string]int{"slashing": 8, "untyped": 15}},
+		{"1d8 Piercing + 1d6 bludgeoning", 14, map[string]int{"piercing": 8, "bludgeoning": 6}},
+		{"(2d6+3) slashing + 1d6 fire", 21, map[string]int{"slashing": 15, "fire": 6}},
+		{"(2d6+8 fire)+(2d6+8 fire)", 40, map[string]int{"untyped": 24, "fire": 16}},
+		{"(1d6 fire + 2 fire) × 2 + 1d4", 20, map[string]int{"fire": 16, "untyped": 4}},
+		{"4d6 fire - 2 fire", 22, map[string]int{"fire": 22}},
+		{"d20 + 5", 25, nil},
+		{"d20 + 2 morale + 1 luck", 23, nil},
+		{"d20 + 5 attack", 25, nil},
+		{"(2d6+3) sneak attack + 1d6 hit", 21, nil},
+	} {
+		d, err := New(ByDescription(test.spec))
+		if err != nil {
+			t.Fatalf("test #%d (%s): error %v", i, test.spec, err)
+		}
+		if d.DamageTypes() != nil {
+			t.Errorf("test #%d (%s): damage types reported before rolling", i, test.spec)
+		}
+		result, err := d.MaxRoll()
+		if err != nil {
+			t.Fatalf("test #%d (%s): error %v", i, test.spec, err)
+		}
+		if result != test.max {
+			t.Errorf("test #%d (%s): result %d, expected %d", i, test.spec, result, test.max)
+		}
+		if damage := d.DamageTypes(); !reflect.DeepEqual(damage, test.expected) {
+			t.Errorf("test #%d (%s): damage types %v, expected %v", i, test.spec, damage, test.expected)
+		}
+	}
+}
+
+func TestDamageTypesInResults(t *testing.T) {
+	dr, err := NewDieRoller()
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+	_, results, err := dr.DoRoll("2d6 + 3 fire | maximized")
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("got %d results, expected 1", len(results))
+	}
+	if expected := map[string]int{"untyped": 12, "fire": 3}; !reflect.DeepEqual(results[0].DamageTypes, expected) {
+		t.Errorf("damage types %v, expected %v", results[0].DamageTypes, expected)
+	}
+}
+
+func TestApplyDamage(t *testing.T) {
+	for i, test := range []struct {
+		profile  DefenderProfile
+		damage   map[string]int
+		attack   []string
+		expected DamageTaken
+	}{
+		{
+			DefenderProfile{},
+			map[string]int{"untyped": 12, "fire": 5},
+			nil,
+			DamageTaken{Total: 17, ByType: map[string]int{"untyped": 12, "fire": 5}},
+		},
+		{
+			DefenderProfile{Resistances: map[string]int{"Fire": 10, "cold": 5}},
+			map[string]int{"untyped": 90, "fire": 15, "acid": 1},
+			nil,
+			DamageTaken{Total: 96, ByType: map[string]int{"untyped": 90, "fire": 5, "acid": 1}, Notes: []string{
+				"fire resistance 10 (-10)",
+			}},
+		},
+		{
+			DefenderProfile{Immunities: []string{"fire"}, Vulnerabilities: []string{"cold"}, Resistances: map[string]int{"cold": 2}},
+			map[string]int{"fire": 20, "cold": 7},
+			nil,
+			DamageTaken{Total: 8, ByType: map[string]int{"fire": 0, "cold": 8}, Notes: []string{
+				"vulnerable to cold (+3)",
+				"cold resistance 2 (-2)",
+				"immune to fire (-20)",
+			}},
+		},
+		{
+			DefenderProfile{DR: []DamageReduction{{Amount: 10, BypassedBy: []string{"magic"}}, {Amount: 5}}},
+			map[string]int{"slashing": 8, "untyped": 3, "fire": 6},
+			nil,
+			DamageTaken{Total: 7, ByType: map[string]int{"slashing": 0, "untyped": 1, "fire": 6}, Notes: []string{
+				"DR 10/magic (-10)",
+			}},
+		},
+		{
+			DefenderProfile{DR: []DamageReduction{{Amount: 10, BypassedBy: []string{"magic"}}, {Amount: 5}}},
+			map[string]int{"slashing": 8, "untyped": 3, "fire": 6},
+			[]string{"Magic"},
+			DamageTaken{Total: 12, ByType: map[string]int{"slashing": 3, "untyped": 3, "fire": 6}, Notes: []string{
+				"DR 5/— (-5)",
+			}},
+		},
+		{
+			DefenderProfile{DR: []DamageReduction{{Amount: 5, BypassedBy: []string{"silver", "slashing"}}}},
+			map[string]int{"slashing": 8},
+			nil,
+			DamageTaken{Total: 8, ByType: map[string]int{"slashing": 8}},
+		},
+		{
+			DefenderProfile{DR: []DamageReduction{{Amount: 5}}},
+			map[string]int{"untyped": -2, "fire": 4},
+			nil,
+			DamageTaken{Total: 4, ByType: map[string]int{"untyped": 0, "fire": 4}},
+		},
+	} {
+		taken := test.profile.ApplyDamage(test.damage, test.attack...)
+		if !reflect.DeepEqual(taken, test.expected) {
+			t.Errorf("test #%d: got %+v, expected %+v", i, taken, test.expected)
+		}
+	}
+}
+
+func TestDamageFrom(t *testing.T) {
+	profile := DefenderProfile{Resistances: map[string]int{"fire": 5}, DR: []DamageReduction{{Amount: 3}}}
+	if taken := profile.DamageFrom(StructuredResult{Result: 10}); taken.Total != 7 {
+		t.Errorf("untyped damage: got %d, expected 7", taken.Total)
+	}
+	if taken := profile.DamageFrom(StructuredResult{Result: 16, DamageTypes: map[string]int{"untyped": 6, "fire": 10}}); taken.Total != 8 {
+		t.Errorf("typed damage: got %d, expected 8", taken.Total)
+	}
+}
+
+// @[00]@| Go-GMA 5.33.0
+// @[01]@|
+// @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+// @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+// @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+// @[13]@| points along that historical time line.
+// @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+// @[15]@| License as described in the accompanying LICENSE file distributed
+// @[16]@| with GMA.
+// @[17]@|
+// @[20]@| Redistribution and use in source and binary forms, with or without
+// @[21]@| modification, are permitted provided that the following conditions
+// @[22]@| are met:
+// @[23]@| 1. Redistributions of source code must retain the above copyright
+// @[24]@|    notice, this list of conditions and the following disclaimer.
+// @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+// @[26]@|    right notice, this list of conditions and the following dis-
+// @[27]@|    claimer in the documentation and/or other materials provided
+// @[28]@|    with the distribution.
+// @[29]@| 3. Neither the name of the copyright holder nor the names of its
+// @[30]@|    contributors may be used to endorse or promote products derived
+// @[31]@|    from this software without specific prior written permission.
+// @[32]@|
+// @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+// @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+// @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+// @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// @[45]@| SUCH DAMAGE.
+// @[46]@|
+// @[50]@| This software is not intended for any use or application in which
+// @[51]@| the safety of lives or property would be at risk due to failure or
+// @[52]@| defect of the software.
+//
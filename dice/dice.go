@@ -48,6 +48,11 @@
 // type, created by the New function, if for some reason the DieRoller
 // interface won't provide what is needed.
 //
+// To examine or check a die-roll expression without rolling it, use Parse,
+// which returns the expression as a tree of Nodes:
+//
+//	expr, err := Parse("Attack=d20+16 | c")
+//
 // NEW in version 5.3: The die-roll expressions now honor the usual algebraic
 // order of operations instead of simply evaluating left-to-right. Parentheses
 // (round brackets) can be used for grouping in the usual sense for math expressions.
@@ -57,6 +62,7 @@ import (
 	"bufio"
 	cryptorand "crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
@@ -64,17 +70,20 @@ import (
 	"math/rand"
 	"os"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/MadScienceZone/go-gma/v5/tcllist"
 	"github.com/schwarmco/go-cartesian-product"
 )
 
 const MinimumSupportedDieRollPresetFileFormat = 1
-const MaximumSupportedDieRollPresetFileFormat = 2
+const MaximumSupportedDieRollPresetFileFormat = 3
 
 var DefaultSeed int64
 
@@ -139,6 +148,21 @@ type Dice struct {
 	// The random number generator to be used with this Dice
 	generator *rand.Rand
 
+	// Where to record the natural values rolled (DieRoller only)
+	naturals *naturalRecorder
+
+	// How to look up the values of variables used in the expression
+	variables VariableResolver
+
+	// Labels which mark critical damage as not multiplied (DieRoller only)
+	nonMultiplying []string
+
+	// Random tables to roll on (DieRoller only)
+	tables []RandomTable
+
+	// Presets which may be referenced in expressions (DieRoller only)
+	presets []DieRollPreset
+
 	_onlydie *dieSpec // for single-die rolls, this is the lone die
 }
 
@@ -173,10 +197,12 @@ type Dice struct {
 //
 // Each die‐roll expression has the general form
 //
-//	[>] [<n>[/<div>]] d <sides> [best|worst of <r>] [<label>]
+//	[>] [<n>[/<div>]] d <sides>[<modifiers>] [best|worst of <r>] [<label>]
 //
 // This calls for <n> dice with the given number of <sides> (which  may  be  a
-// number  or the character “%” which means percentile dice or d100).  The
+// number, the character “%” which means percentile dice or d100, the letter
+// “F” for FATE/Fudge dice, or a list of custom faces in braces such as
+// “{1,1,2,2,3,4}”; see below).  The
 // optional <div> part of the expression allows a fractional number of dice:
 // the  expression  “1/2d20” rolls half of a d20 (in other words, it rolls
 // 1d20 and divides the result by 2, truncating the result).  The optional qualifier
@@ -184,6 +210,55 @@ type Dice struct {
 // best result. (You may also use the word worst in place of best to  take
 // the lowest of the rolls.)
 //
+// A FATE (or Fudge) die, “dF”, has two faces each of -1, 0, and +1, so “4dF” yields a
+// result from -4 to +4. Any other die may be described by listing its faces in braces,
+// such as “d{1,1,2,2,3,4}” for a six-sided die with the values 1, 1, 2, 2, 3, and 4.
+// Each face may be a number, a name (which counts as 0), or a name and value
+// separated by a colon, such as “d{miss,hit:1,crit:2}”. The names of the faces rolled
+// are included in the structured description of the roll. Custom dice cannot explode.
+//
+// The <modifiers>, if any, must immediately follow the <sides> with no intervening
+// spaces. They may be any of the following:
+//
+//	!      Exploding dice: each die which rolls its maximum value is rolled again,
+//	       with the new roll added as another die (which may itself explode).
+//	!!     Compounding dice: as with exploding dice, but the extra rolls are added
+//	       into the value of the die which exploded rather than counted as new dice.
+//	!p     Penetrating dice: as with exploding dice, but 1 is subtracted from each
+//	       extra die rolled.
+//
+// Any of these may be followed by “><t>” to explode on any natural roll of <t> or
+// more instead of only on the maximum value, so “d10!>8” explodes on 8, 9, or 10.
+// (Maximized die rolls do not explode.)
+//
+//	kh<n>  Keep only the highest <n> dice, so “4d6kh3” rolls 4d6 but drops the lowest die.
+//	kl<n>  Keep only the lowest <n> dice.
+//	dh<n>  Drop the highest <n> dice.
+//	dl<n>  Drop the lowest <n> dice, so “4d6dl1” is the same as “4d6kh3”.
+//
+// Unlike “best of”, which rerolls the entire set of dice, these select individual
+// dice from the set which was rolled (including any extra dice from explosions).
+//
+//	r<c>   Reroll any die whose natural value meets the condition <c>, once. The
+//	       new value is kept even if it also meets the condition.
+//	rr<c>  Keep rerolling any such die until it no longer meets the condition <c>.
+//
+// The condition <c> is a number <n> (the die rolled exactly <n>), “<<n>” (the die rolled
+// <n> or less) or “><n>” (the die rolled <n> or more). For example, “2d6r1” rerolls any
+// 1s once, while “2d6rr<3” rerolls each die until it comes up higher than 3.
+// Any dice which were rerolled are reported along with the final results.
+//
+//	cs>=<n> Roll a dice pool: the value of the dice is the number of them which
+//	        came up <n> or higher (each a “success”) rather than their sum.
+//	cs<=<n> Likewise, but count the dice which came up <n> or lower.
+//	dbl<c>  In a dice pool, dice meeting this condition count as two successes.
+//	f<c>    In a dice pool, dice meeting this condition subtract one success.
+//
+// For example, “8d10cs>=7” counts how many of the eight d10s came up 7 or higher, and
+// “8d10cs>=7dbl10f1” does the same, but counts 10s twice and subtracts one success for
+// each 1 rolled. Note that this is not the same as “8d10>=7”, which constrains the
+// sum of the dice to be at least 7, as described above.
+//
 // Arbitrary  text  (<label>) may appear at the end of the expression. It is
 // simply reported back in the result as a label to  describe  that  value
 // (e.g.   “1d10  + 1d6 fire + 2d6 sneak”.)  The <label> must begin with a letter
@@ -191,6 +266,31 @@ type Dice struct {
 // underscores, commas, and/or periods (full stops). The notion of "letter" and
 // "digit" follows the Unicode character classifications.
 //
+// If the label on a constant or variable added to the expression begins with one
+// of the NonStackingBonusTypes, it is a typed bonus, and only the highest bonus of
+// each type counts toward the result. For example, “d20 + 2 morale + 1 morale + 3 enhancement”
+// adds only 5 to the d20. A variable is compared using its value at the time the
+// dice are rolled. The bonuses which don't count are still reported, each
+// followed by a “suppressed” element explaining why. Penalties (subtracted
+// values) always stack, and bonuses inside parentheses or multiplied by something
+// are not considered.
+//
+// Labels also give the type of damage each value represents. If the first word
+// of the label is one of the EnergyDamageTypes or PhysicalDamageTypes (such as
+// “fire” or “slashing”), that is the damage type, so the result of “15d6 + 15 fire + 1 acid”
+// is broken down into 15 fire, 1 acid, and the rest untyped damage, in the
+// DamageTypes field of the result. A DefenderProfile can then work out how much
+// of that damage a creature actually takes after its energy resistance, damage
+// reduction, immunities, and vulnerabilities.
+//
+// Besides constants and die-roll expressions, a value may be a variable
+// reference of the form “$<name>” (optionally followed by a <label> as
+// above), such as “d20 + $STR + $BAB attack”. The variable's value is looked
+// up when the dice are rolled, using the resolver supplied with the
+// WithVariables option. It is an error to refer to a variable the resolver
+// doesn't know about. The resolved value is reported as a “constant”
+// followed by a “variable” naming it in the structured description of the roll.
+//
 // If the expression begins with
 // the character “>”, then the first die in the set is maximized:  in  the
 // expression  “>3d6”,  the  first d6 is assumed to have the maximum value
@@ -296,6 +396,52 @@ func WithGenerator(source rand.Source) func(*Dice) error {
 	}
 }
 
+// A VariableResolver looks up the values of variables referenced by name
+// (as “$NAME”) in die-roll expressions.
+type VariableResolver interface {
+	// LookupVariable returns the current value of the named variable,
+	// or false if there is no such variable.
+	LookupVariable(name string) (int, bool)
+}
+
+// Variables is a simple VariableResolver which holds a fixed set of
+// variable values.
+type Variables map[string]int
+
+// LookupVariable returns the value of the named variable from the map.
+func (v Variables) LookupVariable(name string) (int, bool) {
+	value, ok := v[name]
+	return value, ok
+}
+
+// WithVariables sets up the Dice value (or DieRoller) to resolve any variables
+// referenced in die-roll expressions using the given resolver. The variables are
+// looked up again each time the dice are rolled, so they always reflect the
+// resolver's current values.
+func WithVariables(resolver VariableResolver) func(*Dice) error {
+	return func(o *Dice) error {
+		o.variables = resolver
+		return nil
+	}
+}
+
+// DefaultNonMultiplyingLabels lists the labels which, unless changed with the
+// WithNonMultiplyingLabels option, mark parts of a critical damage expression
+// (see the “crit” option to DieRoller.DoRoll) which are not multiplied on a
+// critical hit.
+var DefaultNonMultiplyingLabels = []string{"precision", "sneak"}
+
+// WithNonMultiplyingLabels sets up a DieRoller to treat any part of a critical
+// damage expression labeled with one of the given words as extra damage which
+// is not multiplied on a critical hit, instead of the DefaultNonMultiplyingLabels.
+// The labels are matched without regard to case.
+func WithNonMultiplyingLabels(labels ...string) func(*Dice) error {
+	return func(o *Dice) error {
+		o.nonMultiplying = labels
+		return nil
+	}
+}
+
 func withSharedGenerator(generator *rand.Rand) func(*Dice) error {
 	return func(o *Dice) error {
 		o.generator = generator
@@ -303,6 +449,15 @@ func withSharedGenerator(generator *rand.Rand) func(*Dice) error {
 	}
 }
 
+// withNaturalRecorder makes the Dice record the natural values rolled on
+// its dice (DieRoller only).
+func withNaturalRecorder(recorder *naturalRecorder) func(*Dice) error {
+	return func(o *Dice) error {
+		o.naturals = recorder
+		return nil
+	}
+}
+
 // StructuredDescription values are used to
 // report die-roll results as a structured description list.
 type StructuredDescription struct {
@@ -369,6 +524,69 @@ type StructuredResult struct {
 
 	// Breakdown of how the result was obtained.
 	Details StructuredDescriptionSet
+
+	// Degree of success against the DC, if the roll was made with the
+	// “degrees” option.
+	Degree DegreeOfSuccess `json:",omitempty"`
+
+	// The result broken down by type of damage, if any of the values
+	// in the expression are labeled with a damage type (see Dice.DamageTypes).
+	DamageTypes map[string]int `json:",omitempty"`
+}
+
+// DegreeOfSuccess classifies the result of a check against a DC the way
+// Pathfinder 2e does, as one of four outcomes rather than simple success
+// or failure. The zero value means the result was not classified.
+type DegreeOfSuccess int
+
+const (
+	NotClassified DegreeOfSuccess = iota
+	CriticalFailure
+	Failure
+	Success
+	CriticalSuccess
+)
+
+// String describes the degree of success in words.
+func (g DegreeOfSuccess) String() string {
+	switch g {
+	case CriticalFailure:
+		return "critical failure"
+	case Failure:
+		return "failure"
+	case Success:
+		return "success"
+	case CriticalSuccess:
+		return "critical success"
+	default:
+		return ""
+	}
+}
+
+// ClassifyDegreeOfSuccess determines the degree of success of a check which
+// produced result against the given dc. Meeting the DC is a success, and
+// beating it by 10 or more is a critical success; missing it is a failure,
+// and missing it by 10 or more is a critical failure. Then a natural maximum
+// roll on the die improves the outcome by one degree, and a natural 1 makes
+// it one degree worse.
+func ClassifyDegreeOfSuccess(result, dc int, naturalMax, natural1 bool) DegreeOfSuccess {
+	var g DegreeOfSuccess
+	switch {
+	case result >= dc+10:
+		g = CriticalSuccess
+	case result >= dc:
+		g = Success
+	case result <= dc-10:
+		g = CriticalFailure
+	default:
+		g = Failure
+	}
+	if naturalMax && g < CriticalSuccess {
+		g++
+	} else if natural1 && g > CriticalFailure {
+		g--
+	}
+	return g
 }
 
 // An evalStack is used when parsing the die-roll expression's algebraic
@@ -442,34 +660,40 @@ func (s *evalStack) applyOp() error {
 		return err
 	}
 
+	v, err := binaryOp(op, x, y)
+	if err != nil {
+		return err
+	}
+	s.push(v)
+	return nil
+}
+
+// binaryOp performs the arithmetic for the binary operator op on the values x and y.
+func binaryOp(op rune, x, y float64) (float64, error) {
 	switch op {
 	case '+':
-		s.push(math.Floor(x + y))
+		return math.Floor(x + y), nil
 	case '-':
-		s.push(math.Floor(x - y))
+		return math.Floor(x - y), nil
 	case '*', '×':
-		s.push(math.Floor(x * y))
+		return math.Floor(x * y), nil
 	case '÷':
 		if y == 0 {
-			return fmt.Errorf("division by zero is not defined")
+			return 0, fmt.Errorf("division by zero is not defined")
 		}
-		s.push(math.Floor(x / y))
+		return math.Floor(x / y), nil
 	case '≤':
 		if x > y {
-			s.push(y)
-		} else {
-			s.push(x)
+			return y, nil
 		}
+		return x, nil
 	case '≥':
 		if x < y {
-			s.push(y)
-		} else {
-			s.push(x)
+			return y, nil
 		}
-	default:
-		return fmt.Errorf("Unknown operator \"%v\"", op)
+		return x, nil
 	}
-	return nil
+	return 0, fmt.Errorf("Unknown operator \"%v\"", op)
 }
 
 func (s *evalStack) nextOp() rune {
@@ -505,6 +729,45 @@ func (s *evalStack) reset() {
 	s.opStack = nil
 }
 
+// operatorStack is implemented by the stacks used to evaluate die-roll expressions,
+// so that the same order-of-operations logic applies whether we are evaluating an
+// expression's value (evalStack) or its probability distribution (distributionStack).
+type operatorStack interface {
+	isOpEmpty() bool
+	nextOp() rune
+	pushOp(rune)
+	discardOp()
+	applyOp() error
+}
+
+// pushOperator applies any pending operators of equal or higher precedence
+// than o, then pushes o onto the operator stack. A unary minus has no value
+// before it to operate on, so it is simply pushed (this lets “--3” negate
+// the negation of 3 rather than whatever came before it).
+func pushOperator(s operatorStack, o dieOperator) error {
+	for o != '‾' && !s.isOpEmpty() && s.nextOp() != '(' && precedence(dieOperator(s.nextOp())) >= precedence(o) {
+		if err := s.applyOp(); err != nil {
+			return err
+		}
+	}
+	s.pushOp(rune(o))
+	return nil
+}
+
+// closeGroup applies all pending operators back to the matching '('.
+func closeGroup(s operatorStack) error {
+	for s.nextOp() != '(' {
+		if err := s.applyOp(); err != nil {
+			return err
+		}
+	}
+	if s.nextOp() != '(' {
+		return fmt.Errorf("')' with no matching '('")
+	}
+	s.discardOp()
+	return nil
+}
+
 // A dieComponent is something that can be assembled with other dieComponents
 // to form a full die-roll spec expression.
 //
@@ -526,6 +789,10 @@ type dieComponent interface {
 	compute(s *evalStack) error
 	computeMaxValue(s *evalStack) error
 
+	// Feed the probability distribution of this value into the
+	// distribution calculation in progress.
+	computeDistribution(s *distributionStack) error
+
 	// Return the most recently calculated value. (This can be used to
 	// get the random value rolled for diespecs.) This legacy method
 	// is not currently used anymore except in a unit test. For non-
@@ -556,6 +823,10 @@ func (l dieLabel) computeMaxValue(s *evalStack) error {
 	return nil
 }
 
+func (l dieLabel) computeDistribution(s *distributionStack) error {
+	return nil
+}
+
 func (l dieLabel) lastValue() int {
 	return 0
 }
@@ -592,19 +863,17 @@ func precedence(op dieOperator) int {
 }
 
 func (o dieOperator) compute(s *evalStack) error {
-	for !s.isOpEmpty() && s.nextOp() != '(' && precedence(dieOperator(s.nextOp())) >= precedence(o) {
-		if err := s.applyOp(); err != nil {
-			return err
-		}
-	}
-	s.pushOp(rune(o))
-	return nil
+	return pushOperator(s, o)
 }
 
 func (o dieOperator) computeMaxValue(s *evalStack) error {
 	return o.compute(s)
 }
 
+func (o dieOperator) computeDistribution(s *distributionStack) error {
+	return pushOperator(s, o)
+}
+
 func (o dieOperator) lastValue() int {
 	return 0
 }
@@ -639,6 +908,11 @@ func (b dieBeginGroup) computeMaxValue(s *evalStack) error {
 	return b.compute(s)
 }
 
+func (b dieBeginGroup) computeDistribution(s *distributionStack) error {
+	s.pushOp('(')
+	return nil
+}
+
 func (b dieBeginGroup) lastValue() int {
 	return 0
 }
@@ -658,22 +932,17 @@ func (b dieBeginGroup) naturalRoll() (int, int) {
 }
 
 func (b dieEndGroup) compute(s *evalStack) error {
-	for s.nextOp() != '(' {
-		if err := s.applyOp(); err != nil {
-			return err
-		}
-	}
-	if s.nextOp() != '(' {
-		return fmt.Errorf("')' with no matching '('")
-	}
-	s.discardOp()
-	return nil
+	return closeGroup(s)
 }
 
 func (b dieEndGroup) computeMaxValue(s *evalStack) error {
 	return b.compute(s)
 }
 
+func (b dieEndGroup) computeDistribution(s *distributionStack) error {
+	return closeGroup(s)
+}
+
 func (b dieEndGroup) lastValue() int {
 	return 0
 }
@@ -700,10 +969,23 @@ type dieConstant struct {
 
 	// An optional label to indicate what the constant actually represents.
 	Label string
+
+	// If this is a typed bonus which doesn't stack with another one in the
+	// expression, this explains why it isn't counted.
+	suppressed string
+}
+
+// effectiveValue is the amount the constant contributes to the expression,
+// which is 0 if it is a bonus that doesn't stack.
+func (d *dieConstant) effectiveValue() float64 {
+	if d.suppressed != "" {
+		return 0
+	}
+	return d.Value
 }
 
 func (d *dieConstant) compute(s *evalStack) error {
-	s.push(d.Value)
+	s.push(d.effectiveValue())
 	return nil
 }
 
@@ -711,6 +993,11 @@ func (d *dieConstant) computeMaxValue(s *evalStack) error {
 	return d.compute(s)
 }
 
+func (d *dieConstant) computeDistribution(s *distributionStack) error {
+	s.push(pmf{d.effectiveValue(): 1})
+	return nil
+}
+
 func (d *dieConstant) lastValue() int {
 	return int(d.Value)
 }
@@ -729,9 +1016,363 @@ func (d *dieConstant) structuredDescribeRoll(resultSuppressed bool) []Structured
 	if d.Label != "" {
 		desc = append(desc, StructuredDescription{Type: "label", Value: d.Label})
 	}
+	if d.suppressed != "" {
+		desc = append(desc, StructuredDescription{Type: "suppressed", Value: d.suppressed})
+	}
 	return desc
 }
 
+// NonStackingBonusTypes lists the types of bonuses which don't stack with
+// other bonuses of the same type, so only the highest one counts. A constant
+// added to a die-roll expression has a bonus type if the first word of its
+// label is one of these (without regard to case), as with the “morale” bonuses
+// in “d20 + 2 morale + 1 morale”. Untyped bonuses, and bonus types not listed
+// here (such as dodge and circumstance bonuses), always stack, as do penalties.
+//
+// These are the Pathfinder 1e bonus types, but this may be changed to suit
+// other game systems.
+var NonStackingBonusTypes = []string{
+	"alchemical", "armor", "competence", "deflection", "enhancement",
+	"insight", "luck", "morale", "natural", "profane", "racial",
+	"resistance", "sacred", "shield", "size", "trait",
+}
+
+// labelWords splits a component's label into its individual words.
+func labelWords(label string) []string {
+	return strings.FieldsFunc(label, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '≡' || r == '‖'
+	})
+}
+
+// bonusType returns the type of bonus a label indicates, or "" if it
+// isn't one of the NonStackingBonusTypes.
+func bonusType(label string) string {
+	words := labelWords(label)
+	if len(words) == 0 {
+		return ""
+	}
+	for _, t := range NonStackingBonusTypes {
+		if strings.EqualFold(words[0], t) {
+			return t
+		}
+	}
+	return ""
+}
+
+// applyBonusStacking looks for typed bonuses among the constants and variables
+// added at the top level of the expression, and suppresses all but the highest
+// of each type. Values which are subtracted, multiplied, or inside parentheses
+// are left alone. Since a variable's value isn't known until it is looked up,
+// this is done again each time the dice are rolled.
+func (d *Dice) applyBonusStacking() {
+	type typedBonus struct {
+		bonusType  string
+		value      float64
+		label      string
+		suppressed *string
+	}
+	best := make(map[string]*typedBonus)
+	var typed []*typedBonus
+	depth := 0
+	var prevOp dieOperator = '+'
+
+	// additive reports whether the value at position i is followed by
+	// addition, subtraction, or the end of the expression.
+	additive := func(i int) bool {
+		for _, c := range d.multiDice[i+1:] {
+			switch v := c.(type) {
+			case *dieLabel:
+				continue
+			case *dieOperator:
+				return *v == '+' || *v == '-'
+			}
+			return false
+		}
+		return true
+	}
+
+	// consider notes a value which may be a typed bonus.
+	consider := func(i int, value float64, label string, suppressed *string) {
+		*suppressed = ""
+		if depth != 0 || prevOp != '+' || !additive(i) {
+			return
+		}
+		if t := bonusType(label); t != "" {
+			b := &typedBonus{bonusType: t, value: value, label: label, suppressed: suppressed}
+			typed = append(typed, b)
+			if prev, ok := best[t]; !ok || value > prev.value {
+				best[t] = b
+			}
+		}
+	}
+
+	for i, c := range d.multiDice {
+		switch v := c.(type) {
+		case *dieBeginGroup:
+			depth++
+		case *dieEndGroup:
+			depth--
+		case *dieOperator:
+			if depth == 0 {
+				prevOp = *v
+			}
+		case *dieConstant:
+			consider(i, v.Value, v.Label, &v.suppressed)
+		case *dieVariable:
+			consider(i, float64(v.Value), v.Label, &v.suppressed)
+		}
+	}
+
+	for _, v := range typed {
+		if b := best[v.bonusType]; b != v {
+			*v.suppressed = fmt.Sprintf("%s bonuses don't stack; using +%s %s", v.bonusType, strconv.FormatFloat(b.value, 'g', -1, 64), b.label)
+		}
+	}
+}
+
+// resolveVariables looks up the current values of all the variables in the
+// expression, and works out again which typed bonuses stack now that their
+// values are known.
+func (d *Dice) resolveVariables() error {
+	var found bool
+	for _, c := range d.multiDice {
+		if v, ok := c.(*dieVariable); ok {
+			if err := v.resolve(); err != nil {
+				return err
+			}
+			found = true
+		}
+	}
+	if found {
+		d.applyBonusStacking()
+	}
+	return nil
+}
+
+// dieVariable is a value which is looked up by name each time
+// the dice are rolled.
+type dieVariable struct {
+	// The name of the variable (without the leading “$”).
+	Name string
+
+	// An optional label to indicate what the variable actually represents.
+	Label string
+
+	// The value of the variable when it was last looked up.
+	Value int
+
+	resolver VariableResolver
+
+	// If this is a typed bonus which doesn't stack with another one in the
+	// expression, this explains why it isn't counted.
+	suppressed string
+}
+
+// effectiveValue is the amount the variable contributes to the expression,
+// which is 0 if it is a bonus that doesn't stack.
+func (d *dieVariable) effectiveValue() float64 {
+	if d.suppressed != "" {
+		return 0
+	}
+	return float64(d.Value)
+}
+
+// resolve looks up the current value of the variable.
+func (d *dieVariable) resolve() error {
+	var ok bool
+	if d.resolver != nil {
+		if d.Value, ok = d.resolver.LookupVariable(d.Name); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("undefined variable $%s in die-roll expression", d.Name)
+}
+
+// compute pushes the variable's value, which must already have been looked
+// up by the Dice value's resolveVariables method.
+func (d *dieVariable) compute(s *evalStack) error {
+	s.push(d.effectiveValue())
+	return nil
+}
+
+func (d *dieVariable) computeMaxValue(s *evalStack) error {
+	return d.compute(s)
+}
+
+func (d *dieVariable) computeDistribution(s *distributionStack) error {
+	s.push(pmf{d.effectiveValue(): 1})
+	return nil
+}
+
+func (d *dieVariable) lastValue() int {
+	return d.Value
+}
+
+func (d *dieVariable) naturalRoll() (int, int) {
+	return 0, 0
+}
+
+func (d *dieVariable) description() string {
+	if d.Label != "" {
+		return "$" + d.Name + " " + d.Label
+	}
+	return "$" + d.Name
+}
+
+func (d *dieVariable) structuredDescribeRoll(resultSuppressed bool) []StructuredDescription {
+	var desc []StructuredDescription
+	if !resultSuppressed {
+		desc = append(desc, StructuredDescription{Type: "constant", Value: strconv.Itoa(d.Value)})
+	}
+	desc = append(desc, StructuredDescription{Type: "variable", Value: d.Name})
+	if d.Label != "" {
+		desc = append(desc, StructuredDescription{Type: "label", Value: d.Label})
+	}
+	if d.suppressed != "" {
+		desc = append(desc, StructuredDescription{Type: "suppressed", Value: d.suppressed})
+	}
+	return desc
+}
+
+// explodeMode indicates how (or if) a die "explodes" when it rolls high enough,
+// causing additional dice to be rolled.
+type explodeMode byte
+
+const (
+	noExplosion   explodeMode = iota
+	explodeDice               // d6!   each extra die is added to the pool as another die
+	compoundDice              // d6!!  extra dice are added into the die which exploded
+	penetrateDice             // d6!p  as explodeDice but each extra die is reduced by 1
+)
+
+// keepMode indicates which of the dice rolled are kept to contribute
+// to the result, if not all of them.
+type keepMode byte
+
+const (
+	keepAll     keepMode = iota
+	keepHighest          // 4d6kh3  keep the highest n dice
+	keepLowest           // 2d20kl1 keep the lowest n dice
+	dropHighest          // 3d6dh1  drop the highest n dice
+	dropLowest           // 4d6dl1  drop the lowest n dice
+)
+
+// maxExplosionsPerDie is a safety limit on the number of times a single die may explode.
+const maxExplosionsPerDie = 100
+
+// maxRepeatedRolls is a safety limit on the number of rolls made by a single
+// DoRoll call, regardless of the repeat, until, and total options.
+const maxRepeatedRolls = 100
+
+// maxRerollsPerDie is a safety limit on the number of times a single die may be rerolled.
+const maxRerollsPerDie = 100
+
+// dieCondition is a comparison made against the natural value rolled on
+// a die, such as the "<3" in "2d6rr<3".
+type dieCondition struct {
+	Op    byte // '=' (exactly), '<' (at most), or '>' (at least)
+	Value int
+}
+
+// parseDieCondition creates a dieCondition from its operator (which may be empty,
+// meaning '=') and value strings.
+func parseDieCondition(op, value string) (*dieCondition, error) {
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "":
+		return &dieCondition{Op: '=', Value: v}, nil
+	case "≤", "<=":
+		return &dieCondition{Op: '<', Value: v}, nil
+	case "≥", ">=":
+		return &dieCondition{Op: '>', Value: v}, nil
+	}
+	return &dieCondition{Op: op[0], Value: v}, nil
+}
+
+// matches returns true if the natural die roll v satisfies the condition.
+func (c dieCondition) matches(v int) bool {
+	switch c.Op {
+	case '<':
+		return v <= c.Value
+	case '>':
+		return v >= c.Value
+	}
+	return v == c.Value
+}
+
+// matchesAll returns true if every face of the die satisfies the condition.
+func (c dieCondition) matchesAll(d *dieSpec) bool {
+	for v := 1; v <= d.Sides; v++ {
+		if !c.matches(d.faceValue(v)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c dieCondition) String() string {
+	if c.Op == '=' {
+		return strconv.Itoa(c.Value)
+	}
+	return string(c.Op) + strconv.Itoa(c.Value)
+}
+
+// dieFace is one face of a die with custom faces. The Name is what is
+// shown on the face, which is usually just its Value.
+type dieFace struct {
+	Name  string
+	Value int
+}
+
+// fateFaces are the faces of a Fudge/FATE die.
+var fateFaces = []dieFace{{"-", -1}, {"-", -1}, {"", 0}, {"", 0}, {"+", 1}, {"+", 1}}
+
+// parseFaces parses a list of custom die faces such as "{1,1,2,2,3,4}".
+// Each face may be a number, a name, or a name and value such as "hit:1".
+// Faces with names but no value have a value of 0.
+func parseFaces(spec string) ([]dieFace, error) {
+	reNumber := regexp.MustCompile(`^[-+]?\d+$`)
+	reNamed := regexp.MustCompile(`^(.*?)\s*:\s*([-+]?\d+)$`)
+	var faces []dieFace
+
+	for _, f := range strings.Split(strings.TrimSuffix(strings.TrimPrefix(spec, "{"), "}"), ",") {
+		f = strings.TrimSpace(f)
+		if reNumber.MatchString(f) {
+			v, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, err
+			}
+			faces = append(faces, dieFace{Name: strconv.Itoa(v), Value: v})
+		} else if m := reNamed.FindStringSubmatch(f); m != nil {
+			v, err := strconv.Atoi(m[2])
+			if err != nil {
+				return nil, err
+			}
+			faces = append(faces, dieFace{Name: m[1], Value: v})
+		} else {
+			faces = append(faces, dieFace{Name: f})
+		}
+	}
+	if len(faces) < 2 {
+		return nil, fmt.Errorf("custom dice must have at least two faces")
+	}
+	sort.SliceStable(faces, func(i, j int) bool { return faces[i].Value < faces[j].Value })
+	return faces, nil
+}
+
+// dieRoll records what happened to a single die rolled as part of a dieSpec.
+type dieRoll struct {
+	natural  int   // the face which came up on the die before any adjustments
+	value    int   // the value this die contributes to the total
+	extra    bool  // this die was added to the pool when another die exploded
+	dropped  bool  // this die was dropped by a keep/drop modifier
+	chain    []int // additional rolls compounded into this die's value
+	rerolled []int // values which were rerolled and replaced by this die's value
+}
+
 // dieSpec is a part of a die-roll expression that specifies a single
 // roll (NdS+B, etc) in a chain of other components.
 type dieSpec struct {
@@ -748,9 +1389,42 @@ type dieSpec struct {
 	Denominator int
 	Sides       int
 
+	// If Faces is not nil, the die's Sides are these faces (in ascending
+	// order of value) instead of being numbered from 1, and FaceSpec is
+	// how they were written in the expression (e.g., "F" or "{1,1,2,2,3,4}").
+	Faces    []dieFace
+	FaceSpec string
+
 	// If making multiple rolls, we keep track of them here.
 	Rerolls int
 
+	// If Explode is anything other than noExplosion, any die which comes
+	// up with a natural value of at least ExplodeOn (or its maximum face if
+	// ExplodeOn is 0) is rolled again, as described for the explodeMode values.
+	Explode   explodeMode
+	ExplodeOn int
+
+	// If Keep is anything other than keepAll, only some of the dice
+	// rolled count toward the result: KeepCount is the number of dice
+	// kept (for keepHighest and keepLowest) or dropped (for dropHighest
+	// and dropLowest).
+	Keep      keepMode
+	KeepCount int
+
+	// If RerollOn is not nil, any die whose natural value satisfies it
+	// is rerolled, once or (if RerollAlways is true) until the condition
+	// is no longer satisfied.
+	RerollOn     *dieCondition
+	RerollAlways bool
+
+	// If Target is not nil, this is a dice pool: instead of adding up
+	// the dice, the result is the number of dice whose value satisfies
+	// Target. Dice which also satisfy DoubleOn count as two successes,
+	// and each die which satisfies FailOn subtracts one success.
+	Target   *dieCondition
+	DoubleOn *dieCondition
+	FailOn   *dieCondition
+
 	// A bonus applied to the die every time.
 	//
 	// Deprecated: use die-roll expression strings instead.
@@ -760,17 +1434,25 @@ type dieSpec struct {
 	Label string
 
 	// A record of the actual die rolls performed, per re-roll attempt.
+	// This includes any extra dice added by exploding dice, but not
+	// any dice dropped from the result.
 	History [][]int
 
-	_natural  int
-	generator *rand.Rand
+	rolls    [][]dieRoll // full details behind History
+	chosen   int         // which attempt in History provided Value
+	_natural int
+	// natural value assumed for the lone die when calculating a
+	// distribution (0 if none)
+	givenNatural int
+	generator    *rand.Rand
+	naturals     *naturalRecorder
 }
 
 // Assuming the die (and it must be a single die) for this component
 // has already been rolled, return the natural value of that die
 // and the number of sides.
 func (d *dieSpec) naturalRoll() (int, int) {
-	return d._natural, d.Sides
+	return d.naturalRank(d._natural)
 }
 
 func sumOf(a []int) (t int) {
@@ -814,56 +1496,324 @@ func intToStrings(a []int) (as []string) {
 	return
 }
 
+// rollDie generates a random natural value for a single die.
+func (d *dieSpec) rollDie() int {
+	var v int
+	if d.generator == nil {
+		v = int(rand.Int31n(int32(d.Sides))) + 1
+	} else {
+		v = int(d.generator.Int31n(int32(d.Sides))) + 1
+	}
+	if d.Faces == nil {
+		d.naturals.record(d.Sides, v)
+	}
+	return v
+}
+
+// faceValue returns the value of the face which came up when the die's
+// natural roll was n. This is just n unless the die has custom faces.
+func (d *dieSpec) faceValue(n int) int {
+	if d.Faces == nil || n < 1 || n > len(d.Faces) {
+		return n
+	}
+	return d.Faces[n-1].Value
+}
+
+// faceName returns what is shown on the face which came up when the die's
+// natural roll was n.
+func (d *dieSpec) faceName(n int) string {
+	if d.Faces == nil || n < 1 || n > len(d.Faces) {
+		return strconv.Itoa(n)
+	}
+	return d.Faces[n-1].Name
+}
+
+// hasNamedFaces returns true if the die has custom faces which show
+// something other than their values.
+func (d *dieSpec) hasNamedFaces() bool {
+	for _, f := range d.Faces {
+		if f.Name != strconv.Itoa(f.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+// naturalRank returns the rank of the face which came up when the die's natural
+// roll was n among the distinct face values on the die (counting from 1 for the
+// lowest), along with the number of such distinct values. For ordinary dice,
+// this is simply n and the number of sides, but for custom dice with duplicated
+// faces, this gives a sensible meaning to a "natural 1" or "natural maximum" roll.
+func (d *dieSpec) naturalRank(n int) (int, int) {
+	if d.Faces == nil || n < 1 || n > len(d.Faces) {
+		return n, d.Sides
+	}
+	rank, distinct := 0, 0
+	for i, f := range d.Faces {
+		if i == 0 || f.Value != d.Faces[i-1].Value {
+			distinct++
+		}
+		if i == n-1 {
+			rank = distinct
+		}
+	}
+	return rank, distinct
+}
+
+// sidesDescription describes the die's sides, e.g., "6" for a d6.
+func (d *dieSpec) sidesDescription() string {
+	if d.Faces != nil {
+		return d.FaceSpec
+	}
+	return strconv.Itoa(d.Sides)
+}
+
+// adjust applies the face value, per-die bonus, and fractional-die divisor to
+// a natural value rolled on a die.
+func (d *dieSpec) adjust(v int) int {
+	v = d.faceValue(v) + d.DieBonus
+	if d.Denominator > 0 {
+		v /= d.Denominator
+		if v < 1 {
+			v = 1
+		}
+	}
+	return v
+}
+
+// explodeThreshold returns the natural value at or above which a die explodes.
+func (d *dieSpec) explodeThreshold() int {
+	if d.ExplodeOn > 0 {
+		return d.ExplodeOn
+	}
+	return d.Sides
+}
+
+// rollAttempt rolls all the dice for a single attempt (of possibly several if
+// we're taking the best or worst of a number of attempts), including any extra
+// dice from explosions.
+func (d *dieSpec) rollAttempt() (this []dieRoll) {
+	for j := 0; j < d.Numerator; j++ {
+		var v int
+		var rerolled []int
+		if d.InitialMax && j == 0 {
+			v = d.Sides
+		} else {
+			v = d.rollDie()
+			if d.RerollOn != nil {
+				for n := 0; d.RerollOn.matches(d.faceValue(v)) && n < maxRerollsPerDie; n++ {
+					rerolled = append(rerolled, d.adjust(v))
+					v = d.rollDie()
+					if !d.RerollAlways {
+						break
+					}
+				}
+			}
+		}
+		this = append(this, dieRoll{natural: v, value: d.adjust(v), rerolled: rerolled})
+
+		if d.Explode == noExplosion {
+			continue
+		}
+		for n := 0; v >= d.explodeThreshold() && n < maxExplosionsPerDie; n++ {
+			v = d.rollDie()
+			switch d.Explode {
+			case compoundDice:
+				last := &this[len(this)-1]
+				last.chain = append(last.chain, v)
+				last.value = d.adjust(last.natural + sumOf(last.chain))
+			case penetrateDice:
+				this = append(this, dieRoll{natural: v, value: d.adjust(v - 1), extra: true})
+			default:
+				this = append(this, dieRoll{natural: v, value: d.adjust(v), extra: true})
+			}
+		}
+	}
+	return
+}
+
+// selectDice marks which dice in a roll attempt are dropped according
+// to the keep/drop modifier in effect, if any.
+func (d *dieSpec) selectDice(this []dieRoll) {
+	if d.Keep == keepAll {
+		return
+	}
+	order := make([]int, len(this))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return this[order[i]].value < this[order[j]].value
+	})
+
+	var drop []int
+	switch d.Keep {
+	case keepHighest:
+		drop = order[:max(0, len(order)-d.KeepCount)]
+	case keepLowest:
+		drop = order[min(d.KeepCount, len(order)):]
+	case dropHighest:
+		drop = order[max(0, len(order)-d.KeepCount):]
+	case dropLowest:
+		drop = order[:min(d.KeepCount, len(order))]
+	}
+	for _, i := range drop {
+		this[i].dropped = true
+	}
+}
+
+// isSingleDie returns true if the result of this component will
+// come from a single die.
+func (d *dieSpec) isSingleDie() bool {
+	switch d.Keep {
+	case keepHighest, keepLowest:
+		return d.Numerator == 1 || d.KeepCount == 1
+	case dropHighest, dropLowest:
+		return d.Numerator == 1 || d.Numerator-d.KeepCount == 1
+	}
+	return d.Numerator == 1
+}
+
+// values returns the values contributed by each die in a roll attempt.
+func rollValues(rolls []dieRoll) (v []int) {
+	for _, r := range rolls {
+		if !r.dropped {
+			v = append(v, r.value)
+		}
+	}
+	return
+}
+
+// rerolledValues returns the values which were rerolled in a roll attempt.
+func rerolledValues(rolls []dieRoll) (v []int) {
+	for _, r := range rolls {
+		v = append(v, r.rerolled...)
+	}
+	return
+}
+
+// droppedValues returns the values of the dice dropped from a roll attempt.
+func droppedValues(rolls []dieRoll) (v []int) {
+	for _, r := range rolls {
+		if r.dropped {
+			v = append(v, r.value)
+		}
+	}
+	return
+}
+
+// explodedValues returns the extra dice rolled due to explosions in a roll attempt.
+func explodedValues(rolls []dieRoll) (v []int) {
+	for _, r := range rolls {
+		if r.extra {
+			v = append(v, r.value)
+		}
+		v = append(v, r.chain...)
+	}
+	return
+}
+
+// poolResult describes how a die in a dice pool contributed to the number of successes.
+type poolResult byte
+
+const (
+	poolMiss    poolResult = iota
+	poolSuccess            // the die counts as a success
+	poolDouble             // the die counts as two successes
+	poolFail               // the die subtracts a success
+)
+
+// poolResultOf determines how a die contributes to the number of successes
+// in a dice pool.
+func (d *dieSpec) poolResultOf(r dieRoll) poolResult {
+	if d.FailOn != nil && d.FailOn.matches(r.value) {
+		return poolFail
+	}
+	if d.Target.matches(r.value) {
+		if d.DoubleOn != nil && d.DoubleOn.matches(r.value) {
+			return poolDouble
+		}
+		return poolSuccess
+	}
+	return poolMiss
+}
+
+// successes counts the number of successes in a roll attempt of a dice pool.
+func (d *dieSpec) successes(rolls []dieRoll) (n int) {
+	for _, r := range rolls {
+		if r.dropped {
+			continue
+		}
+		switch d.poolResultOf(r) {
+		case poolSuccess:
+			n++
+		case poolDouble:
+			n += 2
+		case poolFail:
+			n--
+		}
+	}
+	return
+}
+
+// poolValues returns the values of the dice in a roll attempt which had a
+// given effect on the number of successes in a dice pool.
+func (d *dieSpec) poolValues(rolls []dieRoll, which poolResult) (v []int) {
+	for _, r := range rolls {
+		if !r.dropped && d.poolResultOf(r) == which {
+			v = append(v, r.value)
+		}
+	}
+	return
+}
+
+// attemptTotals returns the total value of each roll attempt in History.
+// Normally this is the sum of the dice, but for dice pools it is the number
+// of successes rolled.
+func (d *dieSpec) attemptTotals() []int {
+	if d.Target == nil {
+		return reduceSums(d.History)
+	}
+	var totals []int
+	for _, attempt := range d.rolls {
+		totals = append(totals, d.successes(attempt))
+	}
+	return totals
+}
+
 func (d *dieSpec) compute(s *evalStack) error {
 	d.History = nil
+	d.rolls = nil
 	d.WasMaximized = false
 	if d.Sides <= 0 {
 		return fmt.Errorf("dice cannot have a nonpositive number of sides")
 	}
+	d.naturals.begin()
 	for i := 0; i <= d.Rerolls; i++ {
-		this := []int{}
-		for j := 0; j < d.Numerator; j++ {
-			v := 0
-			if d.InitialMax && j == 0 {
-				v = d.Sides + d.DieBonus
-			} else {
-				if d.generator == nil {
-					v = int(rand.Int31n(int32(d.Sides))) + 1 + d.DieBonus
-				} else {
-					v = int(d.generator.Int31n(int32(d.Sides))) + 1 + d.DieBonus
-				}
-			}
-			if d.Denominator > 0 {
-				v /= d.Denominator
-				if v < 1 {
-					v = 1
-				}
-			}
-			this = append(this, v)
-		}
-		d.History = append(d.History, this)
+		this := d.rollAttempt()
+		d.selectDice(this)
+		d.rolls = append(d.rolls, this)
+		d.History = append(d.History, rollValues(this))
 	}
 
-	var pos int
 	if d.Rerolls > 0 {
 		// select the best or worst roll
 		if d.BestReroll {
-			d.Value, pos = maxOf(reduceSums(d.History))
-		} else {
-			d.Value, pos = minOf(reduceSums(d.History))
-		}
-		if d.Numerator == 1 {
-			d._natural = d.History[pos][0] - d.DieBonus
+			d.Value, d.chosen = maxOf(d.attemptTotals())
 		} else {
-			d._natural = -1
+			d.Value, d.chosen = minOf(d.attemptTotals())
 		}
 	} else {
 		// no rerolls, so we just have one set of results
-		d.Value = reduceSums(d.History)[0]
-		if d.Numerator == 1 {
-			d._natural = d.History[0][0] - d.DieBonus
-		} else {
-			d._natural = -1
+		d.Value, d.chosen = d.attemptTotals()[0], 0
+	}
+	d._natural = -1
+	if d.isSingleDie() {
+		for _, r := range d.rolls[d.chosen] {
+			if !r.dropped && !r.extra {
+				d._natural = r.natural
+				break
+			}
 		}
 	}
 
@@ -871,22 +1821,21 @@ func (d *dieSpec) compute(s *evalStack) error {
 	return nil
 }
 
+// computeMaxValue assumes every die came up at its maximum value.
+// Exploding dice are not exploded in this case, since there would
+// be no limit to the result.
 func (d *dieSpec) computeMaxValue(s *evalStack) error {
 	d.WasMaximized = true
 	d.History = nil
-	this := []int{}
+	d.rolls = nil
+	this := []dieRoll{}
 	for j := 0; j < d.Numerator; j++ {
-		v := d.Sides + d.DieBonus
-		if d.Denominator > 0 {
-			v /= d.Denominator
-			if v < 1 {
-				v = 1
-			}
-		}
-		this = append(this, v)
+		this = append(this, dieRoll{natural: d.Sides, value: d.adjust(d.Sides)})
 	}
-	d.History = append(d.History, this)
-	d.Value = reduceSums(d.History)[0]
+	d.selectDice(this)
+	d.rolls = append(d.rolls, this)
+	d.History = append(d.History, rollValues(this))
+	d.Value, d.chosen = d.attemptTotals()[0], 0
 	s.push(float64(d.Value))
 	return nil
 }
@@ -895,16 +1844,201 @@ func (d *dieSpec) lastValue() int {
 	return d.Value
 }
 
+// modifierDescription describes the per-die modifiers (explosions, etc.)
+// in the same notation used to specify them.
+func (d *dieSpec) modifierDescription() string {
+	var desc string
+	if d.RerollOn != nil {
+		if d.RerollAlways {
+			desc += "rr" + d.RerollOn.String()
+		} else {
+			desc += "r" + d.RerollOn.String()
+		}
+	}
+	switch d.Explode {
+	case explodeDice:
+		desc += "!"
+	case compoundDice:
+		desc += "!!"
+	case penetrateDice:
+		desc += "!p"
+	}
+	if d.Explode != noExplosion && d.ExplodeOn > 0 {
+		desc += fmt.Sprintf(">%d", d.ExplodeOn)
+	}
+	if d.Target != nil {
+		desc += fmt.Sprintf("cs%c=%d", d.Target.Op, d.Target.Value)
+		if d.DoubleOn != nil {
+			desc += "dbl" + d.DoubleOn.String()
+		}
+		if d.FailOn != nil {
+			desc += "f" + d.FailOn.String()
+		}
+	}
+	switch d.Keep {
+	case keepHighest:
+		desc += fmt.Sprintf("kh%d", d.KeepCount)
+	case keepLowest:
+		desc += fmt.Sprintf("kl%d", d.KeepCount)
+	case dropHighest:
+		desc += fmt.Sprintf("dh%d", d.KeepCount)
+	case dropLowest:
+		desc += fmt.Sprintf("dl%d", d.KeepCount)
+	}
+	return desc
+}
+
+// dieModifierPattern matches any one of the per-die modifiers which may
+// immediately follow the number of sides in a die-roll expression, such as
+// the "!" in "3d6!".
+const dieModifierPattern = `(?:!!|!p|!)(?:>\d+)?|(?:kh|kl|dh|dl)\d+|rr?[<>]?\d+|cs(?:[<>]=?|[≤≥])?\d+|dbl[<>]?\d+|f[<>]?\d+`
+
+// reDieTermEnd matches text which ends with a die and its modifiers followed by
+// the “cs” which introduces the target number of a dice pool, such as “8d10cs”.
+var reDieTermEnd = regexp.MustCompile(`[Dd]\s*(?:%|\d+|[Ff]|\{[^{}]*\})(?:` + dieModifierPattern + `)*cs$`)
+
+// rePermutations matches a set of choices in braces such as “{17/12/7}”. If
+// the braces follow a “d”, they are the faces of a custom die instead, and the
+// first submatch is not empty.
+var rePermutations = regexp.MustCompile(`([Dd]\s*)?\{(.*?)\}`)
+
+// joinPoolTargets puts back together die terms like "8d10cs≥7" which were split
+// apart at the "≥" or "≤", since here it is part of the target number of a dice
+// pool rather than an operator constraining the value of the expression.
+func joinPoolTargets(parts []string) []string {
+	var joined []string
+	for i := 0; i < len(parts); i++ {
+		if n := len(joined); n > 0 && i+1 < len(parts) && (parts[i] == "≥" || parts[i] == "≤") &&
+			reDieTermEnd.MatchString(joined[n-1]) && parts[i+1] != "" && unicode.IsDigit(rune(parts[i+1][0])) {
+			joined[n-1] += parts[i] + parts[i+1]
+			i++
+			continue
+		}
+		joined = append(joined, parts[i])
+	}
+	return joined
+}
+
+// parseModifiers sets up the dieSpec according to the string of per-die
+// modifiers which followed the die's sides in the expression.
+func (d *dieSpec) parseModifiers(mods string) error {
+	var err error
+	reExplode := regexp.MustCompile(`^(!!|!p|!)(?:>(\d+))?`)
+	reKeep := regexp.MustCompile(`^(kh|kl|dh|dl)(\d+)`)
+	reReroll := regexp.MustCompile(`^(rr?)([<>])?(\d+)`)
+	rePool := regexp.MustCompile(`^(dbl|f|cs)([<>]=?|[≤≥])?(\d+)`)
+
+	for mods != "" {
+		if m := reExplode.FindStringSubmatch(mods); m != nil {
+			if d.Explode != noExplosion {
+				return fmt.Errorf("only one of !, !!, or !p may be applied to a die")
+			}
+			switch m[1] {
+			case "!!":
+				d.Explode = compoundDice
+			case "!p":
+				d.Explode = penetrateDice
+			default:
+				d.Explode = explodeDice
+			}
+			if m[2] != "" {
+				if d.ExplodeOn, err = strconv.Atoi(m[2]); err != nil {
+					return err
+				}
+				if d.ExplodeOn < 2 {
+					return fmt.Errorf("dice cannot explode on %d since they would explode forever", d.ExplodeOn)
+				}
+			}
+			mods = mods[len(m[0]):]
+			continue
+		}
+		if m := reKeep.FindStringSubmatch(mods); m != nil {
+			if d.Keep != keepAll {
+				return fmt.Errorf("only one of kh, kl, dh, or dl may be applied to a die")
+			}
+			switch m[1] {
+			case "kh":
+				d.Keep = keepHighest
+			case "kl":
+				d.Keep = keepLowest
+			case "dh":
+				d.Keep = dropHighest
+			default:
+				d.Keep = dropLowest
+			}
+			if d.KeepCount, err = strconv.Atoi(m[2]); err != nil {
+				return err
+			}
+			if d.KeepCount < 1 {
+				return fmt.Errorf("the number of dice to keep or drop must be at least 1")
+			}
+			mods = mods[len(m[0]):]
+			continue
+		}
+		if m := reReroll.FindStringSubmatch(mods); m != nil {
+			if d.RerollOn != nil {
+				return fmt.Errorf("only one r or rr modifier may be applied to a die")
+			}
+			if d.RerollOn, err = parseDieCondition(m[2], m[3]); err != nil {
+				return err
+			}
+			d.RerollAlways = m[1] == "rr"
+			if d.RerollAlways && d.RerollOn.matchesAll(d) {
+				return fmt.Errorf("a d%s rerolled on %s would be rerolled forever", d.sidesDescription(), d.RerollOn)
+			}
+			mods = mods[len(m[0]):]
+			continue
+		}
+		if m := rePool.FindStringSubmatch(mods); m != nil {
+			var c **dieCondition
+			switch m[1] {
+			case "dbl":
+				c = &d.DoubleOn
+			case "f":
+				c = &d.FailOn
+			default:
+				if !strings.Contains(m[2], "=") && m[2] != "≥" && m[2] != "≤" {
+					return fmt.Errorf("a dice pool's success target must be given as cs>=<n> or cs<=<n>, not \"%s\"", m[0])
+				}
+				c = &d.Target
+			}
+			if *c != nil {
+				return fmt.Errorf("only one %s modifier may be applied to a die", m[1])
+			}
+			if *c, err = parseDieCondition(m[2], m[3]); err != nil {
+				return err
+			}
+			mods = mods[len(m[0]):]
+			continue
+		}
+		return fmt.Errorf("invalid die modifier \"%s\"", mods)
+	}
+	if d.Explode != noExplosion && d.Sides < 2 {
+		return fmt.Errorf("a d%d cannot explode since it would explode forever", d.Sides)
+	}
+	if d.Explode != noExplosion && d.Faces != nil {
+		return fmt.Errorf("dice with custom faces cannot explode")
+	}
+	if d.Target == nil && (d.DoubleOn != nil || d.FailOn != nil) {
+		return fmt.Errorf("dbl and f modifiers may only be used with a success target such as \"cs>=7\"")
+	}
+	return nil
+}
+
+// dieDescription describes the die itself, e.g., "3d6" or "1/2d20!".
+func (d *dieSpec) dieDescription() string {
+	if d.Denominator > 0 {
+		return fmt.Sprintf("%d/%dd%s", d.Numerator, d.Denominator, d.sidesDescription()) + d.modifierDescription()
+	}
+	return fmt.Sprintf("%dd%s", d.Numerator, d.sidesDescription()) + d.modifierDescription()
+}
+
 func (d *dieSpec) description() string {
 	desc := ""
 	if d.InitialMax {
 		desc += ">"
 	}
-	if d.Denominator > 0 {
-		desc += fmt.Sprintf("%d/%dd%d", d.Numerator, d.Denominator, d.Sides)
-	} else {
-		desc += fmt.Sprintf("%dd%d", d.Numerator, d.Sides)
-	}
+	desc += d.dieDescription()
 	if d.DieBonus > 0 {
 		desc += fmt.Sprintf(" (%+d per die)", d.DieBonus)
 	}
@@ -921,15 +2055,58 @@ func (d *dieSpec) description() string {
 	return desc
 }
 
-// Returns true if the value rolled for this component was a 1.
+// Returns true if the natural value rolled for this component was a 1
+// (or the lowest face of a die with custom faces).
 func (d *dieSpec) isMinRoll() bool {
-	return d.Value == 1
+	rank, _ := d.naturalRank(d._natural)
+	return rank == 1
 }
 
-// Returns true if the value rolled for this component is the same as
-// the number of sides on the die.
+// Returns true if the natural value rolled for this component is the same as
+// the number of sides on the die (or the highest face of a die with custom faces).
 func (d *dieSpec) isMaxRoll() bool {
-	return d.Value == d.Sides
+	rank, distinct := d.naturalRank(d._natural)
+	return rank == distinct
+}
+
+// describeAttempt reports the dice rolled in a single attempt.
+func (d *dieSpec) describeAttempt(rollType string, attempt int) []StructuredDescription {
+	desc := []StructuredDescription{
+		{Type: rollType, Value: strings.Join(intToStrings(d.History[attempt]), ",")},
+	}
+	if d.hasNamedFaces() {
+		var faces []string
+		for _, r := range d.rolls[attempt] {
+			if !r.dropped {
+				faces = append(faces, d.faceName(r.natural))
+			}
+		}
+		desc = append(desc, StructuredDescription{Type: "faces", Value: strings.Join(faces, ",")})
+	}
+	if rerolled := rerolledValues(d.rolls[attempt]); len(rerolled) > 0 {
+		desc = append(desc, StructuredDescription{Type: "rerolled", Value: strings.Join(intToStrings(rerolled), ",")})
+	}
+	if exploded := explodedValues(d.rolls[attempt]); len(exploded) > 0 {
+		desc = append(desc, StructuredDescription{Type: "exploded", Value: strings.Join(intToStrings(exploded), ",")})
+	}
+	if dropped := droppedValues(d.rolls[attempt]); len(dropped) > 0 {
+		desc = append(desc, StructuredDescription{Type: "dropped", Value: strings.Join(intToStrings(dropped), ",")})
+	}
+	if d.Target != nil {
+		for _, marker := range []struct {
+			t     string
+			which poolResult
+		}{
+			{"successes", poolSuccess},
+			{"doubled", poolDouble},
+			{"failures", poolFail},
+		} {
+			if v := d.poolValues(d.rolls[attempt], marker.which); len(v) > 0 {
+				desc = append(desc, StructuredDescription{Type: marker.t, Value: strings.Join(intToStrings(v), ",")})
+			}
+		}
+	}
+	return desc
 }
 
 // Given a dieSpec value, the StructuredDescribeRoll method
@@ -947,11 +2124,7 @@ func (d *dieSpec) structuredDescribeRoll(resultSuppressed bool) []StructuredDesc
 	if d.InitialMax {
 		desc = append(desc, StructuredDescription{Type: "maximized", Value: ">"})
 	}
-	if d.Denominator > 0 {
-		desc = append(desc, StructuredDescription{Type: "diespec", Value: fmt.Sprintf("%d/%dd%d", d.Numerator, d.Denominator, d.Sides)})
-	} else {
-		desc = append(desc, StructuredDescription{Type: "diespec", Value: fmt.Sprintf("%dd%d", d.Numerator, d.Sides)})
-	}
+	desc = append(desc, StructuredDescription{Type: "diespec", Value: d.dieDescription()})
 	if d.DieBonus > 0 {
 		desc = append(desc, StructuredDescription{Type: "diebonus", Value: fmt.Sprintf("%+d", d.DieBonus)})
 	}
@@ -970,20 +2143,18 @@ func (d *dieSpec) structuredDescribeRoll(resultSuppressed bool) []StructuredDesc
 		} else {
 			if d.BestReroll {
 				desc = append(desc, StructuredDescription{Type: "best", Value: strconv.Itoa(d.Rerolls + 1)})
-				_, choice := maxOf(reduceSums(d.History))
 				for i, roll := range d.History {
-					if i == choice {
-						desc = append(desc, StructuredDescription{Type: rollType, Value: strings.Join(intToStrings(roll), ",")})
+					if i == d.chosen {
+						desc = append(desc, d.describeAttempt(rollType, i)...)
 					} else {
 						desc = append(desc, StructuredDescription{Type: "discarded", Value: strings.Join(intToStrings(roll), ",")})
 					}
 				}
 			} else {
 				desc = append(desc, StructuredDescription{Type: "worst", Value: strconv.Itoa(d.Rerolls + 1)})
-				_, choice := minOf(reduceSums(d.History))
 				for i, roll := range d.History {
-					if i == choice {
-						desc = append(desc, StructuredDescription{Type: rollType, Value: strings.Join(intToStrings(roll), ",")})
+					if i == d.chosen {
+						desc = append(desc, d.describeAttempt(rollType, i)...)
 					} else {
 						desc = append(desc, StructuredDescription{Type: "discarded", Value: strings.Join(intToStrings(roll), ",")})
 					}
@@ -991,7 +2162,7 @@ func (d *dieSpec) structuredDescribeRoll(resultSuppressed bool) []StructuredDesc
 			}
 		}
 	} else if !resultSuppressed {
-		desc = append(desc, StructuredDescription{Type: rollType, Value: strings.Join(intToStrings(d.History[0]), ",")})
+		desc = append(desc, d.describeAttempt(rollType, 0)...)
 	}
 
 	if d.Label != "" {
@@ -1040,15 +2211,16 @@ func New(options ...func(*Dice) error) (*Dice, error) {
 		reMin := regexp.MustCompile(`^\s*min\s*([+-]?\d+)\s*$`)
 		reMax := regexp.MustCompile(`^\s*max\s*([+-]?\d+)\s*$`)
 		reMinmax := regexp.MustCompile(`\b(min|max)\s*[+-]?\d+`)
-		reOpSplit := regexp.MustCompile(`[-+*×÷()≤≥]|[^-+*×÷()≤≥]+`)
+		reOpSplit := regexp.MustCompile(`(?:[^-+*×÷()≤≥{}]|\{[^{}]*\})+|[-+*×÷()≤≥{}]`)
 		reIsOp := regexp.MustCompile(`^[-+*×÷()≤≥]$`)
 		reIsDie := regexp.MustCompile(`\d+\s*[dD]\d*\d+`)
 		reIsWS := regexp.MustCompile(`^\s+$`)
 		reIsBareLabel := regexp.MustCompile(`^\s*([\p{L}_][\p{L}\p{N}_,.]*\s*)+(≡(#[0-9a-fA-F]+|\w+)){0,2}(\s*‖\s*([\p{L}_][\p{L}\p{N}_,.]*\s*)+(≡(#[0-9a-fA-F]+|\w+)){0,2})*\s*$`)
 		reConstant := regexp.MustCompile(`^\s*(\d+(?:\.\d+)?|\.\d+)\s*(.*?)\s*$`)
-		//                                  max?    numerator    denominator       sides          best/worst         rerolls   label
-		//                                   _1_    __2__          __3__            __4___       _____5_____         __6__     __7__
-		reDieSpec := regexp.MustCompile(`^\s*(>)?\s*(\d*)\s*(?:/\s*(\d+))?\s*[Dd]\s*(%|\d+)\s*(?:(best|worst)\s*of\s*(\d+))?\s*(.*?)\s*$`)
+		reVariable := regexp.MustCompile(`^\s*\$([\p{L}_][\p{L}\p{N}_]*)\s*(.*?)\s*$`)
+		//                                  max?    numerator    denominator       sides       modifiers             best/worst         rerolls   label
+		//                                   _1_    __2__          __3__            __4___     ____5____            _____6_____         __7__     __8__
+		reDieSpec := regexp.MustCompile(`^\s*(>)?\s*(\d*)\s*(?:/\s*(\d+))?\s*[Dd]\s*(%|\d+|[Ff]|\{[^{}]*\})((?:` + dieModifierPattern + `)*)\s*(?:(best|worst)\s*of\s*(\d+))?\s*(.*?)\s*$`)
 
 		//
 		// break apart the major pieces separated by |
@@ -1088,8 +2260,8 @@ func New(options ...func(*Dice) error) (*Dice, error) {
 		expr := strings.Replace(d.desc, "//", "÷", -1)
 		expr = strings.Replace(expr, ">=", "≥", -1)
 		expr = strings.Replace(expr, "<=", "≤", -1)
-		exprParts := reOpSplit.FindAllString(expr, -1)
-		expectedSyntax := "[<n>[/<d>]] d [<sides>|%] [best|worst of <n>] [+|-|*|×|÷|//|<=|>=|≤|≥ ...] ['|'min <n>] ['|'max <n>]"
+		exprParts := joinPoolTargets(reOpSplit.FindAllString(expr, -1))
+		expectedSyntax := "[<n>[/<d>]] d [<sides>|%|F|{<face>,...}][r|rr[<|>]<n>][!|!!|!p[><t>]][cs<=|>=<n>[dbl<n>][f<n>]][kh|kl|dh|dl<n>] [best|worst of <n>] [+|-|*|×|÷|//|<=|>=|≤|≥ ...] ['|'min <n>] ['|'max <n>]"
 
 		if len(exprParts) == 0 {
 			return nil, fmt.Errorf("syntax error in die roll description \"%s\"; should be \"%s\"", d.desc, expectedSyntax)
@@ -1192,6 +2364,22 @@ func New(options ...func(*Dice) error) (*Dice, error) {
 					continue
 				}
 				//
+				// Or it may be a variable, which must be defined now
+				// even though we'll look it up again each time we roll.
+				//
+				if xValues = reVariable.FindStringSubmatch(part); xValues != nil {
+					labelText := strings.TrimSpace(xValues[2])
+					if labelText != "" && !reIsBareLabel.MatchString(labelText) {
+						return nil, fmt.Errorf("variable label \"%v\" has illegal characters", labelText)
+					}
+					dv := &dieVariable{Name: xValues[1], Label: labelText, resolver: d.variables}
+					if err := dv.resolve(); err != nil {
+						return nil, fmt.Errorf("undefined variable $%s in die-roll expression \"%s\"", dv.Name, d.desc)
+					}
+					d.multiDice = append(d.multiDice, dv)
+					continue
+				}
+				//
 				// Ok, doesn't look valid then.
 				//
 				return nil, fmt.Errorf("syntax error in die roll subexpression \"%s\" in \"%s\"; should be \"%s\"", part, d.desc, expectedSyntax)
@@ -1211,7 +2399,7 @@ func New(options ...func(*Dice) error) (*Dice, error) {
 			// Ok, now let's digest the more complex die-roll spec pattern
 			// and construct a dieSpec to describe it.
 			//
-			ds := &dieSpec{generator: d.generator}
+			ds := &dieSpec{generator: d.generator, naturals: d.naturals}
 			d._onlydie = ds
 			diceCount++
 			if xValues[1] != "" {
@@ -1231,21 +2419,37 @@ func New(options ...func(*Dice) error) (*Dice, error) {
 					return nil, fmt.Errorf("value error in die roll subexpression \"%s\": %v", part, err)
 				}
 			}
-			if xValues[4] == "%" {
+			switch {
+			case xValues[4] == "%":
 				ds.Sides = 100
-			} else {
+			case xValues[4] == "F" || xValues[4] == "f":
+				ds.Faces = fateFaces
+				ds.FaceSpec = "F"
+				ds.Sides = len(ds.Faces)
+			case strings.HasPrefix(xValues[4], "{"):
+				if ds.Faces, err = parseFaces(xValues[4]); err != nil {
+					return nil, fmt.Errorf("value error in die roll subexpression \"%s\": %v", part, err)
+				}
+				ds.FaceSpec = xValues[4]
+				ds.Sides = len(ds.Faces)
+			default:
 				ds.Sides, err = strconv.Atoi(xValues[4])
 				if err != nil {
 					return nil, fmt.Errorf("value error in die roll subexpression \"%s\": %v", part, err)
 				}
 			}
 			if xValues[5] != "" {
-				ds.Rerolls, err = strconv.Atoi(xValues[6])
+				if err = ds.parseModifiers(xValues[5]); err != nil {
+					return nil, fmt.Errorf("value error in die roll subexpression \"%s\": %v", part, err)
+				}
+			}
+			if xValues[6] != "" {
+				ds.Rerolls, err = strconv.Atoi(xValues[7])
 				if err != nil {
 					return nil, fmt.Errorf("value error in die roll subexpression \"%s\": %v", part, err)
 				}
 				ds.Rerolls--
-				switch xValues[5] {
+				switch xValues[6] {
 				case "best":
 					ds.BestReroll = true
 				case "worst":
@@ -1254,14 +2458,14 @@ func New(options ...func(*Dice) error) (*Dice, error) {
 					return nil, fmt.Errorf("value error in die roll subexpression \"%s\": expecting \"best\" or \"worst\"", part)
 				}
 			}
-			if xValues[7] != "" {
-				if reIsDie.MatchString(xValues[7]) {
+			if xValues[8] != "" {
+				if reIsDie.MatchString(xValues[8]) {
 					return nil, fmt.Errorf("label following die roll in \"%s\" looks like another die roll--did you forget an operator?", part)
 				}
-				if !reIsBareLabel.MatchString(xValues[7]) {
-					return nil, fmt.Errorf("label \"%v\" has illegal characters", xValues[7])
+				if !reIsBareLabel.MatchString(xValues[8]) {
+					return nil, fmt.Errorf("label \"%v\" has illegal characters", xValues[8])
 				}
-				ds.Label = strings.TrimSpace(xValues[7])
+				ds.Label = strings.TrimSpace(xValues[8])
 			}
 			d.multiDice = append(d.multiDice, ds)
 		}
@@ -1272,6 +2476,7 @@ func New(options ...func(*Dice) error) (*Dice, error) {
 		if diceCount != 1 {
 			d._onlydie = nil
 		}
+		d.applyBonusStacking()
 	}
 
 	if d.qty > 0 && d.sides > 0 {
@@ -1281,6 +2486,7 @@ func New(options ...func(*Dice) error) (*Dice, error) {
 			DieBonus:    d.diebonus,
 			Denominator: d.div,
 			generator:   d.generator,
+			naturals:    d.naturals,
 		})
 	}
 	if d.bonus < 0 {
@@ -1325,6 +2531,9 @@ func (d *Dice) MaxRollToConfirm(bonus int) (int, error) {
 	var err error
 	stack := &evalStack{}
 
+	if err = d.resolveVariables(); err != nil {
+		return 0, err
+	}
 	for _, die := range d.multiDice {
 		if err := die.computeMaxValue(stack); err != nil {
 			return 0, err
@@ -1402,6 +2611,9 @@ func (d *Dice) RollToConfirm(confirm bool, threat int, bonus int) (int, error) {
 	stack := &evalStack{}
 	var err error
 
+	if err = d.resolveVariables(); err != nil {
+		return 0, err
+	}
 	for _, die := range d.multiDice {
 		if err = die.compute(stack); err != nil {
 			return 0, err
@@ -1526,7 +2738,7 @@ func (d *Dice) StructuredDescribeRoll(options ...func(*sdrOptions)) ([]Structure
 	}
 	if !opts.resultSuppressed {
 		if opts.autoSF {
-			if d._onlydie == nil || d._onlydie.Numerator != 1 {
+			if d._onlydie == nil || !d._onlydie.isSingleDie() {
 				return nil, fmt.Errorf("you can't indicate auto-success/fail (|sf option) because it involves multiple dice")
 			}
 			if d._onlydie.isMinRoll() {
@@ -1601,9 +2813,20 @@ type DieRoller struct {
 	// roll to be "successful".
 	DC int
 
+	// If Degrees is true, we report the degree of success against
+	// the DC instead of just whether it was met.
+	Degrees bool
+
 	critThreat int // --threat threshold (0=default for die type)
 	critBonus  int // --added to confirmation rolls
 
+	critMultiplier int    // --damage multiplier for confirmed criticals
+	critDamage     string // --critical damage expression as given by the user
+	critDice       *Dice  // --critical damage to roll (already multiplied)
+
+	fullAttack     []fullAttackProfile // --damage for each attack in a full attack
+	fullAttackSpec string              // --full option as given by the user
+
 	// User-defined label for this entire die-roll specification, such as
 	// "Knowledge Skill Check".
 	LabelText string
@@ -1626,8 +2849,14 @@ type DieRoller struct {
 	// Postfix expression(s) generated by the most recent roll
 	Postfix []string
 
-	generator *rand.Rand
-	d         *Dice // underlying Dice object
+	generator      *rand.Rand
+	variables      VariableResolver
+	nonMultiplying []string
+	tables         map[string]RandomTable
+	presets        map[string][]DieRollPreset // by display name
+	presetRefs     []StructuredDescription    // presets expanded in current spec
+	naturals       *naturalRecorder           // natural values rolled by DoRoll
+	d              *Dice                      // underlying Dice object
 }
 
 // RandFloat64 generates a pseudorandom number in the range [0.0, 1.0) using
@@ -1660,7 +2889,8 @@ func (d *DieRoller) RandIntn(n int) int {
 //
 // You may pass zero or more option specifiers to this function as already
 // described for the New constructor, although the only ones which apply
-// here are WithSeed(s) and WithGenerator(s).
+// here are WithSeed(s), WithGenerator(s), WithVariables(r),
+// WithNonMultiplyingLabels(l...), WithTables(t...), and WithPresets(p...).
 //
 // Initially it is set up to roll a single d20, but this can be changed with
 // each DoRoll call.
@@ -1679,8 +2909,20 @@ func NewDieRoller(options ...func(*Dice) error) (*DieRoller, error) {
 	if opts.generator != nil {
 		dr.generator = opts.generator
 	}
+	dr.variables = opts.variables
+	dr.naturals = new(naturalRecorder)
+	dr.nonMultiplying = DefaultNonMultiplyingLabels
+	if opts.nonMultiplying != nil {
+		dr.nonMultiplying = opts.nonMultiplying
+	}
+	if opts.tables != nil {
+		dr.DefineTables(opts.tables)
+	}
+	if opts.presets != nil {
+		dr.DefinePresets(opts.presets)
+	}
 
-	dr.d, err = New(ByDieType(1, 20, 0), withSharedGenerator(dr.generator))
+	dr.d, err = New(ByDieType(1, 20, 0), withSharedGenerator(dr.generator), withNaturalRecorder(dr.naturals))
 	if err != nil {
 		return nil, err
 	}
@@ -1688,14 +2930,68 @@ func NewDieRoller(options ...func(*Dice) error) (*DieRoller, error) {
 	return dr, nil
 }
 
+// setNewSpecification prepares the DieRoller to roll the dice described by spec.
+// If spec is invalid, the error returned will come from Parse if possible, since
+// that pinpoints where the problem is.
 func (d *DieRoller) setNewSpecification(spec string) error {
 	var err error
 
+	d.presetRefs = nil
+	if spec, d.presetRefs, err = d.expandPresets(spec, nil); err != nil {
+		return err
+	}
+	if err = d.applySpecification(spec); err != nil {
+		// Parse can say where the problem is, so report its error instead
+		// if it found the same problem.
+		if _, perr := Parse(spec); perr != nil && sameProblem(err, perr) {
+			return perr
+		}
+		return err
+	}
+	return nil
+}
+
+// sameProblem reports whether the error err found by the DieRoller is the
+// same problem as the error perr found by Parse: either they give the same
+// explanation, or err complains about text which includes the place perr
+// points to.
+func sameProblem(err, perr error) bool {
+	var pe *ParseError
+	if !errors.As(perr, &pe) {
+		return false
+	}
+	if pe.Message != "" && strings.Contains(err.Error(), pe.Message) {
+		return true
+	}
+	for _, q := range regexp.MustCompile(`"([^"]+)"`).FindAllStringSubmatch(err.Error(), -1) {
+		for from := 0; ; {
+			i := strings.Index(pe.Spec[from:], q[1])
+			if i < 0 {
+				break
+			}
+			start := utf8.RuneCountInString(pe.Spec[:from+i]) + 1
+			if pe.Column >= start && pe.Column < start+utf8.RuneCountInString(q[1]) {
+				return true
+			}
+			from += i + 1
+		}
+	}
+	return false
+}
+
+func (d *DieRoller) applySpecification(spec string) error {
+	var err error
+
 	d.d = nil
 	d.LabelText = ""
 	d.Confirm = false
 	d.critThreat = 0
 	d.critBonus = 0
+	d.critMultiplier = 0
+	d.critDamage = ""
+	d.critDice = nil
+	d.fullAttack = nil
+	d.fullAttackSpec = ""
 	d.sfOpt = ""
 	d.SuccessMessage = ""
 	d.FailMessage = ""
@@ -1706,19 +3002,22 @@ func (d *DieRoller) setNewSpecification(spec string) error {
 	d.RepeatFor = 1
 	d.DoMax = false
 	d.DC = 0
+	d.Degrees = false
 	d.PctChance = -1
 	d.PctLabel = ""
 
 	reLabel := regexp.MustCompile(`^\s*(.*?)\s*=\s*(.*?)\s*$`)
 	reModMinmax := regexp.MustCompile(`^\s*(min|max)\s*[+-]?\d+`)
 	reModConfirm := regexp.MustCompile(`^\s*c(\d+)?([-+]\d+)?\s*$`)
+	reModCrit := regexp.MustCompile(`^\s*crit\s*[x×]\s*(\d+)\s+(\S.*?)\s*$`)
 	reModUntil := regexp.MustCompile(`^\s*until\s*(-?\d+)\s*$`)
 	reModUntilTotal := regexp.MustCompile(`^\s*total\s*(-?\d+)\s*$`)
 	reModRepeat := regexp.MustCompile(`^\s*repeat\s*(\d+)\s*$`)
 	reModMaximized := regexp.MustCompile(`^\s*(!|maximized)\s*$`)
 	reModDC := regexp.MustCompile(`^\s*[Dd][Cc]\s*(-?\d+)\s*$`)
+	reModDegrees := regexp.MustCompile(`^\s*degrees\s*$`)
+	reModFull := regexp.MustCompile(`^\s*full\s+(\S.*?)\s*$`)
 	reModSF := regexp.MustCompile(`^\s*sf(?:\s+(\S.*?)(?:/(\S.*?))?)?\s*$`)
-	rePermutations := regexp.MustCompile(`\{(.*?)\}`)
 	rePctRoll := regexp.MustCompile(`^\s*(\d+)%(.*)$`)
 
 	//
@@ -1784,6 +3083,34 @@ func (d *DieRoller) setNewSpecification(spec string) error {
 					if d.FailMessage == "" {
 						d.FailMessage = "MISS"
 					}
+				} else if fields := reModCrit.FindStringSubmatch(majorPieces[i]); fields != nil {
+					//
+					// MODIFIER
+					//  | crit x<m> <expr>
+					// Roll <expr> as damage multiplied by <m> if a critical
+					// threat is confirmed (implies | c if not given).
+					//
+					d.critMultiplier, err = strconv.Atoi(fields[1])
+					if err != nil {
+						return fmt.Errorf("value error in die roll crit expression: %v", err)
+					}
+					if d.critMultiplier < 2 {
+						return fmt.Errorf("critical damage multiplier must be at least 2")
+					}
+					d.critDamage = fields[2]
+					d.critDice, err = New(
+						ByDescription(multipliedDamage(strings.Replace(d.critDamage, "//", "÷", -1), d.critMultiplier, d.nonMultiplying)),
+						withSharedGenerator(d.generator), withNaturalRecorder(d.naturals), WithVariables(d.variables))
+					if err != nil {
+						return fmt.Errorf("error in critical damage expression: %v", err)
+					}
+					d.Confirm = true
+					if d.SuccessMessage == "" {
+						d.SuccessMessage = "HIT"
+					}
+					if d.FailMessage == "" {
+						d.FailMessage = "MISS"
+					}
 				} else if fields := reModUntilTotal.FindStringSubmatch(majorPieces[i]); fields != nil {
 					//
 					// MODIFIER
@@ -1828,6 +3155,21 @@ func (d *DieRoller) setNewSpecification(spec string) error {
 					if err != nil {
 						return fmt.Errorf("value error in die roll DC clause: %v", err)
 					}
+				} else if reModDegrees.MatchString(majorPieces[i]) {
+					//
+					// MODIFIER
+					//  | degrees
+					// Report the degree of success against the DC
+					//
+					d.Degrees = true
+				} else if fields := reModFull.FindStringSubmatch(majorPieces[i]); fields != nil {
+					//
+					// MODIFIER
+					//  | full <damage>[/<damage>...]
+					// Make a full-attack sequence; the damage options
+					// are interpreted after all the others are known.
+					//
+					d.fullAttackSpec = fields[1]
 				} else if fields := reModSF.FindStringSubmatch(majorPieces[i]); fields != nil {
 					//
 					// MODIFIER
@@ -1860,11 +3202,22 @@ func (d *DieRoller) setNewSpecification(spec string) error {
 						d.FailMessage = "FAIL"
 					}
 				} else {
-					return fmt.Errorf("global modifier option \"%s\" not understood; must be !, c, dc, min, max, maximized, sf, total, until, or repeat", majorPieces[i])
+					return fmt.Errorf("global modifier option \"%s\" not understood; must be !, c, crit, dc, degrees, full, min, max, maximized, sf, total, until, or repeat", majorPieces[i])
 				}
 			}
 		}
 	}
+	if d.Degrees && d.DC == 0 {
+		return fmt.Errorf("you can't report degrees of success without a DC")
+	}
+	if d.fullAttackSpec != "" {
+		if d.critDice != nil || d.Degrees || d.DoMax || d.sfOpt != "" || d.RepeatUntil > 0 || d.RepeatUntilTotal > 0 {
+			return fmt.Errorf("a full attack can't be combined with the crit, degrees, maximized, sf, total, or until options")
+		}
+		if d.fullAttack, err = d.parseFullAttack(d.fullAttackSpec); err != nil {
+			return err
+		}
+	}
 
 	//
 	// The global options are all taken care of.
@@ -1884,9 +3237,14 @@ func (d *DieRoller) setNewSpecification(spec string) error {
 	//  "d20+5+2d6+2"
 	//
 	spec = strings.Replace(spec, "//", "÷", -1)
+	// (Braces immediately following a "d" are the faces of a custom die
+	// rather than permutations.)
 	if permList := rePermutations.FindAllStringSubmatch(spec, -1); permList != nil {
 		for _, perm := range permList {
-			valueset := strings.Split(perm[1], "/")
+			if perm[1] != "" {
+				continue
+			}
+			valueset := strings.Split(perm[2], "/")
 			if len(valueset) < 2 {
 				return fmt.Errorf("invalid die-roll specification \"%s\": Values in braces must have more than one value separated by slashes", perm[0])
 			}
@@ -1901,11 +3259,19 @@ func (d *DieRoller) setNewSpecification(spec string) error {
 		// to form a template into which we'll substitute all of the permuted values
 		// out of d.Permutations.
 		//
-		pos := -1
-		d.Template = rePermutations.ReplaceAllStringFunc(spec, func(_ string) string {
-			pos++
-			return "{" + strconv.Itoa(pos) + "}"
-		})
+		if d.Permutations != nil {
+			pos := -1
+			d.Template = rePermutations.ReplaceAllStringFunc(spec, func(perm string) string {
+				if perm[0] == 'd' || perm[0] == 'D' {
+					return perm
+				}
+				pos++
+				return "{" + strconv.Itoa(pos) + "}"
+			})
+		}
+	}
+	if len(d.fullAttack) > 1 && len(d.fullAttack) != d.attackCount() {
+		return fmt.Errorf("a full attack with %d attack rolls needs one damage expression, or one for each attack (not %d)", d.attackCount(), len(d.fullAttack))
 	}
 
 	if fields := rePctRoll.FindStringSubmatch(spec); fields != nil {
@@ -1925,7 +3291,7 @@ func (d *DieRoller) setNewSpecification(spec string) error {
 		if d.DC != 0 {
 			return fmt.Errorf("you can't have a percentile die roll with a DC")
 		}
-		d.d, err = New(ByDieType(1, 100, 0), withSharedGenerator(d.generator))
+		d.d, err = New(ByDieType(1, 100, 0), withSharedGenerator(d.generator), withNaturalRecorder(d.naturals))
 		if err != nil {
 			return err
 		}
@@ -1939,7 +3305,7 @@ func (d *DieRoller) setNewSpecification(spec string) error {
 		// Normal case: use the remaining string in spec to define a Dice object
 		// that we will subsequently roll using our local modifiers and such.
 		//
-		d.d, err = New(ByDescription(spec), withSharedGenerator(d.generator))
+		d.d, err = New(ByDescription(spec), withSharedGenerator(d.generator), withNaturalRecorder(d.naturals), WithVariables(d.variables))
 		if err != nil {
 			return err
 		}
@@ -2007,11 +3373,54 @@ func (d *DieRoller) setNewSpecification(spec string) error {
 // (The notation "±" here means either a "-" or "+" may appear at that
 // position in the string.)
 //
+//	| crit x<m> <expr>
+//
+// This implies the “c” option (which may also be given to set the threat
+// range or confirmation bonus), and if the critical threat is confirmed, the damage
+// expression <expr> is rolled multiplied by <m>. Following Pathfinder rules,
+// this means rolling <expr> <m> times and adding the results together, except
+// that any additive terms of <expr> labeled with one of the words in
+// DefaultNonMultiplyingLabels (“precision” or “sneak”; this may be changed with
+// the WithNonMultiplyingLabels option) are extra damage which is only rolled once.
+// The confirmation succeeds if its die roll was a natural maximum or its result
+// meets the DC given with the “dc” option. If there is no DC, the damage is rolled
+// anyway and marked as applying only if the threat was confirmed.
+// The damage roll appears as another result after the confirmation roll
+// in the list of results, starting with a “critdamage” element which gives
+// the multiplier.
+//
 //	| dc <n>
 //
 // This is a roll against a known difficulty class <n>. If the
 // result is at least <n>, the roll is "successful".
 //
+//	| full <damage>[/<damage>...]
+//
+// Make a full-attack sequence. Each permutation of the die-roll expression
+// is a separate attack roll (e.g., “d20+{16/11/6}|full 1d8+5” makes three
+// attacks), paired with the damage expression in the same position in the
+// list (or the only one given, if it applies to every attack). Each damage
+// expression may be followed by “c<t>[{+|-}<b>]” and/or “x<m>” to set that
+// attack's critical threat range, confirmation bonus, and damage multiplier,
+// which otherwise default to those given in the “c” option and ×2.
+// A fractional die may begin a damage expression (“full 1/2d6+3/1d4”);
+// anywhere else in it, put the fractional die in parentheses so its slash
+// isn't taken to separate two attacks (“full 1d8+(1/2d6)/1d4”).
+// If there is a DC, it's the target's armor class: attacks which hit roll
+// damage (multiplied for confirmed critical hits), and those which miss
+// do not. The results for each attack begin with an “attack” element,
+// and a final summary result gives the total damage and the “outcome”
+// of each attack.
+//
+//	| degrees
+//
+// With a dc option, classify the result as a critical success (beating the
+// DC by 10 or more), success, failure, or critical failure (missing the DC
+// by 10 or more), as in Pathfinder 2e. If the roll involves only a single
+// die, a natural maximum value improves this by one degree and a natural 1
+// makes it one degree worse. The outcome is reported in the result's Degree
+// field and in a “degree” element of its description.
+//
 //	| sf [<success>[/<fail>]]
 //
 // Auto-success/fail: the roll, which must involve only a single
@@ -2085,11 +3494,20 @@ func (d *DieRoller) setNewSpecification(spec string) error {
 //		"2d10+3d6+12"     Roll 2d10, 3d6, add their results and add 12 to the sum.
 //		"d20+15|c"        Roll d20+15, automatically rolling to confirm on a natural 20.
 //		"d20+15|c19+2"    Roll d20+15, rolling to confirm on natural 19 or 20 with +2 bonus.
+//		"d20+15|crit x3 2d6+8 + 2d6 sneak|dc 18"
+//		                  Roll d20+15, rolling to confirm on natural 20 and then, if the
+//		                  confirmation roll is at least 18, rolling (2d6+8)×3 + 2d6 damage.
 //		"d%"              Roll percentile dice, giving result 1-100.
+//		"4dF+2"           Roll 4 FATE dice (each -1, 0, or +1), add 2.
+//		"d{1,1,2,2,3,4}"  Roll a six-sided die with custom faces.
 //		"40%"             Roll percentile dice, giving result 1 with 40% probability.
 //		"d20+12|max20"    Roll d20+12 but any result > 20 is capped at 20.
 //		"d20 best of 2"   Roll d20 twice, discarding the worse result.
 //		"d20+4|dc 10"     Roll d20+4, signalling success if the result is 10 or greater.
+//		"d20+{16/11/6}|c19|full 1d8+5/1d8+5/1d6+2 x3|dc 20"
+//		                  Full attack with three attacks against AC 20, each with its own damage.
+//		"d20+9|dc 18|degrees"
+//		                  Roll d20+9, reporting the degree of success against DC 18.
 //		"3d6 fire+1d4 acid+2 bonus"
 //		                  Roll 3d6+1d4+2. In the structured results, it will show the values
 //		                  rolled for the 3d6 fire, 1d4 acid, and 2 bonus individually.
@@ -2114,6 +3532,7 @@ func (d *DieRoller) DoRoll(spec string) (string, []StructuredResult, error) {
 			return "", nil, err
 		}
 	}
+	d.naturals.reset()
 
 	var overallResults []StructuredResult
 	var results []StructuredResult
@@ -2123,7 +3542,15 @@ func (d *DieRoller) DoRoll(spec string) (string, []StructuredResult, error) {
 	repeatCount := 0
 	repeatTotal := 0
 	for repeatIter < d.RepeatFor {
-		if d.Template != "" {
+		if d.fullAttack != nil {
+			// Each permutation is an attack in the sequence, which
+			// rolls its own confirmation and damage.
+			results, err = d.rollFullAttack()
+			if err != nil {
+				return "", nil, err
+			}
+			overallResults = append(overallResults, results...)
+		} else if d.Template != "" {
 			// If we're working with a set of permutations, expand them now
 			// into their Cartesian product so we can then substitute each set
 			// of those values into the template for each roll of the dice.
@@ -2131,7 +3558,7 @@ func (d *DieRoller) DoRoll(spec string) (string, []StructuredResult, error) {
 			for iteration := range iterlist {
 				d.d, err = New(
 					ByDescription(substituteTemplateValues(d.Template, iteration)),
-					withSharedGenerator(d.generator))
+					withSharedGenerator(d.generator), withNaturalRecorder(d.naturals), WithVariables(d.variables))
 				if err != nil {
 					return "", nil, err
 				}
@@ -2168,7 +3595,7 @@ func (d *DieRoller) DoRoll(spec string) (string, []StructuredResult, error) {
 		repeatCount++
 
 		// Safety limit
-		if repeatCount >= 100 {
+		if repeatCount >= maxRepeatedRolls {
 			break
 		}
 	}
@@ -2176,6 +3603,9 @@ func (d *DieRoller) DoRoll(spec string) (string, []StructuredResult, error) {
 		d.d = nil
 	}
 
+	for i := range overallResults {
+		overallResults[i].Details = append(overallResults[i].Details, d.presetRefs...)
+	}
 	return d.LabelText, overallResults, nil
 }
 
@@ -2221,6 +3651,18 @@ func (d *DieRoller) ExplainSecretRoll(spec, notice string) (string, StructuredRe
 				Type: "critspec", Value: c,
 			})
 		}
+		if d.critDice != nil {
+			thisResult = append(thisResult,
+				StructuredDescription{Type: "moddelim", Value: "|"},
+				StructuredDescription{Type: "critspec", Value: fmt.Sprintf("crit ×%d %s", d.critMultiplier, d.critDamage)},
+			)
+		}
+		if d.fullAttack != nil {
+			thisResult = append(thisResult,
+				StructuredDescription{Type: "moddelim", Value: "|"},
+				StructuredDescription{Type: "full", Value: d.fullAttackSpec},
+			)
+		}
 		if d.RepeatFor > 1 {
 			thisResult = append(thisResult,
 				StructuredDescription{Type: "moddelim", Value: "|"},
@@ -2245,6 +3687,12 @@ func (d *DieRoller) ExplainSecretRoll(spec, notice string) (string, StructuredRe
 				StructuredDescription{Type: "dc", Value: strconv.Itoa(d.DC)},
 			)
 		}
+		if d.Degrees {
+			thisResult = append(thisResult,
+				StructuredDescription{Type: "moddelim", Value: "|"},
+				StructuredDescription{Type: "degrees", Value: "degrees"},
+			)
+		}
 		if d.sfOpt != "" {
 			thisResult = append(thisResult,
 				StructuredDescription{Type: "moddelim", Value: "|"},
@@ -2327,6 +3775,7 @@ func (d *DieRoller) ExplainSecretRoll(spec, notice string) (string, StructuredRe
 		}
 	}
 
+	thisResult = append(thisResult, d.presetRefs...)
 	return d.LabelText, StructuredResult{ResultSuppressed: true, Details: thisResult}, nil
 }
 
@@ -2341,6 +3790,23 @@ func substituteTemplateValues(template string, values []any) string {
 	return result
 }
 
+// describeDCRoll describes the results of a roll with a DC value.
+// In this case we want to indicate the margin above or below
+// the DC that was rolled.
+func describeDCRoll(dc, result int) (desc StructuredDescription) {
+	if result > dc {
+		desc.Type = "exceeded"
+		desc.Value = strconv.Itoa(result - dc)
+	} else if result == dc {
+		desc.Type = "met"
+		desc.Value = "successful"
+	} else {
+		desc.Type = "short"
+		desc.Value = strconv.Itoa(dc - result)
+	}
+	return
+}
+
 // This does the work of performing a die roll (possibly two, if we're confirming
 // a critical roll) based on the exact specifications already set in place by
 // the caller.
@@ -2348,27 +3814,9 @@ func (d *DieRoller) rollDice(repeatIter, repeatCount, repeatTotal int) (int, []S
 	var results []StructuredResult
 	var thisResult []StructuredDescription
 	var result int
+	var degree DegreeOfSuccess
 	var err error
 
-	//
-	// how to describe the results of a roll with a DC value.
-	// in this case we want to indicate the margin above or below
-	// the DC that was rolled.
-	//
-	describeDCRoll := func(dc, result int) (desc StructuredDescription) {
-		if result > dc {
-			desc.Type = "exceeded"
-			desc.Value = strconv.Itoa(result - dc)
-		} else if result == dc {
-			desc.Type = "met"
-			desc.Value = "successful"
-		} else {
-			desc.Type = "short"
-			desc.Value = strconv.Itoa(dc - result)
-		}
-		return
-	}
-
 	//
 	// How to report back on the options (aka modifiers) in play for the die roll.
 	// This updates the thisResult value in-place.
@@ -2389,6 +3837,12 @@ func (d *DieRoller) rollDice(repeatIter, repeatCount, repeatTotal int) (int, []S
 				Type: "critspec", Value: c,
 			})
 		}
+		if d.critDice != nil {
+			thisResult = append(thisResult,
+				StructuredDescription{Type: "moddelim", Value: "|"},
+				StructuredDescription{Type: "critspec", Value: fmt.Sprintf("crit ×%d %s", d.critMultiplier, d.critDamage)},
+			)
+		}
 		if d.RepeatFor > 1 {
 			thisResult = append(thisResult,
 				StructuredDescription{Type: "moddelim", Value: "|"},
@@ -2419,6 +3873,14 @@ func (d *DieRoller) rollDice(repeatIter, repeatCount, repeatTotal int) (int, []S
 				describeDCRoll(d.DC, result),
 			)
 		}
+		if d.Degrees {
+			degree = ClassifyDegreeOfSuccess(result, d.DC, d.IsNaturalMax(), d.IsNatural1())
+			thisResult = append(thisResult,
+				StructuredDescription{Type: "moddelim", Value: "|"},
+				StructuredDescription{Type: "degrees", Value: "degrees"},
+				StructuredDescription{Type: "degree", Value: degree.String()},
+			)
+		}
 		if d.sfOpt != "" {
 			thisResult = append(thisResult,
 				StructuredDescription{Type: "moddelim", Value: "|"},
@@ -2432,8 +3894,8 @@ func (d *DieRoller) rollDice(repeatIter, repeatCount, repeatTotal int) (int, []S
 	// The result will be 0 or 1 and we'll describe the outcome in words
 	// like "hit" or "miss"
 	//
-	reSlashDelim := regexp.MustCompile(`\s*/\s*`)
 	reportPctRoll := func(chance int, label string, maximized bool) {
+		reSlashDelim := regexp.MustCompile(`\s*/\s*`)
 		thisResult = nil
 		builtInLabels := map[string]string{
 			"hit":  "miss",
@@ -2521,7 +3983,7 @@ func (d *DieRoller) rollDice(repeatIter, repeatCount, repeatTotal int) (int, []S
 				StructuredDescription{Type: "fullmax", Value: "maximized"},
 			)
 			if d.Confirm {
-				results = append(results, StructuredResult{Result: result, Details: thisResult})
+				results = append(results, StructuredResult{Result: result, Details: thisResult, Degree: degree, DamageTypes: d.d.DamageTypes()})
 				result2, err := d.d.MaxRollToConfirm(d.critBonus)
 				if err != nil {
 					return 0, nil, repeatTotal, err
@@ -2541,8 +4003,13 @@ func (d *DieRoller) rollDice(repeatIter, repeatCount, repeatTotal int) (int, []S
 					StructuredDescription{Type: "fullmax", Value: "maximized"},
 				)
 				results = append(results, StructuredResult{Result: result2, Details: thisResult})
+				if damage, err := d.rollCritDamage(result2, true); err != nil {
+					return 0, nil, repeatTotal, err
+				} else if damage != nil {
+					results = append(results, *damage)
+				}
 			} else {
-				results = append(results, StructuredResult{Result: result, Details: thisResult})
+				results = append(results, StructuredResult{Result: result, Details: thisResult, Degree: degree, DamageTypes: d.d.DamageTypes()})
 			}
 		}
 	} else {
@@ -2564,7 +4031,7 @@ func (d *DieRoller) rollDice(repeatIter, repeatCount, repeatTotal int) (int, []S
 			thisResult = append(thisResult, sdesc...)
 			reportOptions()
 			if d.Confirm {
-				results = append(results, StructuredResult{Result: result, Details: thisResult})
+				results = append(results, StructuredResult{Result: result, Details: thisResult, Degree: degree, DamageTypes: d.d.DamageTypes()})
 				result2, err := d.d.RollToConfirm(true, d.critThreat, d.critBonus)
 				if err != nil {
 					return 0, nil, repeatTotal, err
@@ -2581,9 +4048,14 @@ func (d *DieRoller) rollDice(repeatIter, repeatCount, repeatTotal int) (int, []S
 						StructuredDescription{Type: "critlabel", Value: "Confirm:"})
 					thisResult = append(thisResult, sdesc...)
 					results = append(results, StructuredResult{Result: result2, Details: thisResult})
+					if damage, err := d.rollCritDamage(result2, false); err != nil {
+						return 0, nil, repeatTotal, err
+					} else if damage != nil {
+						results = append(results, *damage)
+					}
 				}
 			} else {
-				results = append(results, StructuredResult{Result: result, Details: thisResult})
+				results = append(results, StructuredResult{Result: result, Details: thisResult, Degree: degree, DamageTypes: d.d.DamageTypes()})
 			}
 		}
 	}
@@ -2591,6 +4063,123 @@ func (d *DieRoller) rollDice(repeatIter, repeatCount, repeatTotal int) (int, []S
 	return result, results, repeatTotal, nil
 }
 
+// rollCritDamage rolls the critical damage expression (if there is one) after
+// a confirmation roll which yielded confirmResult. The damage is only rolled
+// if the threat was confirmed: the confirmation roll was a natural maximum, or
+// met the DC of the roll. If there is no DC, we can't tell whether it was confirmed,
+// so the damage is rolled anyway and marked as applying only if the threat was
+// confirmed. Returns nil if there's no damage to report.
+func (d *DieRoller) rollCritDamage(confirmResult int, maximized bool) (*StructuredResult, error) {
+	if d.critDice == nil {
+		return nil, nil
+	}
+
+	multiplier := fmt.Sprintf("×%d", d.critMultiplier)
+	if !maximized && !d.IsNaturalMax() {
+		if d.IsNatural1() || (d.DC != 0 && confirmResult < d.DC) {
+			return nil, nil
+		}
+		if d.DC == 0 {
+			multiplier += " if confirmed"
+		}
+	}
+
+	var result int
+	var err error
+	if maximized {
+		result, err = d.critDice.MaxRoll()
+	} else {
+		result, err = d.critDice.Roll()
+	}
+	if err != nil {
+		return nil, err
+	}
+	sdesc, err := d.critDice.StructuredDescribeRoll()
+	if err != nil {
+		return nil, err
+	}
+	details := append(StructuredDescriptionSet{{Type: "critdamage", Value: multiplier}}, sdesc...)
+	if maximized {
+		details = append(details,
+			StructuredDescription{Type: "moddelim", Value: "|"},
+			StructuredDescription{Type: "fullmax", Value: "maximized"},
+		)
+	}
+	return &StructuredResult{Result: result, Details: details, DamageTypes: d.critDice.DamageTypes()}, nil
+}
+
+// multipliedDamage rewrites a critical damage expression so that it will be
+// rolled multiplier times, as with “(2d6+8)+(2d6+8)+(2d6+8)” for “2d6+8”
+// multiplied by 3. Any additive terms of the expression labeled with one of the
+// nonMultiplying words (such as “2d6 sneak”) are added once after that instead.
+func multipliedDamage(expr string, multiplier int, nonMultiplying []string) string {
+	reWord := regexp.MustCompile(`[\p{L}_][\p{L}\p{N}_]*`)
+	var multiplied, extra strings.Builder
+
+nextTerm:
+	for _, term := range additiveTerms(expr) {
+		for _, word := range reWord.FindAllString(term, -1) {
+			for _, label := range nonMultiplying {
+				if strings.EqualFold(word, label) {
+					if term[0] != '+' && term[0] != '-' {
+						extra.WriteString("+")
+					}
+					extra.WriteString(term)
+					continue nextTerm
+				}
+			}
+		}
+		if multiplied.Len() == 0 {
+			multiplied.WriteString(strings.TrimPrefix(term, "+"))
+		} else {
+			if term[0] != '+' && term[0] != '-' {
+				multiplied.WriteString("+")
+			}
+			multiplied.WriteString(term)
+		}
+	}
+
+	if multiplied.Len() == 0 {
+		return strings.TrimPrefix(extra.String(), "+")
+	}
+	groups := make([]string, multiplier)
+	for i := range groups {
+		groups[i] = "(" + multiplied.String() + ")"
+	}
+	return strings.Join(groups, "+") + extra.String()
+}
+
+// additiveTerms splits a die-roll expression into the terms which are added or
+// subtracted at the top level (outside any parentheses), each beginning with
+// its + or - operator (except possibly the first).
+func additiveTerms(expr string) []string {
+	var terms []string
+	depth := 0
+	start := 0
+	afterOperator := true
+
+	for i, r := range expr {
+		switch r {
+		case '(', '{':
+			depth++
+		case ')', '}':
+			depth--
+		case '+', '-':
+			if depth == 0 && !afterOperator {
+				terms = append(terms, strings.TrimSpace(expr[start:i]))
+				start = i
+			}
+		}
+		if !unicode.IsSpace(r) {
+			afterOperator = strings.ContainsRune("+-*×÷≤≥(", r)
+		}
+	}
+	if term := strings.TrimSpace(expr[start:]); term != "" {
+		terms = append(terms, term)
+	}
+	return terms
+}
+
 // Roll rolls the dice specified by the specification string, without
 // requiring a separate step to create a DieRoller first.
 //
@@ -2720,10 +4309,46 @@ func (sr StructuredDescriptionSet) Text() (string, error) {
 		case "critlabel", "critspec", "fullmax", "moddelim", "separator":
 			fmt.Fprintf(&t, "%s ", r.Value)
 
+		case "table":
+			fmt.Fprintf(&t, "%s: ", r.Value)
+
+		case "tableentry":
+			fmt.Fprintf(&t, " → %s", r.Value)
+
+		case "preset":
+			fmt.Fprintf(&t, " [@%s]", r.Value)
+
+		case "attack":
+			fmt.Fprintf(&t, "Attack #%s: ", r.Value)
+
+		case "critdamage":
+			fmt.Fprintf(&t, "Critical %s: ", r.Value)
+
+		case "damage":
+			fmt.Fprintf(&t, "%s ", r.Value)
+
+		case "full":
+			fmt.Fprintf(&t, "full %s", r.Value)
+
+		case "fullattack":
+			fmt.Fprintf(&t, "Full attack (%s attacks), total damage: ", r.Value)
+
+		case "outcome":
+			fmt.Fprintf(&t, "[%s]", strings.ToUpper(r.Value))
+
+		case "variable":
+			fmt.Fprintf(&t, " ($%s)", r.Value)
+
 		case "dc":
 			fmt.Fprintf(&t, "DC %s ", r.Value)
 
-		case "diespec", "maximized", "operator":
+		case "degree":
+			fmt.Fprintf(&t, "(%s) ", strings.ToUpper(r.Value))
+
+		case "degrees":
+			fmt.Fprintf(&t, "%s ", r.Value)
+
+		case "begingroup", "diespec", "endgroup", "maximized", "operator":
 			fmt.Fprintf(&t, "%s", r.Value)
 
 		case "discarded":
@@ -2732,9 +4357,26 @@ func (sr StructuredDescriptionSet) Text() (string, error) {
 		case "exceeded":
 			fmt.Fprintf(&t, "(EXCEEDED DC by %s) ", r.Value)
 
+		case "doubled":
+			fmt.Fprintf(&t, "{doubled %s}", r.Value)
+
+		case "dropped":
+			fmt.Fprintf(&t, "{dropped %s}", r.Value)
+
+		case "exploded":
+			fmt.Fprintf(&t, "{exploded %s}", r.Value)
+
 		case "fail", "success":
 			fmt.Fprintf(&t, "(%s) ", r.Value)
 
+		case "failures":
+			fmt.Fprintf(&t, "{failures %s}", r.Value)
+
+		case "faces":
+			for _, face := range strings.Split(r.Value, ",") {
+				fmt.Fprintf(&t, "[%s]", face)
+			}
+
 		case "iteration":
 			fmt.Fprintf(&t, "(#%s) ", r.Value)
 
@@ -2759,6 +4401,9 @@ func (sr StructuredDescriptionSet) Text() (string, error) {
 		case "repeat":
 			fmt.Fprintf(&t, "(x%s) ", r.Value)
 
+		case "rerolled":
+			fmt.Fprintf(&t, "{rerolled %s}", r.Value)
+
 		case "result":
 			fmt.Fprintf(&t, "[%s] ", r.Value)
 
@@ -2771,6 +4416,12 @@ func (sr StructuredDescriptionSet) Text() (string, error) {
 		case "subtotal":
 			fmt.Fprintf(&t, "(%s)", r.Value)
 
+		case "suppressed":
+			fmt.Fprintf(&t, " {%s}", r.Value)
+
+		case "successes":
+			fmt.Fprintf(&t, "{successes %s}", r.Value)
+
 		case "total":
 			fmt.Fprintf(&t, " (until total %s) ", r.Value)
 
@@ -2817,8 +4468,234 @@ type DieRollPreset struct {
 	Description string `json:",omitempty"`
 
 	// The die-roll specification to send to the server. This must be in a
-	// form acceptable to the dice.Roll function.
+	// form acceptable to the dice.Roll function, once any parameters
+	// (see PresetParameter) have been given values.
 	DieRollSpec string
+
+	// The parameters declared in DieRollSpec, as reported by ParsePresetParameters.
+	// This is filled in when presets are read from a file or sent by the server, for the
+	// convenience of clients which prompt the user for their values before rolling
+	// the preset. The declarations in DieRollSpec itself are what actually count.
+	Parameters []PresetParameter `json:",omitempty"`
+}
+
+// MaxPresetNesting is the maximum depth to which die-roll presets may refer
+// to other presets which refer to other presets, and so on.
+const MaxPresetNesting = 10
+
+// WithPresets sets up a DieRoller with a set of die-roll presets which may be
+// referred to by name in the die-roll expressions it rolls.
+func WithPresets(presets ...DieRollPreset) func(*Dice) error {
+	return func(o *Dice) error {
+		o.presets = presets
+		return nil
+	}
+}
+
+// DefinePresets replaces the set of die-roll presets which may be referred to
+// in die-roll expressions.
+//
+// A reference to a preset is an at-sign (“@”) followed by the preset's name
+// as displayed to the user (i.e., without any sorting prefix ending in “|”),
+// as in “@Longsword to-hit + 2 flanking”. The longest preset name which
+// matches the text following the “@” is used. Alternatively, the name may
+// be enclosed in braces, as in “@{Longsword to-hit}+2 flanking”.
+//
+// If the preset has parameters (see PresetParameter), their values may be given
+// after a colon inside the braces, as in “@{Greatsword: power_attack=2}”. Any
+// parameters not given take their default values.
+//
+// The reference is replaced by the preset's expression in parentheses, and any
+// options given in the preset (such as “| c”) are added to those of the
+// expression which referred to it (ahead of the ones it already has, so its own
+// options take precedence). If the expression has no title, it takes the title
+// of the first preset it refers to which has one.
+//
+// If more than one preset has the same name, a user's own preset is used
+// instead of a global one with that name.
+func (d *DieRoller) DefinePresets(presets []DieRollPreset) {
+	d.presets = make(map[string][]DieRollPreset)
+	for _, preset := range presets {
+		name := presetDisplayName(preset.Name)
+		d.presets[name] = append(d.presets[name], preset)
+	}
+}
+
+// presetDisplayName returns the name of a preset as shown to the user.
+func presetDisplayName(name string) string {
+	if pos := strings.IndexRune(name, '|'); pos >= 0 {
+		return strings.TrimSpace(name[pos+1:])
+	}
+	return strings.TrimSpace(name)
+}
+
+// ExpandPresets returns the die-roll expression spec with all references to
+// presets (see DefinePresets) replaced by the presets they refer to, and any
+// parameters in spec replaced by their default values. This is done
+// automatically when rolling dice, but may be useful to see what will be rolled,
+// or to check the expanded expression using Parse.
+func (d *DieRoller) ExpandPresets(spec string) (string, error) {
+	spec, _, err := d.expandPresets(spec, nil)
+	return spec, err
+}
+
+// lookupPreset finds the preset with the given display name.
+func (d *DieRoller) lookupPreset(name string) (DieRollPreset, error) {
+	candidates := d.presets[name]
+	if len(candidates) > 1 {
+		var own []DieRollPreset
+		for _, preset := range candidates {
+			if !preset.Global {
+				own = append(own, preset)
+			}
+		}
+		if len(own) > 0 {
+			candidates = own
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return DieRollPreset{}, fmt.Errorf("there is no die-roll preset named \"%s\"", name)
+	case 1:
+		return candidates[0], nil
+	}
+	return DieRollPreset{}, fmt.Errorf("there is more than one die-roll preset named \"%s\"", name)
+}
+
+// expandPresets replaces the preset references in spec, returning the expanded
+// spec and a description of each preset used. The active parameter lists the
+// presets we're in the middle of expanding already.
+func (d *DieRoller) expandPresets(spec string, active []string) (string, []StructuredDescription, error) {
+	spec, err := substituteParameters(spec, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	if !strings.ContainsRune(spec, '@') {
+		return spec, nil, nil
+	}
+
+	var title, firstTitle, rest string
+	var refs []StructuredDescription
+	var addedOptions []string
+
+	if pos := titleSeparator(spec); pos >= 0 {
+		title, rest = strings.TrimSpace(spec[:pos]), spec[pos+1:]
+	} else {
+		rest = spec
+	}
+
+	expand := func(text string) (string, error) {
+		var b strings.Builder
+		for {
+			pos := strings.IndexRune(text, '@')
+			if pos < 0 {
+				b.WriteString(text)
+				return b.String(), nil
+			}
+			b.WriteString(text[:pos])
+			text = text[pos+1:]
+
+			var name string
+			var args map[string]string
+			if strings.HasPrefix(text, "{") {
+				end := strings.IndexRune(text, '}')
+				if end < 0 {
+					return "", fmt.Errorf("preset reference \"@%s\" is missing its closing brace", text)
+				}
+				name = strings.TrimSpace(text[1:end])
+				text = text[end+1:]
+				if n, a, ok := strings.Cut(name, ":"); ok && d.presets[name] == nil {
+					var err error
+					if args, err = parsePresetArguments(a); err != nil {
+						return "", err
+					}
+					name = strings.TrimSpace(n)
+				}
+			} else {
+				for candidate := range d.presets {
+					if len(candidate) > len(name) && strings.HasPrefix(text, candidate) {
+						if r, _ := utf8.DecodeRuneInString(text[len(candidate):]); len(text) == len(candidate) || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+							name = candidate
+						}
+					}
+				}
+				if name == "" {
+					word := strings.FieldsFunc(text, func(r rune) bool { return unicode.IsSpace(r) || strings.ContainsRune("-+*×÷()≤≥|", r) })
+					if len(word) == 0 {
+						return "", fmt.Errorf("\"@\" in die-roll expression is not followed by the name of a preset")
+					}
+					return "", fmt.Errorf("there is no die-roll preset named \"%s\"", word[0])
+				}
+				text = text[len(name):]
+			}
+
+			if slices.Contains(active, name) {
+				return "", fmt.Errorf("die-roll preset \"%s\" refers to itself (%s → %s)", name, strings.Join(active, " → "), name)
+			}
+			if len(active) >= MaxPresetNesting {
+				return "", fmt.Errorf("die-roll presets are nested more than %d levels deep (%s → %s)", MaxPresetNesting, strings.Join(active, " → "), name)
+			}
+			preset, err := d.lookupPreset(name)
+			if err != nil {
+				return "", err
+			}
+			presetSpec, err := preset.SpecWithArguments(args)
+			if err != nil {
+				return "", err
+			}
+			expansion, subRefs, err := d.expandPresets(presetSpec, append(slices.Clone(active), name))
+			if err != nil {
+				return "", err
+			}
+			if pos := titleSeparator(expansion); pos >= 0 {
+				if firstTitle == "" {
+					firstTitle = strings.TrimSpace(expansion[:pos])
+				}
+				expansion = expansion[pos+1:]
+			}
+			pieces := strings.Split(expansion, "|")
+			body := strings.TrimSpace(pieces[0])
+			addedOptions = append(addedOptions, pieces[1:]...)
+			b.WriteString("(" + body + ")")
+			refs = append(refs, StructuredDescription{Type: "preset", Value: name + "=" + body})
+			refs = append(refs, subRefs...)
+		}
+	}
+
+	pieces := strings.Split(rest, "|")
+	for i := range pieces {
+		var err error
+		if pieces[i], err = expand(pieces[i]); err != nil {
+			return "", nil, err
+		}
+	}
+	if title == "" {
+		title = firstTitle
+	}
+
+	result := strings.Join(slices.Concat(pieces[:1], addedOptions, pieces[1:]), "|")
+	if title != "" {
+		result = title + "=" + result
+	}
+	return result, refs, nil
+}
+
+// titleSeparator returns the index of the "=" which separates the title from the
+// rest of a die-roll spec, or -1 if there is none. An "=" inside braces (such as
+// in the arguments to a preset) doesn't count.
+func titleSeparator(spec string) int {
+	braces := 0
+	for i, r := range spec {
+		switch {
+		case r == '{':
+			braces++
+		case r == '}' && braces > 0:
+			braces--
+		case r == '=' && braces == 0 && (i == 0 || (spec[i-1] != '<' && spec[i-1] != '>')):
+			return i
+		}
+	}
+	return -1
 }
 
 type DieRollPresetMetaData struct {
@@ -2849,7 +4726,7 @@ func WriteDieRollPresetFile(path string, presets []DieRollPreset, meta DieRollPr
 // but we won't stop you if you do it anyway.
 func SaveDieRollPresetFile(output io.Writer, presets []DieRollPreset, meta DieRollPresetMetaData) error {
 	writer := bufio.NewWriter(output)
-	writer.WriteString("__DICE__:2\n")
+	writer.WriteString("__DICE__:3\n")
 	if meta.Timestamp == 0 {
 		now := time.Now()
 		meta.Timestamp = now.Unix()
@@ -2868,6 +4745,10 @@ func SaveDieRollPresetFile(output io.Writer, presets []DieRollPreset, meta DieRo
 	})
 
 	for _, preset := range presets {
+		var err error
+		if preset.Parameters, err = ParsePresetParameters(preset.DieRollSpec); err != nil {
+			return fmt.Errorf("unable to save preset \"%s\": %v", preset.Name, err)
+		}
 		data, err := json.MarshalIndent(preset, "", "    ")
 		if err != nil {
 			return fmt.Errorf("unable to serialize preset \"%s\": %v", preset.Name, err)
@@ -2926,11 +4807,15 @@ func loadLegacyDieRollPresetFile(scanner *bufio.Scanner, meta DieRollPresetMetaD
 		if len(f) != 3 {
 			return nil, meta, fmt.Errorf("legacy die-roll preset file has invalid record: field count %d", len(f))
 		}
-		presets = append(presets, DieRollPreset{
+		preset := DieRollPreset{
 			Name:        f[0],
 			Description: f[1],
 			DieRollSpec: f[2],
-		})
+		}
+		if preset.Parameters, err = ParsePresetParameters(preset.DieRollSpec); err != nil {
+			return nil, meta, fmt.Errorf("legacy die-roll preset file has invalid preset \"%s\": %v", preset.Name, err)
+		}
+		presets = append(presets, preset)
 	}
 
 	return presets, meta, nil
@@ -3003,7 +4888,11 @@ func LoadDieRollPresetFile(input io.Reader) ([]DieRollPreset, DieRollPresetMetaD
 				case "PRESET":
 					var preset DieRollPreset
 					if err = json.Unmarshal([]byte(dataPacket.String()), &preset); err == nil {
-						presets = append(presets, preset)
+						// The parameters are always taken from the die-roll spec, whatever the
+						// file says they are (versions before 3 didn't say at all).
+						if preset.Parameters, err = ParsePresetParameters(preset.DieRollSpec); err == nil {
+							presets = append(presets, preset)
+						}
 					}
 
 				default:
@@ -3027,7 +4916,7 @@ const DieRollExpressionSyntax = `
 ==[Die-Roll Expression Syntax]==
 The general form for die roll expressions is:
 
-[//name//**=**] [//qty//[**/**//div//]] **d** //sides// [**best**|**worst of** //n//] [...] [**|**//modifiers]
+[//name//**=**] [//qty//[**/**//div//]] **d** (//sides//|**%**|**F**|**{**//faces//**}**)[**r**|**rr**[**<**|**>**]//n//][**!**|**!!**|**!p**[**>**//t//]][**cs<=**|**cs>=**//n//[**dbl**//n//][**f**//n//]][**kh**|**kl**|**dh**|**dl**//n//] [**best**|**worst of** //n//] [...] [**|**//modifiers]
 
 (Here, **bold** text means to type something literally as shown; //italics// indicates values to substitute, and 
 [square brackets] surround optional components.)
@@ -3052,10 +4941,57 @@ expression, such as “**(3d6+27+1d10)>=10**”.
 
 Unicode U+2264 and U+2265 (**≤** and **≥**) may also be used instead of **<=** and **>=** respectively.
 
+==(Variables)==
+Where a program supports it, a value in the expression may be given as a variable name
+preceded by a dollar sign, as in “**d20 + $STR + $BAB attack**”. The current values of the variables
+are substituted when the dice are rolled, so the same expression keeps working as those values change.
+It is an error to use a variable which isn't defined.
+
+==(Preset References)==
+Where a program supports it, a stored die-roll preset may be used in an expression by giving its name
+after an at-sign, as in “**@Longsword to-hit + 2 flanking**”. The preset's expression is substituted in parentheses,
+and its options (such as “**| c**”) are added to the roll. If the name could run into the text which follows it,
+enclose it in braces, as in “**@{Longsword to-hit}+2**”. Presets may refer to other presets, but not (directly or
+indirectly) to themselves.
+
+A preset may declare //parameters// whose values are supplied each time it is rolled, in the form
+“**${**//name//[**:**//type//][**=**//default//]**}**”, as in “**d20+12 + ${power_attack:int=0}*-1**”. The //type// may be **int**
+(the default) or **bool** (true or false, substituted as 1 or 0). Once declared, a parameter may be used again as “**${**//name//**}**”.
+Give values for the parameters after a colon when referring to the preset, as in “**@{Greatsword: power_attack=2, flanking=true}**”.
+Any parameters not given take their default values; it is an error to leave out one which has no default.
+
 ==(Special)==
 If a dice value is prefixed with a **>** symbol, as in “**>5d10**”, then the first die will be assumed to come up with its
 maximum value (so what's really rolled in this example is 10+4d10).
 
+==(Exploding Dice)==
+Adding “**!**” immediately after the dice value, as in “**3d6!**”, makes the dice //explode//: any die which rolls its maximum
+value is rolled again, adding another die to the total (which may itself explode). Use “**!!**” instead for //compounding// dice,
+where the extra rolls are added into the die that exploded rather than counting as separate dice, or “**!p**” for //penetrating//
+dice, where 1 is subtracted from each extra die rolled. Any of these may be followed by “**>**//t//” to explode on a roll of //t//
+or higher, so “**d10!>8**” explodes on any roll of 8, 9, or 10.
+
+==(Rerolling Individual Dice)==
+Adding “**r**//n//” immediately after the dice value rerolls (once) any die which comes up //n//, so “**2d6r1**” rerolls
+any 1s but keeps the new value even if it's another 1. Using “**rr**” instead keeps rerolling until the die no longer
+meets the condition. Instead of a single number //n//, you can specify “**<**//n//” for rolls of //n// or less, or
+“**>**//n//” for rolls of //n// or more. For example, “**4d6rr<2**” rerolls any 1s or 2s until they come up 3 or higher.
+
+==(Dice Pools)==
+Adding “**cs>=**//n//” immediately after the dice value makes it a //dice pool//: instead of adding up the dice, the value
+is the number of dice which came up //n// or higher (each of which is a “success”). For example, “**8d10cs>=7**”
+rolls eight d10s and counts the ones that rolled 7, 8, 9, or 10. Use “**cs<=**//n//” instead to count dice that rolled //n// or less.
+(Note that this is different from “**8d10>=7**”, which adds up the dice but forces the total to be at least 7.)
+
+After the target number, “**dbl**//n//” makes any die which rolled //n// count as two successes, and “**f**//n//”
+makes any die which rolled //n// subtract a success. These may also be given as “**dbl>**//n//”, “**f<**//n//”, etc.
+For example, “**8d10cs>=7dbl10f1**” counts 10s twice and subtracts a success for each 1.
+
+==(Keeping and Dropping Dice)==
+Adding “**kh**//n//” immediately after the dice value keeps only the highest //n// dice rolled, so “**4d6kh3**” rolls
+four six-sided dice and adds up the best three. Likewise, “**kl**//n//” keeps the lowest //n// dice, “**dh**//n//” drops
+the highest //n// dice, and “**dl**//n//” drops the lowest //n// dice. For example, “**2d20kh1**” rolls two d20s and takes the higher one.
+
 ==(Rerolls)==
 If you put “**best of** //n//” or “**worst of** //n//” after a dice value, such as “**d20 best of 2**”, it will roll the die
 that may times and take the best (or worst) of all those rolls to use for that set of dice. Note that this is part of the dice,
@@ -3069,6 +5005,13 @@ To just get a roll to indicate something that is successful //x// percent of the
 To customize the success/failure messages, you can add those messages to the end, like “**42% miss**” (where the result will be 
 “miss” 42% of the time or else “hit”), or “**15% red/blue**” (where the result will be “red” 15% of the time or else “blue”).
 
+==(FATE and Custom Dice)==
+Use “**dF**” to roll a FATE (or Fudge) die, which has two faces each of -1, 0, and +1, so “**4dF+2**” gives a result from -2 to 6.
+Other dice may be described by listing their faces in braces, as in “**d{1,1,2,2,3,4}**”. Each face may be a number,
+a name (which counts as 0), or a name and value separated by a colon, so “**3d{miss,hit:1,crit:2}**” counts up hits
+where a crit is worth two. The names of the faces rolled are shown along with the results.
+Custom dice may be rerolled, kept, dropped, or used in dice pools, but they cannot explode.
+
 ==(Titles and Labels)==
 You can put arbitrary text at the start of the string, followed by an equals sign (**=**), to place a title on the
 whole roll, like “**Attack roll = d20+12**”. 
@@ -3078,6 +5021,11 @@ represents. In this case, the label must consist of one or more words which begi
 and contain only letters, digits, underscores, periods (**.**), and commas (**,**).  For example,
 “**5d6 + 1d6 fire + 1d6 acid spray**”.
 
+If the label of a constant or variable starts with a Pathfinder bonus type (alchemical, armor, competence, deflection, enhancement, insight, luck,
+morale, natural, profane, racial, resistance, sacred, shield, size, or trait), bonuses of the same type don't stack: only the
+highest one counts, and the others are shown as suppressed. For example, “**d20 + 2 morale + 1 morale + 3 enhancement**” only
+adds 5 to the roll. Dodge, circumstance, and untyped bonuses always stack, as do penalties (like “**- 1 size**”).
+
 ==(Custom Colors for Titles and Labels)==
 To make a die roll title stand out, for example to make damage monster attack rolls or critical damage rolls stand out so you can notice them
 at a glance when scrolling back through the die-roll/chat window, you can colorize the title by adding color codes after the title text (just
@@ -3118,8 +5066,14 @@ meaning of the whole thing. Each option begins with a vertical bar (**|**) chara
 
 **|c** //t//**+**//b// (As above but add the bonus //b// to the confirmation roll.)
 
+**|crit x**//m// //damage// (As **|c** but if the critical hit is confirmed, also roll the //damage// expression multiplied by //m//. For example, “**d20+12|c19|crit x2 1d8+6 + 2d6 sneak|dc 18**”. Multiplying damage means rolling it //m// times and adding them together, except that any parts of the //damage// labeled “sneak” or “precision” are only rolled once. If there's no **|dc** option, the damage is rolled for any critical threat since it can't tell if the confirmation roll succeeded.)
+
 **|dc** //n// (Indicate that the roll was a “success” if the result was at least //n//.)
 
+**|full** //damage//[**/**//damage//...] (Make a full attack, where each permutation of the die-roll expression (e.g., “**d20+{16/11/6}**”) is an attack roll which is paired with the //damage// in the same position in the list, or with the only //damage// given. Each //damage// may be followed by **c**//t//[**+**//b//] to give that attack its own threat range and confirmation bonus, and **x**//m// to give its critical damage multiplier (the default is ×2). Threatened criticals are confirmed separately for each attack. If there is a **|dc** option, it is the target's armor class, and damage is rolled only for hits, multiplied for confirmed critical hits. The total damage is reported at the end. For example, “**d20+{16/11/6}|c19|full 1d8+5/1d8+5/1d6+2 x3|dc 20**”.)
+
+**|degrees** (With **|dc**, report the degree of success as in Pathfinder 2e: a critical success if the result was at least 10 over //n//, a success if it was at least //n//, a critical failure if it was at least 10 under //n//, and a failure otherwise. A natural 20 (or whatever the die's maximum is) improves this by one degree and a natural 1 makes it one degree worse.)
+
 **|maximized** (All die rolls are forced to their maximum possible values.)
 
 **|repeat** //n// (Roll //n// times.)
@@ -24,9 +24,12 @@
 package dice
 
 import (
+	"fmt"
 	"log"
+	"math"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -179,6 +182,29 @@ abc456 {} d12
 			Comment:     "a test file",
 			FileVersion: 2,
 		}, false},
+
+		// 8
+		{`__DICE__:3
+«__META__» {"Timestamp": 123456}
+«PRESET» {
+	"Name": "Power Attack",
+	"DieRollSpec": "d20+12 + ${power_attack:int=0}*-1",
+	"Parameters": [{"Name": "power_attack", "Type": "int", "Default": "0"}]
+}
+«__EOF__»
+`, 3, []DieRollPreset{
+			{Name: "Power Attack", DieRollSpec: "d20+12 + ${power_attack:int=0}*-1"},
+		}, DieRollPresetMetaData{
+			Timestamp:   123456,
+			FileVersion: 3,
+		}, false},
+
+		// 9
+		{`__DICE__:4
+«__EOF__»
+`, 4, nil, DieRollPresetMetaData{
+			FileVersion: 4,
+		}, true},
 	} {
 		input := strings.NewReader(tcase.inputData)
 		presets, meta, err := LoadDieRollPresetFile(input)
@@ -320,6 +346,9 @@ func compareSingleResult(a, b StructuredResult) bool {
 	if a.ResultSuppressed != b.ResultSuppressed {
 		return false
 	}
+	if a.Degree != b.Degree {
+		return false
+	}
 	if len(a.Details) != len(b.Details) {
 		return false
 	}
@@ -2155,6 +2184,1106 @@ func TestDicePrivateRolls(t *testing.T) {
 	}
 }
 
+func TestDiceExploding(t *testing.T) {
+	d, err := NewDieRoller(WithSeed(12345))
+	if err != nil {
+		t.Fatalf("Error creating new DieRoller: %v", err)
+	}
+
+	type testcase struct {
+		Roll    string
+		Reslist []StructuredResult
+		Error   bool
+	}
+
+	testcases := []testcase{
+		// 0
+		{Roll: "3d6!", Reslist: []StructuredResult{
+			{Result: 18, Details: []StructuredDescription{
+				{Type: "result", Value: "18"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "3d6!"},
+				{Type: "subtotal", Value: "18"},
+				{Type: "roll", Value: "6,2,5,5"},
+				{Type: "exploded", Value: "2"},
+			}},
+		}},
+		// 1
+		{Roll: "", Reslist: []StructuredResult{
+			{Result: 16, Details: []StructuredDescription{
+				{Type: "result", Value: "16"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "3d6!"},
+				{Type: "subtotal", Value: "16"},
+				{Type: "roll", Value: "6,6,1,1,2"},
+				{Type: "exploded", Value: "6,1"},
+			}},
+		}},
+		// 2
+		{Roll: "", Reslist: []StructuredResult{
+			{Result: 8, Details: []StructuredDescription{
+				{Type: "result", Value: "8"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "3d6!"},
+				{Type: "subtotal", Value: "8"},
+				{Type: "roll", Value: "1,3,4"},
+			}},
+		}},
+		// 3
+		{Roll: "8d6!p", Reslist: []StructuredResult{
+			{Result: 36, Details: []StructuredDescription{
+				{Type: "result", Value: "36"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "8d6!p"},
+				{Type: "subtotal", Value: "36"},
+				{Type: "roll", Value: "4,3,1,5,6,5,3,2,5,2"},
+				{Type: "exploded", Value: "5,3"},
+			}},
+		}},
+		// 4
+		{Roll: "2d6!!>5 fire", Reslist: []StructuredResult{
+			{Result: 7, Details: []StructuredDescription{
+				{Type: "result", Value: "7"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "2d6!!>5"},
+				{Type: "subtotal", Value: "7"},
+				{Type: "roll", Value: "3,4"},
+				{Type: "label", Value: "fire"},
+			}},
+		}},
+		// 5
+		{Roll: "", Reslist: []StructuredResult{
+			{Result: 9, Details: []StructuredDescription{
+				{Type: "result", Value: "9"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "2d6!!>5"},
+				{Type: "subtotal", Value: "9"},
+				{Type: "roll", Value: "1,8"},
+				{Type: "exploded", Value: "3"},
+				{Type: "label", Value: "fire"},
+			}},
+		}},
+		// 6
+		{Roll: "3d6!|maximized", Reslist: []StructuredResult{
+			{Result: 18, Details: []StructuredDescription{
+				{Type: "result", Value: "18"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "3d6!"},
+				{Type: "subtotal", Value: "18"},
+				{Type: "maxroll", Value: "6,6,6"},
+				{Type: "moddelim", Value: "|"},
+				{Type: "fullmax", Value: "maximized"},
+			}},
+		}},
+		// 7
+		{Roll: "d6!>1", Error: true},
+		// 8
+		{Roll: "d1!", Error: true},
+		// 9
+		{Roll: "d6!!!", Error: true},
+	}
+
+	for i, test := range testcases {
+		_, results, err := d.DoRoll(test.Roll)
+		if test.Error {
+			if err == nil {
+				t.Fatalf("test #%d error expected, but none was raised", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test #%d error %v", i, err)
+		}
+		if !compareResults(results, test.Reslist) {
+			t.Fatalf("test #%d result %v, expected %v", i, results, test.Reslist)
+		}
+	}
+}
+
+func TestDiceKeepDrop(t *testing.T) {
+	d, err := NewDieRoller(WithSeed(12345))
+	if err != nil {
+		t.Fatalf("Error creating new DieRoller: %v", err)
+	}
+
+	type testcase struct {
+		Roll    string
+		Reslist []StructuredResult
+		Error   bool
+	}
+
+	testcases := []testcase{
+		// 0
+		{Roll: "4d6kh3", Reslist: []StructuredResult{
+			{Result: 16, Details: []StructuredDescription{
+				{Type: "result", Value: "16"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "4d6kh3"},
+				{Type: "subtotal", Value: "16"},
+				{Type: "roll", Value: "6,5,5"},
+				{Type: "dropped", Value: "2"},
+			}},
+		}},
+		// 1
+		{Roll: "2d20kl1|sf", Reslist: []StructuredResult{
+			{Result: 2, Details: []StructuredDescription{
+				{Type: "result", Value: "2"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "2d20kl1"},
+				{Type: "roll", Value: "2"},
+				{Type: "dropped", Value: "16"},
+				{Type: "moddelim", Value: "|"},
+				{Type: "sf", Value: "sf"},
+			}},
+		}},
+		// 2
+		{Roll: "4d6dl1|maximized", Reslist: []StructuredResult{
+			{Result: 18, Details: []StructuredDescription{
+				{Type: "result", Value: "18"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "4d6dl1"},
+				{Type: "subtotal", Value: "18"},
+				{Type: "maxroll", Value: "6,6,6"},
+				{Type: "dropped", Value: "6"},
+				{Type: "moddelim", Value: "|"},
+				{Type: "fullmax", Value: "maximized"},
+			}},
+		}},
+		// 3
+		{Roll: "6d6dh2+1", Reslist: []StructuredResult{
+			{Result: 6, Details: []StructuredDescription{
+				{Type: "result", Value: "6"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "6d6dh2"},
+				{Type: "subtotal", Value: "5"},
+				{Type: "roll", Value: "1,1,2,1"},
+				{Type: "dropped", Value: "3,4"},
+				{Type: "operator", Value: "+"},
+				{Type: "constant", Value: "1"},
+			}},
+		}},
+		// 4
+		{Roll: "d6kh0", Error: true},
+		// 5
+		{Roll: "d6kh1dl1", Error: true},
+		// 6
+		{Roll: "3d6|sf", Error: true},
+	}
+
+	for i, test := range testcases {
+		_, results, err := d.DoRoll(test.Roll)
+		if test.Error {
+			if err == nil {
+				t.Fatalf("test #%d error expected, but none was raised", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test #%d error %v", i, err)
+		}
+		if !compareResults(results, test.Reslist) {
+			t.Fatalf("test #%d result %v, expected %v", i, results, test.Reslist)
+		}
+	}
+
+	// With only a single die kept, we should be able to tell if
+	// that die was a natural maximum.
+	for i := 0; i < 1000; i++ {
+		_, r, err := d.DoRollOnce("2d20kh1")
+		if err != nil {
+			t.Fatalf("advantage roll error %v", err)
+		}
+		if d.IsNaturalMax() != (r.Result == 20) {
+			t.Fatalf("advantage roll of %d reported IsNaturalMax()=%v", r.Result, d.IsNaturalMax())
+		}
+		if d.IsNatural1() != (r.Result == 1) {
+			t.Fatalf("advantage roll of %d reported IsNatural1()=%v", r.Result, d.IsNatural1())
+		}
+	}
+}
+
+func TestDiceRerollDice(t *testing.T) {
+	d, err := NewDieRoller(WithSeed(12345))
+	if err != nil {
+		t.Fatalf("Error creating new DieRoller: %v", err)
+	}
+
+	type testcase struct {
+		Roll    string
+		Reslist []StructuredResult
+		Error   bool
+	}
+
+	testcases := []testcase{
+		// 0
+		{Roll: "4d6r1", Reslist: []StructuredResult{
+			{Result: 18, Details: []StructuredDescription{
+				{Type: "result", Value: "18"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "4d6r1"},
+				{Type: "subtotal", Value: "18"},
+				{Type: "roll", Value: "6,2,5,5"},
+			}},
+		}},
+		// 1
+		{Roll: "", Reslist: []StructuredResult{
+			{Result: 15, Details: []StructuredDescription{
+				{Type: "result", Value: "15"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "4d6r1"},
+				{Type: "subtotal", Value: "15"},
+				{Type: "roll", Value: "6,6,1,2"},
+				{Type: "rerolled", Value: "1"},
+			}},
+		}},
+		// 2
+		{Roll: "2d6rr<3", Reslist: []StructuredResult{
+			{Result: 8, Details: []StructuredDescription{
+				{Type: "result", Value: "8"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "2d6rr<3"},
+				{Type: "subtotal", Value: "8"},
+				{Type: "roll", Value: "4,4"},
+				{Type: "rerolled", Value: "1,3"},
+			}},
+		}},
+		// 3
+		{Roll: "", Reslist: []StructuredResult{
+			{Result: 11, Details: []StructuredDescription{
+				{Type: "result", Value: "11"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "2d6rr<3"},
+				{Type: "subtotal", Value: "11"},
+				{Type: "roll", Value: "5,6"},
+				{Type: "rerolled", Value: "3,1"},
+			}},
+		}},
+		// 4
+		{Roll: "4d6kh3rr<2", Reslist: []StructuredResult{
+			{Result: 15, Details: []StructuredDescription{
+				{Type: "result", Value: "15"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "4d6rr<2kh3"},
+				{Type: "subtotal", Value: "15"},
+				{Type: "roll", Value: "6,4,5"},
+				{Type: "rerolled", Value: "2,2"},
+				{Type: "dropped", Value: "3"},
+			}},
+		}},
+		// 5
+		{Roll: "d6rr<6", Error: true},
+		// 6
+		{Roll: "d6rr>1", Error: true},
+		// 7
+		{Roll: "d6r1r2", Error: true},
+	}
+
+	for i, test := range testcases {
+		_, results, err := d.DoRoll(test.Roll)
+		if test.Error {
+			if err == nil {
+				t.Fatalf("test #%d error expected, but none was raised", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test #%d error %v", i, err)
+		}
+		if !compareResults(results, test.Reslist) {
+			t.Fatalf("test #%d result %v, expected %v", i, results, test.Reslist)
+		}
+	}
+}
+
+func TestDicePools(t *testing.T) {
+	d, err := NewDieRoller(WithSeed(12345))
+	if err != nil {
+		t.Fatalf("Error creating new DieRoller: %v", err)
+	}
+
+	type testcase struct {
+		Roll    string
+		Reslist []StructuredResult
+		Error   bool
+	}
+
+	testcases := []testcase{
+		// 0
+		{Roll: "8d10cs>=7", Reslist: []StructuredResult{
+			{Result: 2, Details: []StructuredDescription{
+				{Type: "result", Value: "2"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "8d10cs>=7"},
+				{Type: "subtotal", Value: "2"},
+				{Type: "roll", Value: "4,4,5,7,2,6,3,7"},
+				{Type: "successes", Value: "7,7"},
+			}},
+		}},
+		// 1
+		{Roll: "8d10cs>=7dbl10f1|dc 3", Reslist: []StructuredResult{
+			{Result: 4, Details: []StructuredDescription{
+				{Type: "result", Value: "4"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "8d10cs>=7dbl10f1"},
+				{Type: "subtotal", Value: "4"},
+				{Type: "roll", Value: "2,9,5,8,6,3,7,9"},
+				{Type: "successes", Value: "9,8,7,9"},
+				{Type: "moddelim", Value: "|"},
+				{Type: "dc", Value: "3"},
+				{Type: "exceeded", Value: "1"},
+			}},
+		}},
+		// 2
+		{Roll: "", Reslist: []StructuredResult{
+			{Result: 4, Details: []StructuredDescription{
+				{Type: "result", Value: "4"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "8d10cs>=7dbl10f1"},
+				{Type: "subtotal", Value: "4"},
+				{Type: "roll", Value: "4,8,8,6,1,10,7,6"},
+				{Type: "successes", Value: "8,8,7"},
+				{Type: "doubled", Value: "10"},
+				{Type: "failures", Value: "1"},
+				{Type: "moddelim", Value: "|"},
+				{Type: "dc", Value: "3"},
+				{Type: "exceeded", Value: "1"},
+			}},
+		}},
+		// 3
+		{Roll: "d20cs>=15|sf", Reslist: []StructuredResult{
+			{Result: 1, Details: []StructuredDescription{
+				{Type: "result", Value: "1"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "1d20cs>=15"},
+				{Type: "roll", Value: "15"},
+				{Type: "successes", Value: "15"},
+				{Type: "moddelim", Value: "|"},
+				{Type: "sf", Value: "sf"},
+			}},
+		}},
+		// 4
+		{Roll: "6d6cs<=2+1", Reslist: []StructuredResult{
+			{Result: 1, Details: []StructuredDescription{
+				{Type: "result", Value: "1"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "6d6cs<=2"},
+				{Type: "subtotal", Value: "0"},
+				{Type: "roll", Value: "5,3,5,3,5,3"},
+				{Type: "operator", Value: "+"},
+				{Type: "constant", Value: "1"},
+			}},
+		}},
+		// 5
+		{Roll: "4d6cs>=5|maximized", Reslist: []StructuredResult{
+			{Result: 4, Details: []StructuredDescription{
+				{Type: "result", Value: "4"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "4d6cs>=5"},
+				{Type: "subtotal", Value: "4"},
+				{Type: "maxroll", Value: "6,6,6,6"},
+				{Type: "successes", Value: "6,6,6,6"},
+				{Type: "moddelim", Value: "|"},
+				{Type: "fullmax", Value: "maximized"},
+			}},
+		}},
+		// 6
+		{Roll: "d6dbl6", Error: true},
+		// 7
+		{Roll: "8d10cs>7", Error: true},
+		// 8
+		{Roll: "8d10cs≥7", Reslist: []StructuredResult{
+			{Result: 4, Details: []StructuredDescription{
+				{Type: "result", Value: "4"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "8d10cs>=7"},
+				{Type: "subtotal", Value: "4"},
+				{Type: "roll", Value: "8,3,6,1,8,5,8,7"},
+				{Type: "successes", Value: "8,8,8,7"},
+			}},
+		}},
+		// 9
+		{Roll: "6d6cs<=2 hits", Reslist: []StructuredResult{
+			{Result: 2, Details: []StructuredDescription{
+				{Type: "result", Value: "2"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "6d6cs<=2"},
+				{Type: "subtotal", Value: "2"},
+				{Type: "roll", Value: "2,3,1,4,5,3"},
+				{Type: "successes", Value: "2,1"},
+				{Type: "label", Value: "hits"},
+			}},
+		}},
+		// 10
+		{Roll: "8d10!cs>=7", Reslist: []StructuredResult{
+			{Result: 3, Details: []StructuredDescription{
+				{Type: "result", Value: "3"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "8d10!cs>=7"},
+				{Type: "subtotal", Value: "3"},
+				{Type: "roll", Value: "4,3,8,6,3,9,3,8"},
+				{Type: "successes", Value: "8,9,8"},
+			}},
+		}},
+		// 11
+		{Roll: "2d10>=15", Reslist: []StructuredResult{
+			{Result: 15, Details: []StructuredDescription{
+				{Type: "result", Value: "15"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "2d10"},
+				{Type: "subtotal", Value: "6"},
+				{Type: "roll", Value: "4,2"},
+				{Type: "operator", Value: "≥"},
+				{Type: "constant", Value: "15"},
+			}},
+		}},
+		// 12
+		{Roll: "(2d10)>=15", Reslist: []StructuredResult{
+			{Result: 15, Details: []StructuredDescription{
+				{Type: "result", Value: "15"},
+				{Type: "separator", Value: "="},
+				{Type: "begingroup", Value: "("},
+				{Type: "diespec", Value: "2d10"},
+				{Type: "subtotal", Value: "6"},
+				{Type: "roll", Value: "1,5"},
+				{Type: "endgroup", Value: ")"},
+				{Type: "operator", Value: "≥"},
+				{Type: "constant", Value: "15"},
+			}},
+		}},
+		// 13
+		{Roll: "8d10cs7", Error: true},
+		// 14
+		{Roll: "8d10>7", Error: true},
+		// 15
+		{Roll: "3d6<=10", Reslist: []StructuredResult{
+			{Result: 10, Details: []StructuredDescription{
+				{Type: "result", Value: "10"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "3d6"},
+				{Type: "subtotal", Value: "12"},
+				{Type: "roll", Value: "6,3,3"},
+				{Type: "operator", Value: "≤"},
+				{Type: "constant", Value: "10"},
+			}},
+		}},
+		// 16
+		{Roll: "8d10cs>=7>=3", Reslist: []StructuredResult{
+			{Result: 5, Details: []StructuredDescription{
+				{Type: "result", Value: "5"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "8d10cs>=7"},
+				{Type: "subtotal", Value: "5"},
+				{Type: "roll", Value: "10,7,1,7,9,9,5,4"},
+				{Type: "successes", Value: "10,7,7,9,9"},
+				{Type: "operator", Value: "≥"},
+				{Type: "constant", Value: "3"},
+			}},
+		}},
+	}
+
+	for i, test := range testcases {
+		_, results, err := d.DoRoll(test.Roll)
+		if test.Error {
+			if err == nil {
+				t.Fatalf("test #%d error expected, but none was raised", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test #%d error %v", i, err)
+		}
+		if !compareResults(results, test.Reslist) {
+			t.Fatalf("test #%d result %v, expected %v", i, results, test.Reslist)
+		}
+	}
+}
+
+func TestDiceVariables(t *testing.T) {
+	vars := Variables{"STR": 4, "BAB": 6}
+	d, err := NewDieRoller(WithSeed(12345), WithVariables(vars))
+	if err != nil {
+		t.Fatalf("Error creating new DieRoller: %v", err)
+	}
+
+	type testcase struct {
+		Roll    string
+		Set     map[string]int
+		Reslist []StructuredResult
+		Error   bool
+	}
+
+	testcases := []testcase{
+		// 0
+		{Roll: "d20 + $STR + $BAB attack", Reslist: []StructuredResult{
+			{Result: 14, Details: []StructuredDescription{
+				{Type: "result", Value: "14"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "1d20"},
+				{Type: "roll", Value: "4"},
+				{Type: "operator", Value: "+"},
+				{Type: "constant", Value: "4"},
+				{Type: "variable", Value: "STR"},
+				{Type: "operator", Value: "+"},
+				{Type: "constant", Value: "6"},
+				{Type: "variable", Value: "BAB"},
+				{Type: "label", Value: "attack"},
+			}},
+		}},
+		// 1 (the variables are looked up again on each roll)
+		{Roll: "", Set: map[string]int{"BAB": 7}, Reslist: []StructuredResult{
+			{Result: 15, Details: []StructuredDescription{
+				{Type: "result", Value: "15"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "1d20"},
+				{Type: "roll", Value: "4"},
+				{Type: "operator", Value: "+"},
+				{Type: "constant", Value: "4"},
+				{Type: "variable", Value: "STR"},
+				{Type: "operator", Value: "+"},
+				{Type: "constant", Value: "7"},
+				{Type: "variable", Value: "BAB"},
+				{Type: "label", Value: "attack"},
+			}},
+		}},
+		// 2
+		{Roll: "($STR strength+2)*2", Reslist: []StructuredResult{
+			{Result: 12, Details: []StructuredDescription{
+				{Type: "result", Value: "12"},
+				{Type: "separator", Value: "="},
+				{Type: "begingroup", Value: "("},
+				{Type: "constant", Value: "4"},
+				{Type: "variable", Value: "STR"},
+				{Type: "label", Value: "strength"},
+				{Type: "operator", Value: "+"},
+				{Type: "constant", Value: "2"},
+				{Type: "endgroup", Value: ")"},
+				{Type: "operator", Value: "×"},
+				{Type: "constant", Value: "2"},
+			}},
+		}},
+		// 3
+		{Roll: "d20+$DEX", Error: true},
+		// 4
+		{Roll: "d20 $STR", Error: true},
+	}
+
+	for i, test := range testcases {
+		for k, v := range test.Set {
+			vars[k] = v
+		}
+		_, results, err := d.DoRoll(test.Roll)
+		if test.Error {
+			if err == nil {
+				t.Fatalf("test #%d error expected, but none was raised", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test #%d error %v", i, err)
+		}
+		if !compareResults(results, test.Reslist) {
+			t.Fatalf("test #%d result %v, expected %v", i, results, test.Reslist)
+		}
+	}
+
+	_, _, err = Roll("d20+$STR")
+	if err == nil || !strings.Contains(err.Error(), "$STR") {
+		t.Errorf("expected error naming undefined variable, got %v", err)
+	}
+}
+
+func TestDiceCustomFaces(t *testing.T) {
+	d, err := NewDieRoller(WithSeed(12345))
+	if err != nil {
+		t.Fatalf("Error creating new DieRoller: %v", err)
+	}
+
+	type testcase struct {
+		Roll    string
+		Reslist []StructuredResult
+		Max     bool
+		Error   bool
+	}
+
+	testcases := []testcase{
+		// 0
+		{Roll: "4dF+2 skill", Reslist: []StructuredResult{
+			{Result: 4, Details: []StructuredDescription{
+				{Type: "result", Value: "4"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "4dF"},
+				{Type: "subtotal", Value: "2"},
+				{Type: "roll", Value: "1,-1,1,1"},
+				{Type: "faces", Value: "+,-,+,+"},
+				{Type: "operator", Value: "+"},
+				{Type: "constant", Value: "2"},
+				{Type: "label", Value: "skill"},
+			}},
+		}},
+		// 1 (4 is the highest face even though there are six of them)
+		{Roll: "d{1,1,2,2,3,4}", Max: true, Reslist: []StructuredResult{
+			{Result: 4, Details: []StructuredDescription{
+				{Type: "result", Value: "4"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "1d{1,1,2,2,3,4}"},
+				{Type: "roll", Value: "4"},
+			}},
+		}},
+		// 2
+		{Roll: "2d{fail:0,hit:1,crit:2}kh1", Max: true, Reslist: []StructuredResult{
+			{Result: 2, Details: []StructuredDescription{
+				{Type: "result", Value: "2"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "2d{fail:0,hit:1,crit:2}kh1"},
+				{Type: "roll", Value: "2"},
+				{Type: "faces", Value: "crit"},
+				{Type: "dropped", Value: "0"},
+			}},
+		}},
+		// 3
+		{Roll: "4dF|maximized", Reslist: []StructuredResult{
+			{Result: 4, Details: []StructuredDescription{
+				{Type: "result", Value: "4"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "4dF"},
+				{Type: "subtotal", Value: "4"},
+				{Type: "maxroll", Value: "1,1,1,1"},
+				{Type: "faces", Value: "+,+,+,+"},
+				{Type: "moddelim", Value: "|"},
+				{Type: "fullmax", Value: "maximized"},
+			}},
+		}},
+		// 4
+		{Roll: "dF!", Error: true},
+		// 5
+		{Roll: "d{5}", Error: true},
+	}
+
+	for i, test := range testcases {
+		_, results, err := d.DoRoll(test.Roll)
+		if test.Error {
+			if err == nil {
+				t.Fatalf("test #%d error expected, but none was raised", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test #%d error %v", i, err)
+		}
+		if !compareResults(results, test.Reslist) {
+			t.Fatalf("test #%d result %v, expected %v", i, results, test.Reslist)
+		}
+		if d.IsNaturalMax() != test.Max {
+			t.Errorf("test #%d IsNaturalMax %v, expected %v", i, d.IsNaturalMax(), test.Max)
+		}
+	}
+
+	dist, err := Distribution("4dF")
+	if err != nil {
+		t.Fatalf("4dF distribution: %v", err)
+	}
+	if !dist.Exact || dist.Min() != -4 || dist.Max() != 4 || math.Abs(dist.Probability[0]-19.0/81.0) > 1e-9 {
+		t.Errorf("4dF distribution %v", dist.Probability)
+	}
+}
+
+func TestDiceCritDamage(t *testing.T) {
+	d, err := NewDieRoller(WithSeed(12345))
+	if err != nil {
+		t.Fatalf("Error creating new DieRoller: %v", err)
+	}
+
+	// Roll until we get a confirmed critical hit, making sure damage is
+	// only rolled when the confirmation roll meets the DC.
+	spec := "d20+5|crit x2 1d8+4|dc 15"
+	var results []StructuredResult
+	for i := 0; i < 100; i++ {
+		_, results, err = d.DoRoll(spec)
+		if err != nil {
+			t.Fatalf("roll #%d error %v", i, err)
+		}
+		confirmed := len(results) > 1 && results[1].Result >= 15
+		if confirmed != (len(results) == 3) {
+			t.Fatalf("roll #%d results %v", i, results)
+		}
+		if confirmed {
+			break
+		}
+	}
+	if !compareResults(results, []StructuredResult{
+		{Result: 25, Details: []StructuredDescription{
+			{Type: "success", Value: "HIT"},
+			{Type: "result", Value: "25"},
+			{Type: "separator", Value: "="},
+			{Type: "diespec", Value: "1d20"},
+			{Type: "roll", Value: "20"},
+			{Type: "operator", Value: "+"},
+			{Type: "constant", Value: "5"},
+			{Type: "moddelim", Value: "|"},
+			{Type: "critspec", Value: "c"},
+			{Type: "moddelim", Value: "|"},
+			{Type: "critspec", Value: "crit ×2 1d8+4"},
+			{Type: "moddelim", Value: "|"},
+			{Type: "dc", Value: "15"},
+			{Type: "exceeded", Value: "10"},
+		}},
+		{Result: 22, Details: []StructuredDescription{
+			{Type: "critlabel", Value: "Confirm:"},
+			{Type: "result", Value: "22"},
+			{Type: "separator", Value: "="},
+			{Type: "diespec", Value: "1d20"},
+			{Type: "roll", Value: "17"},
+			{Type: "operator", Value: "+"},
+			{Type: "constant", Value: "5"},
+		}},
+		{Result: 17, Details: []StructuredDescription{
+			{Type: "critdamage", Value: "×2"},
+			{Type: "result", Value: "17"},
+			{Type: "separator", Value: "="},
+			{Type: "begingroup", Value: "("},
+			{Type: "diespec", Value: "1d8"},
+			{Type: "roll", Value: "2"},
+			{Type: "operator", Value: "+"},
+			{Type: "constant", Value: "4"},
+			{Type: "endgroup", Value: ")"},
+			{Type: "operator", Value: "+"},
+			{Type: "begingroup", Value: "("},
+			{Type: "diespec", Value: "1d8"},
+			{Type: "roll", Value: "7"},
+			{Type: "operator", Value: "+"},
+			{Type: "constant", Value: "4"},
+			{Type: "endgroup", Value: ")"},
+		}},
+	}) {
+		t.Errorf("confirmed critical results %v", results)
+	}
+
+	// Extra damage such as sneak attack isn't multiplied.
+	_, results, err = d.DoRoll("d20|crit x3 1d6+2 + 1d6 sneak|maximized")
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+	if len(results) != 3 || results[2].Result != 30 {
+		t.Errorf("maximized critical results %v", results)
+	}
+
+	for _, test := range []struct {
+		expr, expected string
+	}{
+		{"2d6+8", "(2d6+8)+(2d6+8)+(2d6+8)"},
+		{"2d6+8 + 2d6 sneak - 1 penalty", "(2d6+8- 1 penalty)+(2d6+8- 1 penalty)+(2d6+8- 1 penalty)+ 2d6 sneak"},
+		{"1d6 Precision+1d8", "(1d8)+(1d8)+(1d8)+1d6 Precision"},
+		{"2d6 sneak", "2d6 sneak"},
+		{"(1d8+2)*-1", "((1d8+2)*-1)+((1d8+2)*-1)+((1d8+2)*-1)"},
+	} {
+		if result := multipliedDamage(test.expr, 3, DefaultNonMultiplyingLabels); result != test.expected {
+			t.Errorf("multipliedDamage(%q) = %q, expected %q", test.expr, result, test.expected)
+		}
+	}
+
+	for _, spec := range []string{"d20|crit x1 1d6", "d20|crit x2 1d6 (", "d20|crit 1d6"} {
+		if _, _, err := d.DoRoll(spec); err == nil {
+			t.Errorf("%q: error expected, but none was raised", spec)
+		}
+	}
+}
+
+func TestDicePresetReferences(t *testing.T) {
+	presets := []DieRollPreset{
+		{Name: "Longsword to-hit", DieRollSpec: "Longsword=d20+12|c19"},
+		{Name: "a|Longsword", DieRollSpec: "1d8+4 slashing"},
+		{Name: "Power", DieRollSpec: "@Longsword + 3 power"},
+		{Name: "Loop", DieRollSpec: "@Loop2+1"},
+		{Name: "Loop2", DieRollSpec: "d4+@Loop"},
+		{Name: "Init", DieRollSpec: "d20+2", Global: true},
+		{Name: "Init", DieRollSpec: "d20+5"},
+		{Name: "Twice", DieRollSpec: "d6", Global: true},
+		{Name: "Twice", DieRollSpec: "d8", Global: true},
+	}
+	for i := 1; i <= MaxPresetNesting; i++ {
+		presets = append(presets, DieRollPreset{Name: fmt.Sprintf("Deep%d", i), DieRollSpec: fmt.Sprintf("@Deep%d+1", i+1)})
+	}
+	presets = append(presets, DieRollPreset{Name: fmt.Sprintf("Deep%d", MaxPresetNesting+1), DieRollSpec: "1"})
+
+	d, err := NewDieRoller(WithSeed(12345), WithPresets(presets...))
+	if err != nil {
+		t.Fatalf("Error creating new DieRoller: %v", err)
+	}
+
+	for _, test := range []struct {
+		spec, expected string
+		err            bool
+	}{
+		{spec: "d20+2", expected: "d20+2"},
+		{spec: "@Longsword to-hit + 2 flanking", expected: "Longsword=(d20+12) + 2 flanking|c19"},
+		{spec: "Hit=@Longsword to-hit+2|dc 20", expected: "Hit=(d20+12)+2|c19|dc 20"},
+		{spec: "@Longsword*2", expected: "(1d8+4 slashing)*2"},
+		{spec: "@{Power}*2", expected: "((1d8+4 slashing) + 3 power)*2"},
+		{spec: "@Init", expected: "(d20+5)"},
+		{spec: "@Twice", err: true},
+		{spec: "@Loop", err: true},
+		{spec: "@Deep2", expected: "((((((((((1)+1)+1)+1)+1)+1)+1)+1)+1)+1)"},
+		{spec: "@Deep1", err: true},
+		{spec: "@Nope+2", err: true},
+		{spec: "@Longswords", err: true},
+		{spec: "@{Longsword", err: true},
+		{spec: "d20+@", err: true},
+	} {
+		expanded, err := d.ExpandPresets(test.spec)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: error expected, but none was raised", test.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: error %v", test.spec, err)
+		} else if expanded != test.expected {
+			t.Errorf("%q expanded to %q, expected %q", test.spec, expanded, test.expected)
+		}
+	}
+
+	label, results, err := d.DoRoll("@Power")
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+	if label != "" || !compareResults(results, []StructuredResult{
+		{Result: 11, Details: []StructuredDescription{
+			{Type: "result", Value: "11"},
+			{Type: "separator", Value: "="},
+			{Type: "begingroup", Value: "("},
+			{Type: "begingroup", Value: "("},
+			{Type: "diespec", Value: "1d8"},
+			{Type: "roll", Value: "4"},
+			{Type: "operator", Value: "+"},
+			{Type: "constant", Value: "4"},
+			{Type: "label", Value: "slashing"},
+			{Type: "endgroup", Value: ")"},
+			{Type: "operator", Value: "+"},
+			{Type: "constant", Value: "3"},
+			{Type: "label", Value: "power"},
+			{Type: "endgroup", Value: ")"},
+			{Type: "preset", Value: "Power=(1d8+4 slashing) + 3 power"},
+			{Type: "preset", Value: "Longsword=1d8+4 slashing"},
+		}},
+	}) {
+		t.Errorf("preset roll %q %v", label, results)
+	}
+	if text, _ := results[0].Details.Text(); !strings.HasSuffix(text, "[@Power=(1d8+4 slashing) + 3 power] [@Longsword=1d8+4 slashing]") {
+		t.Errorf("preset roll text %q", text)
+	}
+
+	if _, _, err := d.DoRoll("@Loop"); err == nil {
+		t.Errorf("recursive preset roll: error expected, but none was raised")
+	}
+	if _, err := Parse("@Power"); err == nil {
+		t.Errorf("Parse of preset reference: error expected, but none was raised")
+	}
+}
+
+func TestDiceDegreesOfSuccess(t *testing.T) {
+	for _, test := range []struct {
+		result, dc int
+		max, one   bool
+		expected   DegreeOfSuccess
+	}{
+		{28, 18, false, false, CriticalSuccess},
+		{27, 18, false, false, Success},
+		{18, 18, false, false, Success},
+		{17, 18, false, false, Failure},
+		{9, 18, false, false, Failure},
+		{8, 18, false, false, CriticalFailure},
+		{27, 18, true, false, CriticalSuccess},
+		{28, 18, true, false, CriticalSuccess},
+		{17, 18, true, false, Success},
+		{8, 18, true, false, Failure},
+		{18, 18, false, true, Failure},
+		{28, 18, false, true, Success},
+		{9, 18, false, true, CriticalFailure},
+		{8, 18, false, true, CriticalFailure},
+	} {
+		if g := ClassifyDegreeOfSuccess(test.result, test.dc, test.max, test.one); g != test.expected {
+			t.Errorf("ClassifyDegreeOfSuccess(%d, %d, %v, %v) = %v, expected %v", test.result, test.dc, test.max, test.one, g, test.expected)
+		}
+	}
+
+	d, err := NewDieRoller(WithSeed(12345))
+	if err != nil {
+		t.Fatalf("Error creating new DieRoller: %v", err)
+	}
+
+	for i := 0; i < 50; i++ {
+		_, results, err := d.DoRoll("d20+9|dc 18|degrees")
+		if err != nil {
+			t.Fatalf("roll #%d error %v", i, err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("roll #%d results %v", i, results)
+		}
+		natural, err := strconv.Atoi(results[0].Details[3].Value)
+		if err != nil {
+			t.Fatalf("roll #%d details %v: %v", i, results[0].Details, err)
+		}
+		expected := ClassifyDegreeOfSuccess(results[0].Result, 18, natural == 20, natural == 1)
+		if results[0].Degree != expected {
+			t.Errorf("roll #%d degree %v, expected %v", i, results[0].Degree, expected)
+		}
+		last := results[0].Details[len(results[0].Details)-1]
+		if last.Type != "degree" || last.Value != expected.String() {
+			t.Errorf("roll #%d details %v", i, results[0].Details)
+		}
+	}
+
+	_, results, err := d.DoRoll("d20+1|dc 11|degrees|maximized")
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+	if !compareResults(results, []StructuredResult{
+		{Result: 21, Degree: CriticalSuccess, Details: []StructuredDescription{
+			{Type: "result", Value: "21"},
+			{Type: "separator", Value: "="},
+			{Type: "diespec", Value: "1d20"},
+			{Type: "maxroll", Value: "20"},
+			{Type: "operator", Value: "+"},
+			{Type: "constant", Value: "1"},
+			{Type: "moddelim", Value: "|"},
+			{Type: "dc", Value: "11"},
+			{Type: "exceeded", Value: "10"},
+			{Type: "moddelim", Value: "|"},
+			{Type: "degrees", Value: "degrees"},
+			{Type: "degree", Value: "critical success"},
+			{Type: "moddelim", Value: "|"},
+			{Type: "fullmax", Value: "maximized"},
+		}},
+	}) {
+		t.Errorf("maximized results %v", results)
+	}
+
+	if _, _, err := d.DoRoll("d20+5|degrees"); err == nil {
+		t.Errorf("degrees without a DC: error expected, but none was raised")
+	}
+}
+
+func TestDiceBonusStacking(t *testing.T) {
+	for i, test := range []struct {
+		spec     string
+		max      int
+		expected []StructuredDescription
+	}{
+		{"d20 +2 morale +1 morale +3 enhancement +1 dodge +1 dodge -1 morale", 26, []StructuredDescription{
+			{Type: "result", Value: "26"},
+			{Type: "separator", Value: "="},
+			{Type: "diespec", Value: "1d20"},
+			{Type: "maxroll", Value: "20"},
+			{Type: "operator", Value: "+"},
+			{Type: "constant", Value: "2"},
+			{Type: "label", Value: "morale"},
+			{Type: "operator", Value: "+"},
+			{Type: "constant", Value: "1"},
+			{Type: "label", Value: "morale"},
+			{Type: "suppressed", Value: "morale bonuses don't stack; using +2 morale"},
+			{Type: "operator", Value: "+"},
+			{Type: "constant", Value: "3"},
+			{Type: "label", Value: "enhancement"},
+			{Type: "operator", Value: "+"},
+			{Type: "constant", Value: "1"},
+			{Type: "label", Value: "dodge"},
+			{Type: "operator", Value: "+"},
+			{Type: "constant", Value: "1"},
+			{Type: "label", Value: "dodge"},
+			{Type: "operator", Value: "-"},
+			{Type: "constant", Value: "1"},
+			{Type: "label", Value: "morale"},
+		}},
+		{"1 Luck bonus + d6 + 3 luck + 2 bonus + 2 bonus", 13, nil},
+		{"d6 + 1 sacred + 1 sacred", 7, nil},
+		{"d6 + (2 luck + 3 luck)", 11, nil},
+		{"d6 + 2 luck × 2 + 3 luck", 13, nil},
+		{"d6 - 2 size - 1 size", 3, nil},
+	} {
+		d, err := New(ByDescription(test.spec))
+		if err != nil {
+			t.Fatalf("test #%d (%s): error %v", i, test.spec, err)
+		}
+		result, err := d.MaxRoll()
+		if err != nil {
+			t.Fatalf("test #%d (%s): error %v", i, test.spec, err)
+		}
+		if result != test.max {
+			t.Errorf("test #%d (%s): result %d, expected %d", i, test.spec, result, test.max)
+		}
+		if test.expected != nil {
+			desc, err := d.StructuredDescribeRoll()
+			if err != nil {
+				t.Fatalf("test #%d (%s): error %v", i, test.spec, err)
+			}
+			if !slices.Equal(desc, test.expected) {
+				t.Errorf("test #%d (%s): description %v, expected %v", i, test.spec, desc, test.expected)
+			}
+		}
+	}
+}
+
+func TestDiceBonusStackingVariables(t *testing.T) {
+	vars := Variables{"STR": 4}
+	d, err := New(ByDescription("d20+$STR morale+2 morale"), WithVariables(vars))
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+
+	for i, test := range []struct {
+		str      int
+		max      int
+		expected []StructuredDescription
+	}{
+		{4, 24, []StructuredDescription{
+			{Type: "result", Value: "24"},
+			{Type: "separator", Value: "="},
+			{Type: "diespec", Value: "1d20"},
+			{Type: "maxroll", Value: "20"},
+			{Type: "operator", Value: "+"},
+			{Type: "constant", Value: "4"},
+			{Type: "variable", Value: "STR"},
+			{Type: "label", Value: "morale"},
+			{Type: "operator", Value: "+"},
+			{Type: "constant", Value: "2"},
+			{Type: "label", Value: "morale"},
+			{Type: "suppressed", Value: "morale bonuses don't stack; using +4 morale"},
+		}},
+		// the variable's new value is used to decide which bonus counts
+		{1, 22, []StructuredDescription{
+			{Type: "result", Value: "22"},
+			{Type: "separator", Value: "="},
+			{Type: "diespec", Value: "1d20"},
+			{Type: "maxroll", Value: "20"},
+			{Type: "operator", Value: "+"},
+			{Type: "constant", Value: "1"},
+			{Type: "variable", Value: "STR"},
+			{Type: "label", Value: "morale"},
+			{Type: "suppressed", Value: "morale bonuses don't stack; using +2 morale"},
+			{Type: "operator", Value: "+"},
+			{Type: "constant", Value: "2"},
+			{Type: "label", Value: "morale"},
+		}},
+	} {
+		vars["STR"] = test.str
+		result, err := d.MaxRoll()
+		if err != nil {
+			t.Fatalf("test #%d: error %v", i, err)
+		}
+		if result != test.max {
+			t.Errorf("test #%d: result %d, expected %d", i, result, test.max)
+		}
+		desc, err := d.StructuredDescribeRoll()
+		if err != nil {
+			t.Fatalf("test #%d: error %v", i, err)
+		}
+		if !slices.Equal(desc, test.expected) {
+			t.Errorf("test #%d: description %v, expected %v", i, desc, test.expected)
+		}
+	}
+}
+
 // @[00]@| Go-GMA 5.33.0
 // @[01]@|
 // @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
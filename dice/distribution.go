@@ -0,0 +1,1079 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/schwarmco/go-cartesian-product"
+)
+
+// MonteCarloSamples is the number of die rolls simulated to estimate the
+// probability distribution of a die-roll expression when it can't be
+// calculated exactly.
+const MonteCarloSamples = 100000
+
+// ConfidenceLevel is the confidence level of the intervals reported for
+// estimated probability distributions.
+const ConfidenceLevel = 0.95
+
+// confidenceZ is the standard normal quantile corresponding to ConfidenceLevel.
+const confidenceZ = 1.959963984540054
+
+// maxDistributionSize limits the number of distinct values we'll track in
+// a distribution before giving up on calculating it exactly.
+const maxDistributionSize = 1 << 16
+
+// explosionCutoff is the probability below which we stop following the
+// possible chains of exploding dice when calculating distributions.
+const explosionCutoff = 1e-12
+
+// errNoExactDistribution indicates that a distribution can't practically
+// be calculated exactly, so we need to estimate it instead.
+var errNoExactDistribution = errors.New("no exact distribution available")
+
+// A RollDistribution describes the probability of each possible result
+// of a die-roll expression, as calculated by the Distribution function.
+type RollDistribution struct {
+	// If the die-roll expression had permutations, this is the
+	// expression rolled for this particular permutation.
+	Expression string `json:",omitempty"`
+
+	// The probability of each possible result. Results which cannot
+	// occur are omitted.
+	Probability map[int]float64
+
+	// True if the probabilities were calculated exactly. Otherwise they
+	// were estimated by simulating Samples die rolls.
+	Exact   bool
+	Samples int `json:",omitempty"`
+
+	// If AutoSF is true, a natural 1 on the die automatically fails and a
+	// natural maximum automatically succeeds (as with the "sf" and "c"
+	// options). The chances of each of these happening on a single roll are
+	// given by AutoFailChance and AutoSuccessChance.
+	AutoSF            bool    `json:",omitempty"`
+	AutoSuccessChance float64 `json:",omitempty"`
+	AutoFailChance    float64 `json:",omitempty"`
+
+	// For rolls with the "c" option, ThreatChance is the probability that
+	// a single roll is a critical threat, and Confirmation is the distribution
+	// of the roll made to confirm it.
+	ThreatChance float64           `json:",omitempty"`
+	Confirmation *RollDistribution `json:",omitempty"`
+
+	// the probability of each result when the die did not come up as a natural
+	// 1 or maximum value (only used if AutoSF is true)
+	ordinary map[int]float64
+}
+
+// Results returns the possible results of the die roll in ascending order.
+func (r *RollDistribution) Results() []int {
+	return sortedResults(r.Probability)
+}
+
+func sortedResults(p map[int]float64) []int {
+	results := make([]int, 0, len(p))
+	for v := range p {
+		results = append(results, v)
+	}
+	sort.Ints(results)
+	return results
+}
+
+// Min returns the smallest possible result.
+func (r *RollDistribution) Min() int {
+	results := r.Results()
+	if len(results) == 0 {
+		return 0
+	}
+	return results[0]
+}
+
+// Max returns the largest possible result.
+func (r *RollDistribution) Max() int {
+	results := r.Results()
+	if len(results) == 0 {
+		return 0
+	}
+	return results[len(results)-1]
+}
+
+// Mean returns the expected value of the die roll.
+func (r *RollDistribution) Mean() (mean float64) {
+	for _, v := range r.Results() {
+		mean += float64(v) * r.Probability[v]
+	}
+	return
+}
+
+// Variance returns the variance of the die roll's results.
+func (r *RollDistribution) Variance() (variance float64) {
+	mean := r.Mean()
+	for _, v := range r.Results() {
+		variance += (float64(v) - mean) * (float64(v) - mean) * r.Probability[v]
+	}
+	return
+}
+
+// StdDev returns the standard deviation of the die roll's results.
+func (r *RollDistribution) StdDev() float64 {
+	return math.Sqrt(r.Variance())
+}
+
+// Percentile returns the smallest result which is at least as large as pct
+// percent of the possible outcomes. For example, Percentile(50) is the median
+// result.
+func (r *RollDistribution) Percentile(pct float64) int {
+	var cumulative float64
+	results := r.Results()
+	for _, v := range results {
+		cumulative += r.Probability[v]
+		if cumulative >= pct/100.0-1e-12 {
+			return v
+		}
+	}
+	if len(results) == 0 {
+		return 0
+	}
+	return results[len(results)-1]
+}
+
+// ChanceAtLeast returns the probability that the result will be at least dc.
+func (r *RollDistribution) ChanceAtLeast(dc int) float64 {
+	return chanceAtLeast(r.Probability, dc)
+}
+
+func chanceAtLeast(p map[int]float64, dc int) (chance float64) {
+	for _, v := range sortedResults(p) {
+		if v >= dc {
+			chance += p[v]
+		}
+	}
+	return min(chance, 1.0)
+}
+
+// ChanceToSucceed returns the probability that the roll succeeds against
+// the given dc. This is the same as ChanceAtLeast(dc) unless AutoSF is true
+// and the distribution describes a single roll (as opposed to the total of
+// repeated rolls), in which case natural 1s always fail and natural maximum
+// rolls always succeed.
+func (r *RollDistribution) ChanceToSucceed(dc int) float64 {
+	if !r.AutoSF || r.ordinary == nil {
+		return r.ChanceAtLeast(dc)
+	}
+	return min(r.AutoSuccessChance+chanceAtLeast(r.ordinary, dc), 1.0)
+}
+
+// ConfirmedCriticalChance returns the probability that a single roll is a critical
+// threat which is then confirmed by a roll which succeeds against the given dc.
+// This is 0 unless the roll was made with the "c" option.
+func (r *RollDistribution) ConfirmedCriticalChance(dc int) float64 {
+	if r.Confirmation == nil {
+		return 0
+	}
+	return r.ThreatChance * r.Confirmation.ChanceToSucceed(dc)
+}
+
+// MeanInterval returns the lower and upper bounds of the confidence interval
+// (at ConfidenceLevel) for the mean. If the distribution was calculated exactly,
+// both are simply the mean.
+func (r *RollDistribution) MeanInterval() (float64, float64) {
+	mean := r.Mean()
+	if r.Exact || r.Samples < 2 {
+		return mean, mean
+	}
+	halfWidth := confidenceZ * math.Sqrt(r.Variance()*float64(r.Samples)/float64(r.Samples-1)/float64(r.Samples))
+	return mean - halfWidth, mean + halfWidth
+}
+
+// ChanceAtLeastInterval returns the lower and upper bounds of the confidence
+// interval (at ConfidenceLevel) for ChanceAtLeast(dc). If the distribution was
+// calculated exactly, both are simply the value of ChanceAtLeast(dc).
+func (r *RollDistribution) ChanceAtLeastInterval(dc int) (float64, float64) {
+	return r.interval(r.ChanceAtLeast(dc))
+}
+
+// ChanceToSucceedInterval returns the lower and upper bounds of the confidence
+// interval (at ConfidenceLevel) for ChanceToSucceed(dc), in the same way as
+// ChanceAtLeastInterval.
+func (r *RollDistribution) ChanceToSucceedInterval(dc int) (float64, float64) {
+	return r.interval(r.ChanceToSucceed(dc))
+}
+
+// interval returns the confidence interval for an estimated proportion p.
+func (r *RollDistribution) interval(p float64) (float64, float64) {
+	if r.Exact || r.Samples < 1 {
+		return p, p
+	}
+	// Wilson score interval
+	n := float64(r.Samples)
+	z2 := confidenceZ * confidenceZ
+	center := (p + z2/(2*n)) / (1 + z2/n)
+	halfWidth := confidenceZ / (1 + z2/n) * math.Sqrt(p*(1-p)/n+z2/(4*n*n))
+	return max(0, center-halfWidth), min(1, center+halfWidth)
+}
+
+// Distribution calculates the probability of each possible result of
+// rolling the dice as described by the specification string, without
+// requiring a separate step to create a DieRoller first.
+//
+// Calling Distribution(spec) is equivalent to the sequence
+//
+//	dr = NewDieRoller()
+//	dr.Distribution(spec)
+func Distribution(spec string) (*RollDistribution, error) {
+	d, err := NewDieRoller()
+	if err != nil {
+		return nil, err
+	}
+	return d.Distribution(spec)
+}
+
+// Distribution calculates the probability of each possible result of
+// rolling the dice as described by the specification string (which has
+// the same form as described for DoRoll). If spec is empty, the previously-used
+// specification is assumed.
+//
+// The distribution is calculated exactly where possible, including the
+// effects of constants, grouping, “best of” and “worst of” rolls, min and max
+// limits, per-die modifiers, and the maximized option. If the expression calls
+// for multiple rolls (via the repeat option), the distribution is of the sum of
+// those rolls. Critical confirmation rolls are not included in that sum, but
+// are described by the ThreatChance and Confirmation fields of the returned value.
+// Percentile rolls such as "40%" have a result of 1 or 0 as described for DoRoll.
+//
+// When an exact calculation is not feasible (e.g., for the until and total options),
+// the distribution is instead estimated by simulating MonteCarloSamples die rolls
+// using the DieRoller's random number generator. In that case, the Exact field of the
+// returned value will be false, and the confidence intervals reported by its MeanInterval
+// and ChanceAtLeastInterval methods will indicate the uncertainty of the estimate.
+//
+// Die-roll specifications with permutations (e.g., "d20+{17/12/7}") are not supported,
+// since they produce multiple independent results. Use Distributions for those.
+func (d *DieRoller) Distribution(spec string) (*RollDistribution, error) {
+	if spec != "" {
+		if err := d.setNewSpecification(spec); err != nil {
+			return nil, err
+		}
+	}
+	if d.Template != "" {
+		return nil, fmt.Errorf("cannot calculate a single distribution for a die-roll expression with permutations")
+	}
+	return d.distributionOf(d.d)
+}
+
+// Distributions is like Distribution, but also accepts die-roll specifications
+// with permutations, returning a separate distribution for each permutation in
+// the order DoRoll would roll them, with their Expression fields set to identify
+// which is which. Otherwise, the returned slice has a single element.
+//
+// Like DoRoll, this also returns the user-specified die-roll label (if any).
+func (d *DieRoller) Distributions(spec string) (string, []*RollDistribution, error) {
+	if spec != "" {
+		if err := d.setNewSpecification(spec); err != nil {
+			return "", nil, err
+		}
+	}
+	if d.Template == "" {
+		dist, err := d.distributionOf(d.d)
+		if err != nil {
+			return "", nil, err
+		}
+		return d.LabelText, []*RollDistribution{dist}, nil
+	}
+
+	var dists []*RollDistribution
+	for iteration := range cartesian.Iter(d.Permutations...) {
+		expression := substituteTemplateValues(d.Template, iteration)
+		permutation, err := New(ByDescription(expression), withSharedGenerator(d.generator), WithVariables(d.variables))
+		if err != nil {
+			return "", nil, err
+		}
+		dist, err := d.distributionOf(permutation)
+		if err != nil {
+			return "", nil, err
+		}
+		dist.Expression = strings.TrimSpace(strings.Replace(expression, "÷", "//", -1))
+		dists = append(dists, dist)
+	}
+	return d.LabelText, dists, nil
+}
+
+// distributionOf calculates the distribution of results from rolling the
+// given Dice according to the DieRoller's current options.
+func (d *DieRoller) distributionOf(dice *Dice) (*RollDistribution, error) {
+	if dice == nil {
+		return nil, fmt.Errorf("no defined Dice object to consume")
+	}
+	if d.RepeatUntil > 0 || d.RepeatUntilTotal > 0 {
+		return d.simulateDistribution(dice, MonteCarloSamples)
+	}
+
+	dist, err := d.singleDistribution(dice)
+	if errors.Is(err, errNoExactDistribution) {
+		return d.simulateDistribution(dice, MonteCarloSamples)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if d.RepeatFor > 1 {
+		dist.ordinary = nil // ChanceToSucceed is for the total, not each roll
+	}
+	single := dist.Probability
+	for i := 1; i < min(d.RepeatFor, maxRepeatedRolls); i++ {
+		next := make(map[int]float64)
+		for x, px := range dist.Probability {
+			for y, py := range single {
+				next[x+y] += px * py
+			}
+		}
+		dist.Probability = next
+	}
+	return dist, nil
+}
+
+// singleDistribution calculates the distribution of results from a single
+// roll of the given Dice.
+func (d *DieRoller) singleDistribution(dice *Dice) (*RollDistribution, error) {
+	if d.PctChance >= 0 {
+		var p float64
+		if d.DoMax {
+			if d.PctChance >= 100 {
+				p = 1
+			}
+		} else {
+			p = float64(min(max(d.PctChance, 0), 100)) / 100.0
+		}
+		dist := &RollDistribution{Probability: make(map[int]float64), Exact: true}
+		if p > 0 {
+			dist.Probability[1] = p
+		}
+		if p < 1 {
+			dist.Probability[0] = 1 - p
+		}
+		return dist, nil
+	}
+
+	if d.DoMax {
+		dist := &RollDistribution{Exact: true}
+		v, err := dice.MaxRoll()
+		if err != nil {
+			return nil, err
+		}
+		dist.Probability = map[int]float64{v: 1}
+		if d.sfOpt != "" || d.Confirm {
+			dist.AutoSF = true
+			dist.AutoSuccessChance = 1
+			dist.ordinary = map[int]float64{}
+		}
+		if d.Confirm {
+			v, err := dice.MaxRollToConfirm(d.critBonus)
+			if err != nil {
+				return nil, err
+			}
+			dist.ThreatChance = 1
+			dist.Confirmation = &RollDistribution{
+				Probability:       map[int]float64{v: 1},
+				Exact:             true,
+				AutoSF:            true,
+				AutoSuccessChance: 1,
+				ordinary:          map[int]float64{},
+			}
+		}
+		return dist, nil
+	}
+
+	if d.sfOpt == "" && !d.Confirm {
+		p, err := dice.distribution(0)
+		if err != nil {
+			return nil, err
+		}
+		return &RollDistribution{Probability: p, Exact: true}, nil
+	}
+
+	dist, err := d.naturalOutcomes(dice, 0)
+	if err != nil {
+		return nil, err
+	}
+	if d.Confirm {
+		if dist.Confirmation, err = d.naturalOutcomes(dice, d.critBonus); err != nil {
+			return nil, err
+		}
+		dist.Confirmation.ThreatChance = 0
+	}
+	return dist, nil
+}
+
+// naturalOutcomes calculates the distribution of results from a single roll
+// of the given Dice (plus a bonus), taking into account the effects of the
+// natural value rolled on its lone die: automatic success or failure and
+// critical threats.
+func (d *DieRoller) naturalOutcomes(dice *Dice, bonus int) (*RollDistribution, error) {
+	die := dice._onlydie
+	if die == nil || !die.isSingleDie() {
+		if d.Confirm {
+			return nil, fmt.Errorf("you can't confirm a critical on this roll because it doesn't involve only a single die")
+		}
+		return nil, fmt.Errorf("you can't indicate auto-success/fail (|sf option) because it involves multiple dice")
+	}
+	naturals, err := die.singleNaturalDistribution()
+	if err != nil {
+		return nil, err
+	}
+
+	_, distinct := die.naturalRank(1)
+	threat := d.critThreat
+	if threat <= 0 {
+		threat = distinct
+	}
+	dist := &RollDistribution{
+		Probability: make(map[int]float64),
+		Exact:       true,
+		AutoSF:      true,
+		ordinary:    make(map[int]float64),
+	}
+	for natural, pn := range naturals {
+		rank, _ := die.naturalRank(int(natural))
+		die.givenNatural = int(natural)
+		given, err := dice.distribution(bonus)
+		die.givenNatural = 0
+		if err != nil {
+			return nil, err
+		}
+		for v, p := range given {
+			dist.Probability[v] += pn * p
+			if rank != 1 && rank != distinct {
+				dist.ordinary[v] += pn * p
+			}
+		}
+		if rank == 1 {
+			dist.AutoFailChance += pn
+		} else if rank == distinct {
+			dist.AutoSuccessChance += pn
+		}
+		if d.Confirm && rank >= threat {
+			dist.ThreatChance += pn
+		}
+	}
+	return dist, nil
+}
+
+// simulateDistribution estimates the distribution of results from rolling
+// the given Dice by actually rolling them the given number of times.
+func (d *DieRoller) simulateDistribution(dice *Dice, samples int) (*RollDistribution, error) {
+	// We roll the dice using a copy of the DieRoller (which still shares
+	// the same random number generator) with the permutation (if any)
+	// already worked out.
+	sim := *d
+	sim.Template = ""
+	sim.Permutations = nil
+	sim.d = dice
+
+	autoSF := d.sfOpt != "" || d.Confirm
+	dist := &RollDistribution{Probability: make(map[int]float64), AutoSF: autoSF, Samples: samples}
+	tally := &RollDistribution{Probability: make(map[int]float64), AutoSF: autoSF, ordinary: make(map[int]float64)}
+	var confirm *RollDistribution
+	if d.Confirm {
+		confirm = &RollDistribution{Probability: make(map[int]float64), AutoSF: true, ordinary: make(map[int]float64)}
+	}
+
+	// tallyResult counts a single result (which will be scaled later to
+	// the proportion of rolls made)
+	tallyResult := func(t *RollDistribution, r StructuredResult, details []StructuredDescription) {
+		t.Samples++
+		t.Probability[r.Result]++
+		if len(details) > 0 && details[0].Type == "success" {
+			t.AutoSuccessChance++
+		} else if len(details) > 0 && details[0].Type == "fail" {
+			t.AutoFailChance++
+		} else if t.ordinary != nil {
+			t.ordinary[r.Result]++
+		}
+	}
+
+	for i := 0; i < samples; i++ {
+		_, results, err := sim.DoRoll("")
+		if err != nil {
+			return nil, err
+		}
+		total := 0
+		for _, r := range results {
+			if len(r.Details) > 0 && r.Details[0].Type == "critlabel" {
+				tally.ThreatChance++
+				tallyResult(confirm, r, r.Details[1:])
+				continue
+			}
+			if len(r.Details) > 0 && r.Details[0].Type == "critdamage" {
+				continue
+			}
+			total += r.Result
+			if autoSF {
+				tallyResult(tally, r, r.Details)
+			}
+		}
+		dist.Probability[total] += 1.0 / float64(samples)
+	}
+
+	if autoSF && tally.Samples > 0 {
+		n := float64(tally.Samples)
+		dist.AutoSuccessChance = tally.AutoSuccessChance / n
+		dist.AutoFailChance = tally.AutoFailChance / n
+		dist.ThreatChance = tally.ThreatChance / n
+		if tally.Samples == samples {
+			// each sample was a single roll
+			dist.ordinary = make(map[int]float64)
+			for v, c := range tally.ordinary {
+				dist.ordinary[v] = c / n
+			}
+		}
+	}
+	if confirm != nil && confirm.Samples > 0 {
+		n := float64(confirm.Samples)
+		for v := range confirm.Probability {
+			confirm.Probability[v] /= n
+		}
+		for v := range confirm.ordinary {
+			confirm.ordinary[v] /= n
+		}
+		confirm.AutoSuccessChance /= n
+		confirm.AutoFailChance /= n
+		dist.Confirmation = confirm
+	}
+	return dist, nil
+}
+
+// distribution calculates the exact probability distribution of the results of
+// rolling the Dice, with a bonus added to each result.
+func (d *Dice) distribution(bonus int) (map[int]float64, error) {
+	stack := &distributionStack{}
+	if err := d.resolveVariables(); err != nil {
+		return nil, err
+	}
+	for _, die := range d.multiDice {
+		if err := die.computeDistribution(stack); err != nil {
+			return nil, err
+		}
+	}
+	values, err := stack.evaluate()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[int]float64)
+	for v, p := range values {
+		v += bonus
+		if d.MaxValue > 0 && v > d.MaxValue {
+			v = d.MaxValue
+		}
+		if d.MinValue > 0 && v < d.MinValue {
+			v = d.MinValue
+		}
+		result[v] += p
+	}
+	return result, nil
+}
+
+// pmf is a probability mass function, mapping each possible value to its probability.
+type pmf map[float64]float64
+
+// combine returns the distribution of f(x, y) for independent values x and y
+// distributed according to p and q respectively.
+func combine(p, q pmf, f func(x, y float64) (float64, error)) (pmf, error) {
+	r := make(pmf)
+	for x, px := range p {
+		for y, py := range q {
+			v, err := f(x, y)
+			if err != nil {
+				return nil, err
+			}
+			r[v] += px * py
+		}
+	}
+	if len(r) > maxDistributionSize {
+		return nil, errNoExactDistribution
+	}
+	return r, nil
+}
+
+func addValues(x, y float64) (float64, error) {
+	return x + y, nil
+}
+
+// distributionStack is the analogue of evalStack, used to calculate the probability
+// distribution of an expression rather than its value.
+type distributionStack struct {
+	stack   []pmf
+	opStack []rune
+}
+
+func (s *distributionStack) isOpEmpty() bool {
+	return len(s.opStack) == 0
+}
+
+func (s *distributionStack) push(v pmf) {
+	s.stack = append(s.stack, v)
+}
+
+func (s *distributionStack) pop() (pmf, error) {
+	stackLen := len(s.stack)
+	if stackLen == 0 {
+		return nil, fmt.Errorf("stack underflow")
+	}
+	poppedValue := s.stack[stackLen-1]
+	s.stack = s.stack[:stackLen-1]
+	return poppedValue, nil
+}
+
+func (s *distributionStack) pushOp(v rune) {
+	s.opStack = append(s.opStack, v)
+}
+
+func (s *distributionStack) popOp() (rune, error) {
+	stackLen := len(s.opStack)
+	if stackLen == 0 {
+		return 0, fmt.Errorf("operator stack underflow")
+	}
+	poppedValue := s.opStack[stackLen-1]
+	s.opStack = s.opStack[:stackLen-1]
+	return poppedValue, nil
+}
+
+func (s *distributionStack) discardOp() {
+	if !s.isOpEmpty() {
+		_, _ = s.popOp()
+	}
+}
+
+func (s *distributionStack) nextOp() rune {
+	stackLen := len(s.opStack)
+	if stackLen == 0 {
+		return 0
+	}
+	return s.opStack[stackLen-1]
+}
+
+func (s *distributionStack) applyOp() error {
+	op, err := s.popOp()
+	if err != nil {
+		return err
+	}
+	if op == '(' || op == ')' {
+		return nil
+	}
+
+	if op == '‾' { // unary - (negation)
+		x, err := s.pop()
+		if err != nil {
+			return err
+		}
+		negated := make(pmf)
+		for v, p := range x {
+			negated[-v] += p
+		}
+		s.push(negated)
+		return nil
+	}
+
+	y, err := s.pop()
+	if err != nil {
+		return err
+	}
+	x, err := s.pop()
+	if err != nil {
+		return err
+	}
+	v, err := combine(x, y, func(a, b float64) (float64, error) {
+		return binaryOp(op, a, b)
+	})
+	if err != nil {
+		return err
+	}
+	s.push(v)
+	return nil
+}
+
+// complete the calculation by applying all remaining operators
+func (s *distributionStack) evaluate() (map[int]float64, error) {
+	for !s.isOpEmpty() {
+		if s.nextOp() == '(' {
+			return nil, fmt.Errorf("'(' without matching ')' in die-roll expression")
+		}
+		if err := s.applyOp(); err != nil {
+			return nil, err
+		}
+	}
+	value, err := s.pop()
+	if err != nil {
+		return nil, err
+	}
+	if len(s.stack) > 0 {
+		return nil, fmt.Errorf("expression stack not empty at end of evaluation")
+	}
+	result := make(map[int]float64)
+	for v, p := range value {
+		result[int(v)] += p
+	}
+	return result, nil
+}
+
+func (d *dieSpec) computeDistribution(s *distributionStack) error {
+	p, err := d.distribution()
+	if err != nil {
+		return err
+	}
+	s.push(p)
+	return nil
+}
+
+// distribution calculates the probability distribution of the value of this component.
+func (d *dieSpec) distribution() (pmf, error) {
+	var total pmf
+	var err error
+
+	if d.Sides <= 0 {
+		return nil, fmt.Errorf("dice cannot have a nonpositive number of sides")
+	}
+
+	if d.givenNatural != 0 {
+		// We're only considering the case where our lone die came up
+		// with this natural value.
+		return d.dieDistribution(pmf{float64(d.givenNatural): 1}, d.score), nil
+	}
+
+	if d.Keep != keepAll {
+		// We can only work out which dice are kept if they're all rolled the
+		// same way, and extra dice don't get added to the pool.
+		if d.InitialMax || d.Explode == explodeDice || d.Explode == penetrateDice {
+			return nil, errNoExactDistribution
+		}
+		values := d.dieDistribution(d.naturalDistribution(), func(v int) float64 { return float64(v) })
+		if total, err = d.keptDistribution(values); err != nil {
+			return nil, err
+		}
+	} else {
+		n := d.Numerator
+		total = pmf{0: 1}
+		if d.InitialMax && n > 0 {
+			total = d.dieDistribution(pmf{float64(d.Sides): 1}, d.score)
+			n--
+		}
+		die := d.dieDistribution(d.naturalDistribution(), d.score)
+		for i := 0; i < n; i++ {
+			if total, err = combine(total, die, addValues); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if d.Rerolls > 0 {
+		total = extremeOf(total, d.Rerolls+1, d.BestReroll)
+	}
+	return total, nil
+}
+
+// naturalDistribution returns the distribution of the natural value rolled
+// on a single die, after any rerolls.
+func (d *dieSpec) naturalDistribution() pmf {
+	p := make(pmf)
+	each := 1.0 / float64(d.Sides)
+
+	if d.RerollOn == nil {
+		for v := 1; v <= d.Sides; v++ {
+			p[float64(v)] = each
+		}
+		return p
+	}
+
+	var matching []int
+	var kept []int
+	for v := 1; v <= d.Sides; v++ {
+		if d.RerollOn.matches(d.faceValue(v)) {
+			matching = append(matching, v)
+		} else {
+			kept = append(kept, v)
+		}
+	}
+	if d.RerollAlways {
+		for _, v := range kept {
+			p[float64(v)] = 1.0 / float64(len(kept))
+		}
+		return p
+	}
+	// The die is rerolled at most once, so we end up with the first roll
+	// if it didn't match, or else any value at all from the second roll.
+	pReroll := float64(len(matching)) * each
+	for _, v := range kept {
+		p[float64(v)] += each
+	}
+	for v := 1; v <= d.Sides; v++ {
+		p[float64(v)] += pReroll * each
+	}
+	return p
+}
+
+// singleNaturalDistribution returns the distribution of the natural value
+// rolled on the die which determines the outcome for this component, which
+// must be one where isSingleDie is true.
+func (d *dieSpec) singleNaturalDistribution() (pmf, error) {
+	if d.Numerator > 1 || d.Rerolls > 0 {
+		// We need to know which die (or roll) will be chosen from its
+		// natural value alone, which means its value must depend only on
+		// (and never decrease with) its natural value.
+		if d.Explode != noExplosion || d.Target != nil || d.Denominator > 0 {
+			return nil, errNoExactDistribution
+		}
+	}
+	if d.InitialMax {
+		return pmf{float64(d.Sides): 1}, nil
+	}
+
+	p := d.naturalDistribution()
+	if d.Numerator > 1 {
+		p = extremeOf(p, d.Numerator, d.Keep == keepHighest || d.Keep == dropLowest)
+	}
+	if d.Rerolls > 0 {
+		p = extremeOf(p, d.Rerolls+1, d.BestReroll)
+	}
+	return p, nil
+}
+
+// score returns the amount a die with value v contributes to the value of
+// this component: v itself, or for dice pools, its effect on the number of
+// successes.
+func (d *dieSpec) score(v int) float64 {
+	if d.Target == nil {
+		return float64(v)
+	}
+	switch d.poolResultOf(dieRoll{value: v}) {
+	case poolSuccess:
+		return 1
+	case poolDouble:
+		return 2
+	case poolFail:
+		return -1
+	}
+	return 0
+}
+
+// chainDistribution returns the distribution of the total of the extra dice
+// rolled after a die explodes, including any further explosions. Each extra
+// die with natural value w contributes extra(w) to the total.
+func (d *dieSpec) chainDistribution(extra func(w int) float64) pmf {
+	threshold := d.explodeThreshold()
+	each := 1.0 / float64(d.Sides)
+	pExplode := float64(max(0, d.Sides-threshold+1)) * each
+
+	chain := pmf{0: 1}
+	reach := 1.0
+	for n := 0; n < maxExplosionsPerDie && reach > explosionCutoff; n++ {
+		next := make(pmf)
+		for w := 1; w <= d.Sides; w++ {
+			x := extra(w)
+			if w >= threshold {
+				for v, p := range chain {
+					next[x+v] += each * p
+				}
+			} else {
+				next[x] += each
+			}
+		}
+		chain = next
+		reach *= pExplode
+	}
+	return chain
+}
+
+// dieDistribution returns the distribution of what a single die (along with any
+// extra dice from explosions) contributes to the value of this component, given
+// the distribution of its natural value. Each die with a value of v (after any
+// per-die adjustments) contributes score(v).
+func (d *dieSpec) dieDistribution(natural pmf, score func(int) float64) pmf {
+	var chain pmf
+	switch d.Explode {
+	case compoundDice:
+		// extra rolls add to the natural value of the die
+		chain = d.chainDistribution(func(w int) float64 { return float64(w) })
+	case penetrateDice:
+		chain = d.chainDistribution(func(w int) float64 { return score(d.adjust(w - 1)) })
+	case explodeDice:
+		chain = d.chainDistribution(func(w int) float64 { return score(d.adjust(w)) })
+	}
+
+	r := make(pmf)
+	for nv, pv := range natural {
+		v := int(nv)
+		if d.Explode == noExplosion || v < d.explodeThreshold() {
+			r[score(d.adjust(v))] += pv
+			continue
+		}
+		if d.Explode == compoundDice {
+			for x, px := range chain {
+				r[score(d.adjust(v+int(x)))] += pv * px
+			}
+			continue
+		}
+		s := score(d.adjust(v))
+		for x, px := range chain {
+			r[s+x] += pv * px
+		}
+	}
+	return r
+}
+
+// keptDistribution returns the distribution of the value of this component when
+// only some of the dice are kept, given the distribution of each die's value.
+func (d *dieSpec) keptDistribution(values pmf) (pmf, error) {
+	n := d.Numerator
+	var keep int
+	var highest bool
+
+	switch d.Keep {
+	case keepHighest:
+		keep, highest = d.KeepCount, true
+	case keepLowest:
+		keep = d.KeepCount
+	case dropHighest:
+		keep = n - d.KeepCount
+	case dropLowest:
+		keep, highest = n-d.KeepCount, true
+	}
+	keep = max(0, min(keep, n))
+
+	order := make([]float64, 0, len(values))
+	for v := range values {
+		order = append(order, v)
+	}
+	sort.Float64s(order)
+	if highest {
+		for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+			order[i], order[j] = order[j], order[i]
+		}
+	}
+
+	// Considering each possible die value in order of preference, we track
+	// how many of the n dice have been assigned values so far and the total
+	// of the kept ones (the first keep dice to be assigned).
+	type state struct {
+		assigned int
+		total    float64
+	}
+	states := map[state]float64{{0, 0}: 1}
+	for _, v := range order {
+		q := values[v]
+		next := make(map[state]float64)
+		for st, p := range states {
+			for c := 0; c <= n-st.assigned; c++ {
+				kept := min(c, max(0, keep-st.assigned))
+				next[state{st.assigned + c, st.total + float64(kept)*d.score(int(v))}] += p * binomial(n-st.assigned, c) * math.Pow(q, float64(c))
+			}
+		}
+		if len(next) > maxDistributionSize {
+			return nil, errNoExactDistribution
+		}
+		states = next
+	}
+
+	r := make(pmf)
+	for st, p := range states {
+		if st.assigned == n {
+			r[st.total] += p
+		}
+	}
+	return r, nil
+}
+
+// binomial returns the binomial coefficient (n choose k).
+func binomial(n, k int) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+	c := 1.0
+	for i := 1; i <= min(k, n-k); i++ {
+		c = c * float64(n-min(k, n-k)+i) / float64(i)
+	}
+	return c
+}
+
+// extremeOf returns the distribution of the best (or worst) of n independent
+// values distributed according to p.
+func extremeOf(p pmf, n int, best bool) pmf {
+	values := make([]float64, 0, len(p))
+	for v := range p {
+		values = append(values, v)
+	}
+	sort.Float64s(values)
+
+	r := make(pmf)
+	var cdf float64
+	for _, v := range values {
+		prev := cdf
+		cdf += p[v]
+		if best {
+			r[v] = math.Pow(cdf, float64(n)) - math.Pow(prev, float64(n))
+		} else {
+			r[v] = math.Pow(1-prev, float64(n)) - math.Pow(1-cdf, float64(n))
+		}
+	}
+	return r
+}
+
+// @[00]@| Go-GMA 5.33.0
+// @[01]@|
+// @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+// @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+// @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+// @[13]@| points along that historical time line.
+// @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+// @[15]@| License as described in the accompanying LICENSE file distributed
+// @[16]@| with GMA.
+// @[17]@|
+// @[20]@| Redistribution and use in source and binary forms, with or without
+// @[21]@| modification, are permitted provided that the following conditions
+// @[22]@| are met:
+// @[23]@| 1. Redistributions of source code must retain the above copyright
+// @[24]@|    notice, this list of conditions and the following disclaimer.
+// @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+// @[26]@|    right notice, this list of conditions and the following dis-
+// @[27]@|    claimer in the documentation and/or other materials provided
+// @[28]@|    with the distribution.
+// @[29]@| 3. Neither the name of the copyright holder nor the names of its
+// @[30]@|    contributors may be used to endorse or promote products derived
+// @[31]@|    from this software without specific prior written permission.
+// @[32]@|
+// @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+// @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+// @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+// @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// @[45]@| SUCH DAMAGE.
+// @[46]@|
+// @[50]@| This software is not intended for any use or application in which
+// @[51]@| the safety of lives or property would be at risk due to failure or
+// @[52]@| defect of the software.
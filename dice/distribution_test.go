@@ -0,0 +1,285 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package dice
+
+import (
+	"math"
+	"testing"
+)
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestDistributionExact(t *testing.T) {
+	type testcase struct {
+		Roll    string
+		Min     int
+		Max     int
+		Mean    float64
+		StdDev  float64
+		Median  int
+		DC      int
+		Chance  float64
+		Exactly map[int]float64
+	}
+
+	for i, test := range []testcase{
+		{Roll: "d6", Min: 1, Max: 6, Mean: 3.5, StdDev: math.Sqrt(35.0 / 12.0), Median: 3, DC: 5, Chance: 2.0 / 6.0,
+			Exactly: map[int]float64{1: 1.0 / 6.0, 6: 1.0 / 6.0}},
+		{Roll: "2d6", Min: 2, Max: 12, Mean: 7, StdDev: math.Sqrt(35.0 / 6.0), Median: 7, DC: 10, Chance: 6.0 / 36.0,
+			Exactly: map[int]float64{7: 6.0 / 36.0, 12: 1.0 / 36.0}},
+		{Roll: "3d6", Min: 3, Max: 18, Mean: 10.5, StdDev: math.Sqrt(8.75), Median: 10, DC: 18, Chance: 1.0 / 216.0},
+		{Roll: "d20+5", Min: 6, Max: 25, Mean: 15.5, StdDev: math.Sqrt(33.25), Median: 15, DC: 24, Chance: 0.1},
+		{Roll: "(d6+1)*2", Min: 4, Max: 14, Mean: 9, StdDev: math.Sqrt(35.0 / 3.0), Median: 8, DC: 10, Chance: 0.5},
+		{Roll: "d20 best of 2", Min: 1, Max: 20, Mean: 13.825, StdDev: math.Sqrt(22.194375), Median: 15, DC: 20, Chance: 0.0975},
+		{Roll: "d20 worst of 2", Min: 1, Max: 20, Mean: 7.175, StdDev: math.Sqrt(22.194375), Median: 6, DC: 20, Chance: 0.0025},
+		{Roll: "4d6kh3", Min: 3, Max: 18, Mean: 15869.0 / 1296.0, StdDev: 2.8468444453115,
+			Median: 12, DC: 18, Chance: 21.0 / 1296.0},
+		{Roll: "4d6dl1", Min: 3, Max: 18, Mean: 15869.0 / 1296.0, StdDev: 2.8468444453115,
+			Median: 12, DC: 18, Chance: 21.0 / 1296.0},
+		{Roll: "2d20kl1", Min: 1, Max: 20, Mean: 7.175, StdDev: math.Sqrt(22.194375), Median: 6, DC: 20, Chance: 0.0025},
+		{Roll: "3d6|maximized", Min: 18, Max: 18, Mean: 18, Median: 18, DC: 18, Chance: 1},
+		{Roll: "d20|min 5", Min: 5, Max: 20, Mean: 11, StdDev: math.Sqrt(26), Median: 10, DC: 5, Chance: 1,
+			Exactly: map[int]float64{5: 0.25, 6: 0.05}},
+		{Roll: "d20|max 10", Min: 1, Max: 10, Mean: 7.75, StdDev: math.Sqrt(9.1875), Median: 10, DC: 10, Chance: 0.55},
+		{Roll: "3d6|repeat 3", Min: 9, Max: 54, Mean: 31.5, StdDev: math.Sqrt(26.25), Median: 31, DC: 54, Chance: math.Pow(216, -3)},
+		{Roll: "d6rr<2", Min: 3, Max: 6, Mean: 4.5, StdDev: math.Sqrt(1.25), Median: 4, DC: 6, Chance: 0.25},
+		{Roll: "d6r1", Min: 1, Max: 6, Mean: 47.0 / 12.0, StdDev: math.Sqrt(2.1875), Median: 4, DC: 2, Chance: 35.0 / 36.0},
+		{Roll: "8d10cs>=7", Min: 0, Max: 8, Mean: 3.2, StdDev: math.Sqrt(1.92), Median: 3, DC: 8, Chance: math.Pow(0.4, 8)},
+		{Roll: "d6!", Min: 1, Mean: 4.2, StdDev: math.Sqrt(10.64), Median: 3, DC: 7, Chance: 1.0 / 6.0},
+		{Roll: "d6!!", Min: 1, Mean: 4.2, StdDev: math.Sqrt(10.64), Median: 3, DC: 7, Chance: 1.0 / 6.0},
+		{Roll: "d6!p", Min: 1, Mean: 4, StdDev: math.Sqrt(8), Median: 3, DC: 6, Chance: 1.0 / 6.0},
+		{Roll: "40%", Min: 0, Max: 1, Mean: 0.4, StdDev: math.Sqrt(0.24), Median: 0, DC: 1, Chance: 0.4},
+	} {
+		d, err := NewDieRoller(WithSeed(12345))
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		dist, err := d.Distribution(test.Roll)
+		if err != nil {
+			t.Errorf("test %d (%s): error %v", i, test.Roll, err)
+			continue
+		}
+		if !dist.Exact {
+			t.Errorf("test %d (%s): expected exact distribution", i, test.Roll)
+		}
+		var total float64
+		for _, p := range dist.Probability {
+			total += p
+		}
+		if !closeTo(total, 1) {
+			t.Errorf("test %d (%s): probabilities sum to %v", i, test.Roll, total)
+		}
+		if dist.Min() != test.Min {
+			t.Errorf("test %d (%s): min %d, expected %d", i, test.Roll, dist.Min(), test.Min)
+		}
+		if test.Max != 0 && dist.Max() != test.Max {
+			t.Errorf("test %d (%s): max %d, expected %d", i, test.Roll, dist.Max(), test.Max)
+		}
+		if !closeTo(dist.Mean(), test.Mean) {
+			t.Errorf("test %d (%s): mean %v, expected %v", i, test.Roll, dist.Mean(), test.Mean)
+		}
+		if !closeTo(dist.StdDev(), test.StdDev) {
+			t.Errorf("test %d (%s): stddev %v, expected %v", i, test.Roll, dist.StdDev(), test.StdDev)
+		}
+		if dist.Percentile(50) != test.Median {
+			t.Errorf("test %d (%s): median %d, expected %d", i, test.Roll, dist.Percentile(50), test.Median)
+		}
+		if !closeTo(dist.ChanceAtLeast(test.DC), test.Chance) {
+			t.Errorf("test %d (%s): chance of %d+ %v, expected %v", i, test.Roll, test.DC, dist.ChanceAtLeast(test.DC), test.Chance)
+		}
+		if lo, hi := dist.MeanInterval(); !closeTo(lo, dist.Mean()) || !closeTo(hi, dist.Mean()) {
+			t.Errorf("test %d (%s): exact mean interval %v..%v", i, test.Roll, lo, hi)
+		}
+		for v, p := range test.Exactly {
+			if !closeTo(dist.Probability[v], p) {
+				t.Errorf("test %d (%s): P(%d) = %v, expected %v", i, test.Roll, v, dist.Probability[v], p)
+			}
+		}
+	}
+}
+
+func TestDistributionEstimated(t *testing.T) {
+	d, err := NewDieRoller(WithSeed(12345))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, roll := range []string{"4d6!kh3", "d20|until 2"} {
+		dist, err := d.Distribution(roll)
+		if err != nil {
+			t.Fatalf("%s: %v", roll, err)
+		}
+		if dist.Exact || dist.Samples != MonteCarloSamples {
+			t.Errorf("%s: expected estimate from %d samples, got exact=%v samples=%d", roll, MonteCarloSamples, dist.Exact, dist.Samples)
+		}
+		lo, hi := dist.MeanInterval()
+		if lo >= dist.Mean() || hi <= dist.Mean() {
+			t.Errorf("%s: mean interval %v..%v doesn't bracket mean %v", roll, lo, hi, dist.Mean())
+		}
+		lo, hi = dist.ChanceAtLeastInterval(10)
+		if p := dist.ChanceAtLeast(10); lo >= p || hi <= p {
+			t.Errorf("%s: chance interval %v..%v doesn't bracket %v", roll, lo, hi, p)
+		}
+	}
+
+	// d20 until 2 is a d20 unless the first roll is a 1, in which case it's
+	// 1 plus another roll like it. The mean is thus 10.5 * 20/19.
+	dist, err := d.Distribution("d20|until 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lo, hi := dist.MeanInterval(); lo > 210.0/19.0 || hi < 210.0/19.0 {
+		t.Errorf("d20 until 2: mean interval %v..%v doesn't include the true mean", lo, hi)
+	}
+}
+
+func TestDistributionPermutations(t *testing.T) {
+	d, err := NewDieRoller(WithSeed(12345))
+	if err != nil {
+		t.Fatal(err)
+	}
+	title, dists, err := d.Distributions("atk=d20+{17/12}+2 bless|c19+2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if title != "atk" {
+		t.Errorf("title %q, expected \"atk\"", title)
+	}
+	if len(dists) != 2 {
+		t.Fatalf("got %d distributions, expected 2", len(dists))
+	}
+	for i, expected := range []struct {
+		Expression string
+		Mean       float64
+		Chance     float64
+		Confirmed  float64
+	}{
+		{"d20+17+2 bless", 29.5, 0.95, 0.1 * 0.95},
+		{"d20+12+2 bless", 24.5, 0.75, 0.1 * 0.85},
+	} {
+		dist := dists[i]
+		if dist.Expression != expected.Expression {
+			t.Errorf("permutation %d: expression %q, expected %q", i, dist.Expression, expected.Expression)
+		}
+		if !closeTo(dist.Mean(), expected.Mean) {
+			t.Errorf("permutation %d: mean %v, expected %v", i, dist.Mean(), expected.Mean)
+		}
+		if !dist.AutoSF || !closeTo(dist.AutoSuccessChance, 0.05) || !closeTo(dist.AutoFailChance, 0.05) {
+			t.Errorf("permutation %d: auto success/fail %v %v/%v", i, dist.AutoSF, dist.AutoSuccessChance, dist.AutoFailChance)
+		}
+		if !closeTo(dist.ThreatChance, 0.1) {
+			t.Errorf("permutation %d: threat chance %v, expected 0.1", i, dist.ThreatChance)
+		}
+		if !closeTo(dist.ChanceToSucceed(20), expected.Chance) {
+			t.Errorf("permutation %d: chance to succeed %v, expected %v", i, dist.ChanceToSucceed(20), expected.Chance)
+		}
+		if !closeTo(dist.ConfirmedCriticalChance(20), expected.Confirmed) {
+			t.Errorf("permutation %d: chance to confirm %v, expected %v", i, dist.ConfirmedCriticalChance(20), expected.Confirmed)
+		}
+	}
+
+	if _, err := d.Distribution(""); err == nil {
+		t.Errorf("Distribution should not accept permutations")
+	}
+}
+
+func TestDistributionAutoSF(t *testing.T) {
+	for i, test := range []struct {
+		Roll    string
+		Success float64
+		Fail    float64
+		DC      int
+		Chance  float64
+	}{
+		{"d20+5|sf", 0.05, 0.05, 25, 0.05},
+		{"d20+5|sf", 0.05, 0.05, 5, 0.95},
+		{"2d20kh1+3|sf", 0.0975, 0.0025, 23, 0.0975},
+		{"d20 worst of 2|sf", 0.0025, 0.0975, 2, 0.9025},
+	} {
+		dist, err := Distribution(test.Roll)
+		if err != nil {
+			t.Errorf("test %d (%s): %v", i, test.Roll, err)
+			continue
+		}
+		if !dist.Exact || !dist.AutoSF {
+			t.Errorf("test %d (%s): exact %v, auto s/f %v", i, test.Roll, dist.Exact, dist.AutoSF)
+		}
+		if !closeTo(dist.AutoSuccessChance, test.Success) || !closeTo(dist.AutoFailChance, test.Fail) {
+			t.Errorf("test %d (%s): auto success %v fail %v, expected %v, %v", i, test.Roll,
+				dist.AutoSuccessChance, dist.AutoFailChance, test.Success, test.Fail)
+		}
+		if !closeTo(dist.ChanceToSucceed(test.DC), test.Chance) {
+			t.Errorf("test %d (%s): chance to meet %d %v, expected %v", i, test.Roll, test.DC, dist.ChanceToSucceed(test.DC), test.Chance)
+		}
+	}
+
+	if _, err := Distribution("3d6|sf"); err == nil {
+		t.Errorf("expected error for auto s/f with multiple dice")
+	}
+}
+
+func TestDistributionErrors(t *testing.T) {
+	for _, roll := range []string{"d6//(d2-1)", "d20+{17/12/7}", "d20+", "(d6"} {
+		if _, err := Distribution(roll); err == nil {
+			t.Errorf("%s: expected error", roll)
+		}
+	}
+}
+
+// @[00]@| Go-GMA 5.33.0
+// @[01]@|
+// @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+// @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+// @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+// @[13]@| points along that historical time line.
+// @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+// @[15]@| License as described in the accompanying LICENSE file distributed
+// @[16]@| with GMA.
+// @[17]@|
+// @[20]@| Redistribution and use in source and binary forms, with or without
+// @[21]@| modification, are permitted provided that the following conditions
+// @[22]@| are met:
+// @[23]@| 1. Redistributions of source code must retain the above copyright
+// @[24]@|    notice, this list of conditions and the following disclaimer.
+// @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+// @[26]@|    right notice, this list of conditions and the following dis-
+// @[27]@|    claimer in the documentation and/or other materials provided
+// @[28]@|    with the distribution.
+// @[29]@| 3. Neither the name of the copyright holder nor the names of its
+// @[30]@|    contributors may be used to endorse or promote products derived
+// @[31]@|    from this software without specific prior written permission.
+// @[32]@|
+// @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+// @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+// @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+// @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// @[45]@| SUCH DAMAGE.
+// @[46]@|
+// @[50]@| This software is not intended for any use or application in which
+// @[51]@| the safety of lives or property would be at risk due to failure or
+// @[52]@| defect of the software.
+//
@@ -0,0 +1,245 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package dice
+
+import (
+	"fmt"
+	"math"
+)
+
+// FairnessDieSizes are the sizes of dice checked by default when testing the
+// fairness of the die roller.
+var FairnessDieSizes = []int{2, 3, 4, 6, 8, 10, 12, 20, 100}
+
+// FairnessSamples is the default number of times each die is rolled when
+// testing the fairness of the die roller.
+const FairnessSamples = 100000
+
+// FairnessReport describes the results of testing whether a die of a given size
+// comes up fairly when rolled by a DieRoller.
+//
+// Two tests are made. The chi-square test checks that each face of the die came up
+// about as often as the others. The runs test checks that the rolls don't follow
+// a pattern, by counting the runs of consecutive rolls which were all above (or all
+// below) the die's average value. A die which always came up 1, 2, 3, 4, 5, 6, 1, 2, ...
+// would pass the first test but fail the second.
+//
+// Each test yields a p-value, which is the probability that a fair die would produce
+// results at least as far off from what is expected as the ones actually rolled. A very
+// small p-value (say, under 0.001) is evidence that the die is not fair. Keep in mind,
+// though, that when many tests are made, a few small p-values are to be expected by
+// chance alone.
+type FairnessReport struct {
+	// The number of sides on the die and how many times it was rolled.
+	Sides   int
+	Samples int
+
+	// The number of times each face came up (Counts[0] for 1, and so on).
+	Counts []int
+
+	// The chi-square statistic, its degrees of freedom, and its p-value.
+	ChiSquare        float64
+	DegreesOfFreedom int
+	ChiSquareP       float64
+
+	// The number of runs above or below the die's average value, the number
+	// expected from a fair die, the z-score of the difference, and its p-value.
+	Runs         int
+	ExpectedRuns float64
+	RunsZ        float64
+	RunsP        float64
+}
+
+// Suspicious reports whether either test's p-value is below alpha.
+func (r FairnessReport) Suspicious(alpha float64) bool {
+	return r.ChiSquareP < alpha || r.RunsP < alpha
+}
+
+// Fairness rolls a single die of each of the given sizes the given number of times,
+// testing how fair the results are (see FairnessReport). If sides is empty, the
+// FairnessDieSizes are used; if samples is 0, FairnessSamples is used.
+//
+// The dice are rolled using the DieRoller's random number generator, so to check a
+// different generator, create the DieRoller with the WithGenerator option:
+//
+//	dr, err := NewDieRoller(WithGenerator(mySource))
+//	reports, err := dr.Fairness(nil, 0)
+//
+// Since this advances the generator just as any other die rolls do, it will change the
+// results of subsequent die rolls made with the DieRoller.
+func (d *DieRoller) Fairness(sides []int, samples int) ([]FairnessReport, error) {
+	if len(sides) == 0 {
+		sides = FairnessDieSizes
+	}
+	if samples == 0 {
+		samples = FairnessSamples
+	}
+	if samples < 2 {
+		return nil, fmt.Errorf("fairness test needs at least 2 samples")
+	}
+
+	var reports []FairnessReport
+	for _, s := range sides {
+		if s < 2 {
+			return nil, fmt.Errorf("can't test the fairness of a %d-sided die", s)
+		}
+		die, err := New(ByDieType(1, s, 0), withSharedGenerator(d.generator))
+		if err != nil {
+			return nil, err
+		}
+		r := FairnessReport{Sides: s, Samples: samples, Counts: make([]int, s)}
+
+		// For the runs test, rolls above the average are "high" and those below
+		// it are "low". On dice with an odd number of sides, the middle face
+		// is neither, and is skipped over.
+		mean := float64(s+1) / 2
+		var high, low int
+		var last float64
+		for range samples {
+			v, err := die.Roll()
+			if err != nil {
+				return nil, err
+			}
+			if v < 1 || v > s {
+				return nil, fmt.Errorf("rolled %d on a d%d", v, s)
+			}
+			r.Counts[v-1]++
+			if side := float64(v) - mean; side != 0 {
+				if side > 0 {
+					high++
+				} else {
+					low++
+				}
+				if math.Signbit(side) != math.Signbit(last) || last == 0 {
+					r.Runs++
+				}
+				last = side
+			}
+		}
+
+		expected := float64(samples) / float64(s)
+		for _, c := range r.Counts {
+			r.ChiSquare += (float64(c) - expected) * (float64(c) - expected) / expected
+		}
+		r.DegreesOfFreedom = s - 1
+		r.ChiSquareP = chiSquareP(r.ChiSquare, r.DegreesOfFreedom)
+
+		n := float64(high + low)
+		r.ExpectedRuns = 2*float64(high)*float64(low)/n + 1
+		variance := (r.ExpectedRuns - 1) * (r.ExpectedRuns - 2) / (n - 1)
+		if variance > 0 {
+			r.RunsZ = (float64(r.Runs) - r.ExpectedRuns) / math.Sqrt(variance)
+			r.RunsP = math.Erfc(math.Abs(r.RunsZ) / math.Sqrt2)
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
+
+// chiSquareP returns the probability of a chi-square statistic of at least x
+// with k degrees of freedom.
+func chiSquareP(x float64, k int) float64 {
+	return upperIncompleteGamma(float64(k)/2, x/2)
+}
+
+// upperIncompleteGamma calculates the regularized upper incomplete gamma function Q(a,x),
+// using its series expansion for small x and its continued fraction otherwise.
+func upperIncompleteGamma(a, x float64) float64 {
+	const (
+		epsilon = 1e-15
+		tiny    = 1e-300
+	)
+	if x <= 0 {
+		return 1
+	}
+	lg, _ := math.Lgamma(a)
+	scale := math.Exp(a*math.Log(x) - x - lg)
+
+	if x < a+1 {
+		term := 1 / a
+		sum := term
+		for n := 1; n < 1000 && math.Abs(term) >= math.Abs(sum)*epsilon; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+		}
+		return max(0, 1-sum*scale)
+	}
+
+	b := x + 1 - a
+	c := 1 / tiny
+	f := 1 / b
+	h := f
+	for i := 1; i < 1000; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		if f = an*f + b; math.Abs(f) < tiny {
+			f = tiny
+		}
+		if c = b + an/c; math.Abs(c) < tiny {
+			c = tiny
+		}
+		f = 1 / f
+		delta := f * c
+		h *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return scale * h
+}
+
+// @[00]@| Go-GMA 5.33.0
+// @[01]@|
+// @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+// @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+// @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+// @[13]@| points along that historical time line.
+// @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+// @[15]@| License as described in the accompanying LICENSE file distributed
+// @[16]@| with GMA.
+// @[17]@|
+// @[20]@| Redistribution and use in source and binary forms, with or without
+// @[21]@| modification, are permitted provided that the following conditions
+// @[22]@| are met:
+// @[23]@| 1. Redistributions of source code must retain the above copyright
+// @[24]@|    notice, this list of conditions and the following disclaimer.
+// @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+// @[26]@|    right notice, this list of conditions and the following dis-
+// @[27]@|    claimer in the documentation and/or other materials provided
+// @[28]@|    with the distribution.
+// @[29]@| 3. Neither the name of the copyright holder nor the names of its
+// @[30]@|    contributors may be used to endorse or promote products derived
+// @[31]@|    from this software without specific prior written permission.
+// @[32]@|
+// @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+// @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+// @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+// @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// @[45]@| SUCH DAMAGE.
+// @[46]@|
+// @[50]@| This software is not intended for any use or application in which
+// @[51]@| the safety of lives or property would be at risk due to failure or
+// @[52]@| defect of the software.
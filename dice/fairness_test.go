@@ -0,0 +1,178 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package dice
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestChiSquareP(t *testing.T) {
+	for i, test := range []struct {
+		x        float64
+		k        int
+		expected float64
+	}{
+		{0, 5, 1},
+		{3.841, 1, 0.05},
+		{6.635, 1, 0.01},
+		{11.070, 5, 0.05},
+		{30.144, 19, 0.05},
+		{36.191, 19, 0.01},
+		{123.225, 99, 0.05},
+		{1.145, 5, 0.95},
+	} {
+		if p := chiSquareP(test.x, test.k); math.Abs(p-test.expected) > 0.0005 {
+			t.Errorf("test #%d: chi-square %v with %d df: p=%v, expected %v", i, test.x, test.k, p, test.expected)
+		}
+	}
+}
+
+// biasedSource makes every fourth die come up 1.
+type biasedSource struct {
+	rand.Source
+	n int
+}
+
+func (s *biasedSource) Int63() int64 {
+	if s.n++; s.n%4 == 0 {
+		return 0
+	}
+	return s.Source.Int63()
+}
+
+// cyclingSource produces values which make dice come up in order: 1, 2, 3, ...
+type cyclingSource struct {
+	next int64
+}
+
+func (s *cyclingSource) Int63() int64 {
+	s.next++
+	return s.next << 32
+}
+
+func (s *cyclingSource) Seed(seed int64) {
+	s.next = seed
+}
+
+func TestFairness(t *testing.T) {
+	dr, err := NewDieRoller(WithSeed(42))
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+	reports, err := dr.Fairness(nil, 20000)
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+	if len(reports) != len(FairnessDieSizes) {
+		t.Fatalf("got %d reports, expected %d", len(reports), len(FairnessDieSizes))
+	}
+	for i, r := range reports {
+		if r.Sides != FairnessDieSizes[i] || r.Samples != 20000 || len(r.Counts) != r.Sides || r.DegreesOfFreedom != r.Sides-1 {
+			t.Errorf("d%d: malformed report %+v", FairnessDieSizes[i], r)
+		}
+		total := 0
+		for _, c := range r.Counts {
+			total += c
+		}
+		if total != r.Samples {
+			t.Errorf("d%d: counts add up to %d, expected %d", r.Sides, total, r.Samples)
+		}
+		if r.Suspicious(0.001) {
+			t.Errorf("d%d: fair generator looks suspicious: %+v", r.Sides, r)
+		}
+	}
+
+	dr, err = NewDieRoller(WithGenerator(&biasedSource{Source: rand.NewSource(42)}))
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+	reports, err = dr.Fairness([]int{6, 20}, 5000)
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+	for _, r := range reports {
+		if r.ChiSquareP >= 0.001 {
+			t.Errorf("d%d: biased generator passed the chi-square test: %+v", r.Sides, r)
+		}
+	}
+
+	dr, err = NewDieRoller(WithGenerator(&cyclingSource{}))
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+	reports, err = dr.Fairness([]int{6, 20}, 6000)
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+	for _, r := range reports {
+		if r.ChiSquareP < 0.001 {
+			t.Errorf("d%d: cycling generator failed the chi-square test: %+v", r.Sides, r)
+		}
+		if r.RunsP >= 0.001 {
+			t.Errorf("d%d: cycling generator passed the runs test: %+v", r.Sides, r)
+		}
+	}
+
+	if _, err = dr.Fairness([]int{1}, 100); err == nil {
+		t.Errorf("fairness of a d1 was tested without error")
+	}
+}
+
+// @[00]@| Go-GMA 5.33.0
+// @[01]@|
+// @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+// @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+// @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+// @[13]@| points along that historical time line.
+// @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+// @[15]@| License as described in the accompanying LICENSE file distributed
+// @[16]@| with GMA.
+// @[17]@|
+// @[20]@| Redistribution and use in source and binary forms, with or without
+// @[21]@| modification, are permitted provided that the following conditions
+// @[22]@| are met:
+// @[23]@| 1. Redistributions of source code must retain the above copyright
+// @[24]@|    notice, this list of conditions and the following disclaimer.
+// @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+// @[26]@|    right notice, this list of conditions and the following dis-
+// @[27]@|    claimer in the documentation and/or other materials provided
+// @[28]@|    with the distribution.
+// @[29]@| 3. Neither the name of the copyright holder nor the names of its
+// @[30]@|    contributors may be used to endorse or promote products derived
+// @[31]@|    from this software without specific prior written permission.
+// @[32]@|
+// @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+// @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+// @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+// @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// @[45]@| SUCH DAMAGE.
+// @[46]@|
+// @[50]@| This software is not intended for any use or application in which
+// @[51]@| the safety of lives or property would be at risk due to failure or
+// @[52]@| defect of the software.
+//
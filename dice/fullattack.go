@@ -0,0 +1,359 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package dice
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/schwarmco/go-cartesian-product"
+)
+
+//  _____      _ _      _   _   _             _
+// |  ___|   _| | |    / \ | |_| |_ __ _  ___| | _____
+// | |_ | | | | | |   / _ \| __| __/ _` |/ __| |/ / __|
+// |  _|| |_| | | |  / ___ \ |_| || (_| | (__|   <\__ \
+// |_|   \__,_|_|_| /_/   \_\__|\__\__,_|\___|_|\_\___/
+//
+
+// fullAttackProfile describes how one attack in a full-attack sequence
+// deals damage and scores critical hits.
+type fullAttackProfile struct {
+	damage     string // damage expression as given by the user
+	threat     int    // threat threshold (0=default for die type)
+	bonus      int    // added to confirmation rolls
+	multiplier int    // damage multiplier for confirmed criticals
+	damageDice *Dice  // damage to roll on a hit
+	critDice   *Dice  // damage to roll on a confirmed critical (already multiplied)
+}
+
+// fullAttackSeparators returns the positions of the slashes which separate
+// the attacks listed in the value of a “| full” option. Slashes inside
+// parentheses or braces, doubled slashes (“//” for division), and the slash
+// in a fractional die at the start of an attack's damage (as in “1/2d6+3”)
+// don't separate attacks. (A fractional die anywhere else in the damage must
+// be put in parentheses, since “1d8+1/2d6” could be read as two attacks.)
+func fullAttackSeparators(spec []rune) []int {
+	var separators []int
+	depth, start := 0, 0
+	reNumerator := regexp.MustCompile(`^\s*>?\s*\d+\s*$`)
+	reDenominator := regexp.MustCompile(`^\s*\d+\s*[Dd]`)
+
+	for i := 0; i < len(spec); i++ {
+		switch spec[i] {
+		case '(', '{':
+			depth++
+		case ')', '}':
+			depth--
+		case '/':
+			if i+1 < len(spec) && spec[i+1] == '/' {
+				i++
+				continue
+			}
+			if depth > 0 || (reNumerator.MatchString(string(spec[start:i])) && reDenominator.MatchString(string(spec[i+1:]))) {
+				continue
+			}
+			separators = append(separators, i)
+			start = i + 1
+		}
+	}
+	return separators
+}
+
+// parseFullAttack interprets the value of a “| full” option, which lists
+// the damage for each attack, separated by slashes. Each may be followed by
+// “c<threat>[±<bonus>]” and/or “x<multiplier>” to set its critical threat
+// range, confirmation bonus, and damage multiplier, which otherwise default
+// to those given with the “| c” option (or a natural maximum with no bonus)
+// and ×2.
+func (d *DieRoller) parseFullAttack(spec string) ([]fullAttackProfile, error) {
+	reAttack := regexp.MustCompile(`^\s*(.*?)(?:\s+c(\d+)?([-+]\d+)?)?(?:\s+[x×](\d+))?\s*$`)
+	var attacks []fullAttackProfile
+	var err error
+
+	runes := []rune(spec)
+	start := 0
+	for _, end := range append(fullAttackSeparators(runes), len(runes)) {
+		entry := strings.Replace(string(runes[start:end]), "//", "÷", -1)
+		start = end + 1
+		fields := reAttack.FindStringSubmatch(entry)
+		if fields == nil || fields[1] == "" {
+			return nil, fmt.Errorf("missing damage expression in full attack")
+		}
+		a := fullAttackProfile{
+			damage:     fields[1],
+			threat:     d.critThreat,
+			bonus:      d.critBonus,
+			multiplier: 2,
+		}
+		if fields[2] != "" {
+			if a.threat, err = strconv.Atoi(fields[2]); err != nil {
+				return nil, fmt.Errorf("value error in full attack threat range: %v", err)
+			}
+		}
+		if fields[3] != "" {
+			if a.bonus, err = strconv.Atoi(fields[3]); err != nil {
+				return nil, fmt.Errorf("value error in full attack confirmation bonus: %v", err)
+			}
+		}
+		if fields[4] != "" {
+			if a.multiplier, err = strconv.Atoi(fields[4]); err != nil {
+				return nil, fmt.Errorf("value error in full attack damage multiplier: %v", err)
+			}
+			if a.multiplier < 2 {
+				return nil, fmt.Errorf("critical damage multiplier must be at least 2")
+			}
+		}
+		if a.damageDice, err = New(ByDescription(a.damage), withSharedGenerator(d.generator), withNaturalRecorder(d.naturals), WithVariables(d.variables)); err != nil {
+			return nil, fmt.Errorf("error in full attack damage expression: %v", err)
+		}
+		if a.critDice, err = New(
+			ByDescription(multipliedDamage(a.damage, a.multiplier, d.nonMultiplying)),
+			withSharedGenerator(d.generator), withNaturalRecorder(d.naturals), WithVariables(d.variables)); err != nil {
+			return nil, fmt.Errorf("error in full attack critical damage expression: %v", err)
+		}
+		attacks = append(attacks, a)
+	}
+	return attacks, nil
+}
+
+// attackCount returns the number of attack rolls a die-roll spec will make,
+// which is one for each permutation of its values.
+func (d *DieRoller) attackCount() int {
+	n := 1
+	for _, values := range d.Permutations {
+		n *= len(values)
+	}
+	return n
+}
+
+// rollFullAttack rolls a full-attack sequence, where each permutation of the
+// die-roll spec is a separate attack roll paired with its own damage and
+// critical hit profile.
+//
+// The results for each attack begin with an “attack” element giving the number
+// of the attack in the sequence. The first is the attack roll, which is a hit
+// if it meets the DC (if there is one) unless it was a natural 1, or if it
+// was a natural maximum. If it hit and is in the threat range, a confirmation
+// roll follows. Then the damage roll follows if the attack hit. If there's no
+// DC, we can't tell if the attack hit or the critical was confirmed, so the
+// damage is rolled anyway and, for a critical threat, the critical damage
+// is also rolled and marked as applying only if the threat was confirmed.
+//
+// Finally, a summary result starting with a “fullattack” element gives the
+// total damage dealt, followed by the “outcome” of each attack.
+func (d *DieRoller) rollFullAttack() ([]StructuredResult, error) {
+	var results []StructuredResult
+	var outcomes []StructuredDescription
+	var attackDice []*Dice
+	var total int
+
+	if d.Template == "" {
+		attackDice = []*Dice{d.d}
+	} else {
+		for iteration := range cartesian.Iter(d.Permutations...) {
+			attack, err := New(
+				ByDescription(substituteTemplateValues(d.Template, iteration)),
+				withSharedGenerator(d.generator), withNaturalRecorder(d.naturals), WithVariables(d.variables))
+			if err != nil {
+				return nil, err
+			}
+			attackDice = append(attackDice, attack)
+		}
+	}
+
+	for i, attack := range attackDice {
+		profile := d.fullAttack[min(i, len(d.fullAttack)-1)]
+		label := StructuredDescription{Type: "attack", Value: strconv.Itoa(i + 1)}
+		outcome := "unknown"
+		d.d = attack
+
+		// The attack roll
+		result, err := attack.Roll()
+		if err != nil {
+			return nil, err
+		}
+		sdesc, err := attack.StructuredDescribeRoll()
+		if err != nil {
+			return nil, err
+		}
+		hit := d.IsNaturalMax() || (d.DC != 0 && !d.IsNatural1() && result >= d.DC)
+		missed := d.IsNatural1() || (d.DC != 0 && !hit)
+		details := StructuredDescriptionSet{label}
+		if hit {
+			details = append(details, StructuredDescription{Type: "success", Value: "HIT"})
+			outcome = "hit"
+		} else if missed {
+			details = append(details, StructuredDescription{Type: "fail", Value: "MISS"})
+			outcome = "miss"
+		}
+		details = append(details, sdesc...)
+		c := "c"
+		if profile.threat != 0 {
+			c += strconv.Itoa(profile.threat)
+		}
+		if profile.bonus != 0 {
+			c += fmt.Sprintf("%+d", profile.bonus)
+		}
+		details = append(details,
+			StructuredDescription{Type: "moddelim", Value: "|"},
+			StructuredDescription{Type: "critspec", Value: fmt.Sprintf("%s ×%d", c, profile.multiplier)},
+		)
+		if d.DC != 0 {
+			details = append(details,
+				StructuredDescription{Type: "moddelim", Value: "|"},
+				StructuredDescription{Type: "dc", Value: strconv.Itoa(d.DC)},
+				describeDCRoll(d.DC, result),
+			)
+		}
+		results = append(results, StructuredResult{Result: result, Details: details})
+		if missed {
+			outcomes = append(outcomes, StructuredDescription{Type: "outcome", Value: outcome})
+			continue
+		}
+
+		// The confirmation roll, if the attack threatened a critical hit
+		var confirmed, threatened bool
+		confirmResult, err := attack.RollToConfirm(true, profile.threat, profile.bonus)
+		if err != nil {
+			return nil, err
+		}
+		if confirmResult != 0 {
+			threatened = true
+			outcome = "threat"
+			sdesc, err := attack.StructuredDescribeRoll(
+				WithAutoSF(true, "HIT", "MISS"),
+				WithRollBonus(profile.bonus))
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, StructuredResult{
+				Result:  confirmResult,
+				Details: append(StructuredDescriptionSet{label, {Type: "critlabel", Value: "Confirm:"}}, sdesc...),
+			})
+			if d.DC != 0 {
+				confirmed = d.IsNaturalMax() || (!d.IsNatural1() && confirmResult >= d.DC)
+				if confirmed {
+					outcome = "critical hit"
+				} else {
+					outcome = "hit"
+				}
+			}
+		}
+
+		// The damage roll
+		damageDice := profile.damageDice
+		damageLabel := StructuredDescription{Type: "damage", Value: "Damage:"}
+		if confirmed {
+			damageDice = profile.critDice
+			damageLabel = StructuredDescription{Type: "critdamage", Value: fmt.Sprintf("×%d", profile.multiplier)}
+		}
+		damage, err := damageDice.Roll()
+		if err != nil {
+			return nil, err
+		}
+		sdesc, err = damageDice.StructuredDescribeRoll()
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, StructuredResult{
+			Result:      damage,
+			Details:     append(StructuredDescriptionSet{label, damageLabel}, sdesc...),
+			DamageTypes: damageDice.DamageTypes(),
+		})
+		total += damage
+
+		if threatened && d.DC == 0 {
+			critDamage, err := profile.critDice.Roll()
+			if err != nil {
+				return nil, err
+			}
+			sdesc, err = profile.critDice.StructuredDescribeRoll()
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, StructuredResult{
+				Result: critDamage,
+				Details: append(StructuredDescriptionSet{
+					label,
+					{Type: "critdamage", Value: fmt.Sprintf("×%d if confirmed", profile.multiplier)},
+				}, sdesc...),
+				DamageTypes: profile.critDice.DamageTypes(),
+			})
+		}
+		outcomes = append(outcomes, StructuredDescription{Type: "outcome", Value: outcome})
+	}
+
+	summary := StructuredDescriptionSet{
+		{Type: "fullattack", Value: strconv.Itoa(len(attackDice))},
+		{Type: "result", Value: strconv.Itoa(total)},
+		{Type: "separator", Value: "="},
+	}
+	summary = append(summary, outcomes...)
+	if d.DC != 0 {
+		summary = append(summary,
+			StructuredDescription{Type: "moddelim", Value: "|"},
+			StructuredDescription{Type: "dc", Value: strconv.Itoa(d.DC)},
+		)
+	}
+	results = append(results, StructuredResult{Result: total, Details: summary})
+	return results, nil
+}
+
+// @[00]@| Go-GMA 5.33.0
+// @[01]@|
+// @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+// @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+// @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+// @[13]@| points along that historical time line.
+// @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+// @[15]@| License as described in the accompanying LICENSE file distributed
+// @[16]@| with GMA.
+// @[17]@|
+// @[20]@| Redistribution and use in source and binary forms, with or without
+// @[21]@| modification, are permitted provided that the following conditions
+// @[22]@| are met:
+// @[23]@| 1. Redistributions of source code must retain the above copyright
+// @[24]@|    notice, this list of conditions and the following disclaimer.
+// @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+// @[26]@|    right notice, this list of conditions and the following dis-
+// @[27]@|    claimer in the documentation and/or other materials provided
+// @[28]@|    with the distribution.
+// @[29]@| 3. Neither the name of the copyright holder nor the names of its
+// @[30]@|    contributors may be used to endorse or promote products derived
+// @[31]@|    from this software without specific prior written permission.
+// @[32]@|
+// @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+// @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+// @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+// @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// @[45]@| SUCH DAMAGE.
+// @[46]@|
+// @[50]@| This software is not intended for any use or application in which
+// @[51]@| the safety of lives or property would be at risk due to failure or
+// @[52]@| defect of the software.
@@ -0,0 +1,195 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package dice
+
+import (
+	"strconv"
+	"testing"
+)
+
+// attackGroups splits the results of a full attack into the results for
+// each attack (by number) and the summary.
+func attackGroups(t *testing.T, results []StructuredResult) (map[int][]StructuredResult, StructuredResult) {
+	groups := make(map[int][]StructuredResult)
+	for _, r := range results[:len(results)-1] {
+		if r.Details[0].Type != "attack" {
+			t.Fatalf("result %v is not part of an attack", r)
+		}
+		n, err := strconv.Atoi(r.Details[0].Value)
+		if err != nil {
+			t.Fatalf("result %v: %v", r, err)
+		}
+		groups[n] = append(groups[n], r)
+	}
+	summary := results[len(results)-1]
+	if summary.Details[0].Type != "fullattack" {
+		t.Fatalf("summary %v is not a full attack", summary)
+	}
+	return groups, summary
+}
+
+func TestFullAttack(t *testing.T) {
+	d, err := NewDieRoller(WithSeed(12345))
+	if err != nil {
+		t.Fatalf("Error creating new DieRoller: %v", err)
+	}
+
+	var sawCritical, sawMiss bool
+	for i := 0; i < 200; i++ {
+		_, results, err := d.DoRoll("d20+{10/5/0}|c19|full 1d8+5/1d8+5/1d6+2 x3|dc 18")
+		if err != nil {
+			t.Fatalf("roll #%d error %v", i, err)
+		}
+		groups, summary := attackGroups(t, results)
+		if len(groups) != 3 {
+			t.Fatalf("roll #%d has %d attacks: %v", i, len(groups), results)
+		}
+		total := 0
+		for n := 1; n <= 3; n++ {
+			group := groups[n]
+			outcome := summary.Details[2+n].Value
+			switch outcome {
+			case "miss":
+				sawMiss = true
+				if len(group) != 1 || group[0].Details[1].Value != "MISS" {
+					t.Errorf("roll #%d attack %d missed but has %v", i, n, group)
+				}
+			case "hit":
+				last := group[len(group)-1]
+				if last.Details[1].Type != "damage" {
+					t.Errorf("roll #%d attack %d hit but has %v", i, n, group)
+				}
+				total += last.Result
+			case "critical hit":
+				sawCritical = true
+				multiplier := "×2"
+				if n == 3 {
+					multiplier = "×3"
+				}
+				if len(group) != 3 || group[1].Details[1].Type != "critlabel" || group[1].Result < 18 && group[1].Details[2].Type != "success" {
+					t.Errorf("roll #%d attack %d critical hit but has %v", i, n, group)
+				}
+				if group[2].Details[1] != (StructuredDescription{Type: "critdamage", Value: multiplier}) {
+					t.Errorf("roll #%d attack %d critical damage %v", i, n, group[2])
+				}
+				total += group[2].Result
+			default:
+				t.Errorf("roll #%d attack %d outcome %q", i, n, outcome)
+			}
+		}
+		if summary.Result != total {
+			t.Errorf("roll #%d total damage %d, expected %d: %v", i, summary.Result, total, results)
+		}
+		if sawCritical && sawMiss {
+			break
+		}
+	}
+	if !sawCritical || !sawMiss {
+		t.Errorf("never saw a critical hit (%v) or a miss (%v)", sawCritical, sawMiss)
+	}
+
+	// Without a DC, a critical threat rolls damage both ways.
+	for i := 0; i < 200; i++ {
+		_, results, err := d.DoRoll("d20+5|c15|full 2d6+3")
+		if err != nil {
+			t.Fatalf("roll #%d error %v", i, err)
+		}
+		groups, summary := attackGroups(t, results)
+		if summary.Details[3].Value != "threat" {
+			continue
+		}
+		if len(groups[1]) != 4 || groups[1][3].Details[1] != (StructuredDescription{Type: "critdamage", Value: "×2 if confirmed"}) {
+			t.Errorf("threat results %v", results)
+		}
+		if summary.Result != groups[1][2].Result {
+			t.Errorf("threat total %d, expected %d", summary.Result, groups[1][2].Result)
+		}
+		break
+	}
+
+	// A fractional die at the start of an attack (or in parentheses)
+	// is part of the damage, not the end of the attack.
+	for spec, expected := range map[string]int{
+		"d20+{10/5/0}|full 1/2d6+3/(1/2d6)+1d8/1 / 2d4": 3,
+		"d20+{10/5}|full 1d8+1/2d6":                     2,
+		"d20+{10/5}|full 2d6 fire // 2/1d8":             2,
+	} {
+		_, results, err := d.DoRoll(spec)
+		if err != nil {
+			t.Fatalf("%q: error %v", spec, err)
+		}
+		if groups, _ := attackGroups(t, results); len(groups) != expected {
+			t.Errorf("%q has %d attacks, expected %d: %v", spec, len(groups), expected, results)
+		}
+	}
+
+	for _, spec := range []string{
+		"d20+{1/2}|full 1d4/1d4/1d4",
+		"d20|full 1d6|crit x2 1d6",
+		"d20|full 1d6|dc 10|degrees",
+		"d20|full 1d6|maximized",
+		"d20|full 1d6 x1",
+		"d20|full 1d6/",
+	} {
+		if _, _, err := d.DoRoll(spec); err == nil {
+			t.Errorf("%q: error expected, but none was raised", spec)
+		}
+	}
+}
+
+// @[00]@| Go-GMA 5.33.0
+// @[01]@|
+// @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+// @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+// @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+// @[13]@| points along that historical time line.
+// @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+// @[15]@| License as described in the accompanying LICENSE file distributed
+// @[16]@| with GMA.
+// @[17]@|
+// @[20]@| Redistribution and use in source and binary forms, with or without
+// @[21]@| modification, are permitted provided that the following conditions
+// @[22]@| are met:
+// @[23]@| 1. Redistributions of source code must retain the above copyright
+// @[24]@|    notice, this list of conditions and the following disclaimer.
+// @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+// @[26]@|    right notice, this list of conditions and the following dis-
+// @[27]@|    claimer in the documentation and/or other materials provided
+// @[28]@|    with the distribution.
+// @[29]@| 3. Neither the name of the copyright holder nor the names of its
+// @[30]@|    contributors may be used to endorse or promote products derived
+// @[31]@|    from this software without specific prior written permission.
+// @[32]@|
+// @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+// @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+// @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+// @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// @[45]@| SUCH DAMAGE.
+// @[46]@|
+// @[50]@| This software is not intended for any use or application in which
+// @[51]@| the safety of lives or property would be at risk due to failure or
+// @[52]@| defect of the software.
+//
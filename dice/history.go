@@ -0,0 +1,273 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package dice
+
+import (
+	"slices"
+	"sort"
+	"time"
+)
+
+//  ____       _ _   _   _ _     _
+// |  _ \ ___ | | | | | | (_)___| |_ ___  _ __ _   _
+// | |_) / _ \| | | | |_| | / __| __/ _ \| '__| | | |
+// |  _ < (_) | | | |  _  | \__ \ || (_) | |  | |_| |
+// |_| \_\___/|_|_| |_| |_|_|___/\__\___/|_|   \__, |
+//                                             |___/
+
+// NaturalRoll records the natural values which came up on the dice of one
+// kind rolled as part of a die-roll expression, before any bonuses or other
+// modifiers were applied.
+type NaturalRoll struct {
+	// Number of sides on the dice.
+	Sides int
+
+	// The values rolled.
+	Values []int
+}
+
+// RollHistoryEntry describes a die roll made by a user, as kept in the
+// server's roll history.
+type RollHistoryEntry struct {
+	// The user who rolled the dice.
+	User string
+
+	// The title of the die roll, if any.
+	Title string `json:",omitempty"`
+
+	// The die-roll expression as requested by the user.
+	RollSpec string
+
+	// The natural values rolled on each die.
+	Dice []NaturalRoll `json:",omitempty"`
+
+	// The total result of the roll (for rolls which produced multiple
+	// results, this is the first one).
+	Result int
+
+	// True if the roll was sent to all users.
+	ToAll bool `json:",omitempty"`
+
+	// The users the roll was sent to, if not everyone.
+	Recipients []string `json:",omitempty"`
+
+	// True if the roll was made privately to the GM.
+	ToGM bool `json:",omitempty"`
+
+	// When the roll was made.
+	When time.Time
+}
+
+// VisibleTo reports whether the given user (who is the GM if gm is true)
+// may see this roll in the history. This follows the same rules as for
+// the results when they were sent: the roll is visible if it was sent to
+// everyone, to the GM (and they are the GM), or to the user. The user who
+// made the roll may also see it, unless it was made privately to the GM,
+// since then they only got a receipt without the result.
+func (e RollHistoryEntry) VisibleTo(user string, gm bool) bool {
+	if e.ToAll || (e.ToGM && gm) || slices.Contains(e.Recipients, user) {
+		return true
+	}
+	return e.User == user && !e.ToGM
+}
+
+// naturalRecorder collects the natural values of the numbered dice rolled
+// by a DieRoller, as they are rolled. A nil *naturalRecorder ignores them.
+type naturalRecorder struct {
+	rolls []NaturalRoll
+	open  bool // the last entry in rolls is still being added to
+}
+
+// reset discards everything recorded so far.
+func (r *naturalRecorder) reset() {
+	if r != nil {
+		r.rolls = nil
+		r.open = false
+	}
+}
+
+// begin notes that a new set of dice is about to be rolled, so the next
+// value recorded starts a new entry.
+func (r *naturalRecorder) begin() {
+	if r != nil {
+		r.open = false
+	}
+}
+
+// record adds the natural value v which came up on a die with the given
+// number of sides.
+func (r *naturalRecorder) record(sides, v int) {
+	if r == nil {
+		return
+	}
+	if !r.open {
+		r.rolls = append(r.rolls, NaturalRoll{Sides: sides})
+		r.open = true
+	}
+	r.rolls[len(r.rolls)-1].Values = append(r.rolls[len(r.rolls)-1].Values, v)
+}
+
+// NaturalRolls returns the natural values which came up on each die rolled
+// by the most recent call to DoRoll, in the order they were rolled. This
+// includes every die actually rolled, even if it was later rerolled,
+// dropped, or discarded in favor of a better roll, as well as any extra dice
+// rolled for explosions or critical confirmations. Dice which are not
+// numbered (such as FATE dice) and dice which were maximized rather than
+// rolled are not included.
+func (d *DieRoller) NaturalRolls() []NaturalRoll {
+	if d.naturals == nil {
+		return nil
+	}
+	return slices.Clone(d.naturals.rolls)
+}
+
+// LuckStats summarizes the d20 rolls made by a user, for settling
+// arguments about whether someone's dice are cursed.
+type LuckStats struct {
+	// The user these statistics describe.
+	User string
+
+	// The number of die rolls made by the user, and how many individual
+	// d20s were rolled in them.
+	Rolls int
+	D20s  int
+
+	// The number of natural 20s and natural 1s rolled.
+	Natural20s int
+	Natural1s  int
+
+	// The average value of all d20s rolled (the expected value is 10.5).
+	AverageD20 float64
+
+	// The number of times each value was rolled on a d20 (D20Counts[0] is the
+	// number of 1s).
+	D20Counts [20]int
+
+	// The longest runs of consecutive d20 rolls of 11 or more (hot streak)
+	// and of 10 or less (cold streak).
+	LongestHotStreak  int
+	LongestColdStreak int
+}
+
+// LuckReport returns statistics about the d20 rolls made by each user in
+// the given roll history, sorted by user name. Streaks are counted in
+// chronological order.
+func LuckReport(history []RollHistoryEntry) []LuckStats {
+	entries := make([]RollHistoryEntry, len(history))
+	copy(entries, history)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].When.Before(entries[j].When) })
+
+	type streaks struct {
+		hot, cold int
+	}
+	stats := make(map[string]*LuckStats)
+	current := make(map[string]*streaks)
+	var users []string
+
+	for _, entry := range entries {
+		s, ok := stats[entry.User]
+		if !ok {
+			s = &LuckStats{User: entry.User}
+			stats[entry.User] = s
+			current[entry.User] = &streaks{}
+			users = append(users, entry.User)
+		}
+		streak := current[entry.User]
+		s.Rolls++
+		for _, die := range entry.Dice {
+			if die.Sides != 20 {
+				continue
+			}
+			for _, v := range die.Values {
+				if v < 1 || v > 20 {
+					continue
+				}
+				s.D20s++
+				s.D20Counts[v-1]++
+				s.AverageD20 += float64(v)
+				switch v {
+				case 1:
+					s.Natural1s++
+				case 20:
+					s.Natural20s++
+				}
+				if v > 10 {
+					streak.hot++
+					streak.cold = 0
+					s.LongestHotStreak = max(s.LongestHotStreak, streak.hot)
+				} else {
+					streak.cold++
+					streak.hot = 0
+					s.LongestColdStreak = max(s.LongestColdStreak, streak.cold)
+				}
+			}
+		}
+	}
+
+	sort.Strings(users)
+	report := make([]LuckStats, 0, len(users))
+	for _, user := range users {
+		s := stats[user]
+		if s.D20s > 0 {
+			s.AverageD20 /= float64(s.D20s)
+		}
+		report = append(report, *s)
+	}
+	return report
+}
+
+// @[00]@| Go-GMA 5.33.0
+// @[01]@|
+// @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+// @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+// @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+// @[13]@| points along that historical time line.
+// @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+// @[15]@| License as described in the accompanying LICENSE file distributed
+// @[16]@| with GMA.
+// @[17]@|
+// @[20]@| Redistribution and use in source and binary forms, with or without
+// @[21]@| modification, are permitted provided that the following conditions
+// @[22]@| are met:
+// @[23]@| 1. Redistributions of source code must retain the above copyright
+// @[24]@|    notice, this list of conditions and the following disclaimer.
+// @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+// @[26]@|    right notice, this list of conditions and the following dis-
+// @[27]@|    claimer in the documentation and/or other materials provided
+// @[28]@|    with the distribution.
+// @[29]@| 3. Neither the name of the copyright holder nor the names of its
+// @[30]@|    contributors may be used to endorse or promote products derived
+// @[31]@|    from this software without specific prior written permission.
+// @[32]@|
+// @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+// @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+// @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+// @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// @[45]@| SUCH DAMAGE.
+// @[46]@|
+// @[50]@| This software is not intended for any use or application in which
+// @[51]@| the safety of lives or property would be at risk due to failure or
+// @[52]@| defect of the software.
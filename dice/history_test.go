@@ -0,0 +1,172 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package dice
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNaturalRolls(t *testing.T) {
+	d, err := NewDieRoller(WithSeed(12345))
+	if err != nil {
+		t.Fatalf("Error creating new DieRoller: %v", err)
+	}
+
+	for i, test := range []struct {
+		Roll     string
+		Expected []NaturalRoll
+	}{
+		{"d20+3|c", []NaturalRoll{{20, []int{4}}}},
+		{"4d6kh3", []NaturalRoll{{6, []int{2, 5, 5, 6}}}},
+		{"2d20 best of 2", []NaturalRoll{{20, []int{16, 3, 7, 2}}}},
+		{">3d6 fire + 1d4 acid", []NaturalRoll{{6, []int{1, 3}}, {4, []int{2}}}},
+		{"40%", []NaturalRoll{{100, []int{86}}}},
+		{"4dF", nil},
+		{"d20|repeat 3", []NaturalRoll{{20, []int{18}}, {20, []int{18}}, {20, []int{6}}}},
+		{"3d6r<2+1", []NaturalRoll{{6, []int{5, 2, 3, 4}}}},
+		{"1/2d6+2d6!p", []NaturalRoll{{6, []int{1}}, {6, []int{5, 3}}}},
+		{"d20|maximized", nil},
+	} {
+		if _, _, err := d.DoRoll(test.Roll); err != nil {
+			t.Fatalf("test #%d error %v", i, err)
+		}
+		if rolls := d.NaturalRolls(); !reflect.DeepEqual(rolls, test.Expected) {
+			t.Errorf("test #%d (%s) natural rolls %v, expected %v", i, test.Roll, rolls, test.Expected)
+		}
+	}
+
+	// Compounded dice and critical confirmations report the natural values
+	// of every die thrown, not the totals shown in the results.
+	for i, test := range []struct {
+		Roll     string
+		Expected []NaturalRoll
+	}{
+		{"d20!!+5", []NaturalRoll{{20, []int{20, 2}}}},
+		{"d20+5|c", []NaturalRoll{{20, []int{20}}, {20, []int{2}}}},
+	} {
+		d, _ := NewDieRoller(WithSeed(103))
+		if _, _, err := d.DoRoll(test.Roll); err != nil {
+			t.Fatalf("test #%d error %v", i, err)
+		}
+		if rolls := d.NaturalRolls(); !reflect.DeepEqual(rolls, test.Expected) {
+			t.Errorf("test #%d (%s) natural rolls %v, expected %v", i, test.Roll, rolls, test.Expected)
+		}
+	}
+}
+
+func TestRollHistoryVisibility(t *testing.T) {
+	for i, test := range []struct {
+		Entry   RollHistoryEntry
+		User    string
+		GM      bool
+		Visible bool
+	}{
+		{RollHistoryEntry{User: "alice", ToAll: true}, "bob", false, true},
+		{RollHistoryEntry{User: "alice", Recipients: []string{"alice", "bob"}}, "bob", false, true},
+		{RollHistoryEntry{User: "alice", Recipients: []string{"alice", "bob"}}, "charlie", false, false},
+		{RollHistoryEntry{User: "alice", Recipients: []string{"alice", "bob"}}, "GM", true, false},
+		{RollHistoryEntry{User: "alice", Recipients: []string{"bob"}}, "alice", false, true},
+		{RollHistoryEntry{User: "alice", ToGM: true}, "GM", true, true},
+		{RollHistoryEntry{User: "alice", ToGM: true}, "bob", false, false},
+		{RollHistoryEntry{User: "alice", ToGM: true}, "alice", false, false},
+		{RollHistoryEntry{User: "GM", ToGM: true}, "GM", true, true},
+	} {
+		if v := test.Entry.VisibleTo(test.User, test.GM); v != test.Visible {
+			t.Errorf("test #%d: visible to %s (gm=%v) is %v, expected %v", i, test.User, test.GM, v, test.Visible)
+		}
+	}
+}
+
+func TestLuckReport(t *testing.T) {
+	start := time.Date(2026, 10, 1, 19, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	report := LuckReport([]RollHistoryEntry{
+		{User: "bob", When: at(3), Dice: []NaturalRoll{{20, []int{20}}}},
+		{User: "alice", When: at(1), Dice: []NaturalRoll{{20, []int{1, 2}}, {6, []int{6, 6}}}},
+		{User: "bob", When: at(0), Dice: []NaturalRoll{{20, []int{12}}}},
+		{User: "alice", When: at(2), Dice: []NaturalRoll{{20, []int{15}}}},
+		{User: "bob", When: at(2), Dice: []NaturalRoll{{20, []int{1}}}},
+		{User: "bob", When: at(4), Dice: []NaturalRoll{{20, []int{11, 19}}}},
+		{User: "carol", When: at(5), Dice: []NaturalRoll{{8, []int{3}}}},
+	})
+
+	var alice, bob LuckStats
+	alice = LuckStats{User: "alice", Rolls: 2, D20s: 3, Natural1s: 1, AverageD20: 6, LongestHotStreak: 1, LongestColdStreak: 2}
+	alice.D20Counts[0], alice.D20Counts[1], alice.D20Counts[14] = 1, 1, 1
+	bob = LuckStats{User: "bob", Rolls: 4, D20s: 5, Natural1s: 1, Natural20s: 1, AverageD20: 12.6, LongestHotStreak: 3, LongestColdStreak: 1}
+	bob.D20Counts[0], bob.D20Counts[10], bob.D20Counts[11], bob.D20Counts[18], bob.D20Counts[19] = 1, 1, 1, 1, 1
+	expected := []LuckStats{alice, bob, {User: "carol", Rolls: 1}}
+
+	if len(report) != len(expected) {
+		t.Fatalf("report has %d users, expected %d: %v", len(report), len(expected), report)
+	}
+	for i := range expected {
+		if !closeTo(report[i].AverageD20, expected[i].AverageD20) {
+			t.Errorf("%s average d20 %v, expected %v", expected[i].User, report[i].AverageD20, expected[i].AverageD20)
+		}
+		report[i].AverageD20 = expected[i].AverageD20
+		if report[i] != expected[i] {
+			t.Errorf("luck report %+v, expected %+v", report[i], expected[i])
+		}
+	}
+}
+
+// @[00]@| Go-GMA 5.33.0
+// @[01]@|
+// @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+// @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+// @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+// @[13]@| points along that historical time line.
+// @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+// @[15]@| License as described in the accompanying LICENSE file distributed
+// @[16]@| with GMA.
+// @[17]@|
+// @[20]@| Redistribution and use in source and binary forms, with or without
+// @[21]@| modification, are permitted provided that the following conditions
+// @[22]@| are met:
+// @[23]@| 1. Redistributions of source code must retain the above copyright
+// @[24]@|    notice, this list of conditions and the following disclaimer.
+// @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+// @[26]@|    right notice, this list of conditions and the following dis-
+// @[27]@|    claimer in the documentation and/or other materials provided
+// @[28]@|    with the distribution.
+// @[29]@| 3. Neither the name of the copyright holder nor the names of its
+// @[30]@|    contributors may be used to endorse or promote products derived
+// @[31]@|    from this software without specific prior written permission.
+// @[32]@|
+// @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+// @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+// @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+// @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// @[45]@| SUCH DAMAGE.
+// @[46]@|
+// @[50]@| This software is not intended for any use or application in which
+// @[51]@| the safety of lives or property would be at risk due to failure or
+// @[52]@| defect of the software.
+//
@@ -0,0 +1,245 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package dice
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The types of values a PresetParameter may have.
+const (
+	IntParameter  = "int"
+	BoolParameter = "bool"
+)
+
+// PresetParameter describes a value which is supplied each time a die-roll
+// preset is rolled, so that clients can prompt the user for it. A parameter is
+// declared where it is used in the preset's die-roll expression as
+//
+//	${name:type=default}
+//
+// as in “d20+12 + ${power_attack:int=0}*-1”. The type may be “int” (the
+// default if it is omitted) or “bool” (which is substituted as 1 for true or 0
+// for false). If there is no default value, one must be supplied when the
+// preset is rolled. Once declared, the parameter may be used again elsewhere
+// in the expression simply as “${name}”.
+type PresetParameter struct {
+	// The name of the parameter, which follows the same rules as a
+	// variable name.
+	Name string
+
+	// The type of value expected (IntParameter or BoolParameter).
+	Type string
+
+	// The value used if none is supplied. If this is empty, a value
+	// must be supplied.
+	Default string `json:",omitempty"`
+}
+
+// presetParameterPattern matches each parameter in a die-roll expression,
+// and parameterDeclPattern breaks one down into its name, type, and default.
+var (
+	presetParameterPattern = regexp.MustCompile(`\$\{([^}]*)\}`)
+	parameterDeclPattern   = regexp.MustCompile(`^\s*([\pL_][\pL\pN_]*)\s*(?::\s*(\w*)\s*)?(=\s*(.*?)\s*)?$`)
+)
+
+// Value checks that arg is a valid value for the parameter, and returns it
+// in the form it is substituted into a die-roll expression.
+func (p PresetParameter) Value(arg string) (string, error) {
+	arg = strings.TrimSpace(arg)
+	if strings.HasPrefix(arg, "(") && strings.HasSuffix(arg, ")") {
+		arg = strings.TrimSpace(arg[1 : len(arg)-1])
+	}
+	switch p.Type {
+	case IntParameter, "":
+		v, err := strconv.Atoi(arg)
+		if err != nil {
+			return "", fmt.Errorf("parameter \"%s\" must be an integer, not \"%s\"", p.Name, arg)
+		}
+		if v < 0 {
+			return "(" + strconv.Itoa(v) + ")", nil
+		}
+		return strconv.Itoa(v), nil
+
+	case BoolParameter:
+		switch strings.ToLower(arg) {
+		case "1", "t", "true", "y", "yes", "on":
+			return "1", nil
+		case "0", "f", "false", "n", "no", "off":
+			return "0", nil
+		}
+		return "", fmt.Errorf("parameter \"%s\" must be true or false, not \"%s\"", p.Name, arg)
+	}
+	return "", fmt.Errorf("parameter \"%s\" has unknown type \"%s\"", p.Name, p.Type)
+}
+
+// ParsePresetParameters returns the parameters declared in a die-roll expression
+// (see PresetParameter), in the order they first appear. It returns nil if there
+// are none.
+func ParsePresetParameters(spec string) ([]PresetParameter, error) {
+	var params []PresetParameter
+	declared := make(map[string]bool)
+
+	for _, m := range presetParameterPattern.FindAllStringSubmatch(spec, -1) {
+		f := parameterDeclPattern.FindStringSubmatch(m[1])
+		if f == nil {
+			return nil, fmt.Errorf("invalid preset parameter \"%s\"", m[0])
+		}
+		p := PresetParameter{Name: f[1], Type: f[2], Default: f[4]}
+		if p.Type == "" {
+			p.Type = IntParameter
+		}
+		if p.Type != IntParameter && p.Type != BoolParameter {
+			return nil, fmt.Errorf("preset parameter \"%s\" has unknown type \"%s\"", p.Name, p.Type)
+		}
+		if p.Default != "" {
+			if _, err := p.Value(p.Default); err != nil {
+				return nil, fmt.Errorf("invalid default value in \"%s\": %v", m[0], err)
+			}
+		}
+		isDeclaration := f[2] != "" || f[3] != ""
+
+		i := -1
+		for j := range params {
+			if params[j].Name == p.Name {
+				i = j
+				break
+			}
+		}
+		switch {
+		case i < 0:
+			params = append(params, p)
+			declared[p.Name] = isDeclaration
+		case !isDeclaration:
+		case !declared[p.Name]:
+			params[i] = p
+			declared[p.Name] = true
+		case params[i] != p:
+			return nil, fmt.Errorf("preset parameter \"%s\" is declared more than once, differently", p.Name)
+		}
+	}
+	return params, nil
+}
+
+// substituteParameters replaces the parameters in spec with the values given in args
+// (or their defaults). It is an error to supply a value for a parameter which isn't
+// declared, or to omit one which has no default.
+func substituteParameters(spec string, args map[string]string) (string, error) {
+	params, err := ParsePresetParameters(spec)
+	if err != nil {
+		return "", err
+	}
+	values := make(map[string]string)
+	for _, p := range params {
+		arg, ok := args[p.Name]
+		if !ok {
+			if p.Default == "" {
+				return "", fmt.Errorf("a value is needed for parameter \"%s\"", p.Name)
+			}
+			arg = p.Default
+		}
+		if values[p.Name], err = p.Value(arg); err != nil {
+			return "", err
+		}
+	}
+	for name := range args {
+		if _, ok := values[name]; !ok {
+			return "", fmt.Errorf("there is no parameter named \"%s\"", name)
+		}
+	}
+	if len(params) == 0 {
+		return spec, nil
+	}
+	return presetParameterPattern.ReplaceAllStringFunc(spec, func(m string) string {
+		return values[parameterDeclPattern.FindStringSubmatch(m[2 : len(m)-1])[1]]
+	}), nil
+}
+
+// SpecWithArguments returns the preset's die-roll expression with the values in
+// args substituted for its parameters, ready to be rolled. Parameters not given
+// in args take their default values. An error is returned if a value is invalid
+// or missing, or if args includes a parameter the preset doesn't have.
+func (p DieRollPreset) SpecWithArguments(args map[string]string) (string, error) {
+	spec, err := substituteParameters(p.DieRollSpec, args)
+	if err != nil {
+		return "", fmt.Errorf("die-roll preset \"%s\": %v", presetDisplayName(p.Name), err)
+	}
+	return spec, nil
+}
+
+// parsePresetArguments parses the arguments given in a reference to a preset,
+// as in the “power_attack=2, flanking=true” of “@{Greatsword: power_attack=2, flanking=true}”.
+func parsePresetArguments(text string) (map[string]string, error) {
+	args := make(map[string]string)
+	for _, arg := range strings.Split(text, ",") {
+		name, value, ok := strings.Cut(arg, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("preset argument \"%s\" should be in the form name=value", strings.TrimSpace(arg))
+		}
+		if _, dup := args[name]; dup {
+			return nil, fmt.Errorf("preset argument \"%s\" is given more than once", name)
+		}
+		args[name] = strings.TrimSpace(value)
+	}
+	return args, nil
+}
+
+// @[00]@| Go-GMA 5.33.0
+// @[01]@|
+// @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+// @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+// @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+// @[13]@| points along that historical time line.
+// @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+// @[15]@| License as described in the accompanying LICENSE file distributed
+// @[16]@| with GMA.
+// @[17]@|
+// @[20]@| Redistribution and use in source and binary forms, with or without
+// @[21]@| modification, are permitted provided that the following conditions
+// @[22]@| are met:
+// @[23]@| 1. Redistributions of source code must retain the above copyright
+// @[24]@|    notice, this list of conditions and the following disclaimer.
+// @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+// @[26]@|    right notice, this list of conditions and the following dis-
+// @[27]@|    claimer in the documentation and/or other materials provided
+// @[28]@|    with the distribution.
+// @[29]@| 3. Neither the name of the copyright holder nor the names of its
+// @[30]@|    contributors may be used to endorse or promote products derived
+// @[31]@|    from this software without specific prior written permission.
+// @[32]@|
+// @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+// @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+// @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+// @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// @[45]@| SUCH DAMAGE.
+// @[46]@|
+// @[50]@| This software is not intended for any use or application in which
+// @[51]@| the safety of lives or property would be at risk due to failure or
+// @[52]@| defect of the software.
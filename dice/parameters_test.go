@@ -0,0 +1,218 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package dice
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParsePresetParameters(t *testing.T) {
+	for i, test := range []struct {
+		spec     string
+		expected []PresetParameter
+		err      bool
+	}{
+		{spec: "d20+12"},
+		{spec: "d20+12 + ${power_attack:int=0}*-1", expected: []PresetParameter{
+			{Name: "power_attack", Type: "int", Default: "0"},
+		}},
+		{spec: "d20 + ${ bonus } + ${flanking:bool=false}*2 + ${bonus:int=1} + $STR", expected: []PresetParameter{
+			{Name: "bonus", Type: "int", Default: "1"},
+			{Name: "flanking", Type: "bool", Default: "false"},
+		}},
+		{spec: "d20 + ${x:int}", expected: []PresetParameter{{Name: "x", Type: "int"}}},
+		{spec: "d20 + ${x=-2} + ${x}", expected: []PresetParameter{{Name: "x", Type: "int", Default: "-2"}}},
+		{spec: "d20 + ${x:int=1} + ${x:int=2}", err: true},
+		{spec: "d20 + ${x:float=1}", err: true},
+		{spec: "d20 + ${x:int=many}", err: true},
+		{spec: "d20 + ${x:bool=maybe}", err: true},
+		{spec: "d20 + ${2x}", err: true},
+		{spec: "d20 + ${}", err: true},
+	} {
+		params, err := ParsePresetParameters(test.spec)
+		if test.err {
+			if err == nil {
+				t.Errorf("test #%d (%s): error expected, but none was raised", i, test.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test #%d (%s): error %v", i, test.spec, err)
+		} else if !reflect.DeepEqual(params, test.expected) {
+			t.Errorf("test #%d (%s): got %v, expected %v", i, test.spec, params, test.expected)
+		}
+	}
+}
+
+func TestPresetSpecWithArguments(t *testing.T) {
+	preset := DieRollPreset{Name: "a|Power", DieRollSpec: "Hit=d20+12 + ${power_attack:int=0}*-1 + ${flanking:bool=no}*2 + ${size}"}
+	for i, test := range []struct {
+		args     map[string]string
+		expected string
+		err      bool
+	}{
+		{args: map[string]string{"size": "1"}, expected: "Hit=d20+12 + 0*-1 + 0*2 + 1"},
+		{args: map[string]string{"size": "-1", "power_attack": "3", "flanking": "True"}, expected: "Hit=d20+12 + 3*-1 + 1*2 + (-1)"},
+		{args: map[string]string{"size": "(-1)"}, expected: "Hit=d20+12 + 0*-1 + 0*2 + (-1)"},
+		{args: nil, err: true},
+		{args: map[string]string{"size": "1", "power_attack": "lots"}, err: true},
+		{args: map[string]string{"size": "1", "flanking": "sort of"}, err: true},
+		{args: map[string]string{"size": "1", "rage": "1"}, err: true},
+	} {
+		spec, err := preset.SpecWithArguments(test.args)
+		if test.err {
+			if err == nil {
+				t.Errorf("test #%d: error expected, but none was raised", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test #%d: error %v", i, err)
+		} else if spec != test.expected {
+			t.Errorf("test #%d: got %q, expected %q", i, spec, test.expected)
+		}
+	}
+}
+
+func TestPresetParameterReferences(t *testing.T) {
+	d, err := NewDieRoller(WithPresets(
+		DieRollPreset{Name: "Greatsword", DieRollSpec: "Greatsword=d20+12 + ${power_attack:int=0}*-1 + ${flanking:bool=false}*2|c19"},
+		DieRollPreset{Name: "Greatsword: Cleave", DieRollSpec: "d20+10"},
+		DieRollPreset{Name: "Smite", DieRollSpec: "d20 + ${cha}"},
+		DieRollPreset{Name: "Power", DieRollSpec: "@{Greatsword: power_attack=${pa=1}}"},
+	))
+	if err != nil {
+		t.Fatalf("Error creating new DieRoller: %v", err)
+	}
+
+	for _, test := range []struct {
+		spec, expected string
+		err            bool
+	}{
+		{spec: "@Greatsword", expected: "Greatsword=(d20+12 + 0*-1 + 0*2)|c19"},
+		{spec: "@{Greatsword: power_attack=3, flanking=yes}+1", expected: "Greatsword=(d20+12 + 3*-1 + 1*2)+1|c19"},
+		{spec: "Hit=@{Greatsword:power_attack=2}|dc 20", expected: "Hit=(d20+12 + 2*-1 + 0*2)|c19|dc 20"},
+		{spec: "@{Greatsword: Cleave}", expected: "(d20+10)"},
+		{spec: "@{Smite: cha=4}", expected: "(d20 + 4)"},
+		{spec: "@{Power}", expected: "Greatsword=((d20+12 + 1*-1 + 0*2))|c19"},
+		{spec: "d20 + ${bonus:int=2}", expected: "d20 + 2"},
+		{spec: "d20 + ${bonus}", err: true},
+		{spec: "@Smite", err: true},
+		{spec: "@{Smite: cha=high}", err: true},
+		{spec: "@{Smite: cha=1, str=2}", err: true},
+		{spec: "@{Smite: cha}", err: true},
+	} {
+		expanded, err := d.ExpandPresets(test.spec)
+		if test.err {
+			if err == nil {
+				t.Errorf("%q: error expected, but none was raised", test.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: error %v", test.spec, err)
+		} else if expanded != test.expected {
+			t.Errorf("%q expanded to %q, expected %q", test.spec, expanded, test.expected)
+		}
+	}
+
+	label, results, err := d.DoRoll("@{Smite: cha=4} | maximized")
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+	if label != "" || len(results) != 1 || results[0].Result != 24 {
+		t.Errorf("preset roll %q %v", label, results)
+	}
+}
+
+func TestSavePresetParameters(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SaveDieRollPresetFile(&buf, []DieRollPreset{
+		{Name: "Power Attack", DieRollSpec: "d20+12 + ${power_attack:int=0}*-1"},
+		{Name: "Plain", DieRollSpec: "d20"},
+	}, DieRollPresetMetaData{}); err != nil {
+		t.Fatalf("save error %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("__DICE__:3\n")) {
+		t.Errorf("saved file has the wrong header: %q", buf.String())
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"Parameters"`)) {
+		t.Errorf("saved file does not list parameters: %q", buf.String())
+	}
+	presets, meta, err := LoadDieRollPresetFile(&buf)
+	if err != nil {
+		t.Fatalf("load error %v", err)
+	}
+	if meta.FileVersion != 3 || len(presets) != 2 {
+		t.Fatalf("loaded version %d with %d presets", meta.FileVersion, len(presets))
+	}
+	if presets[0].Parameters != nil {
+		t.Errorf("%s: unexpected parameters %v", presets[0].Name, presets[0].Parameters)
+	}
+	if expected := []PresetParameter{{Name: "power_attack", Type: "int", Default: "0"}}; !reflect.DeepEqual(presets[1].Parameters, expected) {
+		t.Errorf("%s: parameters %v, expected %v", presets[1].Name, presets[1].Parameters, expected)
+	}
+
+	if err := SaveDieRollPresetFile(&buf, []DieRollPreset{{Name: "Bad", DieRollSpec: "d20+${x:float}"}}, DieRollPresetMetaData{}); err == nil {
+		t.Errorf("saving invalid parameters: error expected, but none was raised")
+	}
+}
+
+// @[00]@| Go-GMA 5.33.0
+// @[01]@|
+// @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+// @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+// @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+// @[13]@| points along that historical time line.
+// @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+// @[15]@| License as described in the accompanying LICENSE file distributed
+// @[16]@| with GMA.
+// @[17]@|
+// @[20]@| Redistribution and use in source and binary forms, with or without
+// @[21]@| modification, are permitted provided that the following conditions
+// @[22]@| are met:
+// @[23]@| 1. Redistributions of source code must retain the above copyright
+// @[24]@|    notice, this list of conditions and the following disclaimer.
+// @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+// @[26]@|    right notice, this list of conditions and the following dis-
+// @[27]@|    claimer in the documentation and/or other materials provided
+// @[28]@|    with the distribution.
+// @[29]@| 3. Neither the name of the copyright holder nor the names of its
+// @[30]@|    contributors may be used to endorse or promote products derived
+// @[31]@|    from this software without specific prior written permission.
+// @[32]@|
+// @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+// @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+// @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+// @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// @[45]@| SUCH DAMAGE.
+// @[46]@|
+// @[50]@| This software is not intended for any use or application in which
+// @[51]@| the safety of lives or property would be at risk due to failure or
+// @[52]@| defect of the software.
+//
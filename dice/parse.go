@@ -0,0 +1,1225 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package dice
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//  ____
+// |  _ \ __ _ _ __ ___  ___
+// | |_) / _` | '__/ __|/ _ \
+// |  __/ (_| | |  \__ \  __/
+// |_|   \__,_|_|  |___/\___|
+//
+
+// An Expression is the parsed form of a die-roll specification string
+// as accepted by DieRoller.DoRoll, as returned by Parse.
+//
+// Exactly one of Chance or Value is set, depending on whether this
+// is a percentile chance roll (“40% hit”) or a normal expression.
+type Expression struct {
+	// The title given before the “=” (if any).
+	Title string
+
+	// The percentile chance roll, if this is one.
+	Chance *ChanceRoll
+
+	// The root of the expression tree, for everything but chance rolls.
+	Value Node
+
+	// The global modifiers given after the expression, in order.
+	Modifiers []Modifier
+}
+
+// A Node is an element of a parsed die-roll expression tree. Its
+// concrete type will be one of *DieTerm, *ConstantTerm, *VariableTerm,
+// *PermutationTerm, *PermutedTerm, *Group, *UnaryOp, or *BinaryOp.
+type Node interface {
+	// Position returns the column (counting runes from 1) in the
+	// original expression where this node begins.
+	Position() int
+
+	// String returns the canonical form of this part of the expression.
+	String() string
+}
+
+// DieTerm is a die roll such as “3d6”, “>1/2d20”, or “4d6kh3 fire”.
+type DieTerm struct {
+	Column int
+
+	// True if the first die is maximized (“>” prefix).
+	InitialMax bool
+
+	// Number of dice, and the divisor if given as a fraction (“1/2d6”).
+	Numerator   int
+	Denominator int `json:",omitempty"`
+
+	// Sides of the die as written: a number, “%”, “F”, or a brace-enclosed
+	// list of faces.
+	Sides string
+
+	// Per-die modifiers such as “!”, “kh3”, or “r<2”, in order.
+	Modifiers []DieModifier `json:",omitempty"`
+
+	// If nonzero, roll this many times and keep the best (or worst) result.
+	BestOf  int `json:",omitempty"`
+	WorstOf int `json:",omitempty"`
+
+	Label string `json:",omitempty"`
+}
+
+// DieModifier is one of the modifiers which may follow a die's sides, such as
+// “!>5” (Name “!”, Comparison “>”, Value 5), “kh3” (Name “kh”, Value 3),
+// or “cs>=7” (Name “cs”, Comparison “>=”, Value 7).
+type DieModifier struct {
+	Name       string `json:",omitempty"`
+	Comparison string `json:",omitempty"`
+	Value      int    `json:",omitempty"`
+}
+
+// ConstantTerm is a constant value such as “12” or “2 bonus”.
+type ConstantTerm struct {
+	Column int
+	Value  float64
+	Label  string `json:",omitempty"`
+}
+
+// VariableTerm is a variable reference such as “$STR”.
+type VariableTerm struct {
+	Column int
+	Name   string
+	Label  string `json:",omitempty"`
+}
+
+// PermutationTerm is a set of alternative values such as “{17/12/7}”, each of
+// which is substituted in turn to make separate die rolls.
+type PermutationTerm struct {
+	Column  int
+	Choices []string
+	Label   string `json:",omitempty"`
+}
+
+// PermutedTerm is a value with permutations embedded in it, such as
+// “{16/11/6}2d6” or “2d6r1{3/4}”. Since the choices are substituted into the
+// text of the value before it is understood, Alternatives holds the
+// expression which results from each combination of choices, with the
+// choices of the first permutation varying the slowest.
+type PermutedTerm struct {
+	Column       int
+	Text         string
+	Alternatives []Node
+}
+
+// Group is a parenthesized subexpression.
+type Group struct {
+	Column int
+	Expr   Node
+	Label  string `json:",omitempty"`
+}
+
+// UnaryOp is a negated subexpression. Op is always '-'.
+type UnaryOp struct {
+	Column  int
+	Op      rune
+	Operand Node
+}
+
+// BinaryOp is an operation on two subexpressions. Op is one of '+', '-',
+// '×', '÷', '≤', or '≥' (other spellings of these, such as “*” and “//”,
+// are normalized to these when parsed).
+type BinaryOp struct {
+	Column int
+	Op     rune
+	Left   Node
+	Right  Node
+}
+
+// ChanceRoll is a percentile roll such as “40% hit/miss”.
+type ChanceRoll struct {
+	Column  int
+	Percent int
+	Label   string `json:",omitempty"`
+}
+
+// Modifier is one of the global options which follow the expression after
+// a vertical bar. Name is one of “c”, “crit”, “dc”, “degrees”, “full”, “max”,
+// “maximized”, “min”, “repeat”, “sf”, “total”, or “until”. The other fields are used as
+// appropriate for that option:
+//
+//	Value      the number given to dc, max, min, repeat, total, or until
+//	Threat     the threat range given to c (or 0 for the default)
+//	Bonus      the confirmation bonus given to c
+//	Multiplier the multiplier given to crit
+//	Damage     the damage expression given to crit
+//	Attacks    the damage for each attack given to full
+//	Success    the success message given to sf
+//	Fail       the failure message given to sf
+type Modifier struct {
+	Column     int
+	Name       string
+	Value      int            `json:",omitempty"`
+	Threat     int            `json:",omitempty"`
+	Bonus      int            `json:",omitempty"`
+	Multiplier int            `json:",omitempty"`
+	Damage     Node           `json:",omitempty"`
+	Attacks    []AttackDamage `json:",omitempty"`
+	Success    string         `json:",omitempty"`
+	Fail       string         `json:",omitempty"`
+}
+
+// AttackDamage is the damage dealt by one attack in a full attack, with the
+// threat range, confirmation bonus, and critical multiplier given for it
+// (each 0 if not given).
+type AttackDamage struct {
+	Damage     Node
+	Threat     int `json:",omitempty"`
+	Bonus      int `json:",omitempty"`
+	Multiplier int `json:",omitempty"`
+}
+
+func (a AttackDamage) String() string {
+	s := a.Damage.String()
+	if a.Threat > 0 || a.Bonus != 0 {
+		s += " c"
+		if a.Threat > 0 {
+			s += strconv.Itoa(a.Threat)
+		}
+		if a.Bonus != 0 {
+			s += fmt.Sprintf("%+d", a.Bonus)
+		}
+	}
+	if a.Multiplier > 0 {
+		s += fmt.Sprintf(" x%d", a.Multiplier)
+	}
+	return s
+}
+
+// ParseError describes a problem found by Parse, including where in the
+// expression it was found.
+type ParseError struct {
+	// The expression being parsed.
+	Spec string
+
+	// The column (counting runes from 1) where the error was found.
+	Column int
+
+	// What the parser expected to see at that point, and what it found instead.
+	Expected string
+	Found    string
+
+	// An explanation of the problem, if it's not simply that the wrong thing was found.
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("error in die-roll expression \"%s\" at column %d: %s", e.Spec, e.Column, e.Message)
+	}
+	return fmt.Sprintf("error in die-roll expression \"%s\" at column %d: expected %s but found %s", e.Spec, e.Column, e.Expected, e.Found)
+}
+
+func (n *DieTerm) Position() int         { return n.Column }
+func (n *ConstantTerm) Position() int    { return n.Column }
+func (n *VariableTerm) Position() int    { return n.Column }
+func (n *PermutationTerm) Position() int { return n.Column }
+func (n *PermutedTerm) Position() int    { return n.Column }
+func (n *Group) Position() int           { return n.Column }
+func (n *UnaryOp) Position() int         { return n.Column }
+func (n *BinaryOp) Position() int        { return n.Column }
+
+func withLabel(s, label string) string {
+	if label == "" {
+		return s
+	}
+	return s + " " + label
+}
+
+func (n *DieTerm) String() string {
+	var b strings.Builder
+	if n.InitialMax {
+		b.WriteString(">")
+	}
+	b.WriteString(strconv.Itoa(n.Numerator))
+	if n.Denominator > 0 {
+		fmt.Fprintf(&b, "/%d", n.Denominator)
+	}
+	b.WriteString("d" + n.Sides)
+	for _, m := range n.Modifiers {
+		b.WriteString(m.String())
+	}
+	if n.BestOf > 0 {
+		fmt.Fprintf(&b, " best of %d", n.BestOf)
+	} else if n.WorstOf > 0 {
+		fmt.Fprintf(&b, " worst of %d", n.WorstOf)
+	}
+	return withLabel(b.String(), n.Label)
+}
+
+func (m DieModifier) String() string {
+	if m.Value == 0 && m.Comparison == "" {
+		return m.Name
+	}
+	return m.Name + m.Comparison + strconv.Itoa(m.Value)
+}
+
+func (n *ConstantTerm) String() string {
+	return withLabel(strconv.FormatFloat(n.Value, 'f', -1, 64), n.Label)
+}
+
+func (n *VariableTerm) String() string {
+	return withLabel("$"+n.Name, n.Label)
+}
+
+func (n *PermutationTerm) String() string {
+	return withLabel("{"+strings.Join(n.Choices, "/")+"}", n.Label)
+}
+
+func (n *PermutedTerm) String() string {
+	return n.Text
+}
+
+func (n *Group) String() string {
+	return withLabel("("+n.Expr.String()+")", n.Label)
+}
+
+func (n *UnaryOp) String() string {
+	if _, isBinary := n.Operand.(*BinaryOp); isBinary {
+		return "-(" + n.Operand.String() + ")"
+	}
+	return "-" + n.Operand.String()
+}
+
+func (n *BinaryOp) String() string {
+	left := n.Left.String()
+	right := n.Right.String()
+	if l, ok := n.Left.(*BinaryOp); ok && precedence(dieOperator(l.Op)) < precedence(dieOperator(n.Op)) {
+		left = "(" + left + ")"
+	}
+	if r, ok := n.Right.(*BinaryOp); ok && precedence(dieOperator(r.Op)) <= precedence(dieOperator(n.Op)) {
+		right = "(" + right + ")"
+	}
+	return left + " " + string(n.Op) + " " + right
+}
+
+func (c *ChanceRoll) String() string {
+	return withLabel(strconv.Itoa(c.Percent)+"%", c.Label)
+}
+
+func (m Modifier) String() string {
+	switch m.Name {
+	case "c":
+		s := "c"
+		if m.Threat > 0 {
+			s += strconv.Itoa(m.Threat)
+		}
+		if m.Bonus != 0 {
+			s += fmt.Sprintf("%+d", m.Bonus)
+		}
+		return s
+	case "crit":
+		return fmt.Sprintf("crit x%d %v", m.Multiplier, m.Damage)
+	case "full":
+		attacks := make([]string, len(m.Attacks))
+		for i, a := range m.Attacks {
+			attacks[i] = a.String()
+		}
+		return "full " + strings.Join(attacks, "/")
+	case "sf":
+		switch {
+		case m.Fail != "":
+			return "sf " + m.Success + "/" + m.Fail
+		case m.Success != "":
+			return "sf " + m.Success
+		}
+		return "sf"
+	case "degrees", "maximized":
+		return m.Name
+	}
+	return fmt.Sprintf("%s %d", m.Name, m.Value)
+}
+
+// String returns the expression in a canonical form, which may be given to
+// DieRoller.DoRoll (or to Parse again, yielding the same Expression).
+// For example, “Hit=d20*2+ 3|c” is formatted as “Hit = 1d20 × 2 + 3 | c”.
+func (e *Expression) String() string {
+	var b strings.Builder
+	if e.Title != "" {
+		b.WriteString(e.Title + " = ")
+	}
+	if e.Chance != nil {
+		b.WriteString(e.Chance.String())
+	} else if e.Value != nil {
+		b.WriteString(e.Value.String())
+	}
+	for _, m := range e.Modifiers {
+		b.WriteString(" | " + m.String())
+	}
+	return b.String()
+}
+
+// exprParser holds the state of a Parse call.
+// Any change to the die-roll syntax accepted by the DieRoller must be made here
+// as well; TestParseAgreesWithDieRoller and TestParseAcceptsSameExpressions
+// check that the two parsers agree.
+type exprParser struct {
+	spec  string
+	runes []rune
+	pos   int
+	end   int
+
+	reDieSpec     *regexp.Regexp
+	reConstant    *regexp.Regexp
+	reVariable    *regexp.Regexp
+	reIsBareLabel *regexp.Regexp
+	reIsDie       *regexp.Regexp
+	reMinmax      *regexp.Regexp
+	reDieModifier *regexp.Regexp
+
+	// True if permutations may appear in the expression being parsed.
+	permutations bool
+}
+
+// Parse parses a die-roll specification string of the form accepted by
+// DieRoller.DoRoll, returning the parsed Expression. This allows a program to
+// examine the expression or check it for errors without rolling any dice.
+//
+// If the expression is not valid, the error returned is a *ParseError which
+// notes where in the expression the problem was found.
+//
+// Parse does not check that variables in the expression are defined, since
+// that depends on the VariableResolver in effect when the dice are rolled.
+// Permutations (“{17/12/7}”) which take the place of an entire value are
+// given as a PermutationTerm; otherwise the value they are part of is given
+// as a PermutedTerm.
+func Parse(spec string) (*Expression, error) {
+	p := newExprParser(spec)
+	e := new(Expression)
+
+	//
+	// [<title> =] <expression> [| <modifier>]...
+	//
+	start := 0
+	for i, r := range p.runes {
+		if r == '=' && (i == 0 || (p.runes[i-1] != '<' && p.runes[i-1] != '>')) {
+			e.Title = strings.TrimSpace(string(p.runes[:i]))
+			start = i + 1
+			break
+		}
+	}
+	end := len(p.runes)
+	for i := start; i < len(p.runes); i++ {
+		if p.runes[i] == '|' {
+			if end == len(p.runes) {
+				end = i
+			}
+			modEnd := i + 1
+			for modEnd < len(p.runes) && p.runes[modEnd] != '|' {
+				modEnd++
+			}
+			m, err := p.parseModifier(i+1, modEnd)
+			if err != nil {
+				return nil, err
+			}
+			e.Modifiers = append(e.Modifiers, m)
+			i = modEnd - 1
+		}
+	}
+	if err := p.checkModifiers(e.Modifiers); err != nil {
+		return nil, err
+	}
+
+	p.pos, p.end = start, end
+	p.skipSpace()
+	if p.pos >= p.end {
+		return nil, p.errorAt(p.pos, "die-roll expression", "")
+	}
+
+	if m := regexp.MustCompile(`^(\d+)%\s*(.*?)\s*$`).FindStringSubmatch(string(p.runes[p.pos:p.end])); m != nil {
+		e.Chance = &ChanceRoll{Column: p.pos + 1, Label: m[2]}
+		e.Chance.Percent, _ = strconv.Atoi(m[1])
+		for i := p.pos; i < p.end; i++ {
+			if p.runes[i] == '{' {
+				return nil, p.explain(i, "permutations with percentile die rolls are not supported")
+			}
+		}
+		for _, mod := range e.Modifiers {
+			switch mod.Name {
+			case "c", "crit", "dc", "degrees", "full", "min", "max":
+				return nil, &ParseError{Spec: spec, Column: mod.Column, Message: fmt.Sprintf("the %s option may not be used with percentile chance rolls", mod.Name)}
+			}
+		}
+		return e, nil
+	}
+
+	var err error
+	p.permutations = true
+	if e.Value, err = p.parseExpr(1); err != nil {
+		return nil, err
+	}
+	if err = p.expectEnd(); err != nil {
+		return nil, err
+	}
+	for _, mod := range e.Modifiers {
+		switch mod.Name {
+		case "full":
+			if attacks := countPermutations(e.Value); len(mod.Attacks) > 1 && len(mod.Attacks) != attacks {
+				return nil, &ParseError{Spec: spec, Column: mod.Column, Message: fmt.Sprintf("a full attack with %d attack rolls needs one damage expression, or one for each attack (not %d)", attacks, len(mod.Attacks))}
+			}
+		case "c", "crit", "sf":
+			if count, maxCount, single := countDice(e.Value); count != 1 || maxCount != 1 || !single {
+				return nil, &ParseError{Spec: spec, Column: mod.Column, Message: fmt.Sprintf("the %s option needs a roll of a single die to tell if it was a natural 1 or maximum", mod.Name)}
+			}
+		}
+	}
+	return e, nil
+}
+
+// newExprParser sets up a parser for the given die-roll specification.
+func newExprParser(spec string) *exprParser {
+	return &exprParser{
+		spec:          spec,
+		runes:         []rune(spec),
+		reDieSpec:     regexp.MustCompile(`^\s*(>)?\s*(\d*)\s*(?:/\s*(\d+))?\s*[Dd]\s*(%|\d+|[Ff]|\{[^{}]*\})((?:` + dieModifierPattern + `)*)\s*(?:(best|worst)\s*of\s*(\d+))?\s*(.*?)\s*$`),
+		reConstant:    regexp.MustCompile(`^\s*(\d+(?:\.\d+)?|\.\d+)\s*(.*?)\s*$`),
+		reVariable:    regexp.MustCompile(`^\s*\$([\p{L}_][\p{L}\p{N}_]*)\s*(.*?)\s*$`),
+		reIsBareLabel: regexp.MustCompile(`^\s*([\p{L}_][\p{L}\p{N}_,.]*\s*)+(≡(#[0-9a-fA-F]+|\w+)){0,2}(\s*‖\s*([\p{L}_][\p{L}\p{N}_,.]*\s*)+(≡(#[0-9a-fA-F]+|\w+)){0,2})*\s*$`),
+		reIsDie:       regexp.MustCompile(`\d+\s*[dD]\d*\d+`),
+		reMinmax:      regexp.MustCompile(`\b(min|max)\s*[+-]?\d+`),
+		reDieModifier: regexp.MustCompile(`^(!!|!p|!|kh|kl|dh|dbl|dl|rr|r|cs|f)?([<>]=?|[≤≥])?(\d+)?$`),
+	}
+}
+
+// parseExpression parses spec, which is just a die-roll expression (without
+// a title or global modifiers).
+func parseExpression(spec string) (Node, error) {
+	p := newExprParser(spec)
+	p.end = len(p.runes)
+	n, err := p.parseExpr(1)
+	if err == nil {
+		err = p.expectEnd()
+	}
+	return n, err
+}
+
+// checkModifiers makes sure the global modifiers make sense together.
+func (p *exprParser) checkModifiers(mods []Modifier) error {
+	var degrees, full, notFull *Modifier
+	hasDC := false
+	for i, m := range mods {
+		switch m.Name {
+		case "dc":
+			hasDC = true
+		case "degrees":
+			degrees = &mods[i]
+		case "full":
+			full = &mods[i]
+		}
+		switch m.Name {
+		case "crit", "degrees", "maximized", "sf", "total", "until":
+			if notFull == nil {
+				notFull = &mods[i]
+			}
+		}
+	}
+	if degrees != nil && !hasDC {
+		return &ParseError{Spec: p.spec, Column: degrees.Column, Message: "you can't report degrees of success without a DC"}
+	}
+	if full != nil && notFull != nil {
+		return &ParseError{Spec: p.spec, Column: notFull.Column, Message: "a full attack can't be combined with the crit, degrees, maximized, sf, total, or until options"}
+	}
+	return nil
+}
+
+// countPermutations returns the number of different expressions which result
+// from substituting the choices of the permutations in n.
+func countPermutations(n Node) int {
+	switch n := n.(type) {
+	case *PermutationTerm:
+		return len(n.Choices)
+	case *PermutedTerm:
+		return len(n.Alternatives)
+	case *Group:
+		return countPermutations(n.Expr)
+	case *UnaryOp:
+		return countPermutations(n.Operand)
+	case *BinaryOp:
+		return countPermutations(n.Left) * countPermutations(n.Right)
+	}
+	return 1
+}
+
+// countDice returns the smallest and largest number of die rolls which may be
+// made by the expression n (which differ only if there are permutations), and
+// whether each of them is a roll of a single die (e.g., “1d20” or “2d20kh1”).
+func countDice(n Node) (minCount, maxCount int, single bool) {
+	switch n := n.(type) {
+	case *DieTerm:
+		return 1, 1, n.singleDie()
+	case *PermutedTerm:
+		single = true
+		for i, a := range n.Alternatives {
+			lo, hi, s := countDice(a)
+			if i == 0 || lo < minCount {
+				minCount = lo
+			}
+			if hi > maxCount {
+				maxCount = hi
+			}
+			single = single && s
+		}
+		return minCount, maxCount, single
+	case *Group:
+		return countDice(n.Expr)
+	case *UnaryOp:
+		return countDice(n.Operand)
+	case *BinaryOp:
+		lLo, lHi, lSingle := countDice(n.Left)
+		rLo, rHi, rSingle := countDice(n.Right)
+		return lLo + rLo, lHi + rHi, lSingle && rSingle
+	}
+	return 0, 0, true
+}
+
+// singleDie reports whether only one die of this term counts toward the result.
+func (n *DieTerm) singleDie() bool {
+	for _, m := range n.Modifiers {
+		switch m.Name {
+		case "kh", "kl":
+			return n.Numerator == 1 || m.Value == 1
+		case "dh", "dl":
+			return n.Numerator == 1 || n.Numerator-m.Value == 1
+		}
+	}
+	return n.Numerator == 1
+}
+
+// errorAt reports that something was expected at (0-origin) position pos.
+// If found is empty, the text at that position is reported.
+func (p *exprParser) errorAt(pos int, expected, found string) *ParseError {
+	if found == "" {
+		if pos >= p.end {
+			found = "end of expression"
+		} else {
+			found = strconv.Quote(strings.TrimSpace(string(p.runes[pos:p.scanValue(pos)])))
+			if found == `""` {
+				found = strconv.Quote(string(p.runes[pos]))
+			}
+		}
+	}
+	return &ParseError{Spec: p.spec, Column: pos + 1, Expected: expected, Found: found}
+}
+
+// explain reports a problem at (0-origin) position pos.
+func (p *exprParser) explain(pos int, format string, args ...any) *ParseError {
+	return &ParseError{Spec: p.spec, Column: pos + 1, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < p.end && unicode.IsSpace(p.runes[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *exprParser) expectEnd() error {
+	p.skipSpace()
+	if p.pos < p.end {
+		return p.errorAt(p.pos, "operator", "")
+	}
+	return nil
+}
+
+// peekOperator returns the binary operator at the current position (if any)
+// and the number of runes it occupies.
+func (p *exprParser) peekOperator() (rune, int) {
+	if p.pos >= p.end {
+		return 0, 0
+	}
+	next := rune(0)
+	if p.pos+1 < p.end {
+		next = p.runes[p.pos+1]
+	}
+	switch r := p.runes[p.pos]; r {
+	case '+', '-', '×', '÷', '≤', '≥':
+		return r, 1
+	case '*':
+		return '×', 1
+	case '/':
+		if next == '/' {
+			return '÷', 2
+		}
+	case '<':
+		if next == '=' {
+			return '≤', 2
+		}
+	case '>':
+		if next == '=' {
+			return '≥', 2
+		}
+	}
+	return 0, 0
+}
+
+// parseExpr parses a sequence of values separated by binary operators
+// of at least the given precedence.
+func (p *exprParser) parseExpr(minPrecedence int) (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		op, width := p.peekOperator()
+		if width == 0 || precedence(dieOperator(op)) < minPrecedence {
+			return left, nil
+		}
+		column := p.pos + 1
+		p.pos += width
+		right, err := p.parseExpr(precedence(dieOperator(op)) + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryOp{Column: column, Op: op, Left: left, Right: right}
+	}
+}
+
+func (p *exprParser) parseUnary() (Node, error) {
+	p.skipSpace()
+	if p.pos < p.end {
+		switch p.runes[p.pos] {
+		case '+':
+			p.pos++
+			return p.parseUnary()
+		case '-':
+			column := p.pos + 1
+			p.pos++
+			operand, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			return &UnaryOp{Column: column, Op: '-', Operand: operand}, nil
+		}
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (Node, error) {
+	var err error
+
+	if p.pos >= p.end {
+		return nil, p.errorAt(p.pos, "value", "")
+	}
+	start := p.pos
+	switch p.runes[p.pos] {
+	case '(':
+		p.pos++
+		g := &Group{Column: start + 1}
+		if g.Expr, err = p.parseExpr(1); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.pos >= p.end || p.runes[p.pos] != ')' {
+			return nil, p.errorAt(p.pos, "operator or ')'", "")
+		}
+		p.pos++
+		if g.Label, err = p.parseBareLabel(); err != nil {
+			return nil, err
+		}
+		return g, nil
+
+	case '{':
+		if !p.permutations {
+			return nil, p.explain(start, "permutations may only appear in the die-roll expression itself")
+		}
+		close := p.pos + 1
+		for close < p.end && p.runes[close] != '}' {
+			close++
+		}
+		if close >= p.end {
+			return nil, p.errorAt(close, "'}'", "")
+		}
+		perm := &PermutationTerm{Column: start + 1}
+		for _, choice := range permutationChoices(string(p.runes[p.pos+1 : close])) {
+			perm.Choices = append(perm.Choices, strings.TrimSpace(choice))
+		}
+		if len(perm.Choices) < 2 {
+			return nil, p.explain(start, "values in braces must have more than one value separated by slashes")
+		}
+		if end := p.scanPermutedValue(close + 1); p.isPermuted(close+1, end) || !p.isBareLabel(close+1, end) {
+			// the permutation is only part of a value
+			return p.parsePermuted(start, end)
+		}
+		for _, choice := range perm.Choices {
+			if _, err := parseExpression(choice); err != nil {
+				return nil, p.explain(start, "the value \"%s\" in braces is not valid: %v", choice, err)
+			}
+		}
+		p.pos = close + 1
+		if perm.Label, err = p.parseBareLabel(); err != nil {
+			return nil, err
+		}
+		return perm, nil
+
+	case '@':
+		return nil, p.explain(start, "preset references must be expanded (see DieRoller.ExpandPresets) before parsing")
+	}
+
+	if op, width := p.peekOperator(); width > 0 || p.runes[p.pos] == ')' || p.runes[p.pos] == '}' {
+		if width == 0 {
+			op = p.runes[p.pos]
+		}
+		return nil, p.errorAt(p.pos, "value", strconv.Quote(string(op)))
+	}
+	if end := p.scanPermutedValue(start); p.isPermuted(start, end) {
+		return p.parsePermuted(start, end)
+	}
+	p.pos = p.scanValue(p.pos)
+	return p.parseValue(start, string(p.runes[start:p.pos]))
+}
+
+// scanPermutedValue returns the position just past the value (with any label)
+// starting at pos, including any permutations embedded in it.
+func (p *exprParser) scanPermutedValue(pos int) int {
+	for {
+		pos = p.scanValue(pos)
+		if pos >= p.end || p.runes[pos] != '{' {
+			return pos
+		}
+		for pos < p.end && p.runes[pos] != '}' {
+			pos++
+		}
+		if pos < p.end {
+			pos++
+		}
+	}
+}
+
+// isPermuted reports whether there are permutations between start and end
+// (other than a die's custom faces).
+func (p *exprParser) isPermuted(start, end int) bool {
+	for _, m := range rePermutations.FindAllStringSubmatch(string(p.runes[start:end]), -1) {
+		if m[1] == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePermuted parses the value between start and end, which has permutations
+// embedded in it. Just as the DieRoller does, we substitute each combination of
+// the choices into the text and parse the result.
+func (p *exprParser) parsePermuted(start, end int) (Node, error) {
+	if !p.permutations {
+		return nil, p.explain(start, "permutations may only appear in the die-roll expression itself")
+	}
+	text := strings.TrimSpace(string(p.runes[start:end]))
+	n := &PermutedTerm{Column: start + 1, Text: text}
+	var pieces []string
+	var choices [][]string
+	prev := 0
+	for _, m := range rePermutations.FindAllStringSubmatchIndex(text, -1) {
+		if m[2] >= 0 {
+			// custom die faces
+			continue
+		}
+		c := permutationChoices(text[m[4]:m[5]])
+		if len(c) < 2 {
+			return nil, p.explain(column(start, text, m[0]), "values in braces must have more than one value separated by slashes")
+		}
+		pieces = append(pieces, text[prev:m[0]])
+		choices = append(choices, c)
+		prev = m[1]
+	}
+	pieces = append(pieces, text[prev:])
+
+	// Go through the combinations of choices, counting through the
+	// indexes in chosen like the digits of a number.
+	chosen := make([]int, len(choices))
+	for {
+		var b strings.Builder
+		for i, c := range choices {
+			b.WriteString(pieces[i] + c[chosen[i]])
+		}
+		b.WriteString(pieces[len(pieces)-1])
+		a, err := parseExpression(b.String())
+		if err != nil {
+			return nil, p.explain(start, "\"%s\" (with permutations substituted) is not valid: %v", b.String(), err)
+		}
+		n.Alternatives = append(n.Alternatives, a)
+
+		i := len(chosen) - 1
+		for ; i >= 0; i-- {
+			if chosen[i]++; chosen[i] < len(choices[i]) {
+				break
+			}
+			chosen[i] = 0
+		}
+		if i < 0 {
+			break
+		}
+	}
+	p.pos = end
+	return n, nil
+}
+
+// permutationChoices splits the text in the braces of a permutation into
+// its choices, which are separated by slashes (but “//” is division).
+func permutationChoices(text string) []string {
+	return strings.Split(strings.ReplaceAll(text, "//", "÷"), "/")
+}
+
+// scanValue returns the position just past the value (with any label)
+// starting at pos.
+func (p *exprParser) scanValue(pos int) int {
+	start := pos
+	for pos < p.end {
+		r := p.runes[pos]
+		if width := p.poolTarget(start, pos); width > 0 {
+			pos += width
+			continue
+		}
+		if r == '{' && pos > 0 && (p.runes[pos-1] == 'd' || p.runes[pos-1] == 'D') {
+			// custom die faces
+			for pos < p.end && p.runes[pos] != '}' {
+				pos++
+			}
+			if pos < p.end {
+				pos++
+			}
+			continue
+		}
+		if strings.ContainsRune("-+*×÷()≤≥{}|", r) {
+			break
+		}
+		if pos+1 < p.end && ((r == '/' && p.runes[pos+1] == '/') || ((r == '<' || r == '>') && p.runes[pos+1] == '=')) {
+			break
+		}
+		pos++
+	}
+	return pos
+}
+
+// poolTarget returns the number of runes occupied by the “>=”, “<=”, “≥”,
+// or “≤” at pos if it is part of the target number of a dice pool (i.e., it
+// immediately follows a die's “cs” modifier and is immediately followed by a
+// number), or 0 if it isn't.
+func (p *exprParser) poolTarget(start, pos int) int {
+	width := 0
+	switch p.runes[pos] {
+	case '≥', '≤':
+		width = 1
+	case '>', '<':
+		if pos+1 < p.end && p.runes[pos+1] == '=' {
+			width = 2
+		}
+	}
+	if width == 0 || pos+width >= p.end || !unicode.IsDigit(p.runes[pos+width]) || !reDieTermEnd.MatchString(string(p.runes[start:pos])) {
+		return 0
+	}
+	return width
+}
+
+// column converts a byte offset into text (which starts at rune position start)
+// into a rune position.
+func column(start int, text string, offset int) int {
+	return start + utf8.RuneCountInString(text[:offset])
+}
+
+// parseValue interprets text (which starts at position start) as a die roll,
+// constant, or variable.
+func (p *exprParser) parseValue(start int, text string) (Node, error) {
+	var err error
+
+	if x := p.reDieSpec.FindStringSubmatchIndex(text); x != nil {
+		field := func(i int) string {
+			if x[2*i] < 0 {
+				return ""
+			}
+			return text[x[2*i]:x[2*i+1]]
+		}
+		at := func(i int) int {
+			return column(start, text, x[2*i])
+		}
+
+		if loc := p.reMinmax.FindStringIndex(text); loc != nil {
+			return nil, p.explain(column(start, text, loc[0]), "min/max limits must appear after the final operator in the expression, since they apply to the entire set of dice rolls")
+		}
+
+		d := &DieTerm{Column: start + 1 + len(text) - len(strings.TrimLeftFunc(text, unicode.IsSpace)), InitialMax: field(1) != "", Numerator: 1, Sides: field(4)}
+		if field(2) != "" {
+			if d.Numerator, err = strconv.Atoi(field(2)); err != nil {
+				return nil, p.explain(at(2), "%v", err)
+			}
+		}
+		if field(3) != "" {
+			if d.Denominator, err = strconv.Atoi(field(3)); err != nil {
+				return nil, p.explain(at(3), "%v", err)
+			}
+		}
+
+		// Let the real die-roll parser check the sides and modifiers for us.
+		ds := &dieSpec{}
+		switch {
+		case d.Sides == "%":
+			ds.Sides = 100
+		case d.Sides == "F" || d.Sides == "f":
+			d.Sides = "F"
+			ds.Faces = fateFaces
+			ds.Sides = len(ds.Faces)
+		case strings.HasPrefix(d.Sides, "{"):
+			if ds.Faces, err = parseFaces(d.Sides); err != nil {
+				return nil, p.explain(at(4), "%v", err)
+			}
+			ds.Sides = len(ds.Faces)
+		default:
+			if ds.Sides, err = strconv.Atoi(d.Sides); err != nil {
+				return nil, p.explain(at(4), "%v", err)
+			}
+			if ds.Sides < 1 {
+				return nil, p.explain(at(4), "dice cannot have a nonpositive number of sides")
+			}
+		}
+		if mods := field(5); mods != "" {
+			if err = ds.parseModifiers(mods); err != nil {
+				return nil, p.explain(at(5), "%v", err)
+			}
+			for _, mod := range regexp.MustCompile(dieModifierPattern).FindAllString(mods, -1) {
+				m := p.reDieModifier.FindStringSubmatch(mod)
+				if m == nil {
+					return nil, p.explain(at(5), "invalid die modifier \"%s\"", mod)
+				}
+				dm := DieModifier{Name: m[1], Comparison: m[2]}
+				switch dm.Comparison {
+				case "≥":
+					dm.Comparison = ">="
+				case "≤":
+					dm.Comparison = "<="
+				}
+				if m[3] != "" {
+					dm.Value, _ = strconv.Atoi(m[3])
+				}
+				d.Modifiers = append(d.Modifiers, dm)
+			}
+		}
+		if field(6) != "" {
+			n, err := strconv.Atoi(field(7))
+			if err != nil {
+				return nil, p.explain(at(7), "%v", err)
+			}
+			if field(6) == "best" {
+				d.BestOf = n
+			} else {
+				d.WorstOf = n
+			}
+		}
+		if d.Label = field(8); d.Label != "" {
+			if p.reIsDie.MatchString(d.Label) {
+				return nil, p.errorAt(at(8), "operator", strconv.Quote(d.Label))
+			}
+			if !p.reIsBareLabel.MatchString(d.Label) {
+				return nil, p.explain(at(8), "label \"%s\" has illegal characters", d.Label)
+			}
+		}
+		return d, nil
+	}
+
+	if x := p.reConstant.FindStringSubmatchIndex(text); x != nil {
+		c := &ConstantTerm{Column: column(start, text, x[2]) + 1, Label: text[x[4]:x[5]]}
+		if c.Value, err = strconv.ParseFloat(text[x[2]:x[3]], 64); err != nil {
+			return nil, p.explain(column(start, text, x[2]), "%v", err)
+		}
+		if c.Label != "" && !p.reIsBareLabel.MatchString(c.Label) {
+			return nil, p.explain(column(start, text, x[4]), "label \"%s\" has illegal characters", c.Label)
+		}
+		return c, nil
+	}
+
+	if x := p.reVariable.FindStringSubmatchIndex(text); x != nil {
+		v := &VariableTerm{Column: column(start, text, x[2]), Name: text[x[2]:x[3]], Label: text[x[4]:x[5]]}
+		if v.Label != "" && !p.reIsBareLabel.MatchString(v.Label) {
+			return nil, p.explain(column(start, text, x[4]), "label \"%s\" has illegal characters", v.Label)
+		}
+		return v, nil
+	}
+
+	return nil, p.errorAt(start, "die roll, number, or $variable", strconv.Quote(strings.TrimSpace(text)))
+}
+
+// isBareLabel reports whether the text between start and end is empty or
+// a label which may follow a group or permutation.
+func (p *exprParser) isBareLabel(start, end int) bool {
+	label := strings.TrimSpace(string(p.runes[start:end]))
+	return label == "" || (p.reIsBareLabel.MatchString(label) && !p.reDieSpec.MatchString(label))
+}
+
+// parseBareLabel parses the optional label which may follow a group or permutation.
+func (p *exprParser) parseBareLabel() (string, error) {
+	start := p.pos
+	p.pos = p.scanValue(p.pos)
+	label := strings.TrimSpace(string(p.runes[start:p.pos]))
+	if label == "" {
+		return "", nil
+	}
+	if !p.reIsBareLabel.MatchString(label) || p.reDieSpec.MatchString(label) {
+		p.pos = start
+		p.skipSpace()
+		return "", p.errorAt(p.pos, "operator", "")
+	}
+	return label, nil
+}
+
+// parseAttacks parses the slash-separated list of attacks given to the full
+// option between positions start and end. Each is a damage expression,
+// possibly followed by “c<threat>[±<bonus>]” and/or “x<multiplier>”.
+func (p *exprParser) parseAttacks(start, end int) ([]AttackDamage, error) {
+	var attacks []AttackDamage
+	var err error
+	reOptions := regexp.MustCompile(`(?:\s+c(\d+)?([-+]\d+)?)?(?:\s+[x×](\d+))?\s*$`)
+	saved, savedEnd := p.pos, p.end
+	defer func() { p.pos, p.end = saved, savedEnd }()
+
+	var bounds []int
+	for _, pos := range fullAttackSeparators(p.runes[start:end]) {
+		bounds = append(bounds, start+pos)
+	}
+	for _, pos := range append(bounds, end) {
+		// the attack runs from start to pos
+		var a AttackDamage
+		text := string(p.runes[start:pos])
+		x := reOptions.FindStringSubmatchIndex(text)
+		if x[4] >= 0 {
+			a.Bonus, _ = strconv.Atoi(text[x[4]:x[5]])
+		}
+		if x[2] >= 0 {
+			a.Threat, _ = strconv.Atoi(text[x[2]:x[3]])
+		}
+		if x[6] >= 0 {
+			if a.Multiplier, _ = strconv.Atoi(text[x[6]:x[7]]); a.Multiplier < 2 {
+				return nil, p.explain(column(start, text, x[6]), "critical damage multiplier must be at least 2")
+			}
+		}
+		p.pos, p.end = start, column(start, text, x[0])
+		if a.Damage, err = p.parseExpr(1); err == nil {
+			err = p.expectEnd()
+		}
+		if err != nil {
+			return nil, err
+		}
+		attacks = append(attacks, a)
+		start = pos + 1
+	}
+	return attacks, nil
+}
+
+// parseModifier parses the global modifier between positions start and end.
+func (p *exprParser) parseModifier(start, end int) (Modifier, error) {
+	var err error
+	text := string(p.runes[start:end])
+	m := Modifier{Column: start + 1 + len(text) - len(strings.TrimLeftFunc(text, unicode.IsSpace))}
+	atoi := func(s string) int {
+		v, _ := strconv.Atoi(s)
+		return v
+	}
+
+	if f := regexp.MustCompile(`^\s*(min|max)\s*([+-]?\d+)\s*$`).FindStringSubmatch(text); f != nil {
+		m.Name, m.Value = f[1], atoi(f[2])
+	} else if f := regexp.MustCompile(`^\s*c(\d+)?([-+]\d+)?\s*$`).FindStringSubmatch(text); f != nil {
+		m.Name, m.Threat, m.Bonus = "c", atoi(f[1]), atoi(f[2])
+	} else if x := regexp.MustCompile(`^\s*crit\s*[x×]\s*(\d+)\s+(\S.*?)\s*$`).FindStringSubmatchIndex(text); x != nil {
+		m.Name, m.Multiplier = "crit", atoi(text[x[2]:x[3]])
+		if m.Multiplier < 2 {
+			return m, p.explain(column(start, text, x[2]), "critical damage multiplier must be at least 2")
+		}
+		saved, savedEnd := p.pos, p.end
+		p.pos, p.end = column(start, text, x[4]), column(start, text, x[5])
+		if m.Damage, err = p.parseExpr(1); err == nil {
+			err = p.expectEnd()
+		}
+		p.pos, p.end = saved, savedEnd
+		if err != nil {
+			return m, err
+		}
+	} else if f := regexp.MustCompile(`^\s*(total|until|repeat)\s*(-?\d+)\s*$`).FindStringSubmatch(text); f != nil {
+		if f[1] == "repeat" && strings.HasPrefix(f[2], "-") {
+			return m, p.explain(m.Column-1, "repeat count may not be negative")
+		}
+		m.Name, m.Value = f[1], atoi(f[2])
+	} else if regexp.MustCompile(`^\s*(!|maximized)\s*$`).MatchString(text) {
+		m.Name = "maximized"
+	} else if f := regexp.MustCompile(`^\s*[Dd][Cc]\s*(-?\d+)\s*$`).FindStringSubmatch(text); f != nil {
+		m.Name, m.Value = "dc", atoi(f[1])
+	} else if regexp.MustCompile(`^\s*degrees\s*$`).MatchString(text) {
+		m.Name = "degrees"
+	} else if x := regexp.MustCompile(`^\s*full\s+(\S.*?)\s*$`).FindStringSubmatchIndex(text); x != nil {
+		m.Name = "full"
+		if m.Attacks, err = p.parseAttacks(column(start, text, x[2]), column(start, text, x[3])); err != nil {
+			return m, err
+		}
+	} else if f := regexp.MustCompile(`^\s*sf(?:\s+(\S.*?)(?:/(\S.*?))?)?\s*$`).FindStringSubmatch(text); f != nil {
+		m.Name, m.Success, m.Fail = "sf", f[1], f[2]
+	} else {
+		return m, p.errorAt(m.Column-1, "global modifier (!, c, crit, dc, degrees, full, min, max, maximized, sf, total, until, or repeat)", strconv.Quote(strings.TrimSpace(text)))
+	}
+	return m, nil
+}
+
+// @[00]@| Go-GMA 5.33.0
+// @[01]@|
+// @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+// @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+// @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+// @[13]@| points along that historical time line.
+// @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+// @[15]@| License as described in the accompanying LICENSE file distributed
+// @[16]@| with GMA.
+// @[17]@|
+// @[20]@| Redistribution and use in source and binary forms, with or without
+// @[21]@| modification, are permitted provided that the following conditions
+// @[22]@| are met:
+// @[23]@| 1. Redistributions of source code must retain the above copyright
+// @[24]@|    notice, this list of conditions and the following disclaimer.
+// @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+// @[26]@|    right notice, this list of conditions and the following dis-
+// @[27]@|    claimer in the documentation and/or other materials provided
+// @[28]@|    with the distribution.
+// @[29]@| 3. Neither the name of the copyright holder nor the names of its
+// @[30]@|    contributors may be used to endorse or promote products derived
+// @[31]@|    from this software without specific prior written permission.
+// @[32]@|
+// @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+// @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+// @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+// @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// @[45]@| SUCH DAMAGE.
+// @[46]@|
+// @[50]@| This software is not intended for any use or application in which
+// @[51]@| the safety of lives or property would be at risk due to failure or
+// @[52]@| defect of the software.
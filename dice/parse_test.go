@@ -0,0 +1,433 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for i, test := range []struct {
+		Spec      string
+		Canonical string
+	}{
+		{"d20", "1d20"},
+		{"Hit=d20*2+ 3|c", "Hit = 1d20 × 2 + 3 | c"},
+		{"3d6 fire + 1d4 acid + 2 bonus", "3d6 fire + 1d4 acid + 2 bonus"},
+		{"1/2 d6", "1/2d6"},
+		{">3d6", ">3d6"},
+		{"d20 best of 2", "1d20 best of 2"},
+		{"4d6kh3", "4d6kh3"},
+		{"d10!>8r<2", "1d10!>8r<2"},
+		{"(1d6+2) fire * 2", "(1d6 + 2) fire × 2"},
+		{"-2d6+3", "-2d6 + 3"},
+		{"- (1+2)", "-(1 + 2)"},
+		{"d20+{17/12/7}|c19+2|sf", "1d20 + {17/12/7} | c19+2 | sf"},
+		{"40% hit/miss", "40% hit/miss"},
+		{"40% | until 3", "40% | until 3"},
+		{"d20+15|crit x3 2d6+8 + 2d6 sneak|dc 18", "1d20 + 15 | crit x3 2d6 + 8 + 2d6 sneak | dc 18"},
+		{"d20 | sf hit | !", "1d20 | sf hit | maximized"},
+		{"d20+9|dc 18|degrees", "1d20 + 9 | dc 18 | degrees"},
+		{"d20+{16/11/6}|c19|full 1d8+5/1d8+5 c20/1d6+2 c18+2 x3|dc 20", "1d20 + {16/11/6} | c19 | full 1d8 + 5/1d8 + 5 c20/1d6 + 2 c18+2 x3 | dc 20"},
+		{"d20+5|full 2d6//2", "1d20 + 5 | full 2d6 ÷ 2"},
+		{"d20+{10/5/0}|full 1/2d6+3/(1/2d6)+1d8/1 / 2d4", "1d20 + {10/5/0} | full 1/2d6 + 3/(1/2d6) + 1d8/1/2d4"},
+		{"d% | min 5|max 90 | repeat 3", "1d% | min 5 | max 90 | repeat 3"},
+		{"4dF+2", "4dF + 2"},
+		{"d{1,1,2,2,3,4}", "1d{1,1,2,2,3,4}"},
+		{"d20 >= 5 - $STR str", "1d20 ≥ 5 - $STR str"},
+		{"8d10cs>=7dbl10 hits", "8d10cs>=7dbl10 hits"},
+		{"8d10!cs≥7 + 6d6cs≤2", "8d10!cs>=7 + 6d6cs<=2"},
+		{"3d6<=10 + 2d10>=5", "3d6 ≤ 10 + 2d10 ≥ 5"},
+		{"(2d10)>=5", "(2d10) ≥ 5"},
+		{"10//3", "10 ÷ 3"},
+		{"1 - (2 - 3)", "1 - (2 - 3)"},
+	} {
+		e, err := Parse(test.Spec)
+		if err != nil {
+			t.Errorf("test #%d (%s): unexpected error %v", i, test.Spec, err)
+			continue
+		}
+		if s := e.String(); s != test.Canonical {
+			t.Errorf("test #%d (%s): canonical form %q, expected %q", i, test.Spec, s, test.Canonical)
+		}
+		if e2, err := Parse(e.String()); err != nil || e2.String() != e.String() {
+			t.Errorf("test #%d (%s): canonical form did not parse back to itself (%v)", i, test.Spec, err)
+		}
+
+		// The canonical form must roll exactly the same as the original.
+		d1, _ := NewDieRoller(WithSeed(42), WithVariables(Variables{"STR": 3}))
+		d2, _ := NewDieRoller(WithSeed(42), WithVariables(Variables{"STR": 3}))
+		_, r1, err1 := d1.DoRoll(test.Spec)
+		_, r2, err2 := d2.DoRoll(e.String())
+		if err1 != nil || err2 != nil || len(r1) != len(r2) {
+			t.Errorf("test #%d (%s): rolls differ: %v, %v, %v, %v", i, test.Spec, r1, r2, err1, err2)
+			continue
+		}
+		for j := range r1 {
+			if r1[j].Result != r2[j].Result {
+				t.Errorf("test #%d (%s): result #%d %d, expected %d", i, test.Spec, j, r2[j].Result, r1[j].Result)
+			}
+		}
+	}
+}
+
+func TestParseTree(t *testing.T) {
+	e, err := Parse("Dmg = 2 × (1d8+2) fire - $STR | c19")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if e.Title != "Dmg" || len(e.Modifiers) != 1 || e.Modifiers[0].Name != "c" || e.Modifiers[0].Threat != 19 || e.Modifiers[0].Column != 33 {
+		t.Errorf("title/modifiers %q %v", e.Title, e.Modifiers)
+	}
+	minus, ok := e.Value.(*BinaryOp)
+	if !ok || minus.Op != '-' || minus.Column != 24 {
+		t.Fatalf("root %#v", e.Value)
+	}
+	if v, ok := minus.Right.(*VariableTerm); !ok || v.Name != "STR" || v.Column != 26 {
+		t.Errorf("right %#v", minus.Right)
+	}
+	times, ok := minus.Left.(*BinaryOp)
+	if !ok || times.Op != '×' {
+		t.Fatalf("left %#v", minus.Left)
+	}
+	if c, ok := times.Left.(*ConstantTerm); !ok || c.Value != 2 || c.Column != 7 {
+		t.Errorf("constant %#v", times.Left)
+	}
+	g, ok := times.Right.(*Group)
+	if !ok || g.Label != "fire" || g.Column != 11 {
+		t.Fatalf("group %#v", times.Right)
+	}
+	plus, ok := g.Expr.(*BinaryOp)
+	if !ok || plus.Op != '+' {
+		t.Fatalf("group expr %#v", g.Expr)
+	}
+	if d, ok := plus.Left.(*DieTerm); !ok || d.Numerator != 1 || d.Sides != "8" || d.Column != 12 {
+		t.Errorf("die %#v", plus.Left)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for i, test := range []struct {
+		Spec     string
+		Column   int
+		Expected string
+		Message  bool
+	}{
+		{"", 1, "die-roll expression", false},
+		{"d20+", 5, "value", false},
+		{"d20 + + ", 9, "value", false},
+		{"3d6 2d6", 5, "operator", false},
+		{"(d20", 5, "operator or ')'", false},
+		{"d20)", 4, "operator", false},
+		{"d20 | foo", 7, "global modifier (!, c, crit, dc, degrees, full, min, max, maximized, sf, total, until, or repeat)", false},
+		{"d20 + xyz", 7, "die roll, number, or $variable", false},
+		{"(1) 2d6", 5, "operator", false},
+		{"d20|crit x2 d6+", 16, "value", false},
+		{"d6!>1", 3, "", true},
+		{"40% | c", 7, "", true},
+		{"{1}", 1, "", true},
+		{"d20 | crit x1 d6", 13, "", true},
+		{"d20 | full d6/", 15, "value", false},
+		{"d20 | full d6 x1", 16, "", true},
+		{"d20 min 3", 5, "", true},
+	} {
+		_, err := Parse(test.Spec)
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("test #%d (%s): expected ParseError, got %v", i, test.Spec, err)
+			continue
+		}
+		if perr.Column != test.Column || perr.Expected != test.Expected || (perr.Message != "") != test.Message {
+			t.Errorf("test #%d (%s): got column %d, expected %q, message %q", i, test.Spec, perr.Column, perr.Expected, perr.Message)
+		}
+	}
+
+	// DoRoll reports the same errors.
+	d, _ := NewDieRoller()
+	_, _, err := d.DoRoll("3d6 2d6")
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Column != 5 {
+		t.Errorf("DoRoll returned %v", err)
+	}
+}
+
+// TestParseAcceptsSameExpressions makes sure Parse and DoRoll agree on
+// which expressions are valid, including all of the examples in
+// DieRollExpressionSyntax.
+func TestParseAcceptsSameExpressions(t *testing.T) {
+	type testcase struct {
+		Spec  string
+		Valid bool
+	}
+	testcases := []testcase{
+		{"10{16/11/6}", true},
+		{"2d6r1{16/11/6}", true},
+		{"{16/11/6}2d6!", true},
+		{"d20+{16/11/6} bonus", true},
+		{"d20+{1/2}{3/4}", true},
+		{"d20 +{1//2/3}", true},
+		{"d{4,5,6}+{1/2}", true},
+		{"d20+{foo/bar}", false},
+		{"{1/2} 3d6", false},
+		{"40% {a/b}", false},
+		{"d20|crit x2 {1/2}", false},
+		{"--2d6rr<3", true},
+		{"2+--3", true},
+		{"10|degrees", false},
+		{"d20|dc 10|degrees", true},
+		{"d20+2d6|sf", false},
+		{"2d6|sf", false},
+		{"10|sf", false},
+		{"{1/2}d6|sf", false},
+		{"2d6|c", false},
+		{"d20+1d6|crit x2 1d8", false},
+		{"d20|sf", true},
+		{"(d20)|sf", true},
+		{"2d20kh1|sf", true},
+		{"4d6dl3|sf", true},
+		{"d20|full d6|sf", false},
+		{"d20|full d6|!", false},
+		{"d20|full d6|repeat 2", true},
+		{"d20+{1/2}|full d6/d6", true},
+		{"d20+{1/2}|full d6/d6/d6", false},
+		{"d0", false},
+	}
+	for i, test := range testcases {
+		_, perr := Parse(test.Spec)
+		d, _ := NewDieRoller(WithVariables(anyVariable{}))
+		_, _, err := d.DoRoll(test.Spec)
+		if (perr == nil) != test.Valid {
+			t.Errorf("test #%d (%s): Parse error %v, expected valid=%v", i, test.Spec, perr, test.Valid)
+		}
+		if (err == nil) != test.Valid {
+			t.Errorf("test #%d (%s): DoRoll error %v, expected valid=%v", i, test.Spec, err, test.Valid)
+		}
+	}
+
+	// Some of the syntax examples are fragments or examples of mistakes,
+	// so we just check that both agree about them.
+	for _, spec := range syntaxExamples() {
+		if strings.Contains(spec, "@") {
+			continue
+		}
+		_, perr := Parse(spec)
+		d, _ := NewDieRoller(WithVariables(anyVariable{}))
+		_, _, err := d.DoRoll(spec)
+		if (perr == nil) != (err == nil) {
+			t.Errorf("%s: Parse error %v, but DoRoll error %v", spec, perr, err)
+		}
+	}
+
+	// Each permutation substituted into a value is parsed separately.
+	e, err := Parse("{1/2}d6+{3/4}")
+	if err != nil {
+		t.Fatalf("Parse error %v", err)
+	}
+	b, ok := e.Value.(*BinaryOp)
+	if !ok {
+		t.Fatalf("expected BinaryOp, got %T", e.Value)
+	}
+	if p, ok := b.Left.(*PermutedTerm); !ok || len(p.Alternatives) != 2 || p.Alternatives[1].String() != "2d6" {
+		t.Errorf("expected PermutedTerm with alternatives 1d6 and 2d6, got %#v", b.Left)
+	}
+	if p, ok := b.Right.(*PermutationTerm); !ok || !slices.Equal(p.Choices, []string{"3", "4"}) {
+		t.Errorf("expected PermutationTerm with choices 3 and 4, got %#v", b.Right)
+	}
+}
+
+func TestSameProblem(t *testing.T) {
+	for i, test := range []struct {
+		err      error
+		perr     error
+		expected bool
+	}{
+		{fmt.Errorf(`expected operator before "2d6" in die-roll expression`), &ParseError{Spec: "3d6 + 2 2d6", Column: 9, Expected: "operator", Found: `"2d6"`}, true},
+		{fmt.Errorf(`label following die roll in "3d6 2d6" looks like another die roll`), &ParseError{Spec: "3d6 2d6", Column: 5, Expected: "operator", Found: `"2d6"`}, true},
+		{fmt.Errorf("critical damage multiplier must be at least 2"), &ParseError{Spec: "d20|crit x1 d6", Column: 11, Message: "critical damage multiplier must be at least 2"}, true},
+		{fmt.Errorf(`global modifier option "foo" not understood`), &ParseError{Spec: "d20 2d6|foo", Column: 5, Expected: "operator", Found: `"2d6"`}, false},
+		{fmt.Errorf("critical damage multiplier must be at least 2"), fmt.Errorf("some other error"), false},
+	} {
+		if got := sameProblem(test.err, test.perr); got != test.expected {
+			t.Errorf("test #%d: sameProblem(%v, %v) = %v, expected %v", i, test.err, test.perr, got, test.expected)
+		}
+	}
+}
+
+// anyVariable resolves every variable, since Parse doesn't check them.
+type anyVariable struct{}
+
+func (anyVariable) LookupVariable(string) (int, bool) { return 3, true }
+
+// syntaxExamples returns the examples of die-roll expressions given in
+// DieRollExpressionSyntax.
+func syntaxExamples() []string {
+	var examples []string
+	for _, m := range regexp.MustCompile(`“\*\*([^*]+)\*\*”`).FindAllStringSubmatch(DieRollExpressionSyntax, -1) {
+		if !strings.Contains(m[1], "//") {
+			examples = append(examples, m[1])
+		}
+	}
+	return examples
+}
+
+// testedSpecs returns the die-roll expressions used by the tests in this package,
+// i.e., the Roll fields of test cases and the literal strings passed to DoRoll,
+// ByDescription, and the like.
+func testedSpecs(t *testing.T) []string {
+	files, err := filepath.Glob("*_test.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var specs []string
+	addLiteral := func(e ast.Expr) {
+		if lit, ok := e.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			if spec, err := strconv.Unquote(lit.Value); err == nil && spec != "" {
+				specs = append(specs, spec)
+			}
+		}
+	}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.KeyValueExpr:
+				if key, ok := n.Key.(*ast.Ident); ok && key.Name == "Roll" {
+					addLiteral(n.Value)
+				}
+			case *ast.CallExpr:
+				var name string
+				switch fn := n.Fun.(type) {
+				case *ast.Ident:
+					name = fn.Name
+				case *ast.SelectorExpr:
+					name = fn.Sel.Name
+				}
+				switch name {
+				case "DoRoll", "ByDescription", "Roll", "RollOnce", "Parse":
+					if len(n.Args) > 0 {
+						addLiteral(n.Args[0])
+					}
+				}
+			}
+			return true
+		})
+	}
+	return specs
+}
+
+// TestParseAgreesWithDieRoller makes sure that Parse and the parser used to
+// actually roll dice accept the same expressions and understand them the same
+// way, by checking every example in DieRollExpressionSyntax and every expression
+// used in this package's tests.
+func TestParseAgreesWithDieRoller(t *testing.T) {
+	specs := append(syntaxExamples(), testedSpecs(t)...)
+	if len(specs) < 200 {
+		t.Fatalf("only found %d expressions to check", len(specs))
+	}
+	for _, spec := range specs {
+		if strings.Contains(spec, "@") {
+			// preset references are expanded before either parser sees them
+			continue
+		}
+		d1, _ := NewDieRoller(WithSeed(42), WithVariables(anyVariable{}))
+		l1, r1, err1 := d1.DoRoll(spec)
+		e, err := Parse(spec)
+		if err != nil {
+			if err1 == nil {
+				t.Errorf("%q: Parse error %v, but the DieRoller rolled it", spec, err)
+			}
+			continue
+		}
+
+		if err1 != nil {
+			t.Errorf("%q: Parse accepted it as %q, but the DieRoller error was %v", spec, e.String(), err1)
+			continue
+		}
+		d2, _ := NewDieRoller(WithSeed(42), WithVariables(anyVariable{}))
+		l2, r2, err2 := d2.DoRoll(e.String())
+		if err2 != nil {
+			t.Errorf("%q: Parse accepted it as %q, but the DieRoller error for that was %v", spec, e.String(), err2)
+			continue
+		}
+		if l1 != l2 || len(r1) != len(r2) {
+			t.Errorf("%q: canonical form %q rolls differently: %q %v, %q %v", spec, e.String(), l1, r1, l2, r2)
+			continue
+		}
+		for i := range r1 {
+			if r1[i].Result != r2[i].Result {
+				t.Errorf("%q: canonical form %q result #%d is %d, expected %d", spec, e.String(), i, r2[i].Result, r1[i].Result)
+			}
+		}
+	}
+}
+
+// @[00]@| Go-GMA 5.33.0
+// @[01]@|
+// @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+// @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+// @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+// @[13]@| points along that historical time line.
+// @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+// @[15]@| License as described in the accompanying LICENSE file distributed
+// @[16]@| with GMA.
+// @[17]@|
+// @[20]@| Redistribution and use in source and binary forms, with or without
+// @[21]@| modification, are permitted provided that the following conditions
+// @[22]@| are met:
+// @[23]@| 1. Redistributions of source code must retain the above copyright
+// @[24]@|    notice, this list of conditions and the following disclaimer.
+// @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+// @[26]@|    right notice, this list of conditions and the following dis-
+// @[27]@|    claimer in the documentation and/or other materials provided
+// @[28]@|    with the distribution.
+// @[29]@| 3. Neither the name of the copyright holder nor the names of its
+// @[30]@|    contributors may be used to endorse or promote products derived
+// @[31]@|    from this software without specific prior written permission.
+// @[32]@|
+// @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+// @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+// @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+// @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// @[45]@| SUCH DAMAGE.
+// @[46]@|
+// @[50]@| This software is not intended for any use or application in which
+// @[51]@| the safety of lives or property would be at risk due to failure or
+// @[52]@| defect of the software.
+//
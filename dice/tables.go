@@ -0,0 +1,490 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package dice
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//  ____                 _                   _____     _     _
+// |  _ \ __ _ _ __   __| | ___  _ __ ___   |_   _|_ _| |__ | | ___  ___
+// | |_) / _` | '_ \ / _` |/ _ \| '_ ` _ \    | |/ _` | '_ \| |/ _ \/ __|
+// |  _ < (_| | | | | (_| | (_) | | | | | |   | | (_| | |_) | |  __/\__ \
+// |_| \_\__,_|_| |_|\__,_|\___/|_| |_| |_|   |_|\__,_|_.__/|_|\___||___/
+//
+
+// MinimumSupportedRandomTableFileFormat and MaximumSupportedRandomTableFileFormat
+// give the range of random table file format versions this package can read.
+const (
+	MinimumSupportedRandomTableFileFormat = 1
+	MaximumSupportedRandomTableFileFormat = 1
+)
+
+// RandomTable describes a table of possible outcomes (such as random encounters
+// or treasure) from which one entry is selected at random each time the table
+// is rolled. Like DieRollPresets, these may be stored on the server or in a file.
+//
+// If Roll is given, it is a die-roll expression (such as “d100” or “2d6”) which
+// is rolled to select the entry whose Low-High range includes the result.
+// Otherwise, the entries are selected at random in proportion to their Weight values.
+//
+// The Result text of the selected entry may include die-roll expressions in
+// square brackets, such as “[2d6] goblins”, which are replaced by the result of
+// rolling them, and references to other tables by name, such as “[@Minor Treasure]”,
+// which are replaced by the result of rolling on those tables.
+type RandomTable struct {
+	// If true, this is a system-wide global table.
+	Global bool `json:",omitempty"`
+
+	// The name by which this table is identified to the user and referenced
+	// from other tables. This must be unique among that user's tables.
+	Name string
+
+	// A text description of the purpose for this table.
+	Description string `json:",omitempty"`
+
+	// The die-roll expression used to select an entry by range, or empty
+	// to select entries by weight.
+	Roll string `json:",omitempty"`
+
+	// The possible outcomes.
+	Entries []RandomTableEntry
+}
+
+// RandomTableEntry is one of the possible outcomes listed in a RandomTable.
+type RandomTableEntry struct {
+	// For tables with a Roll expression, this entry is selected by
+	// rolls from Low to High, inclusive. If High is 0, only a roll of
+	// exactly Low selects this entry.
+	Low  int `json:",omitempty"`
+	High int `json:",omitempty"`
+
+	// For tables without a Roll expression, the relative likelihood of
+	// selecting this entry. If 0, a weight of 1 is assumed.
+	Weight int `json:",omitempty"`
+
+	// The outcome text, possibly including [die-roll] expressions and
+	// [@table] references.
+	Result string
+}
+
+// span returns the range of rolls which selects this entry.
+func (e RandomTableEntry) span() (int, int) {
+	if e.High == 0 {
+		return e.Low, e.Low
+	}
+	return e.Low, e.High
+}
+
+// weight returns the relative likelihood of selecting this entry.
+func (e RandomTableEntry) weight() int {
+	if e.Weight == 0 {
+		return 1
+	}
+	return e.Weight
+}
+
+// Validate checks the table for errors such as overlapping entries, returning
+// an error describing the first problem found, or nil if the table is valid.
+func (t RandomTable) Validate() error {
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("random table has no name")
+	}
+	if len(t.Entries) == 0 {
+		return fmt.Errorf("random table \"%s\" has no entries", t.Name)
+	}
+	if t.Roll == "" {
+		for i, e := range t.Entries {
+			if e.Low != 0 || e.High != 0 {
+				return fmt.Errorf("random table \"%s\" entry #%d has a roll range but the table has no Roll expression", t.Name, i)
+			}
+			if e.Weight < 0 {
+				return fmt.Errorf("random table \"%s\" entry #%d has negative weight", t.Name, i)
+			}
+		}
+		return nil
+	}
+
+	if _, err := New(ByDescription(t.Roll)); err != nil {
+		return fmt.Errorf("random table \"%s\" roll expression: %v", t.Name, err)
+	}
+	for i, e := range t.Entries {
+		if e.Weight != 0 {
+			return fmt.Errorf("random table \"%s\" entry #%d has a weight but the table has a Roll expression", t.Name, i)
+		}
+		low, high := e.span()
+		if low > high {
+			return fmt.Errorf("random table \"%s\" entry #%d has range %d-%d", t.Name, i, low, high)
+		}
+		for j, other := range t.Entries[:i] {
+			if olow, ohigh := other.span(); low <= ohigh && olow <= high {
+				return fmt.Errorf("random table \"%s\" entries #%d and #%d overlap", t.Name, j, i)
+			}
+		}
+	}
+	return nil
+}
+
+// WithTables sets up a DieRoller with a set of random tables which may be
+// rolled by its RollTable method.
+func WithTables(tables ...RandomTable) func(*Dice) error {
+	return func(o *Dice) error {
+		o.tables = tables
+		return nil
+	}
+}
+
+// DefineTables replaces the set of random tables known to the DieRoller.
+// If more than one table has the same name, the last one given is used.
+func (d *DieRoller) DefineTables(tables []RandomTable) {
+	d.tables = make(map[string]RandomTable)
+	for _, table := range tables {
+		d.tables[table.Name] = table
+	}
+}
+
+// RollTable rolls on the named random table (which must have been defined by the
+// WithTables option or the DefineTables method), including any die rolls and other
+// tables referenced by the selected entry.
+//
+// It returns the text of the outcome (with all die rolls and table references
+// replaced by their results) and a list of StructuredResult values describing
+// the rolls made. The first of these is the roll on the named table itself, whose Details
+// begin with a “table” element naming the table and end with a “tableentry” element
+// giving the outcome text. This is followed by the results of each die roll and
+// table referenced by that entry, in turn.
+//
+// It is an error for a table to refer to itself, directly or through other tables.
+func (d *DieRoller) RollTable(name string) (string, []StructuredResult, error) {
+	return d.rollTable(name, nil)
+}
+
+func (d *DieRoller) rollTable(name string, active []string) (string, []StructuredResult, error) {
+	if slices.Contains(active, name) {
+		return "", nil, fmt.Errorf("random table \"%s\" refers to itself (%s)", name, strings.Join(append(active, name), " → "))
+	}
+	table, ok := d.tables[name]
+	if !ok {
+		return "", nil, fmt.Errorf("there is no random table named \"%s\"", name)
+	}
+	if err := table.Validate(); err != nil {
+		return "", nil, err
+	}
+
+	spec := table.Roll
+	if spec == "" {
+		total := 0
+		for _, e := range table.Entries {
+			total += e.weight()
+		}
+		if total <= 0 {
+			return "", nil, fmt.Errorf("random table \"%s\" has no entries with positive weight", name)
+		}
+		spec = "d" + strconv.Itoa(total)
+	}
+	tableDice, err := New(ByDescription(spec), withSharedGenerator(d.generator), WithVariables(d.variables))
+	if err != nil {
+		return "", nil, err
+	}
+	result, err := tableDice.Roll()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var entry *RandomTableEntry
+	if table.Roll == "" {
+		n := result
+		for i := range table.Entries {
+			if n -= table.Entries[i].weight(); n <= 0 {
+				entry = &table.Entries[i]
+				break
+			}
+		}
+	} else {
+		for i := range table.Entries {
+			if low, high := table.Entries[i].span(); low <= result && result <= high {
+				entry = &table.Entries[i]
+				break
+			}
+		}
+	}
+	if entry == nil {
+		return "", nil, fmt.Errorf("random table \"%s\" has no entry for a roll of %d", name, result)
+	}
+
+	text, moreResults, err := d.expandTableEntry(entry.Result, append(active, name))
+	if err != nil {
+		return "", nil, err
+	}
+	sdesc, err := tableDice.StructuredDescribeRoll()
+	if err != nil {
+		return "", nil, err
+	}
+	details := append(StructuredDescriptionSet{{Type: "table", Value: name}}, sdesc...)
+	details = append(details, StructuredDescription{Type: "tableentry", Value: text})
+	return text, append([]StructuredResult{{Result: result, Details: details}}, moreResults...), nil
+}
+
+// expandTableEntry replaces the [die-roll] expressions and [@table] references
+// in an entry's text with their results.
+func (d *DieRoller) expandTableEntry(entry string, active []string) (string, []StructuredResult, error) {
+	reReference := regexp.MustCompile(`\[\s*([^\[\]]*?)\s*\]`)
+	var results []StructuredResult
+	var err error
+
+	text := reReference.ReplaceAllStringFunc(entry, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+		expr := reReference.FindStringSubmatch(ref)[1]
+		if tableName, isTable := strings.CutPrefix(expr, "@"); isTable {
+			text, tableResults, tableErr := d.rollTable(strings.TrimSpace(tableName), active)
+			if tableErr != nil {
+				err = tableErr
+				return ref
+			}
+			results = append(results, tableResults...)
+			return text
+		}
+
+		exprDice, exprErr := New(ByDescription(expr), withSharedGenerator(d.generator), WithVariables(d.variables))
+		if exprErr != nil {
+			err = exprErr
+			return ref
+		}
+		value, exprErr := exprDice.Roll()
+		if exprErr != nil {
+			err = exprErr
+			return ref
+		}
+		sdesc, exprErr := exprDice.StructuredDescribeRoll()
+		if exprErr != nil {
+			err = exprErr
+			return ref
+		}
+		results = append(results, StructuredResult{Result: value, Details: sdesc})
+		return strconv.Itoa(value)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return text, results, nil
+}
+
+// WriteRandomTableFile writes a slice of random tables to the named file.
+func WriteRandomTableFile(path string, tables []RandomTable, meta DieRollPresetMetaData) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := file.Close(); err != nil {
+			fmt.Printf("WARNING: WriteRandomTableFile was unable to close the output file: %v\n", err)
+		}
+	}()
+
+	return SaveRandomTableFile(file, tables, meta)
+}
+
+// SaveRandomTableFile writes a slice of random tables to an open stream.
+// The format is the same as for die-roll preset files, except that the file
+// begins with “__TABLES__:1” and the records are of type “TABLE”.
+func SaveRandomTableFile(output io.Writer, tables []RandomTable, meta DieRollPresetMetaData) error {
+	writer := bufio.NewWriter(output)
+	writer.WriteString("__TABLES__:1\n")
+	if meta.Timestamp == 0 {
+		now := time.Now()
+		meta.Timestamp = now.Unix()
+		meta.DateTime = now.String()
+	}
+	data, err := json.MarshalIndent(meta, "", "    ")
+	if err != nil {
+		return err
+	}
+	writer.WriteString("«__META__» ")
+	writer.WriteString(string(data))
+	writer.WriteString("\n")
+
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name < tables[j].Name
+	})
+
+	for _, table := range tables {
+		data, err := json.MarshalIndent(table, "", "    ")
+		if err != nil {
+			return fmt.Errorf("unable to serialize random table \"%s\": %v", table.Name, err)
+		}
+
+		writer.WriteString("«TABLE» ")
+		writer.WriteString(string(data))
+		writer.WriteString("\n")
+	}
+	writer.WriteString("«__EOF__»\n")
+	writer.Flush()
+	return nil
+}
+
+// ReadRandomTableFile reads in and returns a slice of random tables from
+// the named file.
+func ReadRandomTableFile(path string) ([]RandomTable, DieRollPresetMetaData, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, DieRollPresetMetaData{}, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			fmt.Printf("WARNING: ReadRandomTableFile was unable to close the file: %v", err)
+		}
+	}()
+	return LoadRandomTableFile(file)
+}
+
+// LoadRandomTableFile reads in and returns a slice of random tables from
+// an open stream. Each table is checked with its Validate method as it is read.
+func LoadRandomTableFile(input io.Reader) ([]RandomTable, DieRollPresetMetaData, error) {
+	var meta DieRollPresetMetaData
+	var tables []RandomTable
+	var err error
+	var f []string
+	var v uint64
+
+	if input == nil {
+		return nil, meta, nil
+	}
+
+	startPattern := regexp.MustCompile("^__TABLES__:(\\d+)\\s*$")
+	recordPattern := regexp.MustCompile("^«(TABLE|__META__)»\\s(.+)$")
+	eofPattern := regexp.MustCompile("^«__EOF__»$")
+	scanner := bufio.NewScanner(input)
+
+	if !scanner.Scan() {
+		return nil, meta, nil
+	}
+
+	if f = startPattern.FindStringSubmatch(scanner.Text()); f == nil {
+		return nil, meta, fmt.Errorf("invalid random table file format in initial header")
+	}
+	if v, err = strconv.ParseUint(f[1], 10, 64); err != nil {
+		return nil, meta, fmt.Errorf("invalid random table file format: can't parse version \"%v\": %v", f[1], err)
+	}
+	meta.FileVersion = uint(v)
+	if v < MinimumSupportedRandomTableFileFormat || v > MaximumSupportedRandomTableFileFormat {
+		if MinimumSupportedRandomTableFileFormat == MaximumSupportedRandomTableFileFormat {
+			return nil, meta, fmt.Errorf("cannot read random table file format version %d (only version %d is supported)", v, MinimumSupportedRandomTableFileFormat)
+		}
+		return nil, meta, fmt.Errorf("cannot read random table file format version %d (only versions %d-%d are supported)", v, MinimumSupportedRandomTableFileFormat, MaximumSupportedRandomTableFileFormat)
+	}
+
+	for scanner.Scan() {
+	rescan:
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		if eofPattern.MatchString(scanner.Text()) {
+			return tables, meta, nil
+		}
+		if f = recordPattern.FindStringSubmatch(scanner.Text()); f == nil {
+			return nil, meta, fmt.Errorf("invalid random table file format: unexpected data \"%v\"", scanner.Text())
+		}
+
+		// Start of record type f[1] with start of JSON string f[2]
+		// collect more lines of JSON data...
+		var dataPacket strings.Builder
+		dataPacket.WriteString(f[2])
+
+		for scanner.Scan() {
+			if strings.HasPrefix(scanner.Text(), "«") {
+				var err error
+
+				switch f[1] {
+				case "__META__":
+					err = json.Unmarshal([]byte(dataPacket.String()), &meta)
+
+				case "TABLE":
+					var table RandomTable
+					if err = json.Unmarshal([]byte(dataPacket.String()), &table); err == nil {
+						if err = table.Validate(); err == nil {
+							tables = append(tables, table)
+						}
+					}
+
+				default:
+					return nil, meta, fmt.Errorf("invalid random table file: unexpected record type \"%s\"", f[1])
+				}
+				if err != nil {
+					return nil, meta, fmt.Errorf("invalid random table file: %v", err)
+				}
+				goto rescan
+			}
+			dataPacket.WriteString(scanner.Text())
+		}
+	}
+	return nil, meta, fmt.Errorf("invalid random table file format: unexpected end of file")
+}
+
+// @[00]@| Go-GMA 5.33.0
+// @[01]@|
+// @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+// @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+// @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+// @[13]@| points along that historical time line.
+// @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+// @[15]@| License as described in the accompanying LICENSE file distributed
+// @[16]@| with GMA.
+// @[17]@|
+// @[20]@| Redistribution and use in source and binary forms, with or without
+// @[21]@| modification, are permitted provided that the following conditions
+// @[22]@| are met:
+// @[23]@| 1. Redistributions of source code must retain the above copyright
+// @[24]@|    notice, this list of conditions and the following disclaimer.
+// @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+// @[26]@|    right notice, this list of conditions and the following dis-
+// @[27]@|    claimer in the documentation and/or other materials provided
+// @[28]@|    with the distribution.
+// @[29]@| 3. Neither the name of the copyright holder nor the names of its
+// @[30]@|    contributors may be used to endorse or promote products derived
+// @[31]@|    from this software without specific prior written permission.
+// @[32]@|
+// @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+// @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+// @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+// @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// @[45]@| SUCH DAMAGE.
+// @[46]@|
+// @[50]@| This software is not intended for any use or application in which
+// @[51]@| the safety of lives or property would be at risk due to failure or
+// @[52]@| defect of the software.
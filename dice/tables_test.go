@@ -0,0 +1,237 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package dice
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var testTables = []RandomTable{
+	{Name: "Encounter", Roll: "d6", Entries: []RandomTableEntry{
+		{Low: 1, High: 3, Result: "[2d6] goblins"},
+		{Low: 4, High: 5, Result: "an ogre with [@Treasure]"},
+		{Low: 6, Result: "nothing"},
+	}},
+	{Name: "Treasure", Entries: []RandomTableEntry{
+		{Weight: 3, Result: "[3d6] gp"},
+		{Result: "a gem"},
+	}},
+	{Name: "Ogre", Entries: []RandomTableEntry{
+		{Result: "an ogre with [@Treasure]"},
+	}},
+	{Name: "Loop", Entries: []RandomTableEntry{
+		{Result: "[@Loop2]"},
+	}},
+	{Name: "Loop2", Entries: []RandomTableEntry{
+		{Result: "[@Loop]"},
+	}},
+	{Name: "Missing", Entries: []RandomTableEntry{
+		{Result: "[@Nowhere]"},
+	}},
+}
+
+func TestRollTable(t *testing.T) {
+	d, err := NewDieRoller(WithSeed(12345), WithTables(testTables...))
+	if err != nil {
+		t.Fatalf("Error creating new DieRoller: %v", err)
+	}
+
+	type testcase struct {
+		Table   string
+		Text    string
+		Reslist []StructuredResult
+		Error   bool
+	}
+
+	testcases := []testcase{
+		// 0
+		{Table: "Encounter", Text: "nothing", Reslist: []StructuredResult{
+			{Result: 6, Details: []StructuredDescription{
+				{Type: "table", Value: "Encounter"},
+				{Type: "result", Value: "6"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "1d6"},
+				{Type: "roll", Value: "6"},
+				{Type: "tableentry", Value: "nothing"},
+			}},
+		}},
+		// 1
+		{Table: "Encounter", Text: "10 goblins", Reslist: []StructuredResult{
+			{Result: 2, Details: []StructuredDescription{
+				{Type: "table", Value: "Encounter"},
+				{Type: "result", Value: "2"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "1d6"},
+				{Type: "roll", Value: "2"},
+				{Type: "tableentry", Value: "10 goblins"},
+			}},
+			{Result: 10, Details: []StructuredDescription{
+				{Type: "result", Value: "10"},
+				{Type: "separator", Value: "="},
+				{Type: "diespec", Value: "2d6"},
+				{Type: "subtotal", Value: "10"},
+				{Type: "roll", Value: "5,5"},
+			}},
+		}},
+		// 2
+		{Table: "Loop", Error: true},
+		// 3
+		{Table: "Missing", Error: true},
+		// 4
+		{Table: "Nowhere", Error: true},
+	}
+
+	for i, test := range testcases {
+		text, results, err := d.RollTable(test.Table)
+		if test.Error {
+			if err == nil {
+				t.Fatalf("test #%d error expected, but none was raised", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test #%d error %v", i, err)
+		}
+		if text != test.Text {
+			t.Errorf("test #%d text %q, expected %q", i, text, test.Text)
+		}
+		if !compareResults(results, test.Reslist) {
+			t.Fatalf("test #%d result %v, expected %v", i, results, test.Reslist)
+		}
+	}
+
+	_, _, err = d.RollTable("Loop")
+	if err == nil || !strings.Contains(err.Error(), "Loop → Loop2 → Loop") {
+		t.Errorf("expected error describing cycle, got %v", err)
+	}
+
+	// Check that the weights are honored and references are followed.
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		text, results, err := d.RollTable("Ogre")
+		if err != nil {
+			t.Fatalf("error %v", err)
+		}
+		if len(results) < 2 || results[1].Details[0].Value != "Treasure" {
+			t.Fatalf("results %v missing treasure roll", results)
+		}
+		if strings.HasSuffix(text, " gp") {
+			counts["gp"]++
+		} else {
+			counts[text]++
+		}
+	}
+	if counts["gp"]+counts["an ogre with a gem"] != 1000 || counts["gp"] < 700 || counts["gp"] > 800 {
+		t.Errorf("unexpected distribution of results %v", counts)
+	}
+}
+
+func TestRandomTableValidate(t *testing.T) {
+	for i, table := range []RandomTable{
+		{Name: "", Entries: []RandomTableEntry{{Result: "x"}}},
+		{Name: "empty"},
+		{Name: "overlap", Roll: "d6", Entries: []RandomTableEntry{{Low: 1, High: 3}, {Low: 3, High: 6}}},
+		{Name: "backwards", Roll: "d6", Entries: []RandomTableEntry{{Low: 3, High: 1}}},
+		{Name: "mixed", Roll: "d6", Entries: []RandomTableEntry{{Low: 1, High: 6, Weight: 2}}},
+		{Name: "ranges", Entries: []RandomTableEntry{{Low: 1, High: 6}}},
+		{Name: "badroll", Roll: "d6+", Entries: []RandomTableEntry{{Low: 1}}},
+	} {
+		if err := table.Validate(); err == nil {
+			t.Errorf("table #%d (%s) expected to be invalid", i, table.Name)
+		}
+	}
+	for _, table := range testTables {
+		if err := table.Validate(); err != nil {
+			t.Errorf("table %s: %v", table.Name, err)
+		}
+	}
+}
+
+func TestRandomTableFile(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SaveRandomTableFile(&buf, testTables[:2], DieRollPresetMetaData{Comment: "test"}); err != nil {
+		t.Fatalf("save error %v", err)
+	}
+	tables, meta, err := LoadRandomTableFile(&buf)
+	if err != nil {
+		t.Fatalf("load error %v", err)
+	}
+	if meta.Comment != "test" || meta.FileVersion != 1 {
+		t.Errorf("metadata %v", meta)
+	}
+	if len(tables) != 2 || tables[0].Name != "Encounter" || tables[1].Name != "Treasure" ||
+		len(tables[0].Entries) != 3 || tables[0].Entries[1].Result != "an ogre with [@Treasure]" ||
+		tables[1].Entries[0].Weight != 3 {
+		t.Errorf("tables %v", tables)
+	}
+
+	for i, data := range []string{
+		"__DICE__:2\n«__EOF__»\n",
+		"__TABLES__:2\n«__EOF__»\n",
+		"__TABLES__:1\n«TABLE» {\"Name\": \"empty\"}\n«__EOF__»\n",
+		"__TABLES__:1\n«TABLE» {\"Name\": \"x\", \"Entries\": [{\"Result\": \"y\"}]}\n",
+	} {
+		if _, _, err := LoadRandomTableFile(strings.NewReader(data)); err == nil {
+			t.Errorf("file #%d expected to be invalid", i)
+		}
+	}
+}
+
+// @[00]@| Go-GMA 5.33.0
+// @[01]@|
+// @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+// @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+// @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+// @[13]@| points along that historical time line.
+// @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+// @[15]@| License as described in the accompanying LICENSE file distributed
+// @[16]@| with GMA.
+// @[17]@|
+// @[20]@| Redistribution and use in source and binary forms, with or without
+// @[21]@| modification, are permitted provided that the following conditions
+// @[22]@| are met:
+// @[23]@| 1. Redistributions of source code must retain the above copyright
+// @[24]@|    notice, this list of conditions and the following disclaimer.
+// @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+// @[26]@|    right notice, this list of conditions and the following dis-
+// @[27]@|    claimer in the documentation and/or other materials provided
+// @[28]@|    with the distribution.
+// @[29]@| 3. Neither the name of the copyright holder nor the names of its
+// @[30]@|    contributors may be used to endorse or promote products derived
+// @[31]@|    from this software without specific prior written permission.
+// @[32]@|
+// @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+// @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+// @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+// @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// @[45]@| SUCH DAMAGE.
+// @[46]@|
+// @[50]@| This software is not intended for any use or application in which
+// @[51]@| the safety of lives or property would be at risk due to failure or
+// @[52]@| defect of the software.
+//
@@ -0,0 +1,174 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package dice
+
+import (
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"slices"
+)
+
+// __     __        _  __ _       _     _        ____       _ _
+// \ \   / /__ _ __(_)/ _(_) __ _| |__ | | ___  |  _ \ ___ | | |___
+//  \ \ / / _ \ '__| | |_| |/ _` | '_ \| |/ _ \ | |_) / _ \| | / __|
+//   \ V /  __/ |  | |  _| | (_| | |_) | |  __/ |  _ < (_) | | \__ \
+//    \_/ \___|_|  |_|_| |_|\__,_|_.__/|_|\___| |_| \_\___/|_|_|___/
+//
+
+// SeedCommitment holds the secret seed used for a series of verifiable
+// die rolls, along with a random salt. The hash of these values (as returned
+// by the Commitment method) can be published before any dice are rolled.
+// Once the seed and salt are revealed afterward, anyone can confirm that they
+// match the commitment and replay each roll to check that the results
+// reported were really the ones rolled.
+//
+// Each roll in the series is identified by a sequence number, and is rolled
+// using a generator seeded from the secret seed and that number, so any roll
+// may be checked on its own without knowing what other rolls were made.
+type SeedCommitment struct {
+	Seed int64
+	Salt string
+}
+
+// RollVerification holds the information needed to replay a verifiable die
+// roll: the commitment to the seed it was rolled under, its sequence number
+// in that series of rolls, and the die-roll expression which was rolled (with
+// any preset references already expanded).
+type RollVerification struct {
+	Commitment string
+	Sequence   int
+	RollSpec   string
+}
+
+// NewSeedCommitment returns a SeedCommitment with a new random seed and salt.
+func NewSeedCommitment() (SeedCommitment, error) {
+	var seed [8]byte
+	var salt [16]byte
+
+	if _, err := cryptorand.Read(seed[:]); err != nil {
+		return SeedCommitment{}, err
+	}
+	if _, err := cryptorand.Read(salt[:]); err != nil {
+		return SeedCommitment{}, err
+	}
+	return SeedCommitment{
+		Seed: int64(binary.BigEndian.Uint64(seed[:])),
+		Salt: hex.EncodeToString(salt[:]),
+	}, nil
+}
+
+// Commitment returns the value which may be published to commit to the seed
+// without revealing it. This is the SHA-256 hash of the salt, a colon, and the
+// seed in decimal, expressed in hexadecimal.
+func (s SeedCommitment) Commitment() string {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s:%d", s.Salt, s.Seed)))
+	return hex.EncodeToString(h[:])
+}
+
+// RollSeed returns the seed used to roll the dice for the roll with the given
+// sequence number.
+func (s SeedCommitment) RollSeed(sequence int) int64 {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%d:%d", s.Seed, sequence)))
+	return int64(binary.BigEndian.Uint64(h[:8]))
+}
+
+// DieRoller returns a new DieRoller set up to make the roll with the given
+// sequence number. Any additional options are passed to NewDieRoller.
+func (s SeedCommitment) DieRoller(sequence int, options ...func(*Dice) error) (*DieRoller, error) {
+	return NewDieRoller(append(slices.Clone(options), WithSeed(s.RollSeed(sequence)))...)
+}
+
+// Verify replays the die roll described by v and returns an error if the
+// results it reported don't match what the dice actually rolled, or if it
+// wasn't made under this seed at all.
+//
+// Preset references in the results are ignored, since v.RollSpec already
+// includes the expanded presets.
+func (s SeedCommitment) Verify(v RollVerification, results []StructuredResult) error {
+	if v.Commitment != s.Commitment() {
+		return fmt.Errorf("roll #%d was made under commitment %s, not %s", v.Sequence, v.Commitment, s.Commitment())
+	}
+	roller, err := s.DieRoller(v.Sequence)
+	if err != nil {
+		return err
+	}
+	_, expected, err := roller.DoRoll(v.RollSpec)
+	if err != nil {
+		return fmt.Errorf("unable to replay roll #%d (%s): %v", v.Sequence, v.RollSpec, err)
+	}
+	if len(results) != len(expected) {
+		return fmt.Errorf("roll #%d (%s) reported %d results but the dice produced %d", v.Sequence, v.RollSpec, len(results), len(expected))
+	}
+	withoutPresets := func(details StructuredDescriptionSet) StructuredDescriptionSet {
+		return slices.DeleteFunc(slices.Clone(details), func(d StructuredDescription) bool { return d.Type == "preset" })
+	}
+	for i := range results {
+		if results[i].Result != expected[i].Result ||
+			results[i].ResultSuppressed != expected[i].ResultSuppressed ||
+			results[i].InvalidRequest != expected[i].InvalidRequest ||
+			!slices.Equal(withoutPresets(results[i].Details), withoutPresets(expected[i].Details)) {
+			return fmt.Errorf("result #%d of roll #%d (%s) does not match the dice rolled: reported %v, but the dice produced %v",
+				i+1, v.Sequence, v.RollSpec, results[i], expected[i])
+		}
+	}
+	return nil
+}
+
+// @[00]@| Go-GMA 5.33.0
+// @[01]@|
+// @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+// @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+// @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+// @[13]@| points along that historical time line.
+// @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+// @[15]@| License as described in the accompanying LICENSE file distributed
+// @[16]@| with GMA.
+// @[17]@|
+// @[20]@| Redistribution and use in source and binary forms, with or without
+// @[21]@| modification, are permitted provided that the following conditions
+// @[22]@| are met:
+// @[23]@| 1. Redistributions of source code must retain the above copyright
+// @[24]@|    notice, this list of conditions and the following disclaimer.
+// @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+// @[26]@|    right notice, this list of conditions and the following dis-
+// @[27]@|    claimer in the documentation and/or other materials provided
+// @[28]@|    with the distribution.
+// @[29]@| 3. Neither the name of the copyright holder nor the names of its
+// @[30]@|    contributors may be used to endorse or promote products derived
+// @[31]@|    from this software without specific prior written permission.
+// @[32]@|
+// @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+// @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+// @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+// @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// @[45]@| SUCH DAMAGE.
+// @[46]@|
+// @[50]@| This software is not intended for any use or application in which
+// @[51]@| the safety of lives or property would be at risk due to failure or
+// @[52]@| defect of the software.
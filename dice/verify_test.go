@@ -0,0 +1,130 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package dice
+
+import (
+	"testing"
+)
+
+func TestSeedCommitment(t *testing.T) {
+	s := SeedCommitment{Seed: 12345, Salt: "pepper"}
+	if c := s.Commitment(); c != "443d71e54221ce0889ab87795f7a9ad8e7a48524a7e79ebad13711c9bac55548" {
+		t.Errorf("commitment %q is not the SHA-256 hash of \"pepper:12345\"", c)
+	}
+	if s.Commitment() != (SeedCommitment{Seed: 12345, Salt: "pepper"}).Commitment() {
+		t.Errorf("commitment is not repeatable")
+	}
+	if s.Commitment() == (SeedCommitment{Seed: 12346, Salt: "pepper"}).Commitment() ||
+		s.Commitment() == (SeedCommitment{Seed: 12345, Salt: "salt"}).Commitment() {
+		t.Errorf("commitment does not depend on both the seed and salt")
+	}
+	if s.RollSeed(1) == s.RollSeed(2) {
+		t.Errorf("roll seeds are the same for different rolls")
+	}
+
+	n, err := NewSeedCommitment()
+	if err != nil {
+		t.Fatalf("NewSeedCommitment error %v", err)
+	}
+	if n.Salt == "" || n.Commitment() == s.Commitment() {
+		t.Errorf("NewSeedCommitment returned %v", n)
+	}
+}
+
+func TestVerifyRoll(t *testing.T) {
+	s := SeedCommitment{Seed: 12345, Salt: "pepper"}
+	roller, err := s.DieRoller(42, WithPresets(DieRollPreset{Name: "Attack", DieRollSpec: "d20+5"}))
+	if err != nil {
+		t.Fatalf("Error creating new DieRoller: %v", err)
+	}
+	_, results, err := roller.DoRoll("@Attack + 2|c")
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+	expanded, err := roller.ExpandPresets("@Attack + 2|c")
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+	v := RollVerification{Commitment: s.Commitment(), Sequence: 42, RollSpec: expanded}
+
+	if err := s.Verify(v, results); err != nil {
+		t.Errorf("verification of genuine roll failed: %v", err)
+	}
+
+	v.Sequence = 43
+	if err := s.Verify(v, results); err == nil {
+		t.Errorf("verification with the wrong sequence number succeeded")
+	}
+	v.Sequence = 42
+
+	if err := (SeedCommitment{Seed: 12346, Salt: "pepper"}).Verify(v, results); err == nil {
+		t.Errorf("verification with the wrong seed succeeded")
+	}
+
+	results[0].Result++
+	if err := s.Verify(v, results); err == nil {
+		t.Errorf("verification of altered result succeeded")
+	}
+	results[0].Result--
+
+	if err := s.Verify(v, results[:0]); err == nil {
+		t.Errorf("verification of missing results succeeded")
+	}
+}
+
+// @[00]@| Go-GMA 5.33.0
+// @[01]@|
+// @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+// @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+// @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+// @[13]@| points along that historical time line.
+// @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+// @[15]@| License as described in the accompanying LICENSE file distributed
+// @[16]@| with GMA.
+// @[17]@|
+// @[20]@| Redistribution and use in source and binary forms, with or without
+// @[21]@| modification, are permitted provided that the following conditions
+// @[22]@| are met:
+// @[23]@| 1. Redistributions of source code must retain the above copyright
+// @[24]@|    notice, this list of conditions and the following disclaimer.
+// @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+// @[26]@|    right notice, this list of conditions and the following dis-
+// @[27]@|    claimer in the documentation and/or other materials provided
+// @[28]@|    with the distribution.
+// @[29]@| 3. Neither the name of the copyright holder nor the names of its
+// @[30]@|    contributors may be used to endorse or promote products derived
+// @[31]@|    from this software without specific prior written permission.
+// @[32]@|
+// @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+// @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+// @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+// @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// @[45]@| SUCH DAMAGE.
+// @[46]@|
+// @[50]@| This software is not intended for any use or application in which
+// @[51]@| the safety of lives or property would be at risk due to failure or
+// @[52]@| defect of the software.
+//
@@ -0,0 +1,322 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+//
+// Unit tests for batching of oversized messages.
+//
+
+package mapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MadScienceZone/go-gma/v5/dice"
+)
+
+// bigText is more than enough text to push a message over MaxServerMessageSize,
+// including characters which need to be escaped in JSON.
+var bigText = strings.Repeat("The \"quick\" brown fox <jumps> over the \\lazy\\ dog\t—ünïcödé. ", 2000)
+
+func bigList(n int) []string {
+	l := make([]string, n)
+	for i := range l {
+		l[i] = fmt.Sprintf("item-%d-%s", i, strings.Repeat("x", 40))
+	}
+	return l
+}
+
+func bigPoints(n int) []Coordinates {
+	p := make([]Coordinates, n)
+	for i := range p {
+		p[i] = Coordinates{X: float64(i) * 1.5, Y: float64(n-i) / 3}
+	}
+	return p
+}
+
+func bigPresets(n int) []dice.DieRollPreset {
+	p := make([]dice.DieRollPreset, n)
+	for i := range p {
+		p[i] = dice.DieRollPreset{
+			Name:        fmt.Sprintf("preset %d", i),
+			Description: "a fairly long description of what this die-roll preset is for",
+			DieRollSpec: fmt.Sprintf("d20+%d", i),
+		}
+	}
+	return p
+}
+
+func bigElement(id string) MapElement {
+	return MapElement{
+		BaseMapObject: BaseMapObject{ID: id},
+		Coordinates:   Coordinates{X: 1, Y: 2},
+		Points:        bigPoints(5000),
+		Line:          "black",
+		Fill:          "#336699",
+		Layer:         "walls",
+	}
+}
+
+// sendAndReceive sends a message from one end of a connection and returns what
+// arrives at the other end.
+func sendAndReceive(t *testing.T, command ServerMessage, data any) (MessagePayload, int, error) {
+	t.Helper()
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	sender := newTestMapConnection(client)
+	receiver := newTestMapConnection(server)
+
+	sent := make(chan int, 1)
+	go func() {
+		defer close(sent)
+		if err := sender.Send(command, data); err != nil {
+			t.Errorf("send: %v", err)
+			return
+		}
+		sent <- len(sender.sendChan)
+		if err := sender.Flush(); err != nil {
+			t.Errorf("flush: %v", err)
+		}
+	}()
+	p, err := receiver.Receive()
+	return p, <-sent, err
+}
+
+func TestBatchedPayloads(t *testing.T) {
+	for _, test := range []struct {
+		Name    string
+		Command ServerMessage
+		Data    any
+	}{
+		{"AddImage", AddImage, ImageDefinition{Name: "map", Sizes: []ImageInstance{{Zoom: 1, File: bigText}, {Zoom: 2, File: "x", ImageData: []byte(bigText)}}}},
+		{"AddDicePresets", AddDicePresets, AddDicePresetsMessagePayload{For: "alice", Presets: bigPresets(1000)}},
+		{"AddObjAttributes", AddObjAttributes, AddObjAttributesMessagePayload{ObjID: "obj1", AttrName: "Points", Values: bigList(2000)}},
+		{"ChatMessage", ChatMessage, ChatMessageMessagePayload{ChatCommon: ChatCommon{Sender: "alice", Recipients: []string{"bob"}}, Text: bigText}},
+		{"DefineDicePresetDelegates", DefineDicePresetDelegates, DefineDicePresetDelegatesMessagePayload{For: "alice", Delegates: bigList(2000)}},
+		{"DefineDicePresets", DefineDicePresets, DefineDicePresetsMessagePayload{For: "alice", Presets: bigPresets(1000)}},
+		{"Echo", Echo, EchoMessagePayload{B: true, I: 42, S: bigText, O: map[string]any{"list": bigList(10)}}},
+		{"HitPointRequest", HitPointRequest, HitPointRequestMessagePayload{Description: bigText, Targets: []string{"bob"}, RequestID: "r1", Health: &HitPointHealthRequest{MaxHP: 10}}},
+		{"LoadArcObject", LoadArcObject, ArcElement{MapElement: bigElement("arc"), Start: 10, Extent: 90}},
+		{"LoadCircleObject", LoadCircleObject, CircleElement{MapElement: bigElement("circ")}},
+		{"LoadLineObject", LoadLineObject, LineElement{MapElement: bigElement("line")}},
+		{"LoadPolygonObject", LoadPolygonObject, PolygonElement{MapElement: bigElement("poly")}},
+		{"LoadRectangleObject", LoadRectangleObject, RectangleElement{MapElement: bigElement("rect")}},
+		{"LoadSpellAreaOfEffectObject", LoadSpellAreaOfEffectObject, SpellAreaOfEffectElement{MapElement: bigElement("saoe")}},
+		{"LoadTextObject", LoadTextObject, TextElement{MapElement: MapElement{BaseMapObject: BaseMapObject{ID: "text"}}, Text: bigText}},
+		{"LoadTileObject", LoadTileObject, TileElement{MapElement: bigElement("tile"), Image: "floor"}},
+		{"QueryImage", QueryImage, ImageDefinition{Name: bigText, Sizes: []ImageInstance{{Zoom: 1}}}},
+		{"TimerRequest", TimerRequest, TimerRequestMessagePayload{RequestID: "t1", Description: bigText, Expires: "1h", Targets: []string{"*"}}},
+		{"PlaceSomeone", PlaceSomeone, CreatureToken{BaseMapObject: BaseMapObject{ID: "c1"}, Name: bigText}},
+		{"PlayAudio", PlayAudio, PlayAudioMessagePayload{Name: bigText}},
+		{"RemoveObjAttributes", RemoveObjAttributes, RemoveObjAttributesMessagePayload{ObjID: "obj1", AttrName: "Points", Values: bigList(2000)}},
+		{"RollDice", RollDice, RollDiceMessagePayload{ChatCommon: ChatCommon{Recipients: bigList(2000)}, RollSpec: "d20+1"}},
+		{"RollResult", RollResult, RollResultMessagePayload{Title: "attack", Result: dice.StructuredResult{Result: 42, Details: dice.StructuredDescriptionSet{{Type: "result", Value: "42"}, {Type: "notice", Value: bigText}}}}},
+		{"UpdateDicePresets", UpdateDicePresets, UpdateDicePresetsMessagePayload{For: "alice", Presets: bigPresets(1000)}},
+		{"UpdateInitiative", UpdateInitiative, UpdateInitiativeMessagePayload{InitiativeList: []InitiativeSlot{{Slot: 1, Name: bigText, CurrentHP: 10}}}},
+		{"UpdateObjAttributes", UpdateObjAttributes, UpdateObjAttributesMessagePayload{ObjID: "obj1", NewAttrs: map[string]any{"Text": bigText}}},
+		{"UpdatePeerList", UpdatePeerList, UpdatePeerListMessagePayload{PeerList: []Peer{{Addr: "127.0.0.1:1234", User: "alice", AKA: bigList(2000)}}}},
+		{"UpdateVersions", UpdateVersions, UpdateVersionsMessagePayload{Packages: []PackageUpdate{{Name: "mapper", Instances: []PackageVersion{{OS: "linux", Version: "1.0", Token: bigText}}}}}},
+	} {
+		expected, err := json.Marshal(test.Data)
+		if err != nil {
+			t.Fatalf("%s: can't marshal test data: %v", test.Name, err)
+		}
+		if len(expected) <= MaxServerMessageSize {
+			t.Fatalf("%s: test payload is only %d bytes; it won't be batched", test.Name, len(expected))
+		}
+
+		p, packets, err := sendAndReceive(t, test.Command, test.Data)
+		if err != nil {
+			t.Errorf("%s: receive: %v", test.Name, err)
+			continue
+		}
+		if want := (len(expected) + BatchFragmentSize - 1) / BatchFragmentSize; packets != want {
+			t.Errorf("%s: sent %d packets, expected %d fragments", test.Name, packets, want)
+		}
+		if p.MessageType() != test.Command {
+			t.Errorf("%s: received %v (%T), expected %v", test.Name, p.MessageType(), p, test.Command)
+			if e, ok := p.(ErrorMessagePayload); ok {
+				t.Errorf("%s: error: %v", test.Name, e.Error)
+			}
+			continue
+		}
+		actual, err := json.Marshal(p)
+		if err != nil {
+			t.Errorf("%s: can't marshal received payload: %v", test.Name, err)
+			continue
+		}
+		if string(actual) != string(expected) {
+			t.Errorf("%s: received payload differs from what was sent (%d bytes received, %d sent)", test.Name, len(actual), len(expected))
+		}
+	}
+}
+
+func TestBatchTooLarge(t *testing.T) {
+	c := newTestMapConnection(nil)
+	if err := c.Send(ChatMessage, ChatMessageMessagePayload{Text: strings.Repeat("x", MaxAllowedGiantPacketSize)}); err == nil {
+		t.Errorf("sending a payload larger than MaxAllowedGiantPacketSize should fail")
+	}
+	if n := len(c.sendChan); n != 0 {
+		t.Errorf("%d packets queued for a payload which couldn't be sent", n)
+	}
+}
+
+// batchLines formats BATCH fragments as they would arrive from the other side.
+func batchLines(t *testing.T, fragments ...BatchFragmentMessagePayload) string {
+	t.Helper()
+	var b strings.Builder
+	for _, f := range fragments {
+		j, err := json.Marshal(f)
+		if err != nil {
+			t.Fatalf("can't marshal fragment: %v", err)
+		}
+		fmt.Fprintf(&b, "BATCH %s\n", j)
+	}
+	return b.String()
+}
+
+// receiveLines feeds raw input lines to a new MapConnection and returns the
+// connection along with the first message received.
+func receiveLines(t *testing.T, input string) (*MapConnection, MessagePayload) {
+	t.Helper()
+	client, server := net.Pipe()
+	defer client.Close()
+	c := newTestMapConnection(server)
+	go func() {
+		client.Write([]byte(input))
+	}()
+	p, err := c.Receive()
+	if err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	return &c, p
+}
+
+func TestBatchAbort(t *testing.T) {
+	c, p := receiveLines(t, batchLines(t,
+		BatchFragmentMessagePayload{ID: "b1", Part: 0, Of: 3, Command: "TO", Data: []byte(`{"Text":"he`)},
+		BatchFragmentMessagePayload{ID: "b1", Part: 1, Of: 3, Command: "TO", Error: "out of cheese"},
+	)+"ECHO {\"s\":\"next\"}\n")
+
+	e, ok := p.(ErrorMessagePayload)
+	if !ok {
+		t.Fatalf("received %T, expected ErrorMessagePayload", p)
+	}
+	if !strings.Contains(e.Error.Error(), "out of cheese") || !strings.Contains(e.Error.Error(), "TO") {
+		t.Errorf("abort error %q doesn't explain what happened", e.Error)
+	}
+	if len(c.batches) != 0 || len(c.batchSeen) != 0 {
+		t.Errorf("abandoned batch still stored: %v", c.batches)
+	}
+}
+
+func TestBatchInvalidFragments(t *testing.T) {
+	for i, fragments := range [][]BatchFragmentMessagePayload{
+		{{ID: "b1", Part: 3, Of: 3, Command: "TO"}},
+		{{ID: "b1", Part: -1, Of: 3, Command: "TO"}},
+		{{ID: "b1", Part: 0, Of: 0, Command: "TO"}},
+		{{ID: "b1", Part: 0, Of: 3, Command: "TO"}, {ID: "b1", Part: 1, Of: 2}},
+		{{ID: "", Part: 0, Of: 3, Command: "TO"}},
+	} {
+		c, p := receiveLines(t, batchLines(t, fragments...))
+		if _, ok := p.(ErrorMessagePayload); !ok {
+			t.Errorf("test %d: received %T, expected ErrorMessagePayload", i, p)
+		}
+		if len(c.batches) != 0 {
+			t.Errorf("test %d: invalid batch still stored: %v", i, c.batches)
+		}
+	}
+}
+
+func TestBatchOversizedReassembly(t *testing.T) {
+	c := newTestMapConnection(nil)
+	big := []byte(strings.Repeat("x", BatchFragmentSize))
+	var err error
+	for part := 0; err == nil; part++ {
+		if part > MaxAllowedGiantPacketSize/BatchFragmentSize+1 {
+			t.Fatalf("batch grew past MaxAllowedGiantPacketSize without an error")
+		}
+		_, err = c.StashBatch(BatchFragmentMessagePayload{ID: "b1", Part: part, Of: 1000, Data: big})
+	}
+	if len(c.batches) != 0 {
+		t.Errorf("oversized batch still stored")
+	}
+}
+
+func TestBatchTimeout(t *testing.T) {
+	c := newTestMapConnection(nil)
+	if more, err := c.StashBatch(BatchFragmentMessagePayload{ID: "old", Part: 0, Of: 2, Command: "TO"}); err != nil || !more {
+		t.Fatalf("StashBatch old: %v, %v", more, err)
+	}
+	if more, err := c.StashBatch(BatchFragmentMessagePayload{ID: "new", Part: 0, Of: 2, Command: "TO"}); err != nil || !more {
+		t.Fatalf("StashBatch new: %v, %v", more, err)
+	}
+
+	// nothing is stale yet
+	if expired := c.ExpireBatches(time.Minute); len(expired) != 0 {
+		t.Errorf("expired %v too soon", expired)
+	}
+
+	// age the old batch past the timeout; the next fragment to arrive
+	// should clear it out.
+	c.batchSeen["old"] = time.Now().Add(-BatchTimeout - time.Second)
+	if _, err := c.StashBatch(BatchFragmentMessagePayload{ID: "new", Part: 1, Of: 2}); err != nil {
+		t.Fatalf("StashBatch new part 1: %v", err)
+	}
+	if _, ok := c.batches["old"]; ok {
+		t.Errorf("stale batch was not discarded")
+	}
+	if _, ok := c.batches["new"]; !ok {
+		t.Errorf("active batch was discarded")
+	}
+
+	if expired := c.ExpireBatches(0); len(expired) != 1 || expired[0] != "new" {
+		t.Errorf("ExpireBatches(0) returned %v, expected [new]", expired)
+	}
+	if len(c.batches) != 0 || len(c.batchSeen) != 0 {
+		t.Errorf("batches still stored after expiring all of them")
+	}
+}
+
+func TestMapConnectionReader(t *testing.T) {
+	input := batchLines(t,
+		BatchFragmentMessagePayload{ID: "b", Part: 0, Of: 2, Command: "TO", Data: []byte(`{"Text":`)},
+		BatchFragmentMessagePayload{ID: "b", Part: 1, Of: 2, Data: []byte(`"batched"}`)},
+	) + deflateLine(t, "TO {\"Text\":\"compressed\"}\nMARCO\n")
+
+	c := NewMapConnectionReader(strings.NewReader(input))
+	for i, expected := range []string{"batched", "compressed"} {
+		p, err := c.Receive()
+		if err != nil {
+			t.Fatalf("message #%d: %v", i, err)
+		}
+		if m, ok := p.(ChatMessageMessagePayload); !ok || m.Text != expected {
+			t.Errorf("message #%d was %#v, expected chat message %q", i, p, expected)
+		}
+	}
+	if p, err := c.Receive(); err != nil || p == nil || p.MessageType() != Marco {
+		t.Errorf("expected MARCO, got %v, %v", p, err)
+	}
+	if p, err := c.Receive(); err != nil || p != nil {
+		t.Errorf("expected end of input, got %v, %v", p, err)
+	}
+}
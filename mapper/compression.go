@@ -0,0 +1,164 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package mapper
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+)
+
+// A client which includes the Compression feature in its ALLOW message
+// asks the server to compress the messages it sends from then on. When
+// the server has several messages waiting to go out to the client at once
+// (as it will while sending the game state in response to a Sync request),
+// it packs as many of them as will fit into a single DEFLATE message whose
+// payload is the DEFLATE-compressed text of the original protocol lines.
+// Receive unpacks these transparently, so the caller sees the original messages.
+//
+// Each DEFLATE message is compressed independently of the others and may
+// not contain another DEFLATE message. Groups of messages totalling less than
+// CompressionThreshold bytes, and messages which wouldn't get any smaller,
+// are sent as they are. No more than CompressionFrameSize bytes of original
+// text are packed into one DEFLATE message.
+//
+// Clients which don't ask for compression (including those which predate it)
+// continue to receive plain protocol lines.
+const (
+	CompressionThreshold = 1024
+	CompressionFrameSize = 256 * 1024
+)
+
+// setCompression turns compression of our outgoing messages on or off.
+// This may be called while another goroutine is sending messages.
+func (c *MapConnection) setCompression(enabled bool) {
+	if c.compress == nil {
+		c.compress = new(atomic.Bool)
+	}
+	c.compress.Store(enabled)
+}
+
+// compressing reports whether our outgoing messages should be compressed.
+func (c *MapConnection) compressing() bool {
+	return c != nil && c.compress != nil && c.compress.Load()
+}
+
+// compressPackets takes a set of outgoing packets (each a complete protocol
+// line including its trailing newline) and packs them into as few DEFLATE
+// messages as possible, returning the packets to be sent in their place.
+func (c *MapConnection) compressPackets(packets []string) []string {
+	var total int
+	for _, packet := range packets {
+		total += len(packet)
+	}
+	if total < CompressionThreshold {
+		return packets
+	}
+
+	var out, group []string
+	var size int
+	for _, packet := range packets {
+		if size > 0 && size+len(packet) > CompressionFrameSize {
+			out = append(out, c.deflatePackets(group, size)...)
+			group, size = nil, 0
+		}
+		group = append(group, packet)
+		size += len(packet)
+	}
+	if size > 0 {
+		out = append(out, c.deflatePackets(group, size)...)
+	}
+	return out
+}
+
+// deflatePackets packs a group of packets totalling size bytes into a single
+// DEFLATE message, or if that would be too large to send, into several of them.
+// Packets which don't get any smaller by doing this are returned unchanged.
+func (c *MapConnection) deflatePackets(packets []string, size int) []string {
+	packet, err := c.deflate(packets)
+	if err != nil {
+		if c.debugf != nil {
+			c.debugf(DebugIO, "unable to compress %d packets; sending them uncompressed: %v", len(packets), err)
+		}
+		return packets
+	}
+	if len(packet) > MaxServerMessageSize && len(packets) > 1 {
+		half := len(packets) / 2
+		var firstSize int
+		for _, p := range packets[:half] {
+			firstSize += len(p)
+		}
+		return append(c.deflatePackets(packets[:half], firstSize), c.deflatePackets(packets[half:], size-firstSize)...)
+	}
+	if len(packet) > MaxServerMessageSize || len(packet) >= size {
+		return packets
+	}
+	if c.debugf != nil {
+		c.debugf(DebugIO, "compressed %d packets from %d to %d bytes", len(packets), size, len(packet))
+	}
+	return []string{packet}
+}
+
+// deflate compresses a group of packets into a DEFLATE message.
+func (c *MapConnection) deflate(packets []string) (string, error) {
+	var buf bytes.Buffer
+	var err error
+
+	if c.deflater == nil {
+		if c.deflater, err = flate.NewWriter(&buf, flate.DefaultCompression); err != nil {
+			return "", err
+		}
+	} else {
+		c.deflater.Reset(&buf)
+	}
+	for _, packet := range packets {
+		if _, err = io.WriteString(c.deflater, packet); err != nil {
+			return "", err
+		}
+	}
+	if err = c.deflater.Close(); err != nil {
+		return "", err
+	}
+	j, err := json.Marshal(CompressedMessagePayload{Data: buf.Bytes()})
+	if err != nil {
+		return "", err
+	}
+	return "DEFLATE " + string(j) + "\n", nil
+}
+
+// inflatePackets recovers the protocol lines carried in a DEFLATE message.
+func inflatePackets(data []byte) ([]string, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+
+	text, err := io.ReadAll(io.LimitReader(r, MaxAllowedGiantPacketSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decompress DEFLATE message: %v", err)
+	}
+	if len(text) > MaxAllowedGiantPacketSize {
+		return nil, fmt.Errorf("rejecting incoming DEFLATE message; decompressed size exceeds maximum %v bytes", MaxAllowedGiantPacketSize)
+	}
+	if len(text) == 0 {
+		return nil, nil
+	}
+	return strings.Split(strings.TrimSuffix(string(text), "\n"), "\n"), nil
+}
@@ -0,0 +1,310 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+//
+// Unit tests and benchmarks for compressed server messages.
+//
+
+package mapper
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"testing"
+)
+
+// syncPackets returns the packets a server would send to a client in response
+// to a Sync request in the middle of a fairly typical game: a dungeon level with
+// a few hundred walls, floors, and labels, a party and the creatures they're
+// fighting, the images needed to draw them, and the current combat state.
+func syncPackets(tb testing.TB) []string {
+	tb.Helper()
+	r := rand.New(rand.NewSource(1))
+	id := func() string {
+		return fmt.Sprintf("%016x%016x", r.Uint64(), r.Uint64())
+	}
+	points := func(n int) []Coordinates {
+		p := make([]Coordinates, n)
+		for i := range p {
+			p[i] = Coordinates{X: float64(r.Intn(200) * 25), Y: float64(r.Intn(200) * 25)}
+		}
+		return p
+	}
+	element := func(layer string) MapElement {
+		return MapElement{
+			BaseMapObject: BaseMapObject{ID: id()},
+			Coordinates:   Coordinates{X: float64(r.Intn(200) * 25), Y: float64(r.Intn(200) * 25)},
+			Z:             r.Intn(1000),
+			Width:         5,
+			Points:        points(1 + r.Intn(6)),
+			Line:          "black",
+			Fill:          "#ccbb99",
+			Layer:         layer,
+		}
+	}
+
+	c := MapConnection{sendChan: make(chan string, 2000)}
+	send := func(command ServerMessage, data any) {
+		if err := c.Send(command, data); err != nil {
+			tb.Fatalf("send %v: %v", command, err)
+		}
+	}
+
+	var peers []Peer
+	for i, user := range []string{"GM", "alice", "bob", "carol", "dave", "erin"} {
+		peers = append(peers, Peer{
+			Addr:            fmt.Sprintf("192.0.2.%d:%d", 10+i, 40000+r.Intn(20000)),
+			User:            user,
+			Client:          "mapper 4.33.2",
+			LastPolo:        r.Float64() * 60,
+			IsAuthenticated: true,
+		})
+	}
+	send(UpdatePeerList, UpdatePeerListMessagePayload{PeerList: peers})
+	send(CombatMode, CombatModeMessagePayload{Enabled: true})
+	send(Toolbar, ToolbarMessagePayload{Enabled: true})
+	send(AdjustView, AdjustViewMessagePayload{XView: 0.25, YView: 0.4})
+	send(UpdateTurn, UpdateTurnMessagePayload{ActorID: "PC1", Hours: 14, Minutes: 32, Seconds: 6, Rounds: 3, Count: 42})
+	var initiative []InitiativeSlot
+	for i := range 12 {
+		initiative = append(initiative, InitiativeSlot{Slot: r.Intn(60), CurrentHP: r.Intn(80), Name: fmt.Sprintf("creature %d", i)})
+	}
+	send(UpdateInitiative, UpdateInitiativeMessagePayload{InitiativeList: initiative})
+	send(UpdateClock, UpdateClockMessagePayload{Absolute: 1234567890, Relative: 1200})
+	for _, condition := range []string{"bleed", "blinded", "confused", "dazed", "deafened", "entangled", "exhausted", "fatigued", "frightened", "grappled", "helpless", "nauseated", "panicked", "paralyzed", "prone", "shaken", "sickened", "slowed", "stable", "staggered", "stunned"} {
+		send(UpdateStatusMarker, UpdateStatusMarkerMessagePayload{StatusMarkerDefinition: StatusMarkerDefinition{Condition: condition, Shape: "|v", Color: "red"}})
+	}
+	for i := range 60 {
+		var sizes []ImageInstance
+		for _, zoom := range []float64{0.25, 0.5, 1, 2} {
+			sizes = append(sizes, ImageInstance{Zoom: zoom, File: id()})
+		}
+		send(AddImage, AddImageMessagePayload{ImageDefinition: ImageDefinition{Name: fmt.Sprintf("dungeon-tile-%d", i), Sizes: sizes}})
+	}
+	for i := range 25 {
+		send(PlaceSomeone, PlaceSomeoneMessagePayload{CreatureToken: CreatureToken{
+			BaseMapObject: BaseMapObject{ID: id()},
+			CreatureType:  CreatureTypeMonster,
+			Gx:            float64(r.Intn(40)),
+			Gy:            float64(r.Intn(40)),
+			Name:          fmt.Sprintf("goblin #%d", i),
+			Health:        &CreatureHealth{MaxHP: 6 + r.Intn(10), LethalDamage: r.Intn(6), Con: 12},
+			Color:         "red",
+			Size:          "S",
+			StatusList:    []string{"shaken"},
+		}})
+	}
+	for range 350 {
+		send(LoadLineObject, LoadLineObjectMessagePayload{LineElement: LineElement{MapElement: element("walls")}})
+	}
+	for range 80 {
+		send(LoadPolygonObject, LoadPolygonObjectMessagePayload{PolygonElement: PolygonElement{MapElement: element("walls")}})
+	}
+	for range 120 {
+		send(LoadRectangleObject, LoadRectangleObjectMessagePayload{RectangleElement: RectangleElement{MapElement: element("floor")}})
+	}
+	for i := range 40 {
+		send(LoadTextObject, LoadTextObjectMessagePayload{TextElement: TextElement{
+			MapElement: element("labels"),
+			Text:       fmt.Sprintf("Room %d", i),
+			Font:       TextFont{Family: "Helvetica", Size: 12},
+		}})
+	}
+
+	packets := make([]string, 0, len(c.sendChan))
+	for len(c.sendChan) > 0 {
+		packets = append(packets, <-c.sendChan)
+	}
+	return packets
+}
+
+func totalSize(packets []string) int {
+	var size int
+	for _, packet := range packets {
+		size += len(packet)
+	}
+	return size
+}
+
+// receiveAll feeds packets to a MapConnection and returns the raw text of
+// each message received from it, or the error if an error message is received.
+func receiveAll(t *testing.T, packets []string, n int) ([]string, error) {
+	t.Helper()
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	c := newTestMapConnection(server)
+	go func() {
+		for _, packet := range packets {
+			if _, err := client.Write([]byte(packet)); err != nil {
+				return
+			}
+		}
+	}()
+
+	var received []string
+	for range n {
+		p, err := c.Receive()
+		if err != nil {
+			t.Fatalf("receive: %v", err)
+		}
+		if e, ok := p.(ErrorMessagePayload); ok {
+			return received, e.Error
+		}
+		received = append(received, p.RawMessage()+"\n")
+	}
+	return received, nil
+}
+
+func TestCompressedSync(t *testing.T) {
+	packets := syncPackets(t)
+	c := newTestMapConnection(nil)
+	compressed := c.compressPackets(packets)
+
+	if len(compressed) >= len(packets) || totalSize(compressed) >= totalSize(packets)/2 {
+		t.Errorf("compressed %d packets (%d bytes) to %d packets (%d bytes)", len(packets), totalSize(packets), len(compressed), totalSize(compressed))
+	}
+	for i, packet := range compressed {
+		if len(packet) > MaxServerMessageSize {
+			t.Errorf("packet %d is %d bytes long", i, len(packet))
+		}
+		if !strings.HasPrefix(packet, "DEFLATE ") {
+			t.Errorf("packet %d was not compressed: %q", i, packet)
+		}
+	}
+
+	received, err := receiveAll(t, compressed, len(packets))
+	if err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	for i := range packets {
+		if received[i] != packets[i] {
+			t.Fatalf("message %d received as %q; expected %q", i, received[i], packets[i])
+		}
+	}
+}
+
+func TestCompressionSplitsLargeGroups(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	var packets []string
+	for i := range 5000 {
+		packets = append(packets, fmt.Sprintf("TO {\"Text\":\"message %d from %016x to %016x\"}\n", i, r.Uint64(), r.Uint64()))
+	}
+	c := newTestMapConnection(nil)
+	compressed := c.compressPackets(packets)
+	if len(compressed) < 3 {
+		t.Fatalf("expected %d bytes to need more than one DEFLATE message", totalSize(packets))
+	}
+	for i, packet := range compressed {
+		if len(packet) > MaxServerMessageSize || !strings.HasPrefix(packet, "DEFLATE ") {
+			t.Errorf("packet %d is a %d-byte %q", i, len(packet), packet[:min(20, len(packet))])
+		}
+	}
+	received, err := receiveAll(t, compressed, len(packets))
+	if err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	if strings.Join(received, "") != strings.Join(packets, "") {
+		t.Errorf("messages were not received intact")
+	}
+}
+
+func TestCompressionPassesThrough(t *testing.T) {
+	c := newTestMapConnection(nil)
+
+	small := []string{"MARCO\n", "TO {\"Text\":\"hello\"}\n"}
+	if out := c.compressPackets(small); strings.Join(out, "") != strings.Join(small, "") {
+		t.Errorf("small packets sent as %q", out)
+	}
+
+	r := rand.New(rand.NewSource(3))
+	noise := make([]byte, 8192)
+	r.Read(noise)
+	random := []string{"// " + base64.StdEncoding.EncodeToString(noise) + "\n"}
+	if out := c.compressPackets(random); strings.Join(out, "") != strings.Join(random, "") {
+		t.Errorf("incompressible packet sent as %d bytes in %d packets", totalSize(out), len(out))
+	}
+}
+
+func deflateLine(t *testing.T, text string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		t.Fatalf("deflate: %v", err)
+	}
+	w.Write([]byte(text))
+	w.Close()
+	j, err := json.Marshal(CompressedMessagePayload{Data: buf.Bytes()})
+	if err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	return "DEFLATE " + string(j) + "\n"
+}
+
+func TestCompressedMessageErrors(t *testing.T) {
+	for _, test := range []struct {
+		name, input, reason string
+	}{
+		{"missing payload", "DEFLATE\n", "missing required payload"},
+		{"corrupt data", "DEFLATE {\"Data\":\"AAAA\"}\n", "unable to decompress"},
+		{"nested", deflateLine(t, deflateLine(t, "MARCO\n")), "may not contain another DEFLATE"},
+		{"oversized", deflateLine(t, strings.Repeat("// "+strings.Repeat("x", 1000)+"\n", MaxAllowedGiantPacketSize/1000)), "exceeds maximum"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			received, err := receiveAll(t, []string{test.input, "MARCO\n"}, 2)
+			if err == nil || !strings.Contains(err.Error(), test.reason) {
+				t.Fatalf("received %q with error %v; expected error containing %q", received, err, test.reason)
+			}
+		})
+	}
+}
+
+func TestAllowCompression(t *testing.T) {
+	c := Connection{Protocol: GMAMapperProtocol, serverConn: MapConnection{sendChan: make(chan string, 1)}}
+	if err := c.Allow(GMAMarkup, Compression); err != nil {
+		t.Fatalf("allow: %v", err)
+	}
+	if packet := <-c.serverConn.sendChan; packet != "ALLOW {\"Features\":[\"GMA-MARKUP\",\"DEFLATE\"]}\n" {
+		t.Errorf("sent %q", packet)
+	}
+}
+
+// BenchmarkCompressedSync measures the cost of compressing the server's
+// response to a Sync request, and reports how much data that saves.
+func BenchmarkCompressedSync(b *testing.B) {
+	packets := syncPackets(b)
+	c := MapConnection{}
+	plain := totalSize(packets)
+	var compressed int
+
+	b.SetBytes(int64(plain))
+	b.ResetTimer()
+	for range b.N {
+		compressed = totalSize(c.compressPackets(packets))
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(plain), "plain-bytes")
+	b.ReportMetric(float64(compressed), "sent-bytes")
+	b.ReportMetric(100*float64(plain-compressed)/float64(plain), "%saved")
+}
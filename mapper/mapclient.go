@@ -44,6 +44,7 @@ package mapper
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"log"
@@ -201,9 +202,22 @@ type Connection struct {
 	Timeout time.Duration
 
 	// The server endpoint, in any form acceptable to the net.Dial
-	// function.
+	// function. If WebSocket is true, this is instead the server's
+	// WebSocket URL ("ws://host:port/path" or "wss://host:port/path").
 	Endpoint string
 
+	// If true, we connect to the server over a WebSocket rather than
+	// a plain TCP socket.
+	WebSocket bool
+
+	// The origin we report to the server when connecting over a
+	// WebSocket.
+	WebSocketOrigin string
+
+	// If not nil, we connect to the server over TLS with this
+	// configuration.
+	TLSConfig *tls.Config
+
 	// Characters received from the server.
 	Characters map[string]PlayerToken
 
@@ -411,6 +425,52 @@ func StayConnected(enable bool) ConnectionOption {
 	}
 }
 
+// WithWebSocket modifies the behavior of the NewConnection function
+// so that the connection to the server is made over a WebSocket
+// instead of a plain TCP socket. In this case, the endpoint passed to
+// NewConnection must be the server's WebSocket URL, such as
+// "ws://mygame.example.org:8080/map", or "wss://mygame.example.org:8080/map"
+// to connect over TLS (see WithTLSConfig).
+//
+// The origin is reported to the server as the source of the connection.
+// If it is empty, DefaultWebSocketOrigin is used.
+func WithWebSocket(origin string) ConnectionOption {
+	return func(c *Connection) error {
+		c.WebSocket = true
+		c.WebSocketOrigin = origin
+		return nil
+	}
+}
+
+// WithTLSConfig modifies the behavior of the NewConnection function
+// so that the connection to the server is made over TLS with the
+// given configuration. If the configuration doesn't specify a
+// ServerName, the host name from the endpoint is used.
+//
+// To log in with a client certificate instead of a password (if the
+// server allows it), include the certificate in the configuration.
+// (See also util.ServerProfile.TLSConfig.)
+//
+// When connecting over a WebSocket (see WithWebSocket), the endpoint
+// URL must use the "wss" scheme for this configuration to be used.
+func WithTLSConfig(config *tls.Config) ConnectionOption {
+	return func(c *Connection) error {
+		c.TLSConfig = config
+		return nil
+	}
+}
+
+// WithRecorder modifies the behavior of the NewConnection function
+// so that every message sent to or received from the server is
+// added to a session recording. The caller is responsible for
+// closing the Recorder when finished with it.
+func WithRecorder(r *Recorder) ConnectionOption {
+	return func(c *Connection) error {
+		c.serverConn.recorder = r
+		return nil
+	}
+}
+
 // WithDebugging modifies the behavior of the NewConnection function
 // so that the operations of the Connection's interaction with the
 // server are logged to varying levels of verbosity.
@@ -432,9 +492,12 @@ func WithDebugging(flags DebugFlags) ConnectionOption {
 //	WithDebugging(level)
 //	WithContext(ctx)
 //	WithLogger(l)
+//	WithRecorder(r)
 //	WithRetries(n)
 //	WithSubscription(ch, msgs...)
 //	WithTimeout(t)
+//	WithTLSConfig(config)
+//	WithWebSocket(origin)
 //
 // Example:
 //
@@ -637,8 +700,10 @@ const (
 	ClearFrom
 	CombatMode
 	Comment
+	Compressed
 	DefineDicePresets
 	DefineDicePresetDelegates
+	DefineRandomTables
 	Denied
 	Echo
 	Failed
@@ -671,11 +736,16 @@ const (
 	QueryDicePresets
 	QueryImage
 	QueryPeers
+	QueryRandomTables
+	QueryRollHistory
 	Ready
 	Redirect
 	RemoveObjAttributes
+	RevealRollSeed
 	RollDice
 	RollResult
+	RollSeed
+	RollTable
 	Sync
 	SyncChat
 	TimerAcknowledge
@@ -689,6 +759,8 @@ const (
 	UpdateObjAttributes
 	UpdatePeerList
 	UpdateProgress
+	UpdateRandomTables
+	UpdateRollHistory
 	UpdateStatusMarker
 	UpdateTurn
 	UpdateVersions
@@ -717,8 +789,10 @@ var ServerMessageByName = map[string]ServerMessage{
 	"ClearFrom":                   ClearFrom,
 	"CombatMode":                  CombatMode,
 	"Comment":                     Comment,
+	"Compressed":                  Compressed,
 	"DefineDicePresets":           DefineDicePresets,
 	"DefineDicePresetDelegates":   DefineDicePresetDelegates,
+	"DefineRandomTables":          DefineRandomTables,
 	"Denied":                      Denied,
 	"Echo":                        Echo,
 	"Failed":                      Failed,
@@ -751,11 +825,16 @@ var ServerMessageByName = map[string]ServerMessage{
 	"QueryDicePresets":            QueryDicePresets,
 	"QueryImage":                  QueryImage,
 	"QueryPeers":                  QueryPeers,
+	"QueryRandomTables":           QueryRandomTables,
+	"QueryRollHistory":            QueryRollHistory,
 	"Ready":                       Ready,
 	"Redirect":                    Redirect,
 	"RemoveObjAttributes":         RemoveObjAttributes,
+	"RevealRollSeed":              RevealRollSeed,
 	"RollDice":                    RollDice,
 	"RollResult":                  RollResult,
+	"RollSeed":                    RollSeed,
+	"RollTable":                   RollTable,
 	"Sync":                        Sync,
 	"SyncChat":                    SyncChat,
 	"TimerAcknowledge":            TimerAcknowledge,
@@ -769,6 +848,8 @@ var ServerMessageByName = map[string]ServerMessage{
 	"UpdateObjAttributes":         UpdateObjAttributes,
 	"UpdatePeerList":              UpdatePeerList,
 	"UpdateProgress":              UpdateProgress,
+	"UpdateRandomTables":          UpdateRandomTables,
+	"UpdateRollHistory":           UpdateRollHistory,
 	"UpdateStatusMarker":          UpdateStatusMarker,
 	"UpdateTurn":                  UpdateTurn,
 	"UpdateVersions":              UpdateVersions,
@@ -829,42 +910,6 @@ func (p BaseMessagePayload) RawBytes() []byte   { return []byte(p.rawMessage) }
 //	}
 func (p BaseMessagePayload) MessageType() ServerMessage { return p.messageType }
 
-//TODO//TODO// BatchableMessagePayload is a type of payload which can be broken into pieces to avoid sending excessively long data.
-//TODO//TODOtype BatchableMessagePayload struct {
-//TODO	Batch        int    `json:",omitempty"`
-//TODO	TotalBatches int    `json:",omitempty"`
-//TODO	BatchGroup   string `json:",omitempty"`
-//TODO	BatchError   string `json:",omitempty"`
-//TODO}
-//TODO
-//TODO// NeedsToBeSplit returns true if we estimate that the payload is likely to exceed the maximum message size allowed.
-//TODO//TODOfunc (b BatchableMessagePayload) NeedsToBeSplit() bool {
-//TODO	return false // this needs to be overridden by specific types that actually have payloads to examine
-//TODO}
-//TODO
-//TODO// IsBatched returns true if this payload is part of a batched set as opposed to a stand-alone packet.
-//TODO//TODOfunc (b BatchableMessagePayload) IsBatched() bool {
-//TODO	return b.BatchGroup != ""
-//TODO}
-//TODO
-//TODO// BatchInfo returns the details about the batch for each fragment.
-//TODO//TODOfunc (b BatchableMessagePayload) BatchInfo() BatchableMessagePayload {
-//TODO	return b
-//TODO}
-//TODO
-//TODO// Split breaks up a payload into multiple parts, returning a slice of them.
-//TODO// This must be defined at the derived type level.
-//TODO//TODO//func (b BatchableMessagePayload) Split() []any {
-//TODO//}
-//TODO
-//TODO// Reassemble combines fragmented pieces back into a single payload. This must be defined at the derived type level.
-//TODO//TODO// func (b BatchableMessagePayload) Reassemble([]any) any {}
-//TODO
-//TODO// AbortPayload generates a new payload of the same type as the original but with batch fields which indicate that we are
-//TODO// bailing out on batching up the payload and won't be completing the operation.
-//TODO// This must be defined at the derived type level.
-//TODO//TODO// func (b BatchableMessagePayload) AbortPayload(reason string, batchNumber int) any {}
-
 // ErrorMessagePayload describes
 // an error which encountered when trying to receive a message.
 type ErrorMessagePayload struct {
@@ -968,99 +1013,9 @@ func (c *Connection) AddAudio(adef AudioDefinition) error {
 // of an image file they should be aware of.
 type AddImageMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	ImageDefinition
 }
 
-//TODOfunc (c AddImageMessagePayload) NeedsToBeSplit() bool {
-//TODO	// AI Animation Name Sizes :
-//TODO	l := 6               // "AI {}"
-//TODO	l += 8 + len(c.Name) // "Name":,
-//TODO	if c.Animation != nil {
-//TODO		l += 61 // "Animation":{"Frames":99999,"FrameSpeed":99999,"Loops":99999}
-//TODO	}
-//TODO	l += 11 // "Sizes": []
-//TODO	for _, i := range c.Sizes {
-//TODO		if i.IsLocalFile {
-//TODO			l += 19 // "IsLocalFile":true,
-//TODO		}
-//TODO		if i.ImageData != nil {
-//TODO			l += 15 + len(i.ImageData) // "ImageData":"",
-//TODO		}
-//TODO		l += 10 + len(i.File) // "File":"",
-//TODO		l += 17               // "Zoom":99999.9999
-//TODO	}
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODO// Split records on Sizes (which are the instances of the images we
-//TODO// have at different zoom factors, etc.)
-//TODO//
-//TODO// 0  Name,
-//TODO// 0  Animation->{Frames,FrameSpeed,Loops}
-//TODO// 0+ Sizes[File,ImageData,IsLocalFile,Zoom]
-//TODOfunc (c AddImageMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.Sizes))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, instance := range c.Sizes {
-//TODO		p := AddImageMessagePayload{
-//TODO			ImageDefinition: ImageDefinition{
-//TODO				Sizes: []ImageInstance{instance},
-//TODO			},
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.Sizes),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.Name = c.Name
-//TODO			p.Animation = c.Animation
-//TODO		}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c AddImageMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	ai := AddImageMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		img, ok := d.(AddImageMessagePayload)
-//TODO		if !ok {
-//TODO			return ai, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if img.Batch != i {
-//TODO			return ai, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), img.Batch)
-//TODO		}
-//TODO		if img.TotalBatches != len(p) {
-//TODO			return ai, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, img.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			ai.Name = img.Name
-//TODO			ai.Sizes = make([]ImageInstance, len(p))
-//TODO			ai.Animation = img.Animation
-//TODO		}
-//TODO		ai.Sizes[i] = img.Sizes[0]
-//TODO	}
-//TODO	return ai, nil
-//TODO}
-//TODO
-//TODOfunc (c AddImageMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return AddImageMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		ImageDefinition: ImageDefinition{
-//TODO			Name: c.Name,
-//TODO		},
-//TODO	}
-//TODO}
-
 // AddImage informs the server and peers about an image they can use.
 func (c *Connection) AddImage(idef ImageDefinition) error {
 	return c.serverConn.Send(AddImage, idef)
@@ -1080,80 +1035,11 @@ func (c *Connection) AddImage(idef ImageDefinition) error {
 // Call the AddObjAttributes method to send this message out to other clients.
 type AddObjAttributesMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	ObjID    string
 	AttrName string
 	Values   []string
 }
 
-//TODOfunc (c AddObjAttributesMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 37 + len(c.ObjID) + len(c.AttrName) // OA+ ObjID:x AttrName:x Values:[]
-//TODO	for _, v := range c.Values {
-//TODO		l += len(v) + 3
-//TODO	}
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c AddObjAttributesMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.Values))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, v := range c.Values {
-//TODO		p := AddObjAttributesMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.Values),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.ObjID = c.ObjID
-//TODO			p.AttrName = c.AttrName
-//TODO		}
-//TODO		p.Values = []string{v}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c AddObjAttributesMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := AddObjAttributesMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(AddObjAttributesMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.ObjID = pp.ObjID
-//TODO			newp.AttrName = pp.AttrName
-//TODO		}
-//TODO		for _, v := range pp.Values {
-//TODO			newp.Values = append(newp.Values, v)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c AddObjAttributesMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return AddObjAttributesMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		ObjID:    c.ObjID,
-//TODO		AttrName: c.AttrName,
-//TODO	}
-//TODO}
-
 // AddObjAttributes informs peers to add a set of string values to the existing
 // value of an object attribute. The attribute must be one whose value is a list
 // of strings, such as StatusList.
@@ -1233,10 +1119,16 @@ const (
 	DiceColorBoxes OptionalFeature = iota
 	DiceColorLabels
 	GMAMarkup
+	Compression
 )
 
 // Allow tells the server which optional features this client is
 // prepared to accept.
+//
+// Including Compression asks the server to compress the messages it sends
+// to us (see CompressionThreshold), which considerably reduces the amount
+// of data sent over the network during a Sync. Servers which don't support
+// it simply continue to send uncompressed messages.
 func (c *Connection) Allow(features ...OptionalFeature) error {
 	var featureList []string
 	if c.Protocol < 333 {
@@ -1250,6 +1142,8 @@ func (c *Connection) Allow(features ...OptionalFeature) error {
 			featureList = append(featureList, "DICE-COLOR-LABELS")
 		case GMAMarkup:
 			featureList = append(featureList, "GMA-MARKUP")
+		case Compression:
+			featureList = append(featureList, "DEFLATE")
 		default:
 			return fmt.Errorf("unknown OptionalFeature code %v", feature)
 		}
@@ -1358,6 +1252,12 @@ type ChallengeMessagePayload struct {
 	ServerActive  time.Time `json:",omitempty"`
 	ServerTime    time.Time `json:",omitempty"`
 	ServerVersion string    `json:",omitempty"`
+
+	// If the client logged in with a TLS client certificate, this
+	// is the user it names. Instead of answering a challenge, the
+	// client sends an AUTH message without a response, to tell the
+	// server which client program it is.
+	CertificateUser string `json:",omitempty"`
 }
 
 //
@@ -1450,7 +1350,6 @@ type ChatCommon struct {
 // Call the ChatMessage, ChatMessageToAll, or ChatMessageToGM methods to send this message out to other clients.
 type ChatMessageMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	ChatCommon
 
 	// True if the message contains GMA markup formatting codes
@@ -1463,97 +1362,6 @@ type ChatMessageMessagePayload struct {
 	Text string
 }
 
-//TODOfunc (c ChatMessageMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 138 + len(c.Sender) // TO Origin:false Replay:false Sender:x MessageID:i ToAll:false ToGM:false Sent:time[35]
-//TODO	l += 16
-//TODO	for _, r := range c.Recipients {
-//TODO		l += len(r) + 3 // Recipients:[x]
-//TODO	}
-//TODO	l += 34 + len(c.Text) // Markup:false Pin:false Text:x
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c ChatMessageMessagePayload) Split() []any {
-//TODO	fragments := len(c.Recipients)
-//TODO	payloads := make([]any, fragments)
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i := range fragments {
-//TODO		p := ChatMessageMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: fragments,
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.Origin = c.Origin
-//TODO			p.Replay = c.Replay
-//TODO			p.Sender = c.Sender
-//TODO			p.MessageID = c.MessageID
-//TODO			p.ToAll = c.ToAll
-//TODO			p.ToGM = c.ToGM
-//TODO			p.Sent = c.Sent
-//TODO			p.Markup = c.Markup
-//TODO			p.Pin = c.Pin
-//TODO			p.Text = c.Text
-//TODO		}
-//TODO		if len(c.Recipients) > i {
-//TODO			p.Recipients = []string{c.Recipients[i]}
-//TODO		}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c ChatMessageMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := ChatMessageMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(ChatMessageMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.Origin = pp.Origin
-//TODO			newp.Replay = pp.Replay
-//TODO			newp.Sender = pp.Sender
-//TODO			newp.MessageID = pp.MessageID
-//TODO			newp.ToAll = pp.ToAll
-//TODO			newp.ToGM = pp.ToGM
-//TODO			newp.Sent = pp.Sent
-//TODO			newp.Pin = pp.Pin
-//TODO			newp.Markup = pp.Markup
-//TODO			newp.Text = pp.Text
-//TODO		}
-//TODO		for _, t := range pp.Recipients {
-//TODO			newp.Recipients = append(newp.Recipients, t)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c ChatMessageMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return ChatMessageMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		ChatCommon: ChatCommon{
-//TODO			MessageID: c.MessageID,
-//TODO			Sender:    c.Sender,
-//TODO		},
-//TODO	}
-//TODO}
-
 // ChatMessage sends a message on the chat channel to other
 // users. The to paramter is a slice of user names of the people
 // who should receive this message.
@@ -1838,6 +1646,25 @@ type CommentMessagePayload struct {
 	Text string
 }
 
+//   ____                                                 _
+//  / ___|___  _ __ ___  _ __  _ __ ___  ___ ___  ___  __| |
+// | |   / _ \| '_ ` _ \| '_ \| '__/ _ \/ __/ __|/ _ \/ _` |
+// | |__| (_) | | | | | | |_) | | |  __/\__ \__ \  __/ (_| |
+//  \____\___/|_| |_| |_| .__/|_|  \___||___/___/\___|\__,_|
+//                      |_|
+
+// CompressedMessagePayload holds a set of messages which were compressed
+// together for transmission to a client which asked for that by including
+// the Compression feature in its ALLOW message. Receive unpacks these
+// automatically, so clients never see this payload type.
+type CompressedMessagePayload struct {
+	BaseMessagePayload
+
+	// DEFLATE-compressed text of the original messages, each
+	// terminated by a newline
+	Data []byte
+}
+
 //   ____               ____        _
 //  / ___|___  _ __ ___|  _ \  __ _| |_ __ _
 // | |   / _ \| '__/ _ \ | | |/ _` | __/ _` |
@@ -2048,7 +1875,6 @@ type DeniedMessagePayload struct {
 // given as the O value.
 type EchoMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 
 	B            bool           `json:"b,omitempty"`
 	I            int            `json:"i,omitempty"`
@@ -2058,102 +1884,6 @@ type EchoMessagePayload struct {
 	SentTime     time.Time      `json:",omitempty"`
 }
 
-//TODOfunc (c EchoMessagePayload) NeedsToBeSplit() bool {
-//TODO	if len(c.O) <= 1 {
-//TODO		return false
-//TODO	}
-//TODO
-//TODO	l := 8 // "ECHO {}"
-//TODO	if c.B {
-//TODO		l += 10 // "b": true,
-//TODO	}
-//TODO	if c.I != 0 {
-//TODO		l += len(fmt.Sprintf("%v", c.I)) + 6 // "i": value,
-//TODO	}
-//TODO	if c.S != "" {
-//TODO		l += len(c.S) + 6 // "s": value,
-//TODO	}
-//TODO	l += 8 // "o": {},
-//TODO	for k, v := range c.O {
-//TODO		l += len(k) + len(fmt.Sprintf("%v", v)) + 8 // "key": "value", est
-//TODO	}
-//TODO	return l >= MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c EchoMessagePayload) Split() []any {
-//TODO	if len(c.O) < 2 {
-//TODO		a := make([]any, 1)
-//TODO		a[0] = c
-//TODO		return a
-//TODO	}
-//TODO
-//TODO	payloads := make([]any, len(c.O))
-//TODO	gid := uuid.NewString()
-//TODO	i := 0
-//TODO	for k, v := range c.O {
-//TODO		p := EchoMessagePayload{}
-//TODO		if i == 0 {
-//TODO			p.B = c.B
-//TODO			p.I = c.I
-//TODO			p.S = c.S
-//TODO			p.ReceivedTime = c.ReceivedTime
-//TODO			p.SentTime = c.SentTime
-//TODO		}
-//TODO		p.O = make(map[string]any, 1)
-//TODO		p.O[k] = v
-//TODO		p.TotalBatches = len(c.O)
-//TODO		p.Batch = i
-//TODO		p.BatchGroup = gid
-//TODO		payloads[i] = p
-//TODO		i++
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c EchoMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	var echo EchoMessagePayload
-//TODO
-//TODO	for i, d := range p {
-//TODO		pkt, ok := d.(EchoMessagePayload)
-//TODO		if !ok {
-//TODO			return echo, fmt.Errorf("batched %T packet #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO
-//TODO		if pkt.Batch != i {
-//TODO			return echo, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pkt.Batch)
-//TODO		}
-//TODO		if pkt.TotalBatches != len(p) {
-//TODO			return echo, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pkt.TotalBatches, len(p))
-//TODO		}
-//TODO		if i == 0 {
-//TODO			echo.B = pkt.B
-//TODO			echo.I = pkt.I
-//TODO			echo.S = pkt.S
-//TODO			echo.O = make(map[string]any, len(p))
-//TODO			echo.ReceivedTime = pkt.ReceivedTime
-//TODO			echo.SentTime = pkt.SentTime
-//TODO		}
-//TODO
-//TODO		for k, v := range pkt.O {
-//TODO			echo.O[k] = v
-//TODO		}
-//TODO	}
-//TODO	return echo, nil
-//TODO}
-//TODO
-//TODOfunc (c EchoMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return EchoMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		B: c.B,
-//TODO		I: c.I,
-//TODO		S: c.S,
-//TODO	}
-//TODO}
-
 func (c *Connection) EchoString(s string) error {
 	if c == nil {
 		return fmt.Errorf("nil connection")
@@ -2369,100 +2099,11 @@ type GrantedMessagePayload struct {
 // that their HitPointRequest message was accepted.
 type HitPointAcknowledgeMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	RequestID        string
 	RequestingClient string `json:",omitempty"`
 	RequestedBy      string `json:",omitempty"`
 }
 
-//TODOfunc (c HitPointRequestMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 108 + len(c.Description) + len(c.RequestID) + len(c.RequestedBy) + len(c.RequestingClient) // HPREQ Targets[] Description:x RequestedBy:x RequestingClient:x RequestID:x Health:* TmpHP:*
-//TODO	for _, t := range c.Targets {
-//TODO		l += len(t) + 3
-//TODO	}
-//TODO	if c.Health == nil {
-//TODO		l += 4
-//TODO	} else {
-//TODO		l += 84 + 7*5 // MaxHP:i LethalDamage:i NonLethalDamage:i AC:i FlatFootedAC:i TouchAC: CMD:i
-//TODO	}
-//TODO	if c.TmpHP == nil {
-//TODO		l += 4
-//TODO	} else {
-//TODO		l += 33 + 10 + len(c.TmpHP.Expires) // TmpHP:i TmpDamage:i Expires:x
-//TODO	}
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c HitPointRequestMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.Targets))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, instance := range c.Targets {
-//TODO		p := HitPointRequestMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.Targets),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.Description = c.Description
-//TODO			p.RequestID = c.RequestID
-//TODO			p.Health = c.Health
-//TODO			p.TmpHP = c.TmpHP
-//TODO			p.RequestedBy = c.RequestedBy
-//TODO			p.RequestingClient = c.RequestingClient
-//TODO		}
-//TODO		p.Targets = []string{instance}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c HitPointRequestMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := HitPointRequestMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(HitPointRequestMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.Description = pp.Description
-//TODO			newp.RequestID = pp.RequestID
-//TODO			newp.Health = pp.Health
-//TODO			newp.TmpHP = pp.TmpHP
-//TODO			newp.RequestedBy = pp.RequestedBy
-//TODO			newp.RequestingClient = pp.RequestingClient
-//TODO		}
-//TODO		for _, t := range pp.Targets {
-//TODO			newp.Targets = append(newp.Targets, t)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c HitPointRequestMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return HitPointRequestMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		Description:      c.Description,
-//TODO		RequestedBy:      c.RequestedBy,
-//TODO		RequestID:        c.RequestID,
-//TODO		RequestingClient: c.RequestingClient,
-//TODO	}
-//TODO}
-
 // .  _   _ _ _   ____       _       _   ____                            _
 // . | | | (_) |_|  _ \ ___ (_)_ __ | |_|  _ \ ___  __ _ _   _  ___  ___| |_
 // . | |_| | | __| |_) / _ \| | '_ \| __| |_) / _ \/ _` | | | |/ _ \/ __| __|
@@ -2473,7 +2114,6 @@ type HitPointAcknowledgeMessagePayload struct {
 // HitPointRequestMessagePayload requests that the GM add temporary hit points to a creature (usually a PC).
 type HitPointRequestMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 
 	// Simple description to explain the request to the GM
 	Description string
@@ -2611,933 +2251,51 @@ func (c *Connection) LoadFrom(path string, local bool, merge bool) error {
 // LoadArcObjectMessagePayload holds the information needed to send an arc element to a map.
 type LoadArcObjectMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	ArcElement
 }
 
 // LoadCircleObjectMessagePayload holds the information needed to send an ellipse element to a map.
 type LoadCircleObjectMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	CircleElement
 }
 
 // LoadLineObjectMessagePayload holds the information needed to send a line element to a map.
 type LoadLineObjectMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	LineElement
 }
 
 // LoadPolygonObjectMessagePayload holds the information needed to send a polygon element to a map.
 type LoadPolygonObjectMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	PolygonElement
 }
 
 // LoadRectangleObjectMessagePayload holds the information needed to send a rectangle element to a map.
 type LoadRectangleObjectMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	RectangleElement
 }
 
 // LoadSpellAreaOfEffectObjectMessagePayload holds the information needed to send a spell area of effect element to a map.
 type LoadSpellAreaOfEffectObjectMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	SpellAreaOfEffectElement
 }
 
 // LoadTextObjectMessagePayload holds the information needed to send a text element to a map.
 type LoadTextObjectMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	TextElement
 }
 
 // LoadTileObjectMessagePayload holds the information needed to send a graphic tile element to a map.
 type LoadTileObjectMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	TileElement
 }
 
-//TODOfunc (c LoadArcObjectMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 8 // LS-ARC
-//TODO	l += 121 + len(c.ID) + len(c.Stipple) + len(c.Line) + len(c.Fill) + len(c.Layer) + len(c.Group) + 45
-//TODO	// ID:x X:x Y:x Z:x Stipple:x Line:x Fill:x Width:i Layer:x Level:i Goup:x Dash:i Hidden:false Locked:false
-//TODO	l += 30 + 22 // ArcMode:i Start:f Extent:f
-//TODO	l += 13      // Points:[X:f Y:f]
-//TODO	l += (10 + 20) * len(c.Points)
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c LoadArcObjectMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.Points))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, instance := range c.Points {
-//TODO		p := LoadArcObjectMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.Points),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.ID = c.ID
-//TODO			p.X = c.X
-//TODO			p.Y = c.Y
-//TODO			p.Z = c.Z
-//TODO			p.Hidden = c.Hidden
-//TODO			p.Locked = c.Locked
-//TODO			p.Dash = c.Dash
-//TODO			p.Width = c.Width
-//TODO			p.Level = c.Level
-//TODO			p.Line = c.Line
-//TODO			p.Fill = c.Fill
-//TODO			p.Stipple = c.Stipple
-//TODO			p.Layer = c.Layer
-//TODO			p.Group = c.Group
-//TODO			//
-//TODO			p.ArcMode = c.ArcMode
-//TODO			p.Start = c.Start
-//TODO			p.Extent = c.Extent
-//TODO		}
-//TODO		p.Points = []Coordinates{instance}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c LoadArcObjectMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := LoadArcObjectMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(LoadArcObjectMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.ID = pp.ID
-//TODO			newp.X = pp.X
-//TODO			newp.Y = pp.Y
-//TODO			newp.Z = pp.Z
-//TODO			newp.Hidden = pp.Hidden
-//TODO			newp.Locked = pp.Locked
-//TODO			newp.Dash = pp.Dash
-//TODO			newp.Width = pp.Width
-//TODO			newp.Level = pp.Level
-//TODO			newp.Line = pp.Line
-//TODO			newp.Fill = pp.Fill
-//TODO			newp.Stipple = pp.Stipple
-//TODO			newp.Layer = pp.Layer
-//TODO			newp.Group = pp.Group
-//TODO			//
-//TODO			newp.ArcMode = pp.ArcMode
-//TODO			newp.Start = pp.Start
-//TODO			newp.Extent = pp.Extent
-//TODO		}
-//TODO		for _, pt := range pp.Points {
-//TODO			newp.Points = append(newp.Points, pt)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c LoadArcObjectMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return LoadArcObjectMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		ArcElement: ArcElement{
-//TODO			MapElement: MapElement{
-//TODO				BaseMapObject: BaseMapObject{
-//TODO					ID: c.ID,
-//TODO				},
-//TODO				Coordinates: Coordinates{
-//TODO					X: c.X,
-//TODO					Y: c.Y,
-//TODO				},
-//TODO				Z: c.Z,
-//TODO			},
-//TODO		},
-//TODO	}
-//TODO}
-//TODO
-//TODOfunc (c LoadCircleObjectMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 9 // LS-CIRC
-//TODO	l += 121 + len(c.ID) + len(c.Stipple) + len(c.Line) + len(c.Fill) + len(c.Layer) + len(c.Group) + 45
-//TODO	// ID:x X:x Y:x Z:x Stipple:x Line:x Fill:x Width:i Layer:x Level:i Goup:x Dash:i Hidden:false Locked:false
-//TODO	l += 13 // Points:[X:f Y:f]
-//TODO	l += (10 + 20) * len(c.Points)
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c LoadCircleObjectMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.Points))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, instance := range c.Points {
-//TODO		p := LoadCircleObjectMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.Points),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.ID = c.ID
-//TODO			p.X = c.X
-//TODO			p.Y = c.Y
-//TODO			p.Z = c.Z
-//TODO			p.Hidden = c.Hidden
-//TODO			p.Locked = c.Locked
-//TODO			p.Dash = c.Dash
-//TODO			p.Width = c.Width
-//TODO			p.Level = c.Level
-//TODO			p.Line = c.Line
-//TODO			p.Fill = c.Fill
-//TODO			p.Stipple = c.Stipple
-//TODO			p.Layer = c.Layer
-//TODO			p.Group = c.Group
-//TODO			//
-//TODO		}
-//TODO		p.Points = []Coordinates{instance}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c LoadCircleObjectMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := LoadCircleObjectMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(LoadCircleObjectMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.ID = pp.ID
-//TODO			newp.X = pp.X
-//TODO			newp.Y = pp.Y
-//TODO			newp.Z = pp.Z
-//TODO			newp.Hidden = pp.Hidden
-//TODO			newp.Locked = pp.Locked
-//TODO			newp.Dash = pp.Dash
-//TODO			newp.Width = pp.Width
-//TODO			newp.Level = pp.Level
-//TODO			newp.Line = pp.Line
-//TODO			newp.Fill = pp.Fill
-//TODO			newp.Stipple = pp.Stipple
-//TODO			newp.Layer = pp.Layer
-//TODO			newp.Group = pp.Group
-//TODO			//
-//TODO		}
-//TODO		for _, pt := range pp.Points {
-//TODO			newp.Points = append(newp.Points, pt)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c LoadCircleObjectMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return LoadCircleObjectMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		CircleElement: CircleElement{
-//TODO			MapElement: MapElement{
-//TODO				BaseMapObject: BaseMapObject{
-//TODO					ID: c.ID,
-//TODO				},
-//TODO				Coordinates: Coordinates{
-//TODO					X: c.X,
-//TODO					Y: c.Y,
-//TODO				},
-//TODO				Z: c.Z,
-//TODO			},
-//TODO		},
-//TODO	}
-//TODO}
-//TODO
-//TODOfunc (c LoadLineObjectMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 8 // LS-LINE
-//TODO	l += 121 + len(c.ID) + len(c.Stipple) + len(c.Line) + len(c.Fill) + len(c.Layer) + len(c.Group) + 45
-//TODO	// ID:x X:x Y:x Z:x Stipple:x Line:x Fill:x Width:i Layer:x Level:i Goup:x Dash:i Hidden:false Locked:false
-//TODO	l += 10 // Arrow:i
-//TODO	l += 13 // Points:[X:f Y:f]
-//TODO	l += (10 + 20) * len(c.Points)
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c LoadLineObjectMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.Points))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, instance := range c.Points {
-//TODO		p := LoadLineObjectMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.Points),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.ID = c.ID
-//TODO			p.X = c.X
-//TODO			p.Y = c.Y
-//TODO			p.Z = c.Z
-//TODO			p.Hidden = c.Hidden
-//TODO			p.Locked = c.Locked
-//TODO			p.Dash = c.Dash
-//TODO			p.Width = c.Width
-//TODO			p.Level = c.Level
-//TODO			p.Line = c.Line
-//TODO			p.Fill = c.Fill
-//TODO			p.Stipple = c.Stipple
-//TODO			p.Layer = c.Layer
-//TODO			p.Group = c.Group
-//TODO			//
-//TODO			p.Arrow = c.Arrow
-//TODO		}
-//TODO		p.Points = []Coordinates{instance}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c LoadLineObjectMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := LoadLineObjectMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(LoadLineObjectMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.ID = pp.ID
-//TODO			newp.X = pp.X
-//TODO			newp.Y = pp.Y
-//TODO			newp.Z = pp.Z
-//TODO			newp.Hidden = pp.Hidden
-//TODO			newp.Locked = pp.Locked
-//TODO			newp.Dash = pp.Dash
-//TODO			newp.Width = pp.Width
-//TODO			newp.Level = pp.Level
-//TODO			newp.Line = pp.Line
-//TODO			newp.Fill = pp.Fill
-//TODO			newp.Stipple = pp.Stipple
-//TODO			newp.Layer = pp.Layer
-//TODO			newp.Group = pp.Group
-//TODO			//
-//TODO			newp.Arrow = pp.Arrow
-//TODO		}
-//TODO		for _, pt := range pp.Points {
-//TODO			newp.Points = append(newp.Points, pt)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c LoadLineObjectMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return LoadLineObjectMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		LineElement: LineElement{
-//TODO			MapElement: MapElement{
-//TODO				BaseMapObject: BaseMapObject{
-//TODO					ID: c.ID,
-//TODO				},
-//TODO				Coordinates: Coordinates{
-//TODO					X: c.X,
-//TODO					Y: c.Y,
-//TODO				},
-//TODO				Z: c.Z,
-//TODO			},
-//TODO		},
-//TODO	}
-//TODO}
-//TODO
-//TODOfunc (c LoadPolygonObjectMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 8 // LS-POLY
-//TODO	l += 121 + len(c.ID) + len(c.Stipple) + len(c.Line) + len(c.Fill) + len(c.Layer) + len(c.Group) + 45
-//TODO	// ID:x X:x Y:x Z:x Stipple:x Line:x Fill:x Width:i Layer:x Level:i Goup:x Dash:i Hidden:false Locked:false
-//TODO	l += 20 // Spline:i Join:i
-//TODO	l += 13 // Points:[X:f Y:f]
-//TODO	l += (10 + 20) * len(c.Points)
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c LoadPolygonObjectMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.Points))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, instance := range c.Points {
-//TODO		p := LoadPolygonObjectMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.Points),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.ID = c.ID
-//TODO			p.X = c.X
-//TODO			p.Y = c.Y
-//TODO			p.Z = c.Z
-//TODO			p.Hidden = c.Hidden
-//TODO			p.Locked = c.Locked
-//TODO			p.Dash = c.Dash
-//TODO			p.Width = c.Width
-//TODO			p.Level = c.Level
-//TODO			p.Line = c.Line
-//TODO			p.Fill = c.Fill
-//TODO			p.Stipple = c.Stipple
-//TODO			p.Layer = c.Layer
-//TODO			p.Group = c.Group
-//TODO			//
-//TODO			p.Spline = c.Spline
-//TODO			p.Join = c.Join
-//TODO		}
-//TODO		p.Points = []Coordinates{instance}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c LoadPolygonObjectMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := LoadPolygonObjectMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(LoadPolygonObjectMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.ID = pp.ID
-//TODO			newp.X = pp.X
-//TODO			newp.Y = pp.Y
-//TODO			newp.Z = pp.Z
-//TODO			newp.Hidden = pp.Hidden
-//TODO			newp.Locked = pp.Locked
-//TODO			newp.Dash = pp.Dash
-//TODO			newp.Width = pp.Width
-//TODO			newp.Level = pp.Level
-//TODO			newp.Line = pp.Line
-//TODO			newp.Fill = pp.Fill
-//TODO			newp.Stipple = pp.Stipple
-//TODO			newp.Layer = pp.Layer
-//TODO			newp.Group = pp.Group
-//TODO			//
-//TODO			newp.Spline = pp.Spline
-//TODO			newp.Join = pp.Join
-//TODO		}
-//TODO		for _, pt := range pp.Points {
-//TODO			newp.Points = append(newp.Points, pt)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c LoadPolygonObjectMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return LoadPolygonObjectMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		PolygonElement: PolygonElement{
-//TODO			MapElement: MapElement{
-//TODO				BaseMapObject: BaseMapObject{
-//TODO					ID: c.ID,
-//TODO				},
-//TODO				Coordinates: Coordinates{
-//TODO					X: c.X,
-//TODO					Y: c.Y,
-//TODO				},
-//TODO				Z: c.Z,
-//TODO			},
-//TODO		},
-//TODO	}
-//TODO}
-//TODO
-//TODOfunc (c LoadRectangleObjectMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 8 // LS-ARC
-//TODO	l += 121 + len(c.ID) + len(c.Stipple) + len(c.Line) + len(c.Fill) + len(c.Layer) + len(c.Group) + 45
-//TODO	// ID:x X:x Y:x Z:x Stipple:x Line:x Fill:x Width:i Layer:x Level:i Goup:x Dash:i Hidden:false Locked:false
-//TODO	l += 13 // Points:[X:f Y:f]
-//TODO	l += (10 + 20) * len(c.Points)
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c LoadRectangleObjectMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.Points))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, instance := range c.Points {
-//TODO		p := LoadRectangleObjectMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.Points),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.ID = c.ID
-//TODO			p.X = c.X
-//TODO			p.Y = c.Y
-//TODO			p.Z = c.Z
-//TODO			p.Hidden = c.Hidden
-//TODO			p.Locked = c.Locked
-//TODO			p.Dash = c.Dash
-//TODO			p.Width = c.Width
-//TODO			p.Level = c.Level
-//TODO			p.Line = c.Line
-//TODO			p.Fill = c.Fill
-//TODO			p.Stipple = c.Stipple
-//TODO			p.Layer = c.Layer
-//TODO			p.Group = c.Group
-//TODO			//
-//TODO		}
-//TODO		p.Points = []Coordinates{instance}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c LoadRectangleObjectMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := LoadRectangleObjectMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(LoadRectangleObjectMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.ID = pp.ID
-//TODO			newp.X = pp.X
-//TODO			newp.Y = pp.Y
-//TODO			newp.Z = pp.Z
-//TODO			newp.Hidden = pp.Hidden
-//TODO			newp.Locked = pp.Locked
-//TODO			newp.Dash = pp.Dash
-//TODO			newp.Width = pp.Width
-//TODO			newp.Level = pp.Level
-//TODO			newp.Line = pp.Line
-//TODO			newp.Fill = pp.Fill
-//TODO			newp.Stipple = pp.Stipple
-//TODO			newp.Layer = pp.Layer
-//TODO			newp.Group = pp.Group
-//TODO			//
-//TODO		}
-//TODO		for _, pt := range pp.Points {
-//TODO			newp.Points = append(newp.Points, pt)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c LoadRectangleObjectMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return LoadRectangleObjectMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		RectangleElement: RectangleElement{
-//TODO			MapElement: MapElement{
-//TODO				BaseMapObject: BaseMapObject{
-//TODO					ID: c.ID,
-//TODO				},
-//TODO				Coordinates: Coordinates{
-//TODO					X: c.X,
-//TODO					Y: c.Y,
-//TODO				},
-//TODO				Z: c.Z,
-//TODO			},
-//TODO		},
-//TODO	}
-//TODO}
-//TODO
-//TODOfunc (c LoadSpellAreaOfEffectObjectMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 10 // LS-SAOE
-//TODO	l += 121 + len(c.ID) + len(c.Stipple) + len(c.Line) + len(c.Fill) + len(c.Layer) + len(c.Group) + 45
-//TODO	// ID:x X:x Y:x Z:x Stipple:x Line:x Fill:x Width:i Layer:x Level:i Goup:x Dash:i Hidden:false Locked:false
-//TODO	l += 30 + 22 // AoEShape:i
-//TODO	l += 13      // Points:[X:f Y:f]
-//TODO	l += (10 + 20) * len(c.Points)
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c LoadSpellAreaOfEffectObjectMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.Points))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, instance := range c.Points {
-//TODO		p := LoadSpellAreaOfEffectObjectMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.Points),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.ID = c.ID
-//TODO			p.X = c.X
-//TODO			p.Y = c.Y
-//TODO			p.Z = c.Z
-//TODO			p.Hidden = c.Hidden
-//TODO			p.Locked = c.Locked
-//TODO			p.Dash = c.Dash
-//TODO			p.Width = c.Width
-//TODO			p.Level = c.Level
-//TODO			p.Line = c.Line
-//TODO			p.Fill = c.Fill
-//TODO			p.Stipple = c.Stipple
-//TODO			p.Layer = c.Layer
-//TODO			p.Group = c.Group
-//TODO			//
-//TODO			p.AoEShape = c.AoEShape
-//TODO		}
-//TODO		p.Points = []Coordinates{instance}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c LoadSpellAreaOfEffectObjectMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := LoadSpellAreaOfEffectObjectMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(LoadSpellAreaOfEffectObjectMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.ID = pp.ID
-//TODO			newp.X = pp.X
-//TODO			newp.Y = pp.Y
-//TODO			newp.Z = pp.Z
-//TODO			newp.Hidden = pp.Hidden
-//TODO			newp.Locked = pp.Locked
-//TODO			newp.Dash = pp.Dash
-//TODO			newp.Width = pp.Width
-//TODO			newp.Level = pp.Level
-//TODO			newp.Line = pp.Line
-//TODO			newp.Fill = pp.Fill
-//TODO			newp.Stipple = pp.Stipple
-//TODO			newp.Layer = pp.Layer
-//TODO			newp.Group = pp.Group
-//TODO			//
-//TODO			newp.AoEShape = pp.AoEShape
-//TODO		}
-//TODO		for _, pt := range pp.Points {
-//TODO			newp.Points = append(newp.Points, pt)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c LoadSpellAreaOfEffectObjectMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return LoadSpellAreaOfEffectObjectMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		SpellAreaOfEffectElement: SpellAreaOfEffectElement{
-//TODO			MapElement: MapElement{
-//TODO				BaseMapObject: BaseMapObject{
-//TODO					ID: c.ID,
-//TODO				},
-//TODO				Coordinates: Coordinates{
-//TODO					X: c.X,
-//TODO					Y: c.Y,
-//TODO				},
-//TODO				Z: c.Z,
-//TODO			},
-//TODO		},
-//TODO	}
-//TODO}
-//TODO
-//TODOfunc (c LoadTextObjectMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 8 // LS-ARC
-//TODO	l += 121 + len(c.ID) + len(c.Stipple) + len(c.Line) + len(c.Fill) + len(c.Layer) + len(c.Group) + 45
-//TODO	// ID:x X:x Y:x Z:x Stipple:x Line:x Fill:x Width:i Layer:x Level:i Goup:x Dash:i Hidden:false Locked:false
-//TODO	l += 78 + len(c.Text) + len(c.Font.Family) // Text:x Font:{Family:x Size:f Weight:i Slant:i} Anchor:i
-//TODO	l += 13                                    // Points:[X:f Y:f]
-//TODO	l += (10 + 20) * len(c.Points)
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c LoadTextObjectMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.Points))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, instance := range c.Points {
-//TODO		p := LoadTextObjectMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.Points),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.ID = c.ID
-//TODO			p.X = c.X
-//TODO			p.Y = c.Y
-//TODO			p.Z = c.Z
-//TODO			p.Hidden = c.Hidden
-//TODO			p.Locked = c.Locked
-//TODO			p.Dash = c.Dash
-//TODO			p.Width = c.Width
-//TODO			p.Level = c.Level
-//TODO			p.Line = c.Line
-//TODO			p.Fill = c.Fill
-//TODO			p.Stipple = c.Stipple
-//TODO			p.Layer = c.Layer
-//TODO			p.Group = c.Group
-//TODO			//
-//TODO			p.Anchor = c.Anchor
-//TODO			p.Text = c.Text
-//TODO			p.Font = c.Font
-//TODO		}
-//TODO		p.Points = []Coordinates{instance}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c LoadTextObjectMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := LoadTextObjectMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(LoadTextObjectMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.ID = pp.ID
-//TODO			newp.X = pp.X
-//TODO			newp.Y = pp.Y
-//TODO			newp.Z = pp.Z
-//TODO			newp.Hidden = pp.Hidden
-//TODO			newp.Locked = pp.Locked
-//TODO			newp.Dash = pp.Dash
-//TODO			newp.Width = pp.Width
-//TODO			newp.Level = pp.Level
-//TODO			newp.Line = pp.Line
-//TODO			newp.Fill = pp.Fill
-//TODO			newp.Stipple = pp.Stipple
-//TODO			newp.Layer = pp.Layer
-//TODO			newp.Group = pp.Group
-//TODO			//
-//TODO			newp.Anchor = pp.Anchor
-//TODO			newp.Text = pp.Text
-//TODO			newp.Font = pp.Font
-//TODO		}
-//TODO		for _, pt := range pp.Points {
-//TODO			newp.Points = append(newp.Points, pt)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c LoadTextObjectMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return LoadTextObjectMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		TextElement: TextElement{
-//TODO			MapElement: MapElement{
-//TODO				BaseMapObject: BaseMapObject{
-//TODO					ID: c.ID,
-//TODO				},
-//TODO				Coordinates: Coordinates{
-//TODO					X: c.X,
-//TODO					Y: c.Y,
-//TODO				},
-//TODO				Z: c.Z,
-//TODO			},
-//TODO			Text: c.Text,
-//TODO		},
-//TODO	}
-//TODO}
-//TODO
-//TODOfunc (c LoadTileObjectMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 10 // LS-TILE
-//TODO	l += 121 + len(c.ID) + len(c.Stipple) + len(c.Line) + len(c.Fill) + len(c.Layer) + len(c.Group) + 45
-//TODO	// ID:x X:x Y:x Z:x Stipple:x Line:x Fill:x Width:i Layer:x Level:i Goup:x Dash:i Hidden:false Locked:false
-//TODO	l += 52 + len(c.Image) // Image:x BBHeight:f BBWidth:f
-//TODO	l += 13                // Points:[X:f Y:f]
-//TODO	l += (10 + 20) * len(c.Points)
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c LoadTileObjectMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.Points))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, instance := range c.Points {
-//TODO		p := LoadTileObjectMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.Points),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.ID = c.ID
-//TODO			p.X = c.X
-//TODO			p.Y = c.Y
-//TODO			p.Z = c.Z
-//TODO			p.Hidden = c.Hidden
-//TODO			p.Locked = c.Locked
-//TODO			p.Dash = c.Dash
-//TODO			p.Width = c.Width
-//TODO			p.Level = c.Level
-//TODO			p.Line = c.Line
-//TODO			p.Fill = c.Fill
-//TODO			p.Stipple = c.Stipple
-//TODO			p.Layer = c.Layer
-//TODO			p.Group = c.Group
-//TODO			//
-//TODO			p.Image = c.Image
-//TODO			p.BBHeight = c.BBHeight
-//TODO			p.BBWidth = c.BBWidth
-//TODO		}
-//TODO		p.Points = []Coordinates{instance}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c LoadTileObjectMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := LoadTileObjectMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(LoadTileObjectMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.ID = pp.ID
-//TODO			newp.X = pp.X
-//TODO			newp.Y = pp.Y
-//TODO			newp.Z = pp.Z
-//TODO			newp.Hidden = pp.Hidden
-//TODO			newp.Locked = pp.Locked
-//TODO			newp.Dash = pp.Dash
-//TODO			newp.Width = pp.Width
-//TODO			newp.Level = pp.Level
-//TODO			newp.Line = pp.Line
-//TODO			newp.Fill = pp.Fill
-//TODO			newp.Stipple = pp.Stipple
-//TODO			newp.Layer = pp.Layer
-//TODO			newp.Group = pp.Group
-//TODO			//
-//TODO			newp.Image = pp.Image
-//TODO			newp.BBHeight = pp.BBHeight
-//TODO			newp.BBWidth = pp.BBWidth
-//TODO		}
-//TODO		for _, pt := range pp.Points {
-//TODO			newp.Points = append(newp.Points, pt)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c LoadTileObjectMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return LoadTileObjectMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		TileElement: TileElement{
-//TODO			MapElement: MapElement{
-//TODO				BaseMapObject: BaseMapObject{
-//TODO					ID: c.ID,
-//TODO				},
-//TODO				Coordinates: Coordinates{
-//TODO					X: c.X,
-//TODO					Y: c.Y,
-//TODO				},
-//TODO				Z: c.Z,
-//TODO			},
-//TODO			Image: c.Image,
-//TODO		},
-//TODO	}
-//TODO}
-
 // LoadObject sends a MapObject to all peers.
 // It may be given a value of any of the supported MapObject
 // types for map graphic elements (Arc, Circle, Line, Polygon,
@@ -3637,161 +2395,9 @@ func (c *Connection) Mark(x, y float64) error {
 // Call the PlaceSomeone method to send this message out to other clients.
 type PlaceSomeoneMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	CreatureToken
 }
 
-//TODOfunc (c PlaceSomeoneMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 100 + len(c.ID)                                                 // PS ID:x Killed:false Dim:false PolyGM:false CreatureType:i MoveMode:i Reach:i Skin:i
-//TODO	l += 112 + len(c.Name) + len(c.Note) + len(c.Size) + len(c.DispSize) // Elev:i Gx:f Gy:f Name:x SkinSize:[x] Note:x Size:x DispSize:x StatusList:[x]
-//TODO	for _, s := range c.SkinSize {
-//TODO		l += len(s)
-//TODO	}
-//TODO	for _, s := range c.StatusList {
-//TODO		l += len(s)
-//TODO	}
-//TODO	l += 73 // CustomReach:{Enabled:false Natural:i Extended:i} Targets:[x]
-//TODO	for _, t := range c.Targets {
-//TODO		l += len(t)
-//TODO	}
-//TODO	l += 23 // TargetedModifiers:{x:{Type:x Shape:x Color:x Modifiers:[x]}}
-//TODO	for tk, tv := range c.TargetedModifiers {
-//TODO		l += len(tk) + 6 + 41 + len(tv.Type) + len(tv.Shape) + len(tv.Color)
-//TODO		for _, m := range tv.Modifiers {
-//TODO			l += len(m)
-//TODO		}
-//TODO	}
-//TODO	if c.AoE == nil {
-//TODO		l += 8
-//TODO	} else {
-//TODO		l += 38 + len(c.AoE.Color) // Aoe*{Radius:f Color:x}
-//TODO	}
-//TODO	if c.Health == nil {
-//TODO		l += 14
-//TODO	} else {
-//TODO		l += 132                          // Health:*{IsFlatFooted:false IsStable:false MaxHP:i TmpHP:i TmpDamage:i LethalDamage:i NonLethalDamage:i}
-//TODO		l += 82 + len(c.Health.Condition) // Con:i HPBlur:i Condition:x AC:i FlatFootedAC:i TouchAC:i CMD:i
-//TODO	}
-//TODO
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c PlaceSomeoneMessagePayload) Split() []any {
-//TODO	fragments := max(len(c.Targets), len(c.TargetedModifiers))
-//TODO	payloads := make([]any, fragments)
-//TODO	gid := uuid.NewString()
-//TODO	tmods := []string{}
-//TODO	for k, _ := range c.TargetedModifiers {
-//TODO		tmods = append(tmods, k)
-//TODO	}
-//TODO
-//TODO	for i := range fragments {
-//TODO		p := PlaceSomeoneMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: fragments,
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.ID = c.ID
-//TODO			p.Killed = c.Killed
-//TODO			p.Dim = c.Dim
-//TODO			p.Hidden = c.Hidden
-//TODO			p.PolyGM = c.PolyGM
-//TODO			p.CreatureType = c.CreatureType
-//TODO			p.MoveMode = c.MoveMode
-//TODO			p.Reach = c.Reach
-//TODO			p.Skin = c.Skin
-//TODO			p.Elev = c.Elev
-//TODO			p.Gx = c.Gx
-//TODO			p.Gy = c.Gy
-//TODO			p.Name = c.Name
-//TODO			p.Health = c.Health
-//TODO			p.SkinSize = c.SkinSize
-//TODO			p.Color = c.Color
-//TODO			p.Note = c.Note
-//TODO			p.Size = c.Size
-//TODO			p.DispSize = c.DispSize
-//TODO			p.StatusList = c.StatusList
-//TODO			p.AoE = c.AoE
-//TODO			p.CustomReach = c.CustomReach
-//TODO		}
-//TODO		if len(c.Targets) > i {
-//TODO			p.Targets = []string{c.Targets[i]}
-//TODO		}
-//TODO		if len(tmods) > i {
-//TODO			p.TargetedModifiers = map[string]CustomConditionModifier{tmods[i]: c.TargetedModifiers[tmods[i]]}
-//TODO		}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c PlaceSomeoneMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := PlaceSomeoneMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(PlaceSomeoneMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.ID = pp.ID
-//TODO			newp.Killed = pp.Killed
-//TODO			newp.Dim = pp.Dim
-//TODO			newp.Hidden = pp.Hidden
-//TODO			newp.PolyGM = pp.PolyGM
-//TODO			newp.CreatureType = pp.CreatureType
-//TODO			newp.MoveMode = pp.MoveMode
-//TODO			newp.Reach = pp.Reach
-//TODO			newp.Skin = pp.Skin
-//TODO			newp.Elev = pp.Elev
-//TODO			newp.Gx = pp.Gx
-//TODO			newp.Gy = pp.Gy
-//TODO			newp.Name = pp.Name
-//TODO			newp.Health = pp.Health
-//TODO			newp.SkinSize = pp.SkinSize
-//TODO			newp.Color = pp.Color
-//TODO			newp.Note = pp.Note
-//TODO			newp.DispSize = pp.DispSize
-//TODO			newp.StatusList = pp.StatusList
-//TODO			newp.AoE = pp.AoE
-//TODO			newp.CustomReach = pp.CustomReach
-//TODO		}
-//TODO		for _, t := range pp.Targets {
-//TODO			newp.Targets = append(newp.Targets, t)
-//TODO		}
-//TODO		for k, v := range pp.TargetedModifiers {
-//TODO			newp.TargetedModifiers[k] = v
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c PlaceSomeoneMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return PlaceSomeoneMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		CreatureToken: CreatureToken{
-//TODO			BaseMapObject: BaseMapObject{
-//TODO				ID: c.ID,
-//TODO			},
-//TODO			Name: c.Name,
-//TODO		},
-//TODO	}
-//TODO}
-
 // PlaceSomeone tells all peers to add a new creature token on their
 // maps. The parameter passed must be either a PlayerToken or MonsterToken.
 //
@@ -3824,7 +2430,6 @@ func (c *Connection) PlaceSomeone(someone any) error {
 // stop playing an audio clip on a client.
 type PlayAudioMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 
 	// Name is the sound clip ID as known by the mapper.
 	// This may be "*" to refer to all sounds (e.g., to stop all playing sounds).
@@ -3846,78 +2451,6 @@ type PlayAudioMessagePayload struct {
 	Addrs []string
 }
 
-//TODOfunc (c PlayAudioMessagePayload) NeedsToBeSplit() bool {
-//TODO	// AI Animation Name Sizes :
-//TODO	l := 71 + len(c.Name) // AA Name:x Loop:false Stop:false IsLocalFile:false  Addrs:[x]
-//TODO	for _, a := range c.Addrs {
-//TODO		l += len(a) + 3
-//TODO	}
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c PlayAudioMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.Addrs))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, instance := range c.Addrs {
-//TODO		p := PlayAudioMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.Addrs),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.Name = c.Name
-//TODO			p.Loop = c.Loop
-//TODO			p.Stop = c.Stop
-//TODO			p.IsLocalFile = c.IsLocalFile
-//TODO		}
-//TODO		p.Addrs = []string{instance}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c PlayAudioMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	ai := PlayAudioMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		img, ok := d.(PlayAudioMessagePayload)
-//TODO		if !ok {
-//TODO			return ai, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if img.Batch != i {
-//TODO			return ai, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), img.Batch)
-//TODO		}
-//TODO		if img.TotalBatches != len(p) {
-//TODO			return ai, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, img.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			ai.Name = img.Name
-//TODO			ai.Loop = img.Loop
-//TODO			ai.Stop = img.Stop
-//TODO			ai.IsLocalFile = img.IsLocalFile
-//TODO		}
-//TODO		for _, a := range img.Addrs {
-//TODO			ai.Addrs = append(ai.Addrs, a)
-//TODO		}
-//TODO	}
-//TODO	return ai, nil
-//TODO}
-//TODO
-//TODOfunc (c PlayAudioMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return PlayAudioMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		Name: c.Name,
-//TODO	}
-//TODO}
-
 // PlayAudio requests that clients start playing a sound.
 func (c *Connection) PlayAudio(name string) error {
 	if c == nil {
@@ -3999,85 +2532,9 @@ type PrivMessagePayload struct {
 // Call the QueryImage method to send this message out to other clients.
 type QueryImageMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	ImageDefinition
 }
 
-//TODOfunc (c QueryImageMessagePayload) NeedsToBeSplit() bool {
-//TODO	// AI Animation Name Sizes :
-//TODO	l := 7                 // "AI? {}"
-//TODO	l += 8 + len(c.Name)   // "Name":,
-//TODO	l += 11                // "Sizes": []
-//TODO	l += len(c.Sizes) * 13 // "Zoom": (float)
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c QueryImageMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return QueryImageMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		ImageDefinition: ImageDefinition{
-//TODO			Name: c.Name,
-//TODO		},
-//TODO	}
-//TODO}
-//TODO
-//TODO// Split records on Sizes (which are the instances of the images we
-//TODO// have at different zoom factors, etc.)
-//TODO//
-//TODO// 0  Name,
-//TODO// 0  Animation->{Frames,FrameSpeed,Loops}
-//TODO// 0+ Sizes[File,ImageData,IsLocalFile,Zoom]
-//TODOfunc (c QueryImageMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.Sizes))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, instance := range c.Sizes {
-//TODO		p := QueryImageMessagePayload{
-//TODO			ImageDefinition: ImageDefinition{
-//TODO				Sizes: []ImageInstance{instance},
-//TODO			},
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.Sizes),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.Name = c.Name
-//TODO		}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c QueryImageMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	ai := QueryImageMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		img, ok := d.(QueryImageMessagePayload)
-//TODO		if !ok {
-//TODO			return ai, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if img.Batch != i {
-//TODO			return ai, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), img.Batch)
-//TODO		}
-//TODO		if img.TotalBatches != len(p) {
-//TODO			return ai, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, img.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			ai.Name = img.Name
-//TODO			ai.Sizes = make([]ImageInstance, len(p))
-//TODO		}
-//TODO		ai.Sizes[i] = img.Sizes[0]
-//TODO	}
-//TODO	return ai, nil
-//TODO}
-
 // QueryImage asks the server and peers if anyone else knows
 // where to find the data for the given image name and zoom factor.
 // If someone does, you'll receive an AddImage message.
@@ -4155,7 +2612,6 @@ type ReadyMessagePayload struct {
 // Call the RemoveObjAttributes method to send this message out to other clients.
 type RemoveObjAttributesMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 
 	// The ID of the object to be modified
 	ObjID string
@@ -4167,74 +2623,6 @@ type RemoveObjAttributesMessagePayload struct {
 	Values []string
 }
 
-//TODOfunc (c RemoveObjAttributesMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 37 + len(c.ObjID) + len(c.AttrName) // OA- ObjID:x AttrName:x Values:[]
-//TODO	for _, v := range c.Values {
-//TODO		l += len(v) + 3
-//TODO	}
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c RemoveObjAttributesMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.Values))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, v := range c.Values {
-//TODO		p := RemoveObjAttributesMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.Values),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.ObjID = c.ObjID
-//TODO			p.AttrName = c.AttrName
-//TODO		}
-//TODO		p.Values = []string{v}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c RemoveObjAttributesMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := RemoveObjAttributesMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(RemoveObjAttributesMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.ObjID = pp.ObjID
-//TODO			newp.AttrName = pp.AttrName
-//TODO		}
-//TODO		for _, v := range pp.Values {
-//TODO			newp.Values = append(newp.Values, v)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c RemoveObjAttributesMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return RemoveObjAttributesMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		ObjID:    c.ObjID,
-//TODO		AttrName: c.AttrName,
-//TODO	}
-//TODO}
-
 // RemoveObjAttributes informs peers to remove a set of string values from the existing
 // value of an object attribute. The attribute must be one whose value is a list
 // of strings, such as StatusList.
@@ -4273,6 +2661,10 @@ func (c *Connection) RemoveObjAttributes(objID, attrName string, values []string
 // dice.Roll function and dice.DieRoller.DoRoll method. See the dice package for details.
 // https://pkg.go.dev/github.com/MadScienceZone/go-gma/v5/dice#DieRoller.DoRoll
 //
+// The rollspec may also refer to the user's (or global) die-roll presets stored on
+// the server by name, as in "@Longsword to-hit + 2 flanking". The server expands
+// these as described for dice.DieRoller.DefinePresets.
+//
 // Added in version 5.30.0: optional list of option parameters to specify different
 // options to the die rolls to avoid needless proliferation of permutations of
 // methods for all the different ways we can arrange die rolls.
@@ -4363,7 +2755,6 @@ func (c *Connection) RollDiceWithID(to []string, rollspec string, requestID stri
 // server when requesting a die roll.
 type RollDiceMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	ChatCommon
 
 	// If you want to track the results to the requests that created them,
@@ -4389,112 +2780,6 @@ const (
 	DTypeDamage      = "damage"
 )
 
-//TODOfunc (c RollDiceMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 52 + len(c.RequestID) + len(c.RollSpec) + len(c.Type) // D RequestID RollSpec Targets Type {}
-//TODO	l += 103 + 10 + 34 + len(c.Sender)                         // ChatCommon: "Origin":false, "Replay":false, "Sender":x, "Recipients":[], "MessageID":int, "ToAll":false, "ToGM":false, "Sent":time[34]
-//TODO	for _, r := range c.Recipients {
-//TODO		l += len(r)
-//TODO	}
-//TODO	for _, t := range c.Targets {
-//TODO		l += len(t)
-//TODO	}
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODO// Split records on Sizes (which are the instances of the images we
-//TODO// have at different zoom factors, etc.)
-//TODO//
-//TODO// 0  Name,
-//TODO// 0  Animation->{Frames,FrameSpeed,Loops}
-//TODO// 0+ Sizes[File,ImageData,IsLocalFile,Zoom]
-//TODOfunc (c RollDiceMessagePayload) Split() []any {
-//TODO	fragments := max(len(c.Targets), len(c.Recipients))
-//TODO	payloads := make([]any, fragments)
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i := range fragments {
-//TODO		p := RollDiceMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: fragments,
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.Origin = c.Origin
-//TODO			p.Replay = c.Replay
-//TODO			p.Sender = c.Sender
-//TODO			p.MessageID = c.MessageID
-//TODO			p.ToAll = c.ToAll
-//TODO			p.ToGM = c.ToGM
-//TODO			p.Sent = c.Sent
-//TODO			p.RequestID = c.RequestID
-//TODO			p.RollSpec = c.RollSpec
-//TODO			p.Type = c.Type
-//TODO		}
-//TODO		if len(c.Recipients) > i {
-//TODO			p.Recipients = []string{c.Recipients[i]}
-//TODO		}
-//TODO		if len(c.Targets) > i {
-//TODO			p.Targets = []string{c.Targets[i]}
-//TODO		}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c RollDiceMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := RollDiceMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(RollDiceMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.Origin = pp.Origin
-//TODO			newp.Replay = pp.Replay
-//TODO			newp.Sender = pp.Sender
-//TODO			newp.MessageID = pp.MessageID
-//TODO			newp.ToAll = pp.ToAll
-//TODO			newp.ToGM = pp.ToGM
-//TODO			newp.RequestID = pp.RequestID
-//TODO			newp.RollSpec = pp.RollSpec
-//TODO			newp.Type = pp.Type
-//TODO		}
-//TODO		for _, s := range pp.Targets {
-//TODO			newp.Targets = append(newp.Targets, s)
-//TODO		}
-//TODO		for _, s := range pp.Recipients {
-//TODO			newp.Recipients = append(newp.Recipients, s)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c RollDiceMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return RollDiceMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		ChatCommon: ChatCommon{
-//TODO			MessageID: c.MessageID,
-//TODO		},
-//TODO		RequestID: c.RequestID,
-//TODO		RollSpec:  c.RollSpec,
-//TODO		Type:      c.Type,
-//TODO	}
-//TODO}
-
 // RollDiceToAll is equivalent to RollDice, sending the results to all users.
 func (c *Connection) RollDiceToAll(rollspec string) error {
 	if c == nil {
@@ -4553,7 +2838,6 @@ func (c *Connection) RollDiceToGMWithID(rollspec, requestID string) error {
 // message. This tells the client the results of a die roll.
 type RollResultMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	ChatCommon
 
 	// True if there will be more results following this one for the same request
@@ -4573,128 +2857,11 @@ type RollResultMessagePayload struct {
 
 	// The die-roll type
 	Type string `json:",omitempty"`
-}
 
-//TODOfunc (c RollResultMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 138 + len(c.Sender) // ROLL Origin:false Replay:false Sender:x MessageID:i ToAll:false ToGM:false Sent:time[35]
-//TODO	l += 16
-//TODO	for _, r := range c.Recipients {
-//TODO		l += len(r) + 3 // Recipients:[x]
-//TODO	}
-//TODO	l += 42 + len(c.RequestID) + len(c.Title) // MoreResults:false RequestID:x Title:x
-//TODO	l += 84                                   // Result:{ResultSuppressed:false InvalidRequest:false Result:i Details:[]{Type:x Value:x}
-//TODO	for _, r := range c.Result.Details {
-//TODO		l += 22 + len(r.Type) + len(r.Value)
-//TODO	}
-//TODO	l += 14 // Targets[x]
-//TODO	for _, r := range c.Targets {
-//TODO		l += len(r) + 3
-//TODO	}
-//TODO	l += 8 + len(c.Type) // Type:x
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c RollResultMessagePayload) Split() []any {
-//TODO	fragments := max(len(c.Targets), len(c.Result.Details), len(c.Recipients))
-//TODO	payloads := make([]any, fragments)
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i := range fragments {
-//TODO		p := RollResultMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: fragments,
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.Origin = c.Origin
-//TODO			p.Replay = c.Replay
-//TODO			p.Sender = c.Sender
-//TODO			p.MessageID = c.MessageID
-//TODO			p.ToAll = c.ToAll
-//TODO			p.ToGM = c.ToGM
-//TODO			p.Sent = c.Sent
-//TODO			p.MoreResults = c.MoreResults
-//TODO			p.RequestID = c.RequestID
-//TODO			p.Title = c.Title
-//TODO			p.Type = c.Type
-//TODO			p.Result.ResultSuppressed = c.Result.ResultSuppressed
-//TODO			p.Result.InvalidRequest = c.Result.InvalidRequest
-//TODO			p.Result.Result = c.Result.Result
-//TODO		}
-//TODO		if len(c.Result.Details) > i {
-//TODO			p.Result.Details = dice.StructuredDescriptionSet([]dice.StructuredDescription{c.Result.Details[i]})
-//TODO		}
-//TODO		if len(c.Recipients) > i {
-//TODO			p.Recipients = []string{c.Recipients[i]}
-//TODO		}
-//TODO		if len(c.Targets) > i {
-//TODO			p.Targets = []string{c.Targets[i]}
-//TODO		}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c RollResultMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := RollResultMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(RollResultMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.Origin = pp.Origin
-//TODO			newp.Replay = pp.Replay
-//TODO			newp.Sender = pp.Sender
-//TODO			newp.MessageID = pp.MessageID
-//TODO			newp.ToAll = pp.ToAll
-//TODO			newp.ToGM = pp.ToGM
-//TODO			newp.Sent = pp.Sent
-//TODO			newp.MoreResults = pp.MoreResults
-//TODO			newp.RequestID = pp.RequestID
-//TODO			newp.Title = pp.Title
-//TODO			newp.Type = pp.Type
-//TODO			newp.Result.ResultSuppressed = pp.Result.ResultSuppressed
-//TODO			newp.Result.InvalidRequest = pp.Result.InvalidRequest
-//TODO			newp.Result.Result = pp.Result.Result
-//TODO		}
-//TODO		for _, t := range pp.Result.Details {
-//TODO			newp.Result.Details = append(newp.Result.Details, t)
-//TODO		}
-//TODO		for _, t := range pp.Recipients {
-//TODO			newp.Recipients = append(newp.Recipients, t)
-//TODO		}
-//TODO		for _, t := range pp.Targets {
-//TODO			newp.Targets = append(newp.Targets, t)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c RollResultMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return RollResultMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		RequestID: c.RequestID,
-//TODO		ChatCommon: ChatCommon{
-//TODO			MessageID: c.MessageID,
-//TODO			Sender:    c.Sender,
-//TODO		},
-//TODO	}
-//TODO}
+	// If the server is making verifiable die rolls, this holds what is needed
+	// to replay the roll once the seed is revealed (see RollSeedMessagePayload).
+	Verification *dice.RollVerification `json:",omitempty"`
+}
 
 //  ____  _          ____                     _
 // |  _ \(_) ___ ___|  _ \ _ __ ___  ___  ___| |_ ___
@@ -4763,7 +2930,6 @@ func (c *Connection) DefineDicePresetsFor(user string, presets []dice.DieRollPre
 
 type DefineDicePresetsMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	Global  bool                 `json:",omitempty"`
 	For     string               `json:",omitempty"`
 	Presets []dice.DieRollPreset `json:",omitempty"`
@@ -4771,211 +2937,10 @@ type DefineDicePresetsMessagePayload struct {
 
 type DefineDicePresetDelegatesMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	For       string   `json:",omitempty"`
 	Delegates []string `json:",omitempty"`
 }
 
-//TODOfunc (c DefineDicePresetDelegatesMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 27 + len(c.For) // DDD For:x Delegates[]
-//TODO	for _, p := range c.Delegates {
-//TODO		l += len(p)
-//TODO	}
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c DefineDicePresetDelegatesMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.Delegates))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, instance := range c.Delegates {
-//TODO		p := DefineDicePresetDelegatesMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.Delegates),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.For = c.For
-//TODO		}
-//TODO		p.Delegates = []string{instance}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c DefineDicePresetDelegatesMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := DefineDicePresetDelegatesMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(DefineDicePresetDelegatesMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.For = pp.For
-//TODO		}
-//TODO		for _, pre := range pp.Delegates {
-//TODO			newp.Delegates = append(newp.Delegates, pre)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c DefineDicePresetDelegatesMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return DefineDicePresetDelegatesMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		For: c.For,
-//TODO	}
-//TODO}
-//TODOfunc (c DefineDicePresetsMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 40 + len(c.For) // DD For:x Global:false Presets:[]
-//TODO	for _, p := range c.Presets {
-//TODO		l += 53 + len(p.Name) + len(p.Description) + len(p.DieRollSpec) // Global:false, Name:x, Description:x, DieRollSpec:x
-//TODO	}
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c DefineDicePresetsMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.Presets))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, instance := range c.Presets {
-//TODO		p := DefineDicePresetsMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.Presets),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.Global = c.Global
-//TODO			p.For = c.For
-//TODO		}
-//TODO		p.Presets = []dice.DieRollPreset{instance}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c DefineDicePresetsMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := DefineDicePresetsMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(DefineDicePresetsMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.Global = pp.Global
-//TODO			newp.For = pp.For
-//TODO		}
-//TODO		for _, pre := range pp.Presets {
-//TODO			newp.Presets = append(newp.Presets, pre)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c DefineDicePresetsMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return DefineDicePresetsMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		For:    c.For,
-//TODO		Global: c.Global,
-//TODO	}
-//TODO}
-//TODO
-//TODOfunc (c AddDicePresetsMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 40 + len(c.For) // DD For:x Global:false Presets:[]
-//TODO	for _, p := range c.Presets {
-//TODO		l += 53 + len(p.Name) + len(p.Description) + len(p.DieRollSpec) // Global:false, Name:x, Description:x, DieRollSpec:x
-//TODO	}
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c AddDicePresetsMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.Presets))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, instance := range c.Presets {
-//TODO		p := AddDicePresetsMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.Presets),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.Global = c.Global
-//TODO			p.For = c.For
-//TODO		}
-//TODO		p.Presets = []dice.DieRollPreset{instance}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c AddDicePresetsMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := AddDicePresetsMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(AddDicePresetsMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.Global = pp.Global
-//TODO			newp.For = pp.For
-//TODO		}
-//TODO		for _, pre := range pp.Presets {
-//TODO			newp.Presets = append(newp.Presets, pre)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c AddDicePresetsMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return AddDicePresetsMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		For:    c.For,
-//TODO		Global: c.Global,
-//TODO	}
-//TODO}
-
 // AddDicePresets is like DefineDicePresets except that it adds the presets
 // passed in to the existing set rather than replacing them.
 func (c *Connection) AddDicePresets(presets []dice.DieRollPreset) error {
@@ -5013,7 +2978,6 @@ func (c *Connection) AddDicePresetsFor(user string, presets []dice.DieRollPreset
 
 type AddDicePresetsMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	Global  bool                 `json:",omitempty"`
 	For     string               `json:",omitempty"`
 	Presets []dice.DieRollPreset `json:",omitempty"`
@@ -5051,6 +3015,238 @@ type QueryDicePresetsMessagePayload struct {
 	For    string `json:",omitempty"`
 }
 
+//  ____                 _                _____     _     _
+// |  _ \ __ _ _ __   __| | ___  _ __ ___|_   _|_ _| |__ | | ___  ___
+// | |_) / _` | '_ \ / _` |/ _ \| '_ ` _ \ | |/ _` | '_ \| |/ _ \/ __|
+// |  _ < (_| | | | | (_| | (_) | | | | | || | (_| | |_) | |  __/\__ \
+// |_| \_\__,_|_| |_|\__,_|\___/|_| |_| |_||_|\__,_|_.__/|_|\___||___/
+//
+
+// DefineRandomTables replaces any existing random tables you have
+// stored on the server with the new set passed as the tables parameter.
+func (c *Connection) DefineRandomTables(tables []dice.RandomTable) error {
+	if c == nil {
+		return fmt.Errorf("nil Connection")
+	}
+	return c.serverConn.Send(DefineRandomTables, DefineRandomTablesMessagePayload{
+		Tables: tables,
+	})
+}
+
+// DefineGlobalRandomTables is like DefineRandomTables but replaces the system-wide
+// global set of tables available to all users (GM only).
+func (c *Connection) DefineGlobalRandomTables(tables []dice.RandomTable) error {
+	if c == nil {
+		return fmt.Errorf("nil Connection")
+	}
+	return c.serverConn.Send(DefineRandomTables, DefineRandomTablesMessagePayload{
+		Global: true,
+		Tables: tables,
+	})
+}
+
+// DefineRandomTablesFor is just like DefineRandomTables but performs the operation
+// for another user (GM only).
+func (c *Connection) DefineRandomTablesFor(user string, tables []dice.RandomTable) error {
+	if c == nil {
+		return fmt.Errorf("nil Connection")
+	}
+	return c.serverConn.Send(DefineRandomTables, DefineRandomTablesMessagePayload{
+		For:    user,
+		Tables: tables,
+	})
+}
+
+// DefineRandomTablesMessagePayload holds the information sent by the client
+// to store a set of random tables on the server.
+type DefineRandomTablesMessagePayload struct {
+	BaseMessagePayload
+	Global bool               `json:",omitempty"`
+	For    string             `json:",omitempty"`
+	Tables []dice.RandomTable `json:",omitempty"`
+}
+
+// QueryRandomTables requests that the server send you the random
+// tables currently available to you (your own plus the global set).
+// It will send you an UpdateRandomTables message.
+func (c *Connection) QueryRandomTables() error {
+	if c == nil {
+		return fmt.Errorf("nil Connection")
+	}
+	return c.serverConn.Send(QueryRandomTables, nil)
+}
+
+// QueryGlobalRandomTables is like QueryRandomTables but queries only the system-wide set.
+func (c *Connection) QueryGlobalRandomTables() error {
+	if c == nil {
+		return fmt.Errorf("nil Connection")
+	}
+	return c.serverConn.Send(QueryRandomTables, QueryRandomTablesMessagePayload{Global: true})
+}
+
+// QueryRandomTablesFor is like QueryRandomTables but queries the tables for a given user (GM only).
+func (c *Connection) QueryRandomTablesFor(user string) error {
+	if c == nil {
+		return fmt.Errorf("nil Connection")
+	}
+	return c.serverConn.Send(QueryRandomTables, QueryRandomTablesMessagePayload{For: user})
+}
+
+// QueryRandomTablesMessagePayload holds the information sent by the client
+// to ask for a set of random tables.
+type QueryRandomTablesMessagePayload struct {
+	BaseMessagePayload
+	Global bool   `json:",omitempty"`
+	For    string `json:",omitempty"`
+}
+
+// UpdateRandomTablesMessagePayload holds the information sent by the server's UpdateRandomTables
+// message. This tells the client which random tables are available, replacing any previous
+// set it was given. Tables from the global set have their Global field set.
+type UpdateRandomTablesMessagePayload struct {
+	BaseMessagePayload
+	Global bool               `json:",omitempty"`
+	For    string             `json:",omitempty"`
+	Tables []dice.RandomTable
+}
+
+// RollTable asks the server to roll on one of the random tables stored there
+// (either one of your own or one from the global set).
+//
+// The results are sent back as RollResult messages just as for RollDice,
+// the first of which describes the table entry that was selected, followed
+// by any die rolls and nested table rolls needed to fill in that entry.
+//
+// The to parameter and options work the same as for RollDice, except that
+// WithRollTargets and WithRollType are ignored.
+func (c *Connection) RollTable(to []string, table string, opt ...RollDiceOption) error {
+	var options dieRollOptions
+
+	if c == nil {
+		return fmt.Errorf("nil Connection")
+	}
+	for _, o := range opt {
+		o(&options)
+	}
+
+	return c.serverConn.Send(RollTable, RollTableMessagePayload{
+		ChatCommon: ChatCommon{
+			Recipients: to,
+			ToAll:      options.toAll,
+			ToGM:       options.toGM,
+		},
+		Table:     table,
+		RequestID: options.id,
+	})
+}
+
+// RollTableMessagePayload holds the data sent from the client to the
+// server when requesting a roll on a random table.
+type RollTableMessagePayload struct {
+	BaseMessagePayload
+	ChatCommon
+
+	// If you want to track the results to the requests that created them,
+	// put a unique ID here. It will be repeated in the corresponding result(s).
+	RequestID string `json:",omitempty"`
+
+	// The name of the table to roll on.
+	Table string
+}
+
+// RevealRollSeed asks the server to reveal the seed it used for a series of
+// verifiable die rolls, so the results can be checked (see RollSeedMessagePayload).
+//
+// If commitment is empty, this ends your own current series of rolls. The server
+// reveals its seed and commits to a new one for any further rolls you make.
+// Otherwise, commitment identifies a series of rolls (made by anyone) which has
+// already ended, such as when the user who made them logged out.
+//
+// The server replies with RollSeed messages.
+func (c *Connection) RevealRollSeed(commitment string) error {
+	if c == nil {
+		return fmt.Errorf("nil Connection")
+	}
+	return c.serverConn.Send(RevealRollSeed, RevealRollSeedMessagePayload{
+		Commitment: commitment,
+	})
+}
+
+// RevealRollSeedMessagePayload holds the information sent by the client
+// to ask the server to reveal the seed used for a series of verifiable
+// die rolls.
+type RevealRollSeedMessagePayload struct {
+	BaseMessagePayload
+	Commitment string `json:",omitempty"`
+}
+
+// RollSeedMessagePayload holds the information sent by the server's RollSeed
+// message, which is only used if the server is making verifiable die rolls.
+//
+// When a user logs in, the server generates a secret seed for their die rolls and
+// sends everyone a RollSeed message with the Commitment to that seed, but not the
+// seed itself. Each RollResult from that user's rolls has a Verification field
+// identifying the commitment and how to replay the roll. When the series of rolls
+// ends, the server sends a RollSeed message with the Revealed field holding the
+// seed, which anyone can check against the commitment and use to verify each
+// roll (see dice.SeedCommitment).
+type RollSeedMessagePayload struct {
+	BaseMessagePayload
+
+	// The user whose rolls are made with this seed.
+	User string `json:",omitempty"`
+
+	// The published commitment to the seed.
+	Commitment string
+
+	// The seed itself, once it has been revealed.
+	Revealed *dice.SeedCommitment `json:",omitempty"`
+}
+
+// QueryRollHistory asks the server to send the history of die rolls it has made
+// for the named users (or for everyone if users is empty) between the since and
+// until times (either of which may be zero to leave that end of the range open).
+// If limit is greater than zero, only that many of the most recent rolls are sent.
+//
+// The server replies with an UpdateRollHistory message. Only the rolls you could see
+// when they were made are included: those sent to everyone or to you, those you made
+// (except privately to the GM), and, if you are the GM, those sent to the GM.
+func (c *Connection) QueryRollHistory(users []string, since, until time.Time, limit int) error {
+	if c == nil {
+		return fmt.Errorf("nil Connection")
+	}
+	return c.serverConn.Send(QueryRollHistory, QueryRollHistoryMessagePayload{
+		Users: users,
+		Since: since,
+		Until: until,
+		Limit: limit,
+	})
+}
+
+// QueryRollHistoryMessagePayload holds the information sent by the client
+// to ask for the history of die rolls made by the server.
+type QueryRollHistoryMessagePayload struct {
+	BaseMessagePayload
+
+	// The users whose rolls are wanted (all users if empty).
+	Users []string `json:",omitempty"`
+
+	// The range of times of the rolls wanted.
+	Since time.Time `json:",omitempty"`
+	Until time.Time `json:",omitempty"`
+
+	// If nonzero, only this many of the most recent rolls are sent.
+	Limit int `json:",omitempty"`
+}
+
+// UpdateRollHistoryMessagePayload holds the information sent by the server's UpdateRollHistory
+// message in response to a QueryRollHistory request. The rolls are listed in the
+// order they were made.
+type UpdateRollHistoryMessagePayload struct {
+	BaseMessagePayload
+	Rolls []dice.RollHistoryEntry
+}
+
 // UpdateClockMessagePayload holds the information sent by the server's UpdateClock
 // message. This tells the client to update its clock display to the new value.
 type UpdateClockMessagePayload struct {
@@ -5089,7 +3285,6 @@ func (c *Connection) UpdateClock(absolute, relative int64, keepRunning bool) err
 // using.
 type UpdateDicePresetsMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	Global      bool `json:",omitempty"`
 	Presets     []dice.DieRollPreset
 	For         string   `json:",omitempty"`
@@ -5097,162 +3292,14 @@ type UpdateDicePresetsMessagePayload struct {
 	Delegates   []string `json:",omitempty"`
 }
 
-//TODOfunc (c UpdateDicePresetsMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 40 + len(c.For) // DD For:x Global:false Presets:[]
-//TODO	l += 34              // DelegateFor:[] Delegates:[]
-//TODO	for _, p := range c.DelegateFor {
-//TODO		l += len(p)
-//TODO	}
-//TODO	for _, p := range c.Delegates {
-//TODO		l += len(p)
-//TODO	}
-//TODO	for _, p := range c.Presets {
-//TODO		l += 53 + len(p.Name) + len(p.Description) + len(p.DieRollSpec) // Global:false, Name:x, Description:x, DieRollSpec:x
-//TODO	}
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c UpdateDicePresetsMessagePayload) Split() []any {
-//TODO	fragments := max(len(c.Presets), len(c.DelegateFor), len(c.Delegates))
-//TODO	payloads := make([]any, fragments)
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i := range fragments {
-//TODO		p := UpdateDicePresetsMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: fragments,
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.Global = c.Global
-//TODO			p.For = c.For
-//TODO		}
-//TODO		if len(c.Presets) > i {
-//TODO			p.Presets = []dice.DieRollPreset{c.Presets[i]}
-//TODO		}
-//TODO		if len(c.Delegates) > i {
-//TODO			p.Delegates = []string{c.Delegates[i]}
-//TODO		}
-//TODO		if len(c.DelegateFor) > i {
-//TODO			p.DelegateFor = []string{c.DelegateFor[i]}
-//TODO		}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c UpdateDicePresetsMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := UpdateDicePresetsMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(UpdateDicePresetsMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.Global = pp.Global
-//TODO			newp.For = pp.For
-//TODO		}
-//TODO		for _, pre := range pp.Presets {
-//TODO			newp.Presets = append(newp.Presets, pre)
-//TODO		}
-//TODO		for _, pre := range pp.Delegates {
-//TODO			newp.Delegates = append(newp.Delegates, pre)
-//TODO		}
-//TODO		for _, pre := range pp.DelegateFor {
-//TODO			newp.DelegateFor = append(newp.DelegateFor, pre)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c UpdateDicePresetsMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return AddDicePresetsMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		For:    c.For,
-//TODO		Global: c.Global,
-//TODO	}
-//TODO}
-
 // UpdateInitiativeMessagePayload holds the information sent by the server's UpdateInitiative
 // message. This tells the client that the initiative order has been changed. Its current
 // notion of the initiative order should be replaced by the one given here.
 type UpdateInitiativeMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	InitiativeList []InitiativeSlot
 }
 
-//TODOfunc (c UpdateInitiativeMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 24 // IL InitiativeList[]
-//TODO	for _, p := range c.InitiativeList {
-//TODO		l += 82 + len(p.Name) // Slot:i Name:x IsHolding:false HasReadiedAction:false IsFlatFooted:false CurrentHP:i
-//TODO	}
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c UpdateInitiativeMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.InitiativeList))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, instance := range c.InitiativeList {
-//TODO		p := UpdateInitiativeMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.InitiativeList),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO			InitiativeList: []InitiativeSlot{instance},
-//TODO		}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c UpdateInitiativeMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := UpdateInitiativeMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(UpdateInitiativeMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO		for _, l := range pp.InitiativeList {
-//TODO			newp.InitiativeList = append(newp.InitiativeList, l)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c UpdateInitiativeMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return UpdateInitiativeMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO	}
-//TODO}
-
 // UpdateInitiative informs our peers of a change to the
 // inititive order.
 func (c *Connection) UpdateInitiative(ilist []InitiativeSlot) error {
@@ -5295,7 +3342,6 @@ type InitiativeSlot struct {
 // Call the UpdateObjAttributes method to send this message out to other clients.
 type UpdateObjAttributesMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 
 	// The ID of the object to be modified.
 	ObjID string
@@ -5304,74 +3350,6 @@ type UpdateObjAttributesMessagePayload struct {
 	NewAttrs map[string]any
 }
 
-//TODOfunc (c UpdateObjAttributesMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 27 + len(c.ObjID) // OA ObjID:x NewAttrs:{}
-//TODO	for k, v := range c.NewAttrs {
-//TODO		l += len(k) + len(fmt.Sprintf("%v", v)) + 8
-//TODO	}
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c UpdateObjAttributesMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.NewAttrs))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	i := 0
-//TODO	for k, v := range c.NewAttrs {
-//TODO		p := UpdateObjAttributesMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.NewAttrs),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.ObjID = c.ObjID
-//TODO		}
-//TODO		p.NewAttrs = map[string]any{k: v}
-//TODO		payloads[i] = p
-//TODO		i++
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c UpdateObjAttributesMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := UpdateObjAttributesMessagePayload{}
-//TODO	newp.NewAttrs = make(map[string]any, len(p))
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(UpdateObjAttributesMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.ObjID = pp.ObjID
-//TODO		}
-//TODO		for k, v := range pp.NewAttrs {
-//TODO			newp.NewAttrs[k] = v
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c UpdateObjAttributesMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return UpdateObjAttributesMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		ObjID: c.ObjID,
-//TODO	}
-//TODO}
-
 // UpdateObjAttributes informs peers that they should modify the
 // specified object's attributes which are mentioned in the newAttrs
 // map. This maps attribute names to their new values.
@@ -5390,7 +3368,6 @@ func (c *Connection) UpdateObjAttributes(objID string, newAttrs map[string]any)
 // other connected peers has changed.
 type UpdatePeerListMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	PeerList []Peer
 }
 
@@ -5445,54 +3422,6 @@ func (c UpdatePeerListMessagePayload) NeedsToBeSplit() bool {
 // 0  Name,
 // 0  Animation->{Frames,FrameSpeed,Loops}
 // 0+ Sizes[File,ImageData,IsLocalFile,Zoom]
-//TODOfunc (c UpdatePeerListMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.PeerList))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, instance := range c.PeerList {
-//TODO		p := UpdatePeerListMessagePayload{
-//TODO			PeerList: []Peer{instance},
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.PeerList),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c UpdatePeerListMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	ai := UpdatePeerListMessagePayload{
-//TODO		PeerList: make([]Peer, len(p)),
-//TODO	}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pr, ok := d.(UpdatePeerListMessagePayload)
-//TODO		if !ok {
-//TODO			return ai, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pr.Batch != i {
-//TODO			return ai, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pr.Batch)
-//TODO		}
-//TODO		if pr.TotalBatches != len(p) {
-//TODO			return ai, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pr.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		ai.PeerList[i] = pr.PeerList[0]
-//TODO	}
-//TODO	return ai, nil
-//TODO}
-//TODOfunc (c UpdatePeerListMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return UpdatePeerListMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO	}
-//TODO}
 
 // QueryPeers asks the server to send an UpdatePeerList
 // message with the current set of peers who are connected
@@ -5708,70 +3637,9 @@ type SyncChatMessagePayload struct {
 
 type UpdateVersionsMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 	Packages []PackageUpdate `json:",omitempty"`
 }
 
-//TODOfunc (c UpdateVersionsMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 25 // UPDATES Packages[]
-//TODO	for _, p := range c.Packages {
-//TODO		l += 57 + len(p.Name) + len(p.VersionPattern) + len(p.MinimumVersion) // Name:x VersionPattern:x MinimumVersion:x Instances[OS:x Arch:x Version:x Token:x]
-//TODO		for _, i := range p.Instances {
-//TODO			l += 34 + len(i.OS) + len(i.Arch) + len(i.Version) + len(i.Token)
-//TODO		}
-//TODO	}
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c UpdateVersionsMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.Packages))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, instance := range c.Packages {
-//TODO		p := UpdateVersionsMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.Packages),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		p.Packages = []PackageUpdate{instance}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c UpdateVersionsMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := UpdateVersionsMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(UpdateVersionsMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO		for _, pkg := range pp.Packages {
-//TODO			newp.Packages = append(newp.Packages, pkg)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c UpdateVersionsMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return UpdateVersionsMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO	}
-//TODO}
-
 type PackageUpdate struct {
 	Name           string
 	VersionPattern string         `json:",omitempty"`
@@ -5821,7 +3689,6 @@ type TimerAcknowledgeMessagePayload struct {
 // to the GM's time tracker.
 type TimerRequestMessagePayload struct {
 	BaseMessagePayload
-//TODO	BatchableMessagePayload
 
 	// If true, the timer should be visible to the players instead of just the GM
 	ShowToAll bool
@@ -5846,87 +3713,6 @@ type TimerRequestMessagePayload struct {
 	RequestingClient string
 }
 
-//TODOfunc (c TimerRequestMessagePayload) NeedsToBeSplit() bool {
-//TODO	l := 123 + len(c.RequestID) + len(c.Description) + len(c.Expires) + len(c.RequestedBy) + len(c.RequestingClient)
-//TODO	// TMRQ ShowToAll:false IsRunning:false RequestID:x Description:x Expires:x Targets:[x] RequestedBy:x RequestingClient:x
-//TODO	for _, a := range c.Targets {
-//TODO		l += len(a) + 3
-//TODO	}
-//TODO	return l > MaxServerMessageSize
-//TODO}
-//TODO
-//TODOfunc (c TimerRequestMessagePayload) Split() []any {
-//TODO	payloads := make([]any, len(c.Targets))
-//TODO	gid := uuid.NewString()
-//TODO
-//TODO	for i, instance := range c.Targets {
-//TODO		p := TimerRequestMessagePayload{
-//TODO//TODO			BatchableMessagePayload: BatchableMessagePayload{
-//TODO				TotalBatches: len(c.Targets),
-//TODO				Batch:        i,
-//TODO				BatchGroup:   gid,
-//TODO			},
-//TODO		}
-//TODO		if i == 0 {
-//TODO			p.ShowToAll = c.ShowToAll
-//TODO			p.IsRunning = c.IsRunning
-//TODO			p.RequestID = c.RequestID
-//TODO			p.Description = c.Description
-//TODO			p.Expires = c.Expires
-//TODO			p.RequestedBy = c.RequestedBy
-//TODO			p.RequestingClient = c.RequestingClient
-//TODO		}
-//TODO		p.Targets = []string{instance}
-//TODO		payloads[i] = p
-//TODO	}
-//TODO	return payloads
-//TODO}
-//TODO
-//TODOfunc (c TimerRequestMessagePayload) Reassemble(p []any) (any, error) {
-//TODO	newp := TimerRequestMessagePayload{}
-//TODO
-//TODO	for i, d := range p {
-//TODO		pp, ok := d.(TimerRequestMessagePayload)
-//TODO		if !ok {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d is of type %T", c, i, len(p), d)
-//TODO		}
-//TODO		if pp.Batch != i {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d of %d claims to be #%d", c, i, len(p), pp.Batch)
-//TODO		}
-//TODO		if pp.TotalBatches != len(p) {
-//TODO			return newp, fmt.Errorf("batched %T packet fragment #%d claims there will be %d batches but %d were collected", c, i, pp.TotalBatches, len(p))
-//TODO		}
-//TODO
-//TODO		if i == 0 {
-//TODO			newp.ShowToAll = pp.ShowToAll
-//TODO			newp.IsRunning = pp.IsRunning
-//TODO			newp.RequestID = pp.RequestID
-//TODO			newp.Description = pp.Description
-//TODO			newp.Expires = pp.Expires
-//TODO			newp.RequestedBy = pp.RequestedBy
-//TODO			newp.RequestingClient = pp.RequestingClient
-//TODO		}
-//TODO		for _, t := range pp.Targets {
-//TODO			newp.Targets = append(newp.Targets, t)
-//TODO		}
-//TODO	}
-//TODO	return newp, nil
-//TODO}
-//TODO
-//TODOfunc (c TimerRequestMessagePayload) AbortPayload(reason string, batchNumber int) any {
-//TODO	return TimerRequestMessagePayload{
-//TODO//TODO		BatchableMessagePayload: BatchableMessagePayload{
-//TODO			BatchError:   reason,
-//TODO			Batch:        batchNumber,
-//TODO			TotalBatches: c.TotalBatches,
-//TODO		},
-//TODO		RequestID:        c.RequestID,
-//TODO		Description:      c.Description,
-//TODO		RequestedBy:      c.RequestedBy,
-//TODO		RequestingClient: c.RequestingClient,
-//TODO	}
-//TODO}
-
 // TimerRequest sends a timer requst to the GM. If approved, the new timer will be added
 // to the list of things being tracked in the game.
 
@@ -6027,6 +3813,15 @@ func (c *Connection) Dial() {
 	}
 }
 
+// dialTCP opens a plain TCP connection to the given address.
+func (c *Connection) dialTCP(address string) (net.Conn, error) {
+	if c.Timeout == 0 {
+		var dialer net.Dialer
+		return dialer.DialContext(c.Context, "tcp", address)
+	}
+	return net.DialTimeout("tcp", address, c.Timeout)
+}
+
 func (c *Connection) tryConnect() error {
 	if c == nil {
 		return fmt.Errorf("nil Connection")
@@ -6039,19 +3834,21 @@ func (c *Connection) tryConnect() error {
 	defer c.debug(DebugIO, "tryConnect() ended")
 
 	for i = 0; c.Retries == 0 || i < c.Retries; i++ {
-		if c.Timeout == 0 {
-			var dialer net.Dialer
-			conn, err = dialer.DialContext(c.Context, "tcp", c.Endpoint)
+		if c.WebSocket {
+			conn, err = c.dialWebSocket()
+		} else if c.TLSConfig != nil {
+			conn, err = c.dialTLS(c.Endpoint)
 		} else {
-			conn, err = net.DialTimeout("tcp", c.Endpoint, c.Timeout)
+			conn, err = c.dialTCP(c.Endpoint)
 		}
 
-		if err != nil {
-			if c.Retries == 0 {
-				c.Logf("attempting connection (try %d): %v", i+1, err)
-			} else {
-				c.Logf("attempting connection (try %d of %d): %v", i+1, c.Retries, err)
-			}
+		if err == nil {
+			break
+		}
+		if c.Retries == 0 {
+			c.Logf("attempting connection (try %d): %v", i+1, err)
+		} else {
+			c.Logf("attempting connection (try %d of %d): %v", i+1, c.Retries, err)
 		}
 	}
 	if err != nil {
@@ -6158,14 +3955,14 @@ func (c *Connection) login(done chan error) {
 		// <- PROTOCOL v
 		// <- AC, DSM, REDIRECT, UPDATES, WORLD, // messages
 		// <- OK
-		// -> AUTH (if authentication required)
+		// -> AUTH (if authentication required or we logged in by certificate)
 		// <- GRANTED or DENIED (if AUTH required and given)
 		// <- AC, DSM, REDIRECT, UPDATES, WORLD, // messages
 		// <- READY
 
 		switch response := incomingPacket.(type) {
 		case ChallengeMessagePayload:
-			// OK Protocol=v [Challenge=data] [ServerUptime=time] [ServerActive=time]
+			// OK Protocol=v [Challenge=data | CertificateUser=name] [ServerUptime=time] [ServerActive=time]
 			if response.Protocol != c.Protocol {
 				c.Logf("server advertised protocol %v initially but then claimed version %v", c.Protocol, response.Protocol)
 				done <- fmt.Errorf("server can't make up its mind whether it uses protocol %v or %v", c.Protocol, response.Protocol)
@@ -6201,6 +3998,20 @@ func (c *Connection) login(done chan error) {
 					c.Logf("can't authenticate: %v", err)
 				}
 				authPending = true
+			} else if response.CertificateUser != "" {
+				c.Logf("server accepted our certificate for %s", response.CertificateUser)
+				var client string
+				if c.Authenticator != nil {
+					client = c.Authenticator.Client
+				}
+				c.serverConn.Send(Auth, AuthMessagePayload{
+					Client: client,
+					User:   response.CertificateUser,
+				})
+				if err := c.serverConn.Flush(); err != nil {
+					c.Logf("can't identify ourselves to the server: %v", err)
+				}
+				authPending = true
 			} else {
 				c.Logf("using protocol %d.", c.Protocol)
 				c.Log("server sync complete. No authentication requested by server.")
@@ -6220,6 +4031,7 @@ func (c *Connection) login(done chan error) {
 			}
 			c.PartialReset()
 			c.Endpoint = fmt.Sprintf("%s:%d", response.Host, response.Port)
+			c.WebSocket = false // redirects only name a host and port, so we can only follow them over TCP
 			done <- ErrRetryConnection
 			return
 
@@ -6332,6 +4144,7 @@ waitForReady:
 			}
 			c.PartialReset()
 			c.Endpoint = fmt.Sprintf("%s:%d", response.Host, response.Port)
+			c.WebSocket = false // redirects only name a host and port, so we can only follow them over TCP
 			done <- ErrRetryConnection
 			return
 
@@ -6677,6 +4490,11 @@ func (c *Connection) listen(done chan error) {
 				ch <- cmd
 			}
 
+		case RollSeedMessagePayload:
+			if ch, ok := c.Subscriptions[RollSeed]; ok {
+				ch <- cmd
+			}
+
 		case TimerAcknowledgeMessagePayload:
 			if ch, ok := c.Subscriptions[TimerAcknowledge]; ok {
 				ch <- cmd
@@ -6702,6 +4520,16 @@ func (c *Connection) listen(done chan error) {
 				ch <- cmd
 			}
 
+		case UpdateRandomTablesMessagePayload:
+			if ch, ok := c.Subscriptions[UpdateRandomTables]; ok {
+				ch <- cmd
+			}
+
+		case UpdateRollHistoryMessagePayload:
+			if ch, ok := c.Subscriptions[UpdateRollHistory]; ok {
+				ch <- cmd
+			}
+
 		case UpdateInitiativeMessagePayload:
 			if ch, ok := c.Subscriptions[UpdateInitiative]; ok {
 				ch <- cmd
@@ -6755,9 +4583,10 @@ func (c *Connection) listen(done chan error) {
 
 		case AcceptMessagePayload, AddDicePresetsMessagePayload, AllowMessagePayload,
 			AuthMessagePayload, DefineDicePresetsMessagePayload, DefineDicePresetDelegatesMessagePayload,
+			DefineRandomTablesMessagePayload,
 			FilterDicePresetsMessagePayload, FilterImagesMessagePayload, FilterAudioMessagePayload, PoloMessagePayload,
-			QueryDicePresetsMessagePayload, QueryPeersMessagePayload,
-			RollDiceMessagePayload, SyncMessagePayload, SyncChatMessagePayload:
+			QueryDicePresetsMessagePayload, QueryPeersMessagePayload, QueryRandomTablesMessagePayload,
+			QueryRollHistoryMessagePayload, RevealRollSeedMessagePayload, RollDiceMessagePayload, RollTableMessagePayload, SyncMessagePayload, SyncChatMessagePayload:
 
 			c.reportError(fmt.Errorf("message type %v should not be sent to a client (ignored)", cmd.MessageType()))
 
@@ -6950,6 +4779,8 @@ func (c *Connection) filterSubscriptions() error {
 			subList = append(subList, "OA-")
 		case RollResult:
 			subList = append(subList, "ROLL")
+		case RollSeed:
+			subList = append(subList, "SEED")
 		case TimerAcknowledge:
 			subList = append(subList, "TMACK")
 		case TimerRequest:
@@ -6972,6 +4803,10 @@ func (c *Connection) filterSubscriptions() error {
 			subList = append(subList, "CONN")
 		case UpdateProgress:
 			subList = append(subList, "PROGRESS")
+		case UpdateRandomTables:
+			subList = append(subList, "DT=")
+		case UpdateRollHistory:
+			subList = append(subList, "DH")
 		case UpdateStatusMarker:
 			subList = append(subList, "DSM")
 		case UpdateTurn:
@@ -39,13 +39,16 @@ package mapper
 import (
 	"bufio"
 	"bytes"
+	"compress/flate"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -54,14 +57,30 @@ import (
 // The GMA Mapper Protocol version number current as of this build,
 // and protocol versions supported by this code.
 const (
-	GMAMapperProtocol           = 423      // @@##@@ auto-configured
+	GMAMapperProtocol           = 424      // @@##@@ auto-configured
 	GoVersionNumber             = "5.33.0" // @@##@@ auto-configured
 	MinimumSupportedMapProtocol = 400
-	MaximumSupportedMapProtocol = 423
+	MaximumSupportedMapProtocol = 424
 	MaxServerMessageSize        = 60 * 1024 // don't send server messages bigger than this
 	MaxAllowedGiantPacketSize   = 1024 * 1024 * 10
 )
 
+// Messages too large to send in one piece (more than MaxServerMessageSize bytes)
+// are automatically split by Send into a batch of BATCH messages, each carrying
+// BatchFragmentSize bytes of the original JSON payload, and reassembled by
+// Receive on the other end. The reassembled payload may not exceed
+// MaxAllowedGiantPacketSize bytes.
+//
+// If the sender can't finish sending a batch, it sends a BATCH message with an
+// Error value, and the receiver discards what it has received so far. A
+// partially-received batch is also discarded if no more of it arrives within
+// BatchTimeout, so a sender which disappears in the middle of a batch doesn't
+// leave it in memory forever.
+const (
+	BatchFragmentSize = 32768
+	BatchTimeout      = 5 * time.Minute
+)
+
 func init() {
 	if MinimumSupportedMapProtocol > GMAMapperProtocol || MaximumSupportedMapProtocol < GMAMapperProtocol {
 		if MinimumSupportedMapProtocol == MaximumSupportedMapProtocol {
@@ -84,7 +103,12 @@ type MapConnection struct {
 	sendBuf    []string                                       // internal buffer of outgoing packets
 	sendChan   chan string                                    // outgoing packets go through this channel
 	batches    map[string]map[int]BatchFragmentMessagePayload // storage for incoming batched packets	(batchID->batch#->packet)
-	bLock      *sync.Mutex                                    // mutex protecting batches
+	batchSeen  map[string]time.Time                           // when we last received a fragment of each batch (batchID->time)
+	bLock      *sync.Mutex                                    // mutex protecting batches and batchSeen
+	compress   *atomic.Bool                                   // are we compressing outgoing packets?
+	deflater   *flate.Writer                                  // compressor for outgoing packets (used only by the writer)
+	pending    []string                                       // lines unpacked from a DEFLATE message waiting to be received
+	recorder   *Recorder                                      // if non-nil, we record all messages here
 	debug      func(DebugFlags, string)
 	debugf     func(DebugFlags, string, ...any)
 }
@@ -97,8 +121,8 @@ func (m *MapConnection) RetrieveBatches(packet BatchFragmentMessagePayload) (str
 	storage := m.batches[packet.ID]
 	storageLen := len(storage)
 	cmd := storage[0].Command
+	defer m.discardBatch(packet.ID)
 	if storageLen != packet.Of {
-		delete(m.batches, packet.ID)
 		return cmd, "", fmt.Errorf("incomplete or corrupt batched payload: expected %d, received %d", packet.Of, storageLen)
 	}
 
@@ -106,26 +130,25 @@ func (m *MapConnection) RetrieveBatches(packet BatchFragmentMessagePayload) (str
 	for i := range storageLen {
 		fragment, ok := storage[i]
 		if !ok {
-			delete(m.batches, packet.ID)
 			return cmd, "", fmt.Errorf("incomplete or corrupt batched payload: missing part %d", i)
 		}
-		newSize, err := buf.Write(fragment.Data)
-		if err != nil {
-			delete(m.batches, packet.ID)
+		if _, err := buf.Write(fragment.Data); err != nil {
 			return cmd, "", fmt.Errorf("error saving fragment data: %v", err)
 		}
-		if newSize > MaxAllowedGiantPacketSize {
-			delete(m.batches, packet.ID)
+		if buf.Len() > MaxAllowedGiantPacketSize {
 			return cmd, "", fmt.Errorf("rejecting incoming %s message; size exceeds maximum %v bytes", cmd, MaxAllowedGiantPacketSize)
 		}
 	}
-	delete(m.batches, packet.ID)
 	return cmd, buf.String(), nil
 }
 
 // StashBatch stashes an incoming message payload which is part of a batched set, assuming we'll assemble all of the
 // pieces later. It returns true if we are still expecting more to arrive and an error if one occurred.
 // If an error is returned, the meaning of the boolean return value is undefined.
+//
+// If the packet signals that the sender has abandoned the batch, the fragments received so far are
+// discarded and an error is returned. Any other batches which have gone stale (see BatchTimeout)
+// are discarded as well.
 func (m *MapConnection) StashBatch(packet BatchFragmentMessagePayload) (bool, error) {
 	if packet.ID == "" {
 		return false, fmt.Errorf("missing BATCH ID")
@@ -134,17 +157,81 @@ func (m *MapConnection) StashBatch(packet BatchFragmentMessagePayload) (bool, er
 	m.bLock.Lock()
 	defer m.bLock.Unlock()
 
+	now := time.Now()
+	for _, id := range m.expireBatches(now.Add(-BatchTimeout)) {
+		if m.debugf != nil {
+			m.debugf(DebugIO|DebugMessages, "discarded stale batch %s", id)
+		}
+	}
+
+	if packet.Error != "" {
+		cmd := packet.Command
+		if first, ok := m.batches[packet.ID][0]; ok {
+			cmd = first.Command
+		}
+		m.discardBatch(packet.ID)
+		return false, fmt.Errorf("sender abandoned batched %s message at part %d of %d: %s", cmd, packet.Part, packet.Of, packet.Error)
+	}
+	if packet.Of < 1 || packet.Part < 0 || packet.Part >= packet.Of {
+		m.discardBatch(packet.ID)
+		return false, fmt.Errorf("invalid BATCH fragment: part %d of %d", packet.Part, packet.Of)
+	}
+
 	if m.batches == nil {
 		m.batches = make(map[string]map[int]BatchFragmentMessagePayload)
+		m.batchSeen = make(map[string]time.Time)
 	}
 	if m.batches[packet.ID] == nil {
 		m.batches[packet.ID] = make(map[int]BatchFragmentMessagePayload)
 	}
+	size := len(packet.Data)
+	for _, fragment := range m.batches[packet.ID] {
+		if fragment.Of != packet.Of {
+			m.discardBatch(packet.ID)
+			return false, fmt.Errorf("inconsistent BATCH fragment: part %d of %d in a batch of %d", packet.Part, packet.Of, fragment.Of)
+		}
+		size += len(fragment.Data)
+	}
+	if size > MaxAllowedGiantPacketSize {
+		m.discardBatch(packet.ID)
+		return false, fmt.Errorf("rejecting incoming batched message; size exceeds maximum %v bytes", MaxAllowedGiantPacketSize)
+	}
 	m.batches[packet.ID][packet.Part] = packet
+	m.batchSeen[packet.ID] = now
 
 	return packet.Of > len(m.batches[packet.ID]), nil
 }
 
+// ExpireBatches discards any partially-received batches which we haven't received
+// any more of for at least maxAge, returning their IDs. This is done automatically
+// with a maxAge of BatchTimeout whenever a BATCH message arrives, but may be called
+// directly to clear them out sooner.
+func (m *MapConnection) ExpireBatches(maxAge time.Duration) []string {
+	m.bLock.Lock()
+	defer m.bLock.Unlock()
+	return m.expireBatches(time.Now().Add(-maxAge))
+}
+
+// expireBatches discards the batches last seen before the given time.
+// The caller must hold bLock.
+func (m *MapConnection) expireBatches(before time.Time) []string {
+	var expired []string
+	for id, seen := range m.batchSeen {
+		if seen.Before(before) {
+			m.discardBatch(id)
+			expired = append(expired, id)
+		}
+	}
+	return expired
+}
+
+// discardBatch removes all stored fragments of a batch.
+// The caller must hold bLock.
+func (m *MapConnection) discardBatch(id string) {
+	delete(m.batches, id)
+	delete(m.batchSeen, id)
+}
+
 func (m *MapConnection) IsReady() bool {
 	return m != nil && m.reader != nil && m.writer != nil
 }
@@ -152,6 +239,7 @@ func (m *MapConnection) IsReady() bool {
 func NewMapConnection(c net.Conn) MapConnection {
 	return MapConnection{
 		bLock:    new(sync.Mutex),
+		compress: new(atomic.Bool),
 		conn:     c,
 		reader:   bufio.NewScanner(c),
 		writer:   bufio.NewWriter(c),
@@ -159,55 +247,27 @@ func NewMapConnection(c net.Conn) MapConnection {
 	}
 }
 
+// NewMapConnectionReader returns a MapConnection which reads messages from r
+// (such as a saved transcript of server messages) instead of from a network
+// connection. Its Receive method decodes them just as they would have been
+// decoded from the server, including reassembling BATCH messages and
+// unpacking DEFLATE messages. Nothing may be sent on it.
+func NewMapConnectionReader(r io.Reader) MapConnection {
+	return MapConnection{
+		bLock:    new(sync.Mutex),
+		compress: new(atomic.Bool),
+		reader:   bufio.NewScanner(r),
+		debug:    func(DebugFlags, string) {},
+		debugf:   func(DebugFlags, string, ...any) {},
+	}
+}
+
 func (c *MapConnection) Close() {
 	if c != nil && c.conn != nil {
 		c.conn.Close()
 	}
 }
 
-//TODO// Batchable is any payload which may be split up into multiple batches.
-//TODO//type Batchable interface {
-//TODO//	NeedsToBeSplit() bool                            // does this payload need to be split up because it's already too large to be sent as it is?
-//TODO//	IsBatched() bool                                 // is this payload part of an incoming batch of payloads?
-//TODO//	Split() []any                                    // split up the message, returning the slice of batched payloads
-//TODO//	AbortPayload(reason string, batchNumber int) any // generate an abort payload
-//TODO//	Reassemble([]any) (any, error)                   // reassemble a slice of batches into a single payload structure
-//TODO//	BatchInfo() BatchableMessagePayload              // return batch details for this message
-//TODO//}
-//TODO//
-//TODO//// These commands are supposed to support batching
-//TODO//var (
-//TODO//	_ Batchable = AddImageMessagePayload{}
-//TODO//	_ Batchable = AddDicePresetsMessagePayload{}
-//TODO//	_ Batchable = AddObjAttributesMessagePayload{}
-//TODO//	_ Batchable = ChatMessageMessagePayload{}
-//TODO//	_ Batchable = DefineDicePresetDelegatesMessagePayload{}
-//TODO//	_ Batchable = DefineDicePresetsMessagePayload{}
-//TODO//	_ Batchable = EchoMessagePayload{}
-//TODO//	_ Batchable = HitPointRequestMessagePayload{}
-//TODO//	_ Batchable = LoadArcObjectMessagePayload{}
-//TODO//	_ Batchable = LoadCircleObjectMessagePayload{}
-//TODO//	_ Batchable = LoadLineObjectMessagePayload{}
-//TODO//	_ Batchable = LoadPolygonObjectMessagePayload{}
-//TODO//	_ Batchable = LoadRectangleObjectMessagePayload{}
-//TODO//	_ Batchable = LoadSpellAreaOfEffectObjectMessagePayload{}
-//TODO//	_ Batchable = LoadTextObjectMessagePayload{}
-//TODO//	_ Batchable = LoadTileObjectMessagePayload{}
-//TODO//	_ Batchable = QueryImageMessagePayload{}
-//TODO//	_ Batchable = TimerRequestMessagePayload{}
-//TODO//	_ Batchable = PlaceSomeoneMessagePayload{}
-//TODO//	_ Batchable = PlayAudioMessagePayload{}
-//TODO//	_ Batchable = RemoveObjAttributesMessagePayload{}
-//TODO//	_ Batchable = RollDiceMessagePayload{}
-//TODO//	_ Batchable = RollResultMessagePayload{}
-//TODO//	_ Batchable = PlayAudioMessagePayload{}
-//TODO//	_ Batchable = UpdateDicePresetsMessagePayload{}
-//TODO//	_ Batchable = UpdateInitiativeMessagePayload{}
-//TODO//	_ Batchable = UpdateObjAttributesMessagePayload{}
-//TODO//	_ Batchable = UpdatePeerListMessagePayload{}
-//TODO//	_ Batchable = UpdateVersionsMessagePayload{}
-//TODO//)
-
 // SendEchoWithTimestamp is identical to Send, but only takes an EchoMessagePayload parameter
 // and writes the SentTime value into it as it sends it out.
 func (c *MapConnection) SendEchoWithTimestamp(command ServerMessage, data EchoMessagePayload) error {
@@ -307,6 +367,10 @@ func (c *MapConnection) Send(command ServerMessage, data any) error {
 		if dd, ok := data.(DefineDicePresetDelegatesMessagePayload); ok {
 			return c.sendJSON("DDD", dd)
 		}
+	case DefineRandomTables:
+		if dt, ok := data.(DefineRandomTablesMessagePayload); ok {
+			return c.sendJSON("DT", dt)
+		}
 	case Denied:
 		if reason, ok := data.(DeniedMessagePayload); ok {
 			return c.sendJSON("DENIED", reason)
@@ -402,7 +466,7 @@ func (c *MapConnection) Send(command ServerMessage, data any) error {
 		}
 	case LoadTileObject:
 		if ob, ok := data.(TileElement); ok {
-			return c.sendJSON("LS-TEXT", ob)
+			return c.sendJSON("LS-TILE", ob)
 		}
 		if ob, ok := data.(LoadTileObjectMessagePayload); ok {
 			return c.sendJSON("LS-TILE", ob)
@@ -464,6 +528,16 @@ func (c *MapConnection) Send(command ServerMessage, data any) error {
 		}
 	case QueryPeers:
 		return c.sendln("/CONN", "")
+	case QueryRandomTables:
+		if dt, ok := data.(QueryRandomTablesMessagePayload); ok {
+			return c.sendJSON("DT?", dt)
+		}
+		return c.sendln("DT?", "")
+	case QueryRollHistory:
+		if dh, ok := data.(QueryRollHistoryMessagePayload); ok {
+			return c.sendJSON("DH?", dh)
+		}
+		return c.sendln("DH?", "")
 	case Ready:
 		return c.sendln("READY", "")
 	case Redirect:
@@ -474,6 +548,11 @@ func (c *MapConnection) Send(command ServerMessage, data any) error {
 		if oa, ok := data.(RemoveObjAttributesMessagePayload); ok {
 			return c.sendJSON("OA-", oa)
 		}
+	case RevealRollSeed:
+		if rs, ok := data.(RevealRollSeedMessagePayload); ok {
+			return c.sendJSON("SEED?", rs)
+		}
+		return c.sendln("SEED?", "")
 	case RollDice:
 		if rd, ok := data.(RollDiceMessagePayload); ok {
 			return c.sendJSON("D", rd)
@@ -482,6 +561,14 @@ func (c *MapConnection) Send(command ServerMessage, data any) error {
 		if rd, ok := data.(RollResultMessagePayload); ok {
 			return c.sendJSON("ROLL", rd)
 		}
+	case RollSeed:
+		if rs, ok := data.(RollSeedMessagePayload); ok {
+			return c.sendJSON("SEED", rs)
+		}
+	case RollTable:
+		if rt, ok := data.(RollTableMessagePayload); ok {
+			return c.sendJSON("DTR", rt)
+		}
 	case Sync:
 		return c.sendln("SYNC", "")
 	case SyncChat:
@@ -516,6 +603,14 @@ func (c *MapConnection) Send(command ServerMessage, data any) error {
 		if dd, ok := data.(UpdateDicePresetsMessagePayload); ok {
 			return c.sendJSON("DD=", dd)
 		}
+	case UpdateRandomTables:
+		if dt, ok := data.(UpdateRandomTablesMessagePayload); ok {
+			return c.sendJSON("DT=", dt)
+		}
+	case UpdateRollHistory:
+		if dh, ok := data.(UpdateRollHistoryMessagePayload); ok {
+			return c.sendJSON("DH", dh)
+		}
 	case UpdateInitiative:
 		if i, ok := data.(UpdateInitiativeMessagePayload); ok {
 			return c.sendJSON("IL", i)
@@ -556,7 +651,6 @@ func (c *MapConnection) Send(command ServerMessage, data any) error {
 }
 
 func (c *MapConnection) sendJSON(commandWord string, data any) error {
-	var err error
 	if c == nil {
 		return fmt.Errorf("nil MapConnection")
 	}
@@ -564,96 +658,68 @@ func (c *MapConnection) sendJSON(commandWord string, data any) error {
 		return c.sendln(commandWord, "")
 	}
 
-	const fragSize = 32768
-	if j, err := json.Marshal(data); err == nil {
-		sj := string(j)
-		if len(sj)+len(commandWord)+2 > MaxServerMessageSize {
-			blob := []byte(sj)
-			totalFragments := len(blob) / fragSize
-			if len(blob)%fragSize != 0 {
-				totalFragments++
-			}
-			batchID := uuid.NewString()
-			bail := func(part int, err error) error {
-				j, e := json.Marshal(BatchFragmentMessagePayload{
-					ID:      batchID,
-					Part:    part,
-					Of:      totalFragments,
-					Command: commandWord,
-					Error:   err.Error(),
-				})
-				if e != nil {
-					return e
-				}
-				e = c.sendln("BATCH", string(j)) // tell the other side we're giving up
-				if e != nil {
-					return e
-				}
-				return err
-			}
-			for part := range totalFragments {
-				batch := BatchFragmentMessagePayload{
-					ID:   batchID,
-					Part: part,
-					Of:   totalFragments,
-					Data: blob[part*fragSize : min((part+1)*fragSize, len(blob))],
-				}
-				if part == 0 {
-					batch.Command = commandWord
-				}
-				j, err := json.Marshal(batch)
-				if err != nil {
-					return bail(part, err)
-				}
-				err = c.sendln("BATCH", string(j)) // send this fragment
-				if err != nil {
-					return bail(part, err)
-				}
-			}
-			return nil
-		}
-		return c.sendln(commandWord, sj)
+	j, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("send: %v", err)
+	}
+	if len(j)+len(commandWord)+2 > MaxServerMessageSize {
+		return c.sendBatched(commandWord, j)
+	}
+	return c.sendln(commandWord, string(j))
+}
+
+// sendBatched sends a JSON payload which is too large to send in one piece
+// as a batch of BATCH fragments.
+func (c *MapConnection) sendBatched(commandWord string, blob []byte) error {
+	if len(blob) > MaxAllowedGiantPacketSize {
+		return fmt.Errorf("send: %s payload size %d exceeds maximum %v bytes", commandWord, len(blob), MaxAllowedGiantPacketSize)
 	}
 
-	//TODO	bail := func(b Batchable, reason string, origError error, batch int) error {
-	//TODO		j, err := json.Marshal(b.AbortPayload(fmt.Sprintf("Error: %s: %v", reason, origError), batch))
-	//TODO		if err != nil {
-	//TODO			return fmt.Errorf("send error %v for batch %d: %v", origError, batch, err)
-	//TODO		}
-	//TODO		return c.sendln(commandWord, string(j))
-	//TODO	}
-	//TODO
-	//TODO	splitIntoBatches := func(b Batchable) error {
-	//TODO		for i, batch := range b.Split() {
-	//TODO			j, err := json.Marshal(batch)
-	//TODO			if err != nil {
-	//TODO				return bail(b, "marshaling payload", err, i)
-	//TODO			}
-	//TODO			err = c.sendln(commandWord, string(j))
-	//TODO			if err != nil {
-	//TODO				return bail(b, "sending payload", err, i)
-	//TODO			}
-	//TODO		}
-	//TODO		return nil
-	//TODO	}
-	//TODO
-	//TODO	if b, isBatchable := data.(Batchable); isBatchable && b.NeedsToBeSplit() {
-	//TODO		return splitIntoBatches(b)
-	//TODO	}
-	//TODO
-	//TODO	if j, err := json.Marshal(data); err == nil {
-	//TODO		sj := string(j)
-	//TODO		if len(sj)+len(commandWord)+2 > MaxServerMessageSize {
-	//TODO			if b, isBatchable := data.(Batchable); isBatchable {
-	//TODO				// the up-front batchable check didn't predict we needed to do this but we ended up here anyway,
-	//TODO				// perhaps because of the cost of character encoding or something. Let's split it up now.
-	//TODO				return splitIntoBatches(b)
-	//TODO			}
-	//TODO			// Otherwise this will fail, but we handle that case in sendln...
-	//TODO		}
-	//TODO		return c.sendln(commandWord, sj)
-	//TODO	}
-	return fmt.Errorf("send: %v", err)
+	totalFragments := len(blob) / BatchFragmentSize
+	if len(blob)%BatchFragmentSize != 0 {
+		totalFragments++
+	}
+	batchID := uuid.NewString()
+	bail := func(part int, err error) error {
+		j, e := json.Marshal(BatchFragmentMessagePayload{
+			ID:      batchID,
+			Part:    part,
+			Of:      totalFragments,
+			Command: commandWord,
+			Error:   err.Error(),
+		})
+		if e != nil {
+			return e
+		}
+		e = c.sendln("BATCH", string(j)) // tell the other side we're giving up
+		if e != nil {
+			return e
+		}
+		return err
+	}
+	if c.debugf != nil {
+		c.debugf(DebugIO|DebugMessages, "sending %d-byte %s payload as batch %s of %d fragments", len(blob), commandWord, batchID, totalFragments)
+	}
+	for part := range totalFragments {
+		batch := BatchFragmentMessagePayload{
+			ID:   batchID,
+			Part: part,
+			Of:   totalFragments,
+			Data: blob[part*BatchFragmentSize : min((part+1)*BatchFragmentSize, len(blob))],
+		}
+		if part == 0 {
+			batch.Command = commandWord
+		}
+		j, err := json.Marshal(batch)
+		if err != nil {
+			return bail(part, err)
+		}
+		err = c.sendln("BATCH", string(j)) // send this fragment
+		if err != nil {
+			return bail(part, err)
+		}
+	}
+	return nil
 }
 
 func (c *MapConnection) sendln(commandWord, data string) error {
@@ -671,7 +737,7 @@ func (c *MapConnection) sendln(commandWord, data string) error {
 
 	if len(data)+len(commandWord)+2 > MaxServerMessageSize {
 		if c.serverSide {
-			c.sendChan <- fmt.Sprintf("FAILED {\"Command\": \"%s\",\"Reason\":\"Transmission failed for server message; payload length %d exceeds maximum allowed\"}\n", commandWord, len(data))
+			c.queue(fmt.Sprintf("FAILED {\"Command\": \"%s\",\"Reason\":\"Transmission failed for server message; payload length %d exceeds maximum allowed\"}\n", commandWord, len(data)))
 		}
 		return fmt.Errorf("protocol error: outgoing data packet length %d would exceed maximum allowed", len(data))
 	}
@@ -688,14 +754,21 @@ func (c *MapConnection) sendln(commandWord, data string) error {
 	//	default:
 	//		return fmt.Errorf("unable to send to server (Dial() not running or data backed up?")
 	//	}
-	c.sendChan <- packet.String()
+	c.queue(packet.String())
 	return nil
 }
 
+// queue hands a packet (including its trailing newline) to the writer,
+// recording it first if we're recording the session.
+func (c *MapConnection) queue(packet string) {
+	c.record(true, strings.TrimSuffix(packet, "\n"))
+	c.sendChan <- packet
+}
+
 // blocking raw data sent to other side
 func (c *MapConnection) sendRaw(data string) error {
 	if c != nil {
-		c.sendChan <- data + "\n"
+		c.queue(data + "\n")
 	}
 	return nil
 }
@@ -726,27 +799,37 @@ func (c *MapConnection) Receive() (MessagePayload, error) {
 	}
 
 	for {
-		if !c.reader.Scan() {
-			//c.debug(DebugIO, "Receive: scan failed; stopping")
-			if err = c.reader.Err(); err != nil {
-				//c.debugf(DebugIO, "Receive: scan failed with %v", err)
-				return nil, err
+		var line string
+		inflated := len(c.pending) > 0
+		if inflated {
+			line, c.pending = c.pending[0], c.pending[1:]
+		} else {
+			if !c.reader.Scan() {
+				//c.debug(DebugIO, "Receive: scan failed; stopping")
+				if err = c.reader.Err(); err != nil {
+					//c.debugf(DebugIO, "Receive: scan failed with %v", err)
+					return nil, err
+				}
+				return nil, nil
 			}
-			return nil, nil
+			line = c.reader.Text()
 		}
 
 		// Comments are anything starting with "//"
 		// The input line is in the form COMMAND-WORD [JSON] \n
-		c.debugf(DebugIO|DebugMessages, "<-%v", c.reader.Text())
+		c.debugf(DebugIO|DebugMessages, "<-%v", line)
 		payload := BaseMessagePayload{
-			rawMessage: c.reader.Text(),
+			rawMessage: line,
+		}
+		commandWord, jsonString, hasJsonPart := strings.Cut(line, " ")
+		if commandWord != "DEFLATE" {
+			c.record(false, payload.rawMessage)
 		}
-		commandWord, jsonString, hasJsonPart := strings.Cut(c.reader.Text(), " ")
 		if strings.Index(commandWord, "//") == 0 {
 			payload.messageType = Comment
 			return CommentMessagePayload{
 				BaseMessagePayload: payload,
-				Text:               c.reader.Text()[2:],
+				Text:               line[2:],
 			}, nil
 		}
 		sendError := func(reason error) (MessagePayload, error) {
@@ -757,6 +840,29 @@ func (c *MapConnection) Receive() (MessagePayload, error) {
 			}, nil
 		}
 
+		if commandWord == "DEFLATE" {
+			if inflated {
+				c.debugf(DebugIO|DebugMessages, "ERROR decoding compressed message: nested DEFLATE message")
+				return sendError(fmt.Errorf("DEFLATE message may not contain another DEFLATE message"))
+			}
+			p := CompressedMessagePayload{BaseMessagePayload: payload}
+			if hasJsonPart {
+				if err = json.Unmarshal([]byte(jsonString), &p); err != nil {
+					c.debugf(DebugIO|DebugMessages, "ERROR decoding compressed message: %v", err)
+					return sendError(err)
+				}
+			} else {
+				c.debugf(DebugIO|DebugMessages, "ERROR decoding compressed message: missing payload")
+				return sendError(fmt.Errorf("DEFLATE message missing required payload"))
+			}
+			if c.pending, err = inflatePackets(p.Data); err != nil {
+				c.debugf(DebugIO|DebugMessages, "ERROR decompressing message: %v", err)
+				return sendError(err)
+			}
+			c.debugf(DebugIO|DebugMessages, "Decompressed %d bytes into %d messages", len(p.Data), len(c.pending))
+			continue
+		}
+
 		if commandWord == "BATCH" {
 			var moreRemaining bool
 
@@ -1091,6 +1197,26 @@ func (c *MapConnection) Receive() (MessagePayload, error) {
 			p.messageType = Denied
 			return p, nil
 
+		case "DH":
+			p := UpdateRollHistoryMessagePayload{BaseMessagePayload: payload}
+			if hasJsonPart {
+				if err = json.Unmarshal([]byte(jsonString), &p); err != nil {
+					break
+				}
+			}
+			p.messageType = UpdateRollHistory
+			return p, nil
+
+		case "DH?":
+			p := QueryRollHistoryMessagePayload{BaseMessagePayload: payload}
+			if hasJsonPart {
+				if err = json.Unmarshal([]byte(jsonString), &p); err != nil {
+					break
+				}
+			}
+			p.messageType = QueryRollHistory
+			return p, nil
+
 		case "DR":
 			p := QueryDicePresetsMessagePayload{BaseMessagePayload: payload}
 			if hasJsonPart {
@@ -1101,6 +1227,46 @@ func (c *MapConnection) Receive() (MessagePayload, error) {
 			p.messageType = QueryDicePresets
 			return p, nil
 
+		case "DT":
+			p := DefineRandomTablesMessagePayload{BaseMessagePayload: payload}
+			if hasJsonPart {
+				if err = json.Unmarshal([]byte(jsonString), &p); err != nil {
+					break
+				}
+			}
+			p.messageType = DefineRandomTables
+			return p, nil
+
+		case "DT?":
+			p := QueryRandomTablesMessagePayload{BaseMessagePayload: payload}
+			if hasJsonPart {
+				if err = json.Unmarshal([]byte(jsonString), &p); err != nil {
+					break
+				}
+			}
+			p.messageType = QueryRandomTables
+			return p, nil
+
+		case "DT=":
+			p := UpdateRandomTablesMessagePayload{BaseMessagePayload: payload}
+			if hasJsonPart {
+				if err = json.Unmarshal([]byte(jsonString), &p); err != nil {
+					break
+				}
+			}
+			p.messageType = UpdateRandomTables
+			return p, nil
+
+		case "DTR":
+			p := RollTableMessagePayload{BaseMessagePayload: payload}
+			if hasJsonPart {
+				if err = json.Unmarshal([]byte(jsonString), &p); err != nil {
+					break
+				}
+			}
+			p.messageType = RollTable
+			return p, nil
+
 		case "DSM":
 			p := UpdateStatusMarkerMessagePayload{BaseMessagePayload: payload}
 			if hasJsonPart {
@@ -1400,6 +1566,26 @@ func (c *MapConnection) Receive() (MessagePayload, error) {
 			p.messageType = RollResult
 			return p, nil
 
+		case "SEED":
+			p := RollSeedMessagePayload{BaseMessagePayload: payload}
+			if hasJsonPart {
+				if err = json.Unmarshal([]byte(jsonString), &p); err != nil {
+					break
+				}
+			}
+			p.messageType = RollSeed
+			return p, nil
+
+		case "SEED?":
+			p := RevealRollSeedMessagePayload{BaseMessagePayload: payload}
+			if hasJsonPart {
+				if err = json.Unmarshal([]byte(jsonString), &p); err != nil {
+					break
+				}
+			}
+			p.messageType = RevealRollSeed
+			return p, nil
+
 		case "SOUND":
 			p := PlayAudioMessagePayload{BaseMessagePayload: payload}
 			if hasJsonPart {
@@ -19,6 +19,7 @@ package mapper
 
 import (
 	"context"
+	"crypto/x509"
 	"fmt"
 	"net"
 	"strings"
@@ -68,6 +69,7 @@ type ClientConnection struct {
 		DiceColorBoxes  bool
 		DiceColorLabels bool
 		GMAMarkup       bool
+		Compression     bool
 	}
 
 	// The client's host and port number
@@ -83,6 +85,11 @@ type ClientConnection struct {
 	// Authentication information for this user
 	Auth *auth.Authenticator
 
+	// If not nil, a client which presents a trusted TLS certificate
+	// is logged in as the user this returns for that certificate
+	// without being challenged for a password.
+	CertificateUser func(*x509.Certificate) string
+
 	// Aliases to map creatures
 	AKA        []string
 	NotPlaying bool
@@ -95,6 +102,12 @@ type ClientConnection struct {
 	Conn MapConnection
 	D    *dice.DieRoller
 
+	// If the server is making verifiable die rolls, this is the seed
+	// committed to for this client's current series of rolls, and the
+	// number of rolls made with it so far.
+	RollSeed     *dice.SeedCommitment
+	RollSequence int
+
 	// Quality of Service tracking
 	QoS struct {
 		QueryImage struct {
@@ -230,6 +243,31 @@ func WithClientAuthenticator(a *auth.Authenticator) ClientConnectionOption {
 	}
 }
 
+// WithClientCertificateUsers allows clients to log in with TLS client
+// certificates instead of passwords. The given function maps each
+// certificate to the corresponding username (e.g., CertificateUsername).
+// A certificate which maps to "GM" grants GM privileges.
+//
+// This only applies to clients connected over TLS whose certificates were
+// verified during the TLS handshake, so the server's TLS configuration
+// must specify which certificate authorities it trusts to sign them.
+func WithClientCertificateUsers(f func(*x509.Certificate) string) ClientConnectionOption {
+	return func(c *ClientConnection) error {
+		c.CertificateUser = f
+		return nil
+	}
+}
+
+// WithClientRecorder adds every message sent to or received from the
+// client to a session recording. The caller is responsible for closing
+// the Recorder when finished with it.
+func WithClientRecorder(r *Recorder) ClientConnectionOption {
+	return func(c *ClientConnection) error {
+		c.Conn.recorder = r
+		return nil
+	}
+}
+
 func (c *ClientConnection) clientIdTag() string {
 	return "[client " + c.IdTag() + "]"
 }
@@ -287,10 +325,10 @@ go c.ServeToClient(ctx, serverStarted, lastPing, nrApp)
     or expired context between reads|
 
   start daemon blocking for         |toSend[1] or c.Conn.sendBuf[] <-c.Conn.sendChan[50]
-                                    |toSend[1]<-pop(c.Conn.sendBuf) if possible <-bufferReadable		any time buffer has data
+                                    |toSend[1]<-all of c.Conn.sendBuf if possible <-bufferReadable		any time buffer has data
 									|<-ctx.Done()
 
-  start daemon blocking for         |c.Conn.writer.WriteString(),.Flush()<-toSend
+  start daemon blocking for         |c.Conn.writer.WriteString()...,.Flush()<-toSend (compressed if client allowed it)
                                     |<-ctx.Done()
 
   for |<-QoS.Log.Ticker.C
@@ -390,7 +428,7 @@ func (c *ClientConnection) ServeToClient(ctx context.Context, serverStarted, las
 	//
 	// We want management of the buffer slice to happen only here in this one goroutine,
 	// so we also feed the buffered data to the toSend channel as fast as it can accept
-	// them (which is tied to the network socket being available and the client's reading
+	// them, handing over everything that has accumulated in the buffer each time (which is tied to the network socket being available and the client's reading
 	// speed, so this is where data backs up in the buffer when the client isn't able to
 	// accept as fast as we can send.
 	//
@@ -407,7 +445,7 @@ func (c *ClientConnection) ServeToClient(ctx context.Context, serverStarted, las
 	// that works for what we're doing now. It's possible this
 	// will evolve later into something more sophisticated such as what was just described.
 
-	toSend := make(chan string, 1)
+	toSend := make(chan []string, 1)
 	clientBufferCtx, cancelClientBuffer := context.WithCancel(ctx)
 	defer cancelClientBuffer()
 
@@ -421,7 +459,7 @@ func (c *ClientConnection) ServeToClient(ctx context.Context, serverStarted, las
 			case packet := <-c.Conn.sendChan:
 				if len(c.Conn.sendBuf) == 0 {
 					select {
-					case toSend <- packet:
+					case toSend <- []string{packet}:
 						c.debugf(DebugIO, "moved packet directly to output channel (buffer empty and channel available now)")
 						continue
 					default:
@@ -439,9 +477,9 @@ func (c *ClientConnection) ServeToClient(ctx context.Context, serverStarted, las
 			case <-bufferReadable:
 				if len(c.Conn.sendBuf) > 0 {
 					select {
-					case toSend <- c.Conn.sendBuf[0]:
-						c.Conn.sendBuf = c.Conn.sendBuf[1:]
-						c.debugf(DebugIO, "moved packet to output channel (depth %d)", len(c.Conn.sendBuf))
+					case toSend <- c.Conn.sendBuf:
+						c.debugf(DebugIO, "moved %d packets to output channel", len(c.Conn.sendBuf))
+						c.Conn.sendBuf = nil
 					default:
 					}
 				}
@@ -459,7 +497,8 @@ func (c *ClientConnection) ServeToClient(ctx context.Context, serverStarted, las
 	// And now start a client sender which watches the output buffer and shuttles data from
 	// the toSend channel to the client socket as fast as we can manage that.  This will block
 	// when the client socket is full, which is fine, the buffer manager will collect the data
-	// backing up until it's ready again.
+	// backing up until it's ready again. If the client asked us to compress our messages, we
+	// do that here, packing each set of packets we get from toSend together.
 
 	clientSenderCtx, cancelClientSender := context.WithCancel(ctx)
 	defer cancelClientSender()
@@ -474,14 +513,19 @@ func (c *ClientConnection) ServeToClient(ctx context.Context, serverStarted, las
 				return
 			}
 			select {
-			case packet := <-toSend:
-				if written, err := c.Conn.writer.WriteString(packet); err != nil {
-					c.Logf("error sending %v to client (wrote %d): %v", packet, written, err)
+			case packets := <-toSend:
+				if c.Conn.compressing() {
+					packets = c.Conn.compressPackets(packets)
+				}
+				for _, packet := range packets {
+					if written, err := c.Conn.writer.WriteString(packet); err != nil {
+						c.Logf("error sending %v to client (wrote %d): %v", packet, written, err)
+					}
 				}
 				if err := c.Conn.writer.Flush(); err != nil {
-					c.Logf("error sending %v to client (in flush): %v", packet, err)
+					c.Logf("error sending %v to client (in flush): %v", packets, err)
 				}
-				c.debugf(DebugIO, "sent %v", packet)
+				c.debugf(DebugIO, "sent %v", packets)
 			case <-ctx.Done():
 				return
 			}
@@ -604,8 +648,8 @@ mainloop:
 				case AddCharacterMessagePayload, ChallengeMessagePayload, ProtocolMessagePayload,
 					UpdateDicePresetsMessagePayload, DeniedMessagePayload, GrantedMessagePayload,
 					MarcoMessagePayload, PrivMessagePayload, ReadyMessagePayload, RedirectMessagePayload,
-					RollResultMessagePayload, UpdateCoreDataMessagePayload, UpdateCoreIndexMessagePayload,
-					UpdatePeerListMessagePayload,
+					RollResultMessagePayload, RollSeedMessagePayload, UpdateCoreDataMessagePayload, UpdateCoreIndexMessagePayload,
+					UpdatePeerListMessagePayload, UpdateRandomTablesMessagePayload, UpdateRollHistoryMessagePayload,
 					UpdateVersionsMessagePayload, WorldMessagePayload:
 					c.Conn.Send(Priv, PrivMessagePayload{
 						Command: p.RawMessage(),
@@ -634,6 +678,7 @@ mainloop:
 					c.Features.DiceColorBoxes = false
 					c.Features.DiceColorLabels = false
 					c.Features.GMAMarkup = false
+					c.Features.Compression = false
 
 					for _, feature := range p.Features {
 						switch feature {
@@ -643,8 +688,11 @@ mainloop:
 							c.Features.DiceColorLabels = true
 						case "GMA-MARKUP":
 							c.Features.GMAMarkup = true
+						case "DEFLATE":
+							c.Features.Compression = true
 						}
 					}
+					c.Conn.setCompression(c.Features.Compression)
 
 				case PoloMessagePayload:
 					c.LastPoloTime = time.Now()
@@ -750,7 +798,51 @@ func (c *ClientConnection) loginClient(ctx context.Context, done chan error, ser
 	}
 
 	// Authentication challenge
-	if c.Auth != nil {
+	if user := c.certificateUser(ctx); user != "" {
+		if c.Auth == nil {
+			c.Auth = &auth.Authenticator{}
+		}
+		c.Auth.Username = user
+		c.Auth.GmMode = user == "GM"
+		c.debugf(DebugAuth, "client certificate identifies user %s; awaiting client identification", user)
+		c.Conn.Send(Challenge, ChallengeMessagePayload{
+			Protocol:        GMAMapperProtocol,
+			CertificateUser: user,
+			ServerStarted:   serverStarted,
+			ServerActive:    lastPing,
+			ServerTime:      time.Now(),
+			ServerVersion:   GoVersionNumber,
+		})
+		if err := c.Conn.Flush(); err != nil {
+			done <- err
+			return
+		}
+
+		// The client doesn't need to answer a challenge, but it still tells us
+		// which client program it is, so we can check that it's one we allow.
+		select {
+		case <-ctx.Done():
+			c.Log("Timeout/cancel while waiting for authentication from client")
+			c.Conn.Send(Denied, DeniedMessagePayload{Reason: "Life is short indeed / I don't have time for waiting / For you to log in"})
+			_ = c.Conn.Flush()
+			time.Sleep(1 * time.Second)
+			done <- fmt.Errorf("timeout waiting for client auth")
+			return
+
+		case packet := <-c.receiveAuth():
+			c.debugf(DebugAuth, "received client identification %v", packet)
+			if reason, err := c.checkClientVersion(packet.Client); err != nil {
+				c.Conn.Send(Denied, DeniedMessagePayload{Reason: reason})
+				_ = c.Conn.Flush()
+				done <- err
+				return
+			}
+			c.Auth.Client = packet.Client
+			c.Logf("login: authenticated as %s by TLS client certificate", user)
+			c.Logf("login: client: %s, platform: %s", packet.Client, packet.Platform)
+			c.Conn.Send(Granted, GrantedMessagePayload{User: user})
+		}
+	} else if c.Auth != nil {
 		c.debug(DebugIO, "issuing authentication challenge")
 		challenge, iterations, err := c.Auth.GenerateChallengeBytesWithIterations()
 		if err != nil {
@@ -771,30 +863,7 @@ func (c *ClientConnection) loginClient(ctx context.Context, done chan error, ser
 			return
 		}
 
-		reply := make(chan AuthMessagePayload, 1)
-		go func(reply chan AuthMessagePayload) {
-			for {
-				packet, err := c.Conn.Receive()
-				if err != nil {
-					c.Logf("error reading auth response from client: %v; stopping", err)
-					return
-				}
-				if packet == nil {
-					c.Log("EOF reading auth response from client; stopping")
-					return
-				}
-				switch p := packet.(type) {
-				case ErrorMessagePayload:
-					c.Logf("error reading auth response from client: %v", p.Error)
-				case PoloMessagePayload:
-					continue
-				case AuthMessagePayload:
-					reply <- p
-					return
-				}
-				c.Logf("Invalid packet of type %T received", packet)
-			}
-		}(reply)
+		reply := c.receiveAuth()
 
 	awaitUserAuth:
 		for {
@@ -809,55 +878,11 @@ func (c *ClientConnection) loginClient(ctx context.Context, done chan error, ser
 
 			case packet := <-reply:
 				c.debugf(DebugAuth, "received client authentication %v", packet)
-				allowed := c.Server.GetAllowedClients()
-
-				if packet.Client != "" && allowed != nil {
-					c.debugf(DebugAuth, "checking for allowed client version")
-					for _, allowedClient := range allowed {
-						if allowedClient.VersionRegex == nil || allowedClient.MinimumVersion == "" {
-							c.debugf(DebugAuth, "no minimum version set for %s, skipping", allowedClient.Name)
-							continue
-						}
-
-						fields := allowedClient.VersionRegex.FindStringSubmatch(packet.Client)
-						if fields == nil {
-							c.debugf(DebugAuth, "client %s does not match pattern %s for %s, trying next package", packet.Client, allowedClient.VersionPattern, allowedClient.Name)
-							continue
-						}
-
-						if len(fields) != 2 {
-							c.debugf(DebugAuth, "package %s pattern %s is invalid: MUST have exactly one capturing group", allowedClient.Name, allowedClient.VersionPattern)
-							continue
-						}
-
-						if fields[1] == "" {
-							c.debugf(DebugAuth, "client %s matches pattern %s for %s, but does not announce its version; denied", packet.Client, allowedClient.VersionPattern, allowedClient.Name)
-							c.Conn.Send(Denied, DeniedMessagePayload{Reason: "disallowed client version"})
-							_ = c.Conn.Flush()
-							done <- fmt.Errorf("client denied")
-							return
-						}
-
-						relVer, err := util.VersionCompare(fields[1], allowedClient.MinimumVersion)
-						if err != nil {
-							c.debugf(DebugAuth, "Error parsing client version %s and minimum version %s: %v", fields[1], allowedClient.MinimumVersion, err)
-							c.Conn.Send(Denied, DeniedMessagePayload{Reason: "unable to understand client version"})
-							_ = c.Conn.Flush()
-							done <- fmt.Errorf("client version error")
-							return
-						}
-
-						if relVer < 0 {
-							c.debugf(DebugAuth, "%s client version %s is older than minimum version %s; denied", allowedClient.Name, fields[1], allowedClient.MinimumVersion)
-							c.Conn.Send(Denied, DeniedMessagePayload{Reason: allowedClient.Name + " client is older than minimum allowed version"})
-							_ = c.Conn.Flush()
-							done <- fmt.Errorf("client version not allowed")
-							return
-						} else {
-							c.debugf(DebugAuth, "%s client version %s is allowed", allowedClient.Name, fields[1])
-						}
-						break
-					}
+				if reason, err := c.checkClientVersion(packet.Client); err != nil {
+					c.Conn.Send(Denied, DeniedMessagePayload{Reason: reason})
+					_ = c.Conn.Flush()
+					done <- err
+					return
 				}
 
 				if strings.HasPrefix(packet.User, "SYS$") {
@@ -939,3 +964,82 @@ func (c *ClientConnection) loginClient(ctx context.Context, done chan error, ser
 		}
 	}
 }
+
+// receiveAuth starts reading from the client until it sends us an AUTH message,
+// which is sent to the returned channel. Anything else other than pings is ignored.
+func (c *ClientConnection) receiveAuth() chan AuthMessagePayload {
+	reply := make(chan AuthMessagePayload, 1)
+	go func(reply chan AuthMessagePayload) {
+		for {
+			packet, err := c.Conn.Receive()
+			if err != nil {
+				c.Logf("error reading auth response from client: %v; stopping", err)
+				return
+			}
+			if packet == nil {
+				c.Log("EOF reading auth response from client; stopping")
+				return
+			}
+			switch p := packet.(type) {
+			case ErrorMessagePayload:
+				c.Logf("error reading auth response from client: %v", p.Error)
+			case PoloMessagePayload:
+				continue
+			case AuthMessagePayload:
+				reply <- p
+				return
+			}
+			c.Logf("Invalid packet of type %T received", packet)
+		}
+	}(reply)
+	return reply
+}
+
+// checkClientVersion makes sure the client program described by the client
+// string from its AUTH message is no older than the minimum version we allow for
+// that program. If it isn't allowed, the reason to give the client for denying
+// access is returned along with an error.
+func (c *ClientConnection) checkClientVersion(client string) (string, error) {
+	allowed := c.Server.GetAllowedClients()
+	if client == "" || allowed == nil {
+		return "", nil
+	}
+
+	c.debugf(DebugAuth, "checking for allowed client version")
+	for _, allowedClient := range allowed {
+		if allowedClient.VersionRegex == nil || allowedClient.MinimumVersion == "" {
+			c.debugf(DebugAuth, "no minimum version set for %s, skipping", allowedClient.Name)
+			continue
+		}
+
+		fields := allowedClient.VersionRegex.FindStringSubmatch(client)
+		if fields == nil {
+			c.debugf(DebugAuth, "client %s does not match pattern %s for %s, trying next package", client, allowedClient.VersionPattern, allowedClient.Name)
+			continue
+		}
+
+		if len(fields) != 2 {
+			c.debugf(DebugAuth, "package %s pattern %s is invalid: MUST have exactly one capturing group", allowedClient.Name, allowedClient.VersionPattern)
+			continue
+		}
+
+		if fields[1] == "" {
+			c.debugf(DebugAuth, "client %s matches pattern %s for %s, but does not announce its version; denied", client, allowedClient.VersionPattern, allowedClient.Name)
+			return "disallowed client version", fmt.Errorf("client denied")
+		}
+
+		relVer, err := util.VersionCompare(fields[1], allowedClient.MinimumVersion)
+		if err != nil {
+			c.debugf(DebugAuth, "Error parsing client version %s and minimum version %s: %v", fields[1], allowedClient.MinimumVersion, err)
+			return "unable to understand client version", fmt.Errorf("client version error")
+		}
+
+		if relVer < 0 {
+			c.debugf(DebugAuth, "%s client version %s is older than minimum version %s; denied", allowedClient.Name, fields[1], allowedClient.MinimumVersion)
+			return allowedClient.Name + " client is older than minimum allowed version", fmt.Errorf("client version not allowed")
+		}
+		c.debugf(DebugAuth, "%s client version %s is allowed", allowedClient.Name, fields[1])
+		break
+	}
+	return "", nil
+}
@@ -0,0 +1,243 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package mapper
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A session recording is a text file with one line for each message sent
+// or received on a connection, in the order they happened, of the form
+//
+//	time sender message
+//
+// where time is when the message was sent or received (in RFC 3339 format
+// with nanoseconds), sender is "server" or "client" to indicate which side
+// sent it, and message is the message exactly as it appeared in the
+// protocol (minus its trailing newline). Messages which arrived in DEFLATE
+// messages are recorded individually, as though they had been sent uncompressed.
+//
+// To record a session, pass a Recorder to NewConnection using the WithRecorder
+// option, or to NewClientConnection using the WithClientRecorder option.
+// A recording may be read back with a RecordingReader.
+
+// RecordedMessage is a single message in a session recording.
+type RecordedMessage struct {
+	// When the message was sent or received.
+	Time time.Time
+
+	// True if the server sent this message; false if the client did.
+	FromServer bool
+
+	// The message exactly as it was sent, without its trailing newline.
+	// For received messages, this is what their RawMessage method returns.
+	Message string
+}
+
+// String returns the message as it appears in a recording.
+func (m RecordedMessage) String() string {
+	sender := "client"
+	if m.FromServer {
+		sender = "server"
+	}
+	return fmt.Sprintf("%s %s %s", m.Time.Format(time.RFC3339Nano), sender, m.Message)
+}
+
+// ParseRecordedMessage interprets a line from a session recording.
+func ParseRecordedMessage(line string) (RecordedMessage, error) {
+	var m RecordedMessage
+	var err error
+
+	fields := strings.SplitN(line, " ", 3)
+	if len(fields) < 3 {
+		return m, fmt.Errorf("recorded message \"%s\" is missing fields", line)
+	}
+	if m.Time, err = time.Parse(time.RFC3339Nano, fields[0]); err != nil {
+		return m, fmt.Errorf("recorded message has invalid time: %v", err)
+	}
+	switch fields[1] {
+	case "server":
+		m.FromServer = true
+	case "client":
+	default:
+		return m, fmt.Errorf("recorded message has invalid sender \"%s\"", fields[1])
+	}
+	m.Message = fields[2]
+	return m, nil
+}
+
+// Recorder writes a session recording. It is safe for concurrent use.
+type Recorder struct {
+	lock   sync.Mutex
+	out    *bufio.Writer
+	closer io.Closer
+}
+
+// NewRecorder creates a Recorder which writes to the given output.
+// If output is also an io.Closer, it will be closed when the
+// Recorder is.
+func NewRecorder(output io.Writer) *Recorder {
+	r := &Recorder{out: bufio.NewWriter(output)}
+	if closer, ok := output.(io.Closer); ok {
+		r.closer = closer
+	}
+	return r
+}
+
+// CreateRecording creates (or truncates) the named file and returns a Recorder
+// which writes to it.
+//
+// A recording holds everything sent over the connection, including the
+// authentication exchange (from which a password could be guessed) and
+// private messages between players, so the file is made readable and
+// writable only by its owner.
+func CreateRecording(path string) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err = f.Chmod(0600); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return NewRecorder(f), nil
+}
+
+// Record adds a message to the recording. Each message is flushed out
+// as it is recorded so the recording is intact up to the last message
+// even if the program doesn't exit cleanly.
+func (r *Recorder) Record(m RecordedMessage) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, err := r.out.WriteString(m.String() + "\n"); err != nil {
+		return err
+	}
+	return r.out.Flush()
+}
+
+// Close flushes any remaining output and closes the recording.
+func (r *Recorder) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	err := r.out.Flush()
+	if r.closer != nil {
+		if cerr := r.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// record adds a message we just sent (if sent is true) or received
+// to our session recording, if we're making one.
+func (c *MapConnection) record(sent bool, message string) {
+	if c == nil || c.recorder == nil {
+		return
+	}
+	if err := c.recorder.Record(RecordedMessage{
+		Time:       time.Now(),
+		FromServer: sent == c.serverSide,
+		Message:    message,
+	}); err != nil && c.debugf != nil {
+		c.debugf(DebugIO, "unable to record message: %v", err)
+	}
+}
+
+// RecordingReader reads the messages from a session recording.
+type RecordingReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewRecordingReader returns a RecordingReader which reads a session recording
+// from the given input.
+func NewRecordingReader(input io.Reader) *RecordingReader {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxAllowedGiantPacketSize)
+	return &RecordingReader{scanner: scanner}
+}
+
+// Next returns the next message from the recording, or io.EOF if there
+// are no more.
+func (r *RecordingReader) Next() (RecordedMessage, error) {
+	for r.scanner.Scan() {
+		r.line++
+		if strings.TrimSpace(r.scanner.Text()) == "" {
+			continue
+		}
+		m, err := ParseRecordedMessage(r.scanner.Text())
+		if err != nil {
+			return m, fmt.Errorf("line %d: %v", r.line, err)
+		}
+		return m, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return RecordedMessage{}, err
+	}
+	return RecordedMessage{}, io.EOF
+}
+
+// Replay reads the rest of the recording, calling send for each message
+// which was sent by the server (if fromServer is true) or by the client
+// (if it is false). It pauses between messages as long as the original
+// sender did, divided by speed, so a speed of 1 plays them back in real time,
+// a speed of 10 plays them back ten times as fast, and a speed of 0 plays them
+// back without pausing at all.
+//
+// Replay returns early if ctx is cancelled or send returns an error.
+func (r *RecordingReader) Replay(ctx context.Context, fromServer bool, speed float64, send func(RecordedMessage) error) error {
+	var previous time.Time
+
+	for {
+		m, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if m.FromServer != fromServer {
+			continue
+		}
+		if speed > 0 && !previous.IsZero() && m.Time.After(previous) {
+			pause := time.NewTimer(time.Duration(float64(m.Time.Sub(previous)) / speed))
+			select {
+			case <-ctx.Done():
+				pause.Stop()
+				return ctx.Err()
+			case <-pause.C:
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		previous = m.Time
+		if err := send(m); err != nil {
+			return err
+		}
+	}
+}
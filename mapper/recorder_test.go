@@ -0,0 +1,242 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+//
+// Unit tests for session recording and playback.
+//
+
+package mapper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionRecording(t *testing.T) {
+	var clientLog, serverLog bytes.Buffer
+	clientSocket, serverSocket := net.Pipe()
+	defer clientSocket.Close()
+	defer serverSocket.Close()
+
+	client := newTestMapConnection(clientSocket)
+	client.recorder = NewRecorder(&clientLog)
+	server := newTestMapConnection(serverSocket)
+	server.serverSide = true
+	server.recorder = NewRecorder(&serverLog)
+
+	exchange := func(from, to *MapConnection, command ServerMessage, data any) {
+		t.Helper()
+		sent := make(chan bool)
+		go func() {
+			defer close(sent)
+			if err := from.Send(command, data); err != nil {
+				t.Errorf("send: %v", err)
+			}
+			if err := from.Flush(); err != nil {
+				t.Errorf("flush: %v", err)
+			}
+		}()
+		if _, err := to.Receive(); err != nil {
+			t.Fatalf("receive: %v", err)
+		}
+		<-sent
+	}
+	exchange(&server, &client, Marco, nil)
+	exchange(&client, &server, Polo, nil)
+	exchange(&client, &server, Toolbar, ToolbarMessagePayload{Enabled: true})
+	exchange(&server, &client, Comment, "that's all")
+
+	expected := []RecordedMessage{
+		{FromServer: true, Message: "MARCO"},
+		{FromServer: false, Message: "POLO"},
+		{FromServer: false, Message: `TB {"Enabled":true}`},
+		{FromServer: true, Message: "// that's all"},
+	}
+	for _, log := range []*bytes.Buffer{&clientLog, &serverLog} {
+		r := NewRecordingReader(strings.NewReader(log.String()))
+		var previous time.Time
+		for i, e := range expected {
+			m, err := r.Next()
+			if err != nil {
+				t.Fatalf("message %d: %v", i, err)
+			}
+			if m.FromServer != e.FromServer || m.Message != e.Message {
+				t.Errorf("message %d recorded as %v; expected %v", i, m, e)
+			}
+			if m.Time.Before(previous) || time.Since(m.Time) > time.Minute {
+				t.Errorf("message %d recorded with time %v", i, m.Time)
+			}
+			previous = m.Time
+		}
+		if m, err := r.Next(); err != io.EOF {
+			t.Errorf("extra message %v recorded (%v)", m, err)
+		}
+	}
+}
+
+func TestCreateRecordingPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not supported on Windows")
+	}
+	dir := t.TempDir()
+	for i, existing := range []bool{false, true} {
+		path := filepath.Join(dir, "session"+strconv.Itoa(i))
+		if existing {
+			if err := os.WriteFile(path, []byte("old recording\n"), 0644); err != nil {
+				t.Fatalf("test %d: %v", i, err)
+			}
+		}
+		r, err := CreateRecording(path)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		r.Close()
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("test %d: recording created with mode %v, expected -rw-------", i, perm)
+		}
+		if info.Size() != 0 {
+			t.Errorf("test %d: recording not truncated (%d bytes)", i, info.Size())
+		}
+	}
+}
+
+func TestRecordedMessageFormat(t *testing.T) {
+	m := RecordedMessage{
+		Time:       time.Date(2026, 10, 17, 19, 30, 5, 250000000, time.UTC),
+		FromServer: true,
+		Message:    `ROLL {"Title":"a b c"}`,
+	}
+	if line := m.String(); line != `2026-10-17T19:30:05.25Z server ROLL {"Title":"a b c"}` {
+		t.Errorf("recorded as %q", line)
+	}
+	parsed, err := ParseRecordedMessage(m.String())
+	if err != nil || !parsed.Time.Equal(m.Time) || parsed.FromServer != m.FromServer || parsed.Message != m.Message {
+		t.Errorf("parsed as %v, %v", parsed, err)
+	}
+
+	for _, line := range []string{
+		"2026-10-17T19:30:05Z server",
+		"yesterday server MARCO",
+		"2026-10-17T19:30:05Z player MARCO",
+	} {
+		if _, err := ParseRecordedMessage(line); err == nil {
+			t.Errorf("%q parsed without error", line)
+		}
+	}
+
+	r := NewRecordingReader(strings.NewReader("2026-10-17T19:30:05Z server MARCO\n\nbogus\n"))
+	if m, err := r.Next(); err != nil || m.Message != "MARCO" {
+		t.Errorf("read %v, %v", m, err)
+	}
+	if _, err := r.Next(); err == nil || !strings.HasPrefix(err.Error(), "line 3:") {
+		t.Errorf("read bad line with error %v", err)
+	}
+}
+
+const testRecording = `2026-10-17T19:30:00Z server PROTOCOL 423
+2026-10-17T19:30:00.1Z client AUTH {"User":"alice"}
+2026-10-17T19:30:00.2Z server READY
+2026-10-17T19:30:00.3Z client TO {"Text":"one"}
+2026-10-17T19:30:00.5Z client TO {"Text":"two"}
+2026-10-17T19:30:00.6Z server // bye
+`
+
+func TestReplay(t *testing.T) {
+	var sent []string
+	start := time.Now()
+	err := NewRecordingReader(strings.NewReader(testRecording)).Replay(context.Background(), false, 2, func(m RecordedMessage) error {
+		sent = append(sent, m.Message)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("replay at double speed took %v; expected at least 200ms", elapsed)
+	}
+	if strings.Join(sent, "\n") != "AUTH {\"User\":\"alice\"}\nTO {\"Text\":\"one\"}\nTO {\"Text\":\"two\"}" {
+		t.Errorf("replayed %q", sent)
+	}
+
+	sent = nil
+	if err := NewRecordingReader(strings.NewReader(testRecording)).Replay(context.Background(), true, 0, func(m RecordedMessage) error {
+		sent = append(sent, m.Message)
+		return nil
+	}); err != nil || len(sent) != 3 {
+		t.Errorf("replayed %q from server, %v", sent, err)
+	}
+
+	stop := errors.New("stop")
+	if err := NewRecordingReader(strings.NewReader(testRecording)).Replay(context.Background(), true, 0, func(m RecordedMessage) error {
+		return stop
+	}); err != stop {
+		t.Errorf("replay returned %v; expected send error", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := NewRecordingReader(strings.NewReader(testRecording)).Replay(ctx, false, 0.001, func(m RecordedMessage) error {
+		return nil
+	}); err != context.DeadlineExceeded {
+		t.Errorf("replay returned %v; expected it to be cancelled", err)
+	}
+}
+
+func TestRecordingCompressedMessages(t *testing.T) {
+	var log bytes.Buffer
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	c := newTestMapConnection(client)
+	c.recorder = NewRecorder(&log)
+	go server.Write([]byte(deflateLine(t, "MARCO\n// hello\n")))
+
+	for range 2 {
+		if _, err := c.Receive(); err != nil {
+			t.Fatalf("receive: %v", err)
+		}
+	}
+	var recorded []string
+	r := NewRecordingReader(&log)
+	for {
+		m, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read recording: %v", err)
+		}
+		recorded = append(recorded, m.Message)
+	}
+	if strings.Join(recorded, "|") != "MARCO|// hello" {
+		t.Errorf("recorded %q", recorded)
+	}
+}
@@ -0,0 +1,120 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package mapper
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+//
+// TLS support.
+//
+// The challenge-response authentication used by the protocol doesn't protect
+// against an attacker who can intercept and alter the traffic between the
+// client and server, and of course nothing else sent over the connection is
+// private. To protect against that, the connection may be made over TLS.
+//
+// The server may additionally require clients to present a certificate signed by
+// a certificate authority it trusts, and log them in as the user named in that
+// certificate instead of asking for a password.
+//
+
+// dialTLS makes a TLS connection to the given address, using the
+// Connection's TLS configuration (or the default configuration if
+// it doesn't have one).
+func (c *Connection) dialTLS(address string) (net.Conn, error) {
+	config := c.TLSConfig
+	if config == nil {
+		config = &tls.Config{}
+	}
+	if config.ServerName == "" {
+		config = config.Clone()
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			config.ServerName = host
+		} else {
+			config.ServerName = address
+		}
+	}
+
+	conn, err := c.dialTCP(address)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := c.Context
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("TLS handshake with %s failed: %v", address, err)
+	}
+	return tlsConn, nil
+}
+
+// CertificateUsername returns the username identified by a client's
+// certificate, which is the common name of its subject.
+// This may be given to WithClientCertificateUsers.
+func CertificateUsername(cert *x509.Certificate) string {
+	return cert.Subject.CommonName
+}
+
+// certificateUser returns the username identified by the client's
+// TLS certificate, or an empty string if the client didn't present
+// a certificate we trust (or isn't connected over TLS at all, or we
+// aren't accepting certificates in place of passwords).
+func (c *ClientConnection) certificateUser(ctx context.Context) string {
+	if c.CertificateUser == nil {
+		return ""
+	}
+	if h, ok := c.Conn.conn.(interface {
+		HandshakeContext(context.Context) error
+	}); ok {
+		hctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		if err := h.HandshakeContext(hctx); err != nil {
+			c.Logf("TLS handshake failed: %v", err)
+			return ""
+		}
+	}
+	s, ok := c.Conn.conn.(interface {
+		ConnectionState() tls.ConnectionState
+	})
+	if !ok {
+		return ""
+	}
+	state := s.ConnectionState()
+	if len(state.VerifiedChains) == 0 || len(state.PeerCertificates) == 0 {
+		return ""
+	}
+	user := c.CertificateUser(state.PeerCertificates[0])
+	if strings.HasPrefix(user, "SYS$") {
+		c.Logf("ignoring client certificate for restricted username %s", user)
+		return ""
+	}
+	return user
+}
@@ -0,0 +1,361 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+//
+// Unit tests for TLS connections.
+//
+
+package mapper
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/MadScienceZone/go-gma/v5/auth"
+)
+
+// testCA is a throwaway certificate authority for signing test certificates.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("can't generate CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("can't create CA certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("can't parse CA certificate: %v", err)
+	}
+	ca := &testCA{cert: cert, key: key, pool: x509.NewCertPool()}
+	ca.pool.AddCert(cert)
+	return ca
+}
+
+// issue signs a new server (if client is false) or client certificate.
+func (ca *testCA) issue(t *testing.T, name string, client bool) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("can't generate key: %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if client {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	} else {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("can't create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// serverTLSConfig accepts (but does not require) client certificates signed by the CA.
+func (ca *testCA) serverTLSConfig(t *testing.T) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{ca.issue(t, "server", false)},
+		ClientCAs:    ca.pool,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+	}
+}
+
+// acceptOne returns the next connection accepted by the listener (after
+// completing the TLS handshake, which the client is waiting for), or closes
+// the channel if there isn't one.
+func acceptOne(listener net.Listener) <-chan net.Conn {
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		defer close(accepted)
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		if tlsConn, ok := conn.(*tls.Conn); ok {
+			tlsConn.Handshake()
+		}
+		accepted <- conn
+	}()
+	return accepted
+}
+
+func TestTLSTransport(t *testing.T) {
+	ca := newTestCA(t)
+	otherCA := newTestCA(t)
+	clientCert := ca.issue(t, "alice", true)
+
+	for i, test := range []struct {
+		WebSocket  bool
+		RootCAs    *x509.CertPool
+		ClientCert *tls.Certificate
+		User       string
+		Fails      bool
+	}{
+		{RootCAs: ca.pool},
+		{RootCAs: ca.pool, ClientCert: &clientCert, User: "alice"},
+		{RootCAs: otherCA.pool, Fails: true},
+		{RootCAs: ca.pool, ClientCert: ptr(otherCA.issue(t, "mallory", true)), Fails: true},
+		{RootCAs: ca.pool, ClientCert: ptr(ca.issue(t, "GM", true)), User: "GM"},
+		{RootCAs: ca.pool, ClientCert: ptr(ca.issue(t, "SYS$PRESET", true))},
+		{WebSocket: true, RootCAs: ca.pool},
+		{WebSocket: true, RootCAs: ca.pool, ClientCert: &clientCert, User: "alice"},
+		{WebSocket: true, RootCAs: otherCA.pool, Fails: true},
+	} {
+		tcp, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("test %d: can't listen: %v", i, err)
+		}
+		listener := tls.NewListener(tcp, ca.serverTLSConfig(t))
+		endpoint := tcp.Addr().String()
+		if test.WebSocket {
+			listener = NewWebSocketListener(listener, "/map")
+			endpoint = "wss://" + endpoint + "/map"
+		}
+		accepted := acceptOne(listener)
+
+		client := &Connection{
+			Context:   context.Background(),
+			Endpoint:  endpoint,
+			Timeout:   5 * time.Second,
+			WebSocket: test.WebSocket,
+			TLSConfig: &tls.Config{RootCAs: test.RootCAs},
+		}
+		if test.ClientCert != nil {
+			client.TLSConfig.Certificates = []tls.Certificate{*test.ClientCert}
+		}
+
+		var clientConn net.Conn
+		if test.WebSocket {
+			clientConn, err = client.dialWebSocket()
+		} else {
+			clientConn, err = client.dialTLS(client.Endpoint)
+		}
+		if test.Fails {
+			if err == nil {
+				// TLS 1.3 clients may not learn that the server rejected their
+				// certificate until they try to use the connection.
+				peer := newTestMapConnection(clientConn)
+				if p, err := peer.Receive(); err == nil && p != nil {
+					t.Errorf("test %d: connection should have failed", i)
+				}
+				clientConn.Close()
+			}
+			listener.Close()
+			if serverConn, ok := <-accepted; ok {
+				serverConn.Close()
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("test %d: can't dial: %v", i, err)
+		}
+
+		serverConn, ok := <-accepted
+		if !ok {
+			t.Fatalf("test %d: no connection accepted", i)
+		}
+		server := ClientConnection{
+			Conn:            newTestMapConnection(serverConn),
+			CertificateUser: CertificateUsername,
+		}
+		if user := server.certificateUser(context.Background()); user != test.User {
+			t.Errorf("test %d: certificate user is %q, expected %q", i, user, test.User)
+		}
+
+		peer := newTestMapConnection(clientConn)
+		if err := peer.Send(Echo, EchoMessagePayload{S: "over TLS", I: i}); err != nil {
+			t.Fatalf("test %d: send: %v", i, err)
+		}
+		if err := peer.Flush(); err != nil {
+			t.Fatalf("test %d: flush: %v", i, err)
+		}
+		checkWebSocketEcho(t, &server.Conn, i, "over TLS")
+
+		clientConn.Close()
+		serverConn.Close()
+		listener.Close()
+	}
+}
+
+// loginTestServer is just enough of a MapServer to log clients in.
+type loginTestServer struct {
+	allowed []PackageUpdate
+}
+
+func (s *loginTestServer) Log(...any)                                            {}
+func (s *loginTestServer) Logf(string, ...any)                                   {}
+func (s *loginTestServer) GetPersonalCredentials(string) []byte                  { return nil }
+func (s *loginTestServer) GetClientPreamble() *ClientPreamble                    { return &ClientPreamble{} }
+func (s *loginTestServer) HandleServerMessage(MessagePayload, *ClientConnection) {}
+func (s *loginTestServer) AddClient(*ClientConnection)                           {}
+func (s *loginTestServer) RemoveClient(*ClientConnection)                        {}
+func (s *loginTestServer) SendGameState(*ClientConnection)                       {}
+func (s *loginTestServer) GetAllowedClients() []PackageUpdate                    { return s.allowed }
+
+func TestCertificateLogin(t *testing.T) {
+	ca := newTestCA(t)
+	server := &loginTestServer{allowed: []PackageUpdate{{
+		Name:           "mapper",
+		VersionPattern: `^mapper(?: ([\d.]+))?$`,
+		VersionRegex:   regexp.MustCompile(`^mapper(?: ([\d.]+))?$`),
+		MinimumVersion: "4.2.0",
+	}}}
+
+	for i, test := range []struct {
+		User   string
+		Client string
+		Denied bool
+	}{
+		{User: "alice", Client: "mapper 4.3.1"},
+		{User: "GM", Client: "mapper 4.2.0"},
+		{User: "alice", Client: "mapper 4.1.9", Denied: true},
+		{User: "alice", Client: "mapper", Denied: true},
+		{User: "alice", Client: "some other client"},
+	} {
+		tcp, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("test %d: can't listen: %v", i, err)
+		}
+		accepted := acceptOne(tls.NewListener(tcp, ca.serverTLSConfig(t)))
+
+		loginDone := make(chan error, 1)
+		var serverSide ClientConnection
+		go func() {
+			conn, ok := <-accepted
+			if !ok {
+				close(loginDone)
+				return
+			}
+			var err error
+			serverSide, err = NewClientConnection(conn, WithServer(server), WithClientCertificateUsers(CertificateUsername))
+			if err != nil {
+				loginDone <- err
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			serverSide.loginClient(ctx, loginDone, time.Now(), time.Now())
+		}()
+
+		client, err := NewConnection(tcp.Addr().String(),
+			WithLogger(nil),
+			WithTimeout(5*time.Second),
+			WithAuthenticator(auth.NewClientAuthenticator("bob", nil, test.Client)),
+			WithTLSConfig(&tls.Config{
+				RootCAs:      ca.pool,
+				Certificates: []tls.Certificate{ca.issue(t, test.User, true)},
+			}),
+		)
+		if err != nil {
+			t.Fatalf("test %d: can't create connection: %v", i, err)
+		}
+		err = client.tryConnect()
+		serverErr := <-loginDone
+
+		if test.Denied {
+			if !errors.Is(err, ErrAuthenticationFailed) {
+				t.Errorf("test %d: client login returned %v, expected it to be denied", i, err)
+			}
+			if serverErr == nil {
+				t.Errorf("test %d: server allowed client %q to log in", i, test.Client)
+			}
+		} else {
+			if err != nil || serverErr != nil {
+				t.Errorf("test %d: login failed: client %v, server %v", i, err, serverErr)
+			} else {
+				if client.Authenticator.Username != test.User {
+					t.Errorf("test %d: client was granted access as %q, expected %q", i, client.Authenticator.Username, test.User)
+				}
+				if serverSide.Auth.Username != test.User || serverSide.Auth.Client != test.Client || serverSide.Auth.GmMode != (test.User == "GM") {
+					t.Errorf("test %d: server logged client in as %+v", i, serverSide.Auth)
+				}
+			}
+		}
+
+		client.Close()
+		serverSide.Close()
+		tcp.Close()
+	}
+}
+
+func TestCertificateUserWithoutTLS(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	c := ClientConnection{
+		Conn:            newTestMapConnection(server),
+		CertificateUser: CertificateUsername,
+	}
+	if user := c.certificateUser(context.Background()); user != "" {
+		t.Errorf("certificate user over plain connection is %q, expected none", user)
+	}
+}
+
+func TestWebSocketSchemeRequiresTLS(t *testing.T) {
+	c := &Connection{
+		Context:   context.Background(),
+		Endpoint:  "ws://127.0.0.1:1/",
+		WebSocket: true,
+		TLSConfig: &tls.Config{},
+	}
+	if conn, err := c.dialWebSocket(); err == nil {
+		conn.Close()
+		t.Errorf("ws:// endpoint with TLS configuration should have failed")
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,217 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package mapper
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+//
+// WebSocket transport.
+//
+// Browser-based clients can't open plain TCP sockets, so the server may also
+// accept connections over WebSockets. The same line-oriented messages are sent
+// in both directions as text frames: each line (including its terminating newline)
+// is sent in one or more frames, and a client should read the incoming frames as
+// a single stream of text, splitting it into messages at the newlines.
+//
+// Since a WebSocket connection works just like any other net.Conn once it's
+// established, a MapConnection doesn't need to know which kind it has.
+//
+
+// DefaultWebSocketOrigin is the origin reported to the server when connecting
+// over a WebSocket, if no other was specified with WithWebSocket.
+const DefaultWebSocketOrigin = "http://localhost/"
+
+// webSocketListener is a net.Listener which accepts client connections
+// over WebSockets.
+type webSocketListener struct {
+	listener  net.Listener
+	server    *http.Server
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// ListenWebSocket listens for clients connecting over WebSockets at the given
+// TCP address ("[host]:port") and URL path (e.g., "/map"). Each incoming client
+// is returned by the Accept method of the returned listener, exactly as a
+// client connecting over a plain TCP socket would be by net.Listen's listener,
+// so they may be handed to NewClientConnection in the same way.
+func ListenWebSocket(address, path string) (net.Listener, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	return NewWebSocketListener(listener, path), nil
+}
+
+// NewWebSocketListener is like ListenWebSocket, but accepts WebSocket
+// connections on an existing listener.
+func NewWebSocketListener(listener net.Listener, path string) net.Listener {
+	l := &webSocketListener{
+		listener: listener,
+		conns:    make(chan net.Conn),
+		done:     make(chan struct{}),
+	}
+	if path == "" {
+		path = "/"
+	}
+	mux := http.NewServeMux()
+	// We don't check the client's origin, since browser-based clients may be served
+	// from anywhere; clients must still authenticate as usual once connected.
+	mux.Handle(path, websocket.Server{Handler: l.serve})
+	l.server = &http.Server{Handler: mux, ReadHeaderTimeout: 30 * time.Second}
+	go l.server.Serve(listener)
+	return l
+}
+
+// serve hands a new WebSocket connection over to Accept, then waits for it to
+// be closed, since the connection ends when this handler returns.
+func (l *webSocketListener) serve(ws *websocket.Conn) {
+	conn := &webSocketConn{Conn: ws, closed: make(chan struct{})}
+	if r := ws.Request(); r != nil {
+		conn.remote = webSocketAddr(r.RemoteAddr)
+	}
+	select {
+	case l.conns <- conn:
+		select {
+		case <-conn.closed:
+		case <-l.done:
+		}
+	case <-l.done:
+	}
+	ws.Close()
+}
+
+// Accept waits for the next client to connect.
+func (l *webSocketListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close stops listening for new connections, and closes any
+// WebSocket connections still open.
+func (l *webSocketListener) Close() error {
+	err := net.ErrClosed
+	l.closeOnce.Do(func() {
+		close(l.done)
+		err = l.server.Close()
+	})
+	return err
+}
+
+// Addr returns the listener's network address.
+func (l *webSocketListener) Addr() net.Addr {
+	return l.listener.Addr()
+}
+
+// webSocketConn is a server-side WebSocket connection which lets its
+// handler know when it has been closed.
+type webSocketConn struct {
+	*websocket.Conn
+	remote    net.Addr
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *webSocketConn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() { close(c.closed) })
+	return err
+}
+
+// RemoteAddr returns the client's network address. (The underlying
+// websocket.Conn reports the client's origin instead.)
+func (c *webSocketConn) RemoteAddr() net.Addr {
+	if c.remote == nil {
+		return c.Conn.RemoteAddr()
+	}
+	return c.remote
+}
+
+// ConnectionState reports the state of the TLS connection underlying
+// the WebSocket, if there is one.
+func (c *webSocketConn) ConnectionState() tls.ConnectionState {
+	if r := c.Request(); r != nil && r.TLS != nil {
+		return *r.TLS
+	}
+	return tls.ConnectionState{}
+}
+
+// webSocketAddr is the network address of a WebSocket client.
+type webSocketAddr string
+
+func (a webSocketAddr) Network() string { return "websocket" }
+func (a webSocketAddr) String() string  { return string(a) }
+
+// dialWebSocket connects to the server's WebSocket URL given as the endpoint.
+func (c *Connection) dialWebSocket() (net.Conn, error) {
+	origin := c.WebSocketOrigin
+	if origin == "" {
+		origin = DefaultWebSocketOrigin
+	}
+	config, err := websocket.NewConfig(c.Endpoint, origin)
+	if err != nil {
+		return nil, err
+	}
+	var conn net.Conn
+	switch config.Location.Scheme {
+	case "ws":
+		if c.TLSConfig != nil {
+			return nil, fmt.Errorf("TLS was requested but the WebSocket URL scheme is \"ws\" (use \"wss\" instead)")
+		}
+		conn, err = c.dialTCP(webSocketAddress(config, "80"))
+	case "wss":
+		conn, err = c.dialTLS(webSocketAddress(config, "443"))
+	default:
+		return nil, fmt.Errorf("unsupported WebSocket URL scheme \"%s\" (must be \"ws\" or \"wss\")", config.Location.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(c.Timeout))
+	}
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return ws, nil
+}
+
+// webSocketAddress returns the host and port to which we connect for a WebSocket URL.
+func webSocketAddress(config *websocket.Config, defaultPort string) string {
+	if config.Location.Port() == "" {
+		return net.JoinHostPort(config.Location.Hostname(), defaultPort)
+	}
+	return config.Location.Host
+}
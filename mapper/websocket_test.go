@@ -0,0 +1,160 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+//
+// Unit tests for the WebSocket transport.
+//
+
+package mapper
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebSocketTransport(t *testing.T) {
+	listener, err := ListenWebSocket("127.0.0.1:0", "/map")
+	if err != nil {
+		t.Fatalf("can't listen: %v", err)
+	}
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Errorf("accept: %v", err)
+			close(accepted)
+			return
+		}
+		accepted <- conn
+	}()
+
+	client := &Connection{
+		Context:  context.Background(),
+		Endpoint: "ws://" + listener.Addr().String() + "/map",
+		Timeout:  5 * time.Second,
+	}
+	clientConn, err := client.dialWebSocket()
+	if err != nil {
+		t.Fatalf("can't dial: %v", err)
+	}
+	defer clientConn.Close()
+
+	serverConn, ok := <-accepted
+	if !ok {
+		t.Fatal("no connection accepted")
+	}
+	defer serverConn.Close()
+
+	if network := serverConn.RemoteAddr().Network(); network != "websocket" {
+		t.Errorf("remote address network is %q, expected \"websocket\"", network)
+	}
+	if host, _, err := net.SplitHostPort(serverConn.RemoteAddr().String()); err != nil || host != "127.0.0.1" {
+		t.Errorf("remote address is %q, expected the client's address on 127.0.0.1 (%v)", serverConn.RemoteAddr(), err)
+	}
+
+	server := newTestMapConnection(serverConn)
+	peer := newTestMapConnection(clientConn)
+
+	for i, text := range []string{
+		"hello, world",
+		"",
+		strings.Repeat("the quick brown fox jumps over the lazy dog ", 500),
+	} {
+		if err := server.Send(Echo, EchoMessagePayload{S: text, I: i}); err != nil {
+			t.Fatalf("test %d: server send: %v", i, err)
+		}
+		if err := server.Flush(); err != nil {
+			t.Fatalf("test %d: server flush: %v", i, err)
+		}
+		checkWebSocketEcho(t, &peer, i, text)
+
+		if err := peer.Send(Echo, EchoMessagePayload{S: text, I: i}); err != nil {
+			t.Fatalf("test %d: client send: %v", i, err)
+		}
+		if err := peer.Flush(); err != nil {
+			t.Fatalf("test %d: client flush: %v", i, err)
+		}
+		checkWebSocketEcho(t, &server, i, text)
+	}
+
+	// the server should see the end of the stream when the client hangs up
+	clientConn.Close()
+	if p, err := server.Receive(); p != nil || err != nil {
+		t.Errorf("expected end of stream after client closed, got %v, %v", p, err)
+	}
+}
+
+func newTestMapConnection(conn net.Conn) MapConnection {
+	c := NewMapConnection(conn)
+	c.debug = func(DebugFlags, string) {}
+	c.debugf = func(DebugFlags, string, ...any) {}
+	return c
+}
+
+func checkWebSocketEcho(t *testing.T, c *MapConnection, i int, text string) {
+	t.Helper()
+	p, err := c.Receive()
+	if err != nil {
+		t.Fatalf("test %d: receive: %v", i, err)
+	}
+	echo, ok := p.(EchoMessagePayload)
+	if !ok {
+		t.Fatalf("test %d: received %T, expected EchoMessagePayload", i, p)
+	}
+	if echo.S != text || echo.I != i {
+		t.Errorf("test %d: received %q (%d), expected %q (%d)", i, echo.S, echo.I, text, i)
+	}
+}
+
+func TestWebSocketDialErrors(t *testing.T) {
+	for i, endpoint := range []string{
+		"http://localhost:2323/",
+		"localhost:2323",
+	} {
+		c := &Connection{Context: context.Background(), Endpoint: endpoint}
+		if conn, err := c.dialWebSocket(); err == nil {
+			conn.Close()
+			t.Errorf("test %d: dialing %q should have failed", i, endpoint)
+		}
+	}
+}
+
+func TestWebSocketListenerClose(t *testing.T) {
+	listener, err := ListenWebSocket("127.0.0.1:0", "")
+	if err != nil {
+		t.Fatalf("can't listen: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := listener.Accept()
+		done <- err
+	}()
+	listener.Close()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("Accept returned a connection after Close")
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Accept still waiting after Close")
+	}
+}
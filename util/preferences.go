@@ -188,6 +188,13 @@ type ServerProfile struct {
 	ScpServer    string `json:"scp_server,omitempty"`
 	ScpProxy     string `json:"scp_proxy,omitempty"`
 	SshPath      string `json:"ssh_path,omitempty"`
+
+	// TLS settings for the connection to the server (see TLSConfig).
+	TLS           bool     `json:"tls,omitempty"`
+	TLSPins       []string `json:"tls_pins,omitempty"`
+	TLSCACert     string   `json:"tls_ca_cert,omitempty"`
+	TLSClientCert string   `json:"tls_client_cert,omitempty"`
+	TLSClientKey  string   `json:"tls_client_key,omitempty"`
 }
 
 // FontWeight is the set of valid font weight values
@@ -627,6 +634,11 @@ func DefaultPreferences() UserPreferences {
 			DieRolls: DieRollStyles{
 				CompactRecents: false,
 				Components: map[string]DieRollComponent{
+					"attack": DieRollComponent{
+						FG:       ColorSet{Dark: "cyan", Light: "blue"},
+						FontName: "Special",
+						Format:   "Attack #%s: ",
+					},
 					"begingroup": DieRollComponent{
 						FontName: "Normal",
 					},
@@ -653,15 +665,34 @@ func DefaultPreferences() UserPreferences {
 						FontName: "Special",
 						Format:   "Confirm: ",
 					},
+					"critdamage": DieRollComponent{
+						FG:       ColorSet{Dark: "#fffb00", Light: "#f05b00"},
+						FontName: "Special",
+						Format:   "Critical %s: ",
+					},
 					"critspec": DieRollComponent{
 						FG:       ColorSet{Dark: "#fffb00", Light: "#f05b00"},
 						FontName: "Special",
 					},
+					"damage": DieRollComponent{
+						FG:       ColorSet{Dark: "#fffb00", Light: "#f05b00"},
+						FontName: "Special",
+						Format:   "Damage: ",
+					},
 					"dc": DieRollComponent{
 						FG:       ColorSet{Dark: "#aaaaaa", Light: "#888888"},
 						FontName: "Special",
 						Format:   "DC %s: ",
 					},
+					"degree": DieRollComponent{
+						FG:       ColorSet{Dark: "#00fa92", Light: "green"},
+						FontName: "Important",
+						Format:   " (%s)",
+					},
+					"degrees": DieRollComponent{
+						FG:       ColorSet{Dark: "#aaaaaa", Light: "#888888"},
+						FontName: "Special",
+					},
 					"diebonus": DieRollComponent{
 						FG:       ColorSet{Dark: "red", Light: "red"},
 						FontName: "Special",
@@ -691,10 +722,25 @@ func DefaultPreferences() UserPreferences {
 						FontName: "Important",
 						Format:   "(%s)",
 					},
+					"faces": DieRollComponent{
+						FG:       ColorSet{Dark: "#aaaaaa", Light: "#888888"},
+						FontName: "Special",
+						Format:   "[%s]",
+					},
 					"from": DieRollComponent{
 						FG:       ColorSet{Dark: "cyan", Light: "blue"},
 						FontName: "Normal",
 					},
+					"full": DieRollComponent{
+						FG:       ColorSet{Dark: "#aaaaaa", Light: "#888888"},
+						FontName: "Special",
+						Format:   "full %s",
+					},
+					"fullattack": DieRollComponent{
+						FG:       ColorSet{Dark: "cyan", Light: "blue"},
+						FontName: "Special",
+						Format:   "Full attack (%s attacks): ",
+					},
 					"fullmax": DieRollComponent{
 						FG:       ColorSet{Dark: "red", Light: "red"},
 						FontName: "Important",
@@ -759,6 +805,16 @@ func DefaultPreferences() UserPreferences {
 					"operator": DieRollComponent{
 						FontName: "Normal",
 					},
+					"outcome": DieRollComponent{
+						FG:       ColorSet{Dark: "#00fa92", Light: "green"},
+						FontName: "Important",
+						Format:   "[%s]",
+					},
+					"preset": DieRollComponent{
+						FG:       ColorSet{Dark: "#aaaaaa", Light: "#888888"},
+						FontName: "Special",
+						Format:   " [@%s]",
+					},
 					"repeat": DieRollComponent{
 						FG:       ColorSet{Dark: "#aaaaaa", Light: "#888888"},
 						FontName: "Special",
@@ -801,10 +857,25 @@ func DefaultPreferences() UserPreferences {
 						FontName: "Important",
 						Format:   "(%s) ",
 					},
+					"suppressed": DieRollComponent{
+						FG:       ColorSet{Dark: "#aaaaaa", Light: "#888888"},
+						FontName: "Special",
+						Format:   " (%s)",
+					},
 					"system": DieRollComponent{
 						FG:       ColorSet{Dark: "cyan", Light: "blue"},
 						FontName: "System",
 					},
+					"table": DieRollComponent{
+						FG:       ColorSet{Dark: "cyan", Light: "blue"},
+						FontName: "Special",
+						Format:   "%s: ",
+					},
+					"tableentry": DieRollComponent{
+						FG:       ColorSet{Dark: "#00fa92", Light: "green"},
+						FontName: "Important",
+						Format:   " → %s",
+					},
 					"title": DieRollComponent{
 						FG:       ColorSet{Dark: "#aaaaaa", Light: "#ffffff"},
 						BG:       ColorSet{Dark: "#000044", Light: "#c7c0ae"},
@@ -824,6 +895,11 @@ func DefaultPreferences() UserPreferences {
 						FontName: "Special",
 						Format:   "until %s",
 					},
+					"variable": DieRollComponent{
+						FG:       ColorSet{Dark: "cyan", Light: "blue"},
+						FontName: "Special",
+						Format:   " ($%s)",
+					},
 					"worst": DieRollComponent{
 						FG:       ColorSet{Dark: "#aaaaaa", Light: "#888888"},
 						FontName: "Special",
@@ -1006,6 +1082,20 @@ func (prefs *UserPreferences) UpdateFromSimpleConfig(profileName string, cfg Sim
 			prefs.Profiles[profile].ChatLog = v
 		case "username", "u":
 			prefs.Profiles[profile].UserName = v
+		case "tls":
+			prefs.Profiles[profile].TLS = true
+		case "tls-pin":
+			prefs.Profiles[profile].TLS = true
+			prefs.Profiles[profile].TLSPins = append(prefs.Profiles[profile].TLSPins, strings.Split(v, ",")...)
+		case "tls-ca-cert":
+			prefs.Profiles[profile].TLS = true
+			prefs.Profiles[profile].TLSCACert = v
+		case "tls-client-cert":
+			prefs.Profiles[profile].TLS = true
+			prefs.Profiles[profile].TLSClientCert = v
+		case "tls-client-key":
+			prefs.Profiles[profile].TLS = true
+			prefs.Profiles[profile].TLSClientKey = v
 		case "proxy-url", "x":
 			prefs.Profiles[profile].CurlProxy = v
 		case "proxy-host", "X":
@@ -0,0 +1,152 @@
+/*
+########################################################################################
+#  __                                                                                  #
+# /__ _                                                                                #
+# \_|(_)                                                                               #
+#  _______  _______  _______             _______     ______   ______      _______      #
+# (  ____ \(       )(  ___  ) Game      (  ____ \   / ___  \ / ___  \    (  __   )     #
+# | (    \/| () () || (   ) | Master's  | (    \/   \/   \  \\/   \  \   | (  )  |     #
+# | |      | || || || (___) | Assistant | (____        ___) /   ___) /   | | /   |     #
+# | | ____ | |(_)| ||  ___  | (Go Port) (_____ \      (___ (   (___ (    | (/ /) |     #
+# | | \_  )| |   | || (   ) |                 ) )         ) \      ) \   |   / | |     #
+# | (___) || )   ( || )   ( |           /\____) ) _ /\___/  //\___/  / _ |  (__) |     #
+# (_______)|/     \||/     \|           \______/ (_)\______/ \______/ (_)(_______)     #
+#                                                                                      #
+########################################################################################
+*/
+
+package util
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CertificateFingerprint returns the SHA-256 fingerprint of a certificate
+// as a string of hex digits, which may be used as a pin in
+// ServerProfile.TLSPins.
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeFingerprint puts a fingerprint in the form returned by
+// CertificateFingerprint, allowing the user to write them in uppercase
+// and/or with colons between the bytes, as many tools print them.
+func normalizeFingerprint(f string) string {
+	return strings.ToLower(strings.NewReplacer(":", "", " ", "").Replace(strings.TrimSpace(f)))
+}
+
+// TLSConfig returns the TLS configuration for connecting to the server
+// described by the profile, or nil if the profile doesn't call for TLS.
+//
+// If any TLSPins are given, the server's certificate is accepted only if its
+// fingerprint (see CertificateFingerprint) matches one of them. This
+// allows self-signed server certificates to be trusted. Otherwise, the
+// certificate must be signed by the certificate authority whose certificate
+// is in the TLSCACert file (or, if there isn't one, by one of the system's
+// trusted authorities).
+//
+// If TLSClientCert and TLSClientKey name a certificate and key file, we offer
+// that certificate to the server, so it may log us in with that instead of
+// a password.
+func (p ServerProfile) TLSConfig() (*tls.Config, error) {
+	if !p.TLS && len(p.TLSPins) == 0 && p.TLSCACert == "" && p.TLSClientCert == "" {
+		return nil, nil
+	}
+	config := &tls.Config{
+		ServerName: p.Host,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if p.TLSCACert != "" {
+		pem, err := os.ReadFile(p.TLSCACert)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", p.TLSCACert)
+		}
+	}
+
+	if p.TLSClientCert != "" || p.TLSClientKey != "" {
+		if p.TLSClientCert == "" || p.TLSClientKey == "" {
+			return nil, fmt.Errorf("TLS client certificate and key must be given together")
+		}
+		cert, err := tls.LoadX509KeyPair(p.TLSClientCert, p.TLSClientKey)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(p.TLSPins) > 0 {
+		pins := make(map[string]bool)
+		for _, pin := range p.TLSPins {
+			if pin = normalizeFingerprint(pin); pin != "" {
+				pins[pin] = true
+			}
+		}
+		// The pins take the place of the usual chain of trust, so we turn that off
+		// and check the certificate ourselves.
+		config.InsecureSkipVerify = true
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return fmt.Errorf("server did not present a certificate")
+			}
+			fingerprint := CertificateFingerprint(state.PeerCertificates[0])
+			if !pins[fingerprint] {
+				return fmt.Errorf("server certificate fingerprint %s does not match any pinned certificate", fingerprint)
+			}
+			return nil
+		}
+	}
+	return config, nil
+}
+
+// @[00]@| Go-GMA 5.33.0
+// @[01]@|
+// @[10]@| Overall GMA package Copyright © 1992–2026 by Steven L. Willoughby (AKA MadScienceZone)
+// @[11]@| steve@madscience.zone (previously AKA Software Alchemy),
+// @[12]@| Aloha, Oregon, USA. All Rights Reserved. Some components were introduced at different
+// @[13]@| points along that historical time line.
+// @[14]@| Distributed under the terms and conditions of the BSD-3-Clause
+// @[15]@| License as described in the accompanying LICENSE file distributed
+// @[16]@| with GMA.
+// @[17]@|
+// @[20]@| Redistribution and use in source and binary forms, with or without
+// @[21]@| modification, are permitted provided that the following conditions
+// @[22]@| are met:
+// @[23]@| 1. Redistributions of source code must retain the above copyright
+// @[24]@|    notice, this list of conditions and the following disclaimer.
+// @[25]@| 2. Redistributions in binary form must reproduce the above copy-
+// @[26]@|    right notice, this list of conditions and the following dis-
+// @[27]@|    claimer in the documentation and/or other materials provided
+// @[28]@|    with the distribution.
+// @[29]@| 3. Neither the name of the copyright holder nor the names of its
+// @[30]@|    contributors may be used to endorse or promote products derived
+// @[31]@|    from this software without specific prior written permission.
+// @[32]@|
+// @[33]@| THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+// @[34]@| CONTRIBUTORS “AS IS” AND ANY EXPRESS OR IMPLIED WARRANTIES,
+// @[35]@| INCLUDING, BUT NOT LIMITED TO, THE IMPLIED WARRANTIES OF
+// @[36]@| MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+// @[37]@| DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS
+// @[38]@| BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY,
+// @[39]@| OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+// @[40]@| PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR
+// @[41]@| PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// @[42]@| THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR
+// @[43]@| TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF
+// @[44]@| THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF
+// @[45]@| SUCH DAMAGE.
+// @[46]@|
+// @[50]@| This software is not intended for any use or application in which
+// @[51]@| the safety of lives or property would be at risk due to failure or
+// @[52]@| defect of the software.